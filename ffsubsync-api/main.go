@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -24,6 +29,151 @@ import (
 type SyncRequest struct {
 	Video    string `json:"video"`
 	Subtitle string `json:"subtitle"`
+
+	// Optional acceptance thresholds. A zero value disables the check.
+	MaxOffsetSeconds float64 `json:"max_offset_seconds,omitempty"`
+	MinScore         float64 `json:"min_score,omitempty"`
+
+	// Optional reference to align against instead of the video's audio. Reference is the
+	// path to a well-timed subtitle file; ReferenceStream is the index of an embedded
+	// subtitle stream in the video, counted like ffmpeg's "s:N" specifier. Reference wins
+	// if both are set.
+	Reference       string `json:"reference,omitempty"`
+	ReferenceStream *int   `json:"reference_stream,omitempty"`
+}
+
+// SyncResult describes the outcome of a sync. When Accepted is false the
+// original subtitle is left untouched. Metrics ffsubsync didn't report are null.
+type SyncResult struct {
+	Message              string   `json:"message"`
+	Accepted             bool     `json:"accepted"`
+	Reason               string   `json:"reason,omitempty"`
+	OffsetSeconds        *float64 `json:"offset_seconds"`
+	FramerateScaleFactor *float64 `json:"framerate_scale_factor"`
+	Score                *float64 `json:"score"`
+	Backup               string   `json:"backup,omitempty"`
+	Reference            string   `json:"reference"`
+}
+
+var (
+	offsetRegex    = regexp.MustCompile(`offset seconds:\s*(-?[\d.]+)`)
+	framerateRegex = regexp.MustCompile(`framerate scale factor:\s*(-?[\d.]+)`)
+	scoreRegex     = regexp.MustCompile(`score:\s*(-?[\d.]+)`)
+)
+
+// parseSyncOutput extracts the offset, framerate ratio and score that ffsubsync logs.
+// Metrics missing from the output, or that don't parse, are left nil rather than 0.
+func parseSyncOutput(output string, result *SyncResult) {
+	parse := func(re *regexp.Regexp) *float64 {
+		if m := re.FindStringSubmatch(output); len(m) > 1 {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return &v
+			}
+		}
+		return nil
+	}
+	result.OffsetSeconds = parse(offsetRegex)
+	result.FramerateScaleFactor = parse(framerateRegex)
+	result.Score = parse(scoreRegex)
+}
+
+// evaluateSync checks the sync metrics against the request thresholds and
+// returns a rejection reason, or "" if the sync is acceptable. A metric
+// ffsubsync didn't report can't be checked, so it doesn't reject the sync;
+// uncheckedMetrics lists those.
+func evaluateSync(req *SyncRequest, result *SyncResult) string {
+	if req.MaxOffsetSeconds > 0 && result.OffsetSeconds != nil && math.Abs(*result.OffsetSeconds) > req.MaxOffsetSeconds {
+		return fmt.Sprintf("offset %.3fs exceeds limit of %.3fs", *result.OffsetSeconds, req.MaxOffsetSeconds)
+	}
+	if req.MinScore > 0 && result.Score != nil && *result.Score < req.MinScore {
+		return fmt.Sprintf("score %.3f is below minimum of %.3f", *result.Score, req.MinScore)
+	}
+	return ""
+}
+
+// uncheckedMetrics returns the thresholds evaluateSync couldn't apply because
+// ffsubsync didn't report the metric.
+func uncheckedMetrics(req *SyncRequest, result *SyncResult) []string {
+	var unchecked []string
+	if req.MaxOffsetSeconds > 0 && result.OffsetSeconds == nil {
+		unchecked = append(unchecked, "max_offset_seconds")
+	}
+	if req.MinScore > 0 && result.Score == nil {
+		unchecked = append(unchecked, "min_score")
+	}
+	return unchecked
+}
+
+// metric returns a metric for logging, or nil when it's unknown.
+func metric(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// buildSyncArgs returns the ffsubsync arguments for the request along with a
+// description of what the subtitle is being aligned against.
+func buildSyncArgs(req *SyncRequest, tempPath string) ([]string, string) {
+	switch {
+	case req.Reference != "":
+		return []string{req.Reference, "-i", req.Subtitle, "-o", tempPath}, "subtitle:" + filepath.Base(req.Reference)
+	case req.ReferenceStream != nil:
+		stream := fmt.Sprintf("s:%d", *req.ReferenceStream)
+		return []string{req.Video, "-i", req.Subtitle, "--reference-stream", stream, "-o", tempPath}, "stream:" + stream
+	default:
+		return []string{req.Video, "-i", req.Subtitle, "-o", tempPath}, "audio"
+	}
+}
+
+// backupPath returns where the pre-sync original of a subtitle is kept.
+func backupPath(subtitlePath string) string {
+	return subtitlePath + ".orig"
+}
+
+// backUpOriginal copies a subtitle to its backup before it's replaced with the synced
+// version, and returns the backup's path. A backup is kept across repeated syncs so they
+// can still be reverted, unless the subtitle was written after the last sync, like a newly
+// downloaded one; markSynced stamps both files so that can be told apart.
+func backUpOriginal(subtitlePath string) (string, error) {
+	backup := backupPath(subtitlePath)
+	subtitle, err := os.Stat(subtitlePath)
+	if err != nil {
+		return backup, err
+	}
+	if previous, err := os.Stat(backup); err == nil && !subtitle.ModTime().After(previous.ModTime()) {
+		return backup, nil
+	}
+	return backup, copyFile(subtitlePath, backup)
+}
+
+// markSynced gives a synced subtitle and its backup the same modification time, which
+// backUpOriginal compares against on the next sync.
+func markSynced(subtitlePath, backup string) error {
+	now := time.Now()
+	if err := os.Chtimes(backup, now, now); err != nil {
+		return err
+	}
+	return os.Chtimes(subtitlePath, now, now)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
 }
 
 var (
@@ -88,20 +238,27 @@ func main() {
 			return
 		}
 
-		if req.Video == "" || req.Subtitle == "" {
+		if req.Subtitle == "" || (req.Video == "" && req.Reference == "") {
 			http.Error(w, "Missing video or subtitle path", http.StatusBadRequest)
 			return
 		}
 
+		if req.ReferenceStream != nil && *req.ReferenceStream < 0 {
+			http.Error(w, "Invalid reference stream index", http.StatusBadRequest)
+			return
+		}
+
 		// Use the absolute path provided in the request as they are mapped in the same way
 		// between Arrgo and this container via the shared media volume.
 		videoPath := req.Video
 		subtitlePath := req.Subtitle
 
-		if !isPathAllowed(videoPath) || !isPathAllowed(subtitlePath) {
+		if (videoPath != "" && !isPathAllowed(videoPath)) || !isPathAllowed(subtitlePath) ||
+			(req.Reference != "" && !isPathAllowed(req.Reference)) {
 			slog.Warn("Access denied for paths outside of allowed directories",
 				"video_path", videoPath,
-				"subtitle_path", subtitlePath)
+				"subtitle_path", subtitlePath,
+				"reference_path", req.Reference)
 			http.Error(w, "Access denied: paths must be within MOVIES_PATH or SHOWS_PATH", http.StatusForbidden)
 			return
 		}
@@ -110,14 +267,22 @@ func main() {
 		syncMutex.Lock()
 		defer syncMutex.Unlock()
 
+		// ffsubsync <video|reference> -i <subtitle> [--reference-stream s:N] -o <temp>
+		// Output goes to a temp file next to the subtitle (same extension so ffsubsync keeps
+		// the format) and only replaces the original once the result passes the thresholds.
+		// Wrapped in 'nice -n 15' to give it lower CPU priority.
+		ext := filepath.Ext(subtitlePath)
+		tempPath := strings.TrimSuffix(subtitlePath, ext) + ".synctmp" + ext
+		defer os.Remove(tempPath)
+
+		syncArgs, reference := buildSyncArgs(req, tempPath)
+
 		slog.Info("Starting subtitle sync",
 			"video", filepath.Base(videoPath),
-			"subtitle", filepath.Base(subtitlePath))
+			"subtitle", filepath.Base(subtitlePath),
+			"reference", reference)
 
-		// ffsubsync <video> -i <subtitle> -o <subtitle>
-		// We use the same path for input and output, directly overwriting the file.
-		// Wrapped in 'nice -n 15' to give it lower CPU priority.
-		cmd := exec.Command("nice", "-n", "15", "ffsubsync", videoPath, "-i", subtitlePath, "-o", subtitlePath)
+		cmd := exec.Command("nice", append([]string{"-n", "15", "ffsubsync"}, syncArgs...)...)
 
 		output, err := cmd.CombinedOutput()
 		if err != nil {
@@ -127,16 +292,61 @@ func main() {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error":   "Failed to process subtitle",
-				"details": string(output),
+				"error":     "Failed to process subtitle",
+				"details":   string(output),
+				"reference": reference,
 			})
 			return
 		}
 
+		result := &SyncResult{Reference: reference}
+		parseSyncOutput(string(output), result)
+		if unchecked := uncheckedMetrics(req, result); len(unchecked) > 0 {
+			slog.Warn("ffsubsync didn't report a metric, skipping its threshold",
+				"subtitle", filepath.Base(subtitlePath),
+				"thresholds", unchecked)
+		}
+
+		if reason := evaluateSync(req, result); reason != "" {
+			slog.Warn("Rejected subtitle sync, keeping original",
+				"subtitle", filepath.Base(subtitlePath),
+				"reason", reason,
+				"offset_seconds", metric(result.OffsetSeconds),
+				"score", metric(result.Score))
+			result.Message = "rejected"
+			result.Reason = reason
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		backup, err := backUpOriginal(subtitlePath)
+		if err != nil {
+			slog.Error("Failed to back up original subtitle", "subtitle", subtitlePath, "error", err)
+			http.Error(w, "Failed to back up original subtitle", http.StatusInternalServerError)
+			return
+		}
+
+		if err := os.Rename(tempPath, subtitlePath); err != nil {
+			slog.Error("Failed to replace subtitle with synced version", "subtitle", subtitlePath, "error", err)
+			http.Error(w, "Failed to write synced subtitle", http.StatusInternalServerError)
+			return
+		}
+		if err := markSynced(subtitlePath, backup); err != nil {
+			slog.Warn("Failed to mark subtitle as synced, its backup may be replaced on the next sync", "subtitle", subtitlePath, "error", err)
+		}
+
 		slog.Info("Successfully synced subtitle",
-			"subtitle", filepath.Base(subtitlePath))
+			"subtitle", filepath.Base(subtitlePath),
+			"reference", reference,
+			"offset_seconds", metric(result.OffsetSeconds),
+			"framerate_scale_factor", metric(result.FramerateScaleFactor),
+			"score", metric(result.Score))
+		result.Message = "success"
+		result.Accepted = true
+		result.Backup = backup
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"message": "success"})
+		json.NewEncoder(w).Encode(result)
 	})
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEvaluateSync(t *testing.T) {
+	limits := &SyncRequest{MaxOffsetSeconds: 60, MinScore: 100}
+	tests := []struct {
+		name          string
+		output        string
+		req           *SyncRequest
+		wantRejected  bool
+		wantUnchecked int
+	}{
+		{"within limits", "offset seconds: 1.500\nframerate scale factor: 1.000\nscore: 4500.000", limits, false, 0},
+		{"offset too large", "offset seconds: -75.2\nscore: 4500.000", limits, true, 0},
+		{"score too low", "offset seconds: 1.5\nscore: 12.000", limits, true, 0},
+		{"score of zero", "offset seconds: 1.5\nscore: 0.000", limits, true, 0},
+		{"score unknown", "offset seconds: 1.5", limits, false, 1},
+		{"nothing reported", "done", limits, false, 2},
+		{"no limits", "score: 0.000", &SyncRequest{}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &SyncResult{}
+			parseSyncOutput(tt.output, result)
+			if reason := evaluateSync(tt.req, result); (reason != "") != tt.wantRejected {
+				t.Errorf("evaluateSync() = %q, want rejected %v", reason, tt.wantRejected)
+			}
+			if got := uncheckedMetrics(tt.req, result); len(got) != tt.wantUnchecked {
+				t.Errorf("uncheckedMetrics() = %v, want %d", got, tt.wantUnchecked)
+			}
+		})
+	}
+}
+
+func TestParseSyncOutputUnknownMetrics(t *testing.T) {
+	result := &SyncResult{}
+	parseSyncOutput("score: not-a-number", result)
+	if result.Score != nil || result.OffsetSeconds != nil || result.FramerateScaleFactor != nil {
+		t.Errorf("unparseable metrics = %v %v %v, want nil", result.OffsetSeconds, result.FramerateScaleFactor, result.Score)
+	}
+}
+
+func TestBackUpOriginal(t *testing.T) {
+	subtitle := filepath.Join(t.TempDir(), "Movie.en.srt")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(subtitle, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(subtitle, modTime, modTime)
+	}
+	// sync does what the handler does once a sync is accepted
+	sync := func(synced string) {
+		t.Helper()
+		backup, err := backUpOriginal(subtitle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(subtitle, []byte(synced), 0644)
+		if err := markSynced(subtitle, backup); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantBackup := func(want string) {
+		t.Helper()
+		got, err := os.ReadFile(backupPath(subtitle))
+		if err != nil || string(got) != want {
+			t.Errorf("backup = %q (%v), want %q", got, err, want)
+		}
+	}
+
+	write("downloaded", time.Now().Add(-time.Hour))
+	sync("synced")
+	wantBackup("downloaded")
+
+	// Syncing the synced subtitle again keeps the first original
+	sync("synced again")
+	wantBackup("downloaded")
+
+	// A subtitle downloaded after the last sync is the new original
+	write("redownloaded", time.Now().Add(time.Hour))
+	sync("resynced")
+	wantBackup("redownloaded")
+}
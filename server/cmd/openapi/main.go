@@ -0,0 +1,28 @@
+// Command openapi writes the OpenAPI spec for the /api/v1 JSON API. It's run from the server
+// directory by `go generate`, which updates docs/openapi.json.
+package main
+
+import (
+	"Arrgo/handlers"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	out := "../docs/openapi.json"
+	if len(os.Args) > 1 {
+		out = os.Args[1]
+	}
+
+	spec, err := json.MarshalIndent(handlers.OpenAPISpec((&handlers.Handlers{}).APIV1Routes()), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to encode spec:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, append(spec, '\n'), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write spec:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Wrote", out)
+}
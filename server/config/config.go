@@ -8,54 +8,114 @@ import (
 )
 
 type Config struct {
-	DatabaseURL         string
-	SessionSecret       string
-	ServerPort          string
-	Environment         string
-	MoviesPath          string
-	ShowsPath           string
-	IncomingMoviesPath  string
-	IncomingShowsPath   string
-	TMDBAPIKey          string
-	TVDBAPIKey          string
-	OpenSubtitlesAPIKey string
-	OpenSubtitlesUser   string
-	OpenSubtitlesPass   string
-	QBittorrentURL      string
-	QBittorrentUser     string
-	QBittorrentPass     string
-	JellyfinURL         string
-	JellyfinAPIKey      string
-	EnableSubSync       bool
-	SubSyncURL          string
-	Debug               bool
-	LogLevel            string
+	DatabaseURL          string
+	SessionSecret        string
+	ServerPort           string
+	Environment          string
+	MoviesPath           string
+	ShowsPath            string
+	IncomingMoviesPath   string
+	IncomingShowsPath    string
+	ImportMode           string
+	WatchIncoming        bool
+	RecycleBinPath       string
+	RecycleBinDays       int
+	RecycleBinMinFreeGB  float64
+	TMDBAPIKey           string
+	TVDBAPIKey           string
+	OpenSubtitlesAPIKey  string
+	OpenSubtitlesUser    string
+	OpenSubtitlesPass    string
+	QBittorrentURL       string
+	QBittorrentUser      string
+	QBittorrentPass      string
+	AuthMode             string
+	DisableRegistration  bool
+	OIDCIssuer           string
+	OIDCClientID         string
+	OIDCClientSecret     string
+	OIDCRedirectURL      string
+	OIDCProviderName     string
+	OIDCScopes           string
+	OIDCGroupsClaim      string
+	OIDCAdminGroup       string
+	OIDCUserGroup        string
+	MediaServer          string
+	JellyfinURL          string
+	JellyfinAPIKey       string
+	PlexURL              string
+	PlexToken            string
+	PlexMoviesSection    string
+	PlexShowsSection     string
+	AutoRequestSeasons   bool
+	SyncCollections      bool
+	FetchTrailers        bool
+	AutoApprovePartial   bool
+	AutoApproveMaxSizeGB float64
+	EnableSubSync        bool
+	SubSyncURL           string
+	SubSyncMaxOffset     float64
+	SubSyncMinScore      float64
+	SubtitleRemoveHI     bool
+	SubtitleQuotaReserve int
+	Debug                bool
+	LogLevel             string
 }
 
 func Load() *Config {
 	cfg := &Config{
-		DatabaseURL:         config.GetEnv("DATABASE_URL", ""),
-		SessionSecret:       config.GetEnv("SESSION_SECRET", ""),
-		ServerPort:          config.GetEnv("PORT", "5003"),
-		Environment:         config.GetEnv("ENV", "development"),
-		MoviesPath:          config.GetEnv("MOVIES_PATH", "/mnt/movies"),
-		ShowsPath:           config.GetEnv("SHOWS_PATH", "/mnt/shows"),
-		IncomingMoviesPath:  config.GetEnv("INCOMING_MOVIES_PATH", "/mnt/incoming/movies"),
-		IncomingShowsPath:   config.GetEnv("INCOMING_SHOWS_PATH", "/mnt/incoming/shows"),
-		TMDBAPIKey:          config.GetEnv("TMDB_API_KEY", ""),
-		TVDBAPIKey:          config.GetEnv("TVDB_API_KEY", ""),
-		OpenSubtitlesAPIKey: config.GetEnv("OPENSUBTITLES_API_KEY", ""),
-		OpenSubtitlesUser:   config.GetEnv("OPENSUBTITLES_USER", ""),
-		OpenSubtitlesPass:   config.GetEnv("OPENSUBTITLES_PASS", ""),
-		QBittorrentURL:      config.GetEnv("QBITTORRENT_URL", "http://localhost:8080"),
-		QBittorrentUser:     config.GetEnv("QBITTORRENT_USER", ""),
-		QBittorrentPass:     config.GetEnv("QBITTORRENT_PASS", ""),
-		JellyfinURL:         config.GetEnv("JELLYFIN_URL", ""),
-		JellyfinAPIKey:      config.GetEnv("JELLYFIN_API_KEY", ""),
-		EnableSubSync:       config.GetEnv("ENABLE_SUBSYNC", "false") == "true",
-		SubSyncURL:          config.GetEnv("FFSUBSYNC_URL", "http://ffsubsync-api:8080"),
-		Debug:               config.GetEnv("DEBUG", "false") == "true",
-		LogLevel:            config.GetEnv("GOLOG_LOG_LEVEL", config.GetEnv("LOG_LEVEL", "error")),
+		DatabaseURL:          config.GetEnv("DATABASE_URL", ""),
+		SessionSecret:        config.GetEnv("SESSION_SECRET", ""),
+		ServerPort:           config.GetEnv("PORT", "5003"),
+		Environment:          config.GetEnv("ENV", "development"),
+		MoviesPath:           config.GetEnv("MOVIES_PATH", "/mnt/movies"),
+		ShowsPath:            config.GetEnv("SHOWS_PATH", "/mnt/shows"),
+		IncomingMoviesPath:   config.GetEnv("INCOMING_MOVIES_PATH", "/mnt/incoming/movies"),
+		IncomingShowsPath:    config.GetEnv("INCOMING_SHOWS_PATH", "/mnt/incoming/shows"),
+		ImportMode:           config.GetEnv("IMPORT_MODE", "copy"),
+		WatchIncoming:        config.GetEnv("WATCH_INCOMING", "true") == "true",
+		RecycleBinPath:       config.GetEnv("RECYCLE_BIN_PATH", ""),
+		RecycleBinDays:       config.GetEnvInt("RECYCLE_BIN_DAYS", 30),
+		RecycleBinMinFreeGB:  config.GetEnvFloat("RECYCLE_BIN_MIN_FREE_GB", 0),
+		TMDBAPIKey:           config.GetEnv("TMDB_API_KEY", ""),
+		TVDBAPIKey:           config.GetEnv("TVDB_API_KEY", ""),
+		OpenSubtitlesAPIKey:  config.GetEnv("OPENSUBTITLES_API_KEY", ""),
+		OpenSubtitlesUser:    config.GetEnv("OPENSUBTITLES_USER", ""),
+		OpenSubtitlesPass:    config.GetEnv("OPENSUBTITLES_PASS", ""),
+		QBittorrentURL:       config.GetEnv("QBITTORRENT_URL", "http://localhost:8080"),
+		QBittorrentUser:      config.GetEnv("QBITTORRENT_USER", ""),
+		QBittorrentPass:      config.GetEnv("QBITTORRENT_PASS", ""),
+		AuthMode:             config.GetEnv("AUTH_MODE", "local"),
+		DisableRegistration:  config.GetEnv("DISABLE_REGISTRATION", "false") == "true",
+		OIDCIssuer:           config.GetEnv("OIDC_ISSUER", ""),
+		OIDCClientID:         config.GetEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:     config.GetEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:      config.GetEnv("OIDC_REDIRECT_URL", ""),
+		OIDCProviderName:     config.GetEnv("OIDC_PROVIDER_NAME", "SSO"),
+		OIDCScopes:           config.GetEnv("OIDC_SCOPES", "openid profile email"),
+		OIDCGroupsClaim:      config.GetEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCAdminGroup:       config.GetEnv("OIDC_ADMIN_GROUP", ""),
+		OIDCUserGroup:        config.GetEnv("OIDC_USER_GROUP", ""),
+		MediaServer:          config.GetEnv("MEDIA_SERVER", "jellyfin"),
+		JellyfinURL:          config.GetEnv("JELLYFIN_URL", ""),
+		JellyfinAPIKey:       config.GetEnv("JELLYFIN_API_KEY", ""),
+		PlexURL:              config.GetEnv("PLEX_URL", ""),
+		PlexToken:            config.GetEnv("PLEX_TOKEN", ""),
+		PlexMoviesSection:    config.GetEnv("PLEX_MOVIES_SECTION", ""),
+		PlexShowsSection:     config.GetEnv("PLEX_SHOWS_SECTION", ""),
+		AutoRequestSeasons:   config.GetEnv("AUTO_REQUEST_NEXT_SEASON", config.GetEnv("JELLYFIN_AUTO_REQUEST_NEXT_SEASON", "false")) == "true",
+		SyncCollections:      config.GetEnv("MEDIA_SERVER_COLLECTIONS", config.GetEnv("JELLYFIN_COLLECTIONS", "false")) == "true",
+		FetchTrailers:        config.GetEnv("FETCH_TRAILERS", "false") == "true",
+		AutoApprovePartial:   config.GetEnv("AUTO_APPROVE_PARTIAL", "false") == "true",
+		AutoApproveMaxSizeGB: config.GetEnvFloat("AUTO_APPROVE_MAX_SIZE_GB", 0),
+		EnableSubSync:        config.GetEnv("ENABLE_SUBSYNC", "false") == "true",
+		SubSyncURL:           config.GetEnv("FFSUBSYNC_URL", "http://ffsubsync-api:8080"),
+		SubSyncMaxOffset:     config.GetEnvFloat("SUBSYNC_MAX_OFFSET", 60),
+		SubSyncMinScore:      config.GetEnvFloat("SUBSYNC_MIN_SCORE", 0),
+		SubtitleRemoveHI:     config.GetEnv("SUBTITLE_REMOVE_HI", "false") == "true",
+		SubtitleQuotaReserve: config.GetEnvInt("SUBTITLE_QUOTA_RESERVE", 5),
+		Debug:                config.GetEnv("DEBUG", "false") == "true",
+		LogLevel:             config.GetEnv("GOLOG_LOG_LEVEL", config.GetEnv("LOG_LEVEL", "error")),
 	}
 
 	// Validate configuration
@@ -82,3 +142,31 @@ func (c *Config) Validate() error {
 	}
 	return nil
 }
+
+// UseJellyfinAuth reports whether logins are validated against Jellyfin (AUTH_MODE=jellyfin)
+func (c *Config) UseJellyfinAuth() bool {
+	return c.AuthMode == "jellyfin" && c.JellyfinURL != ""
+}
+
+// LinkSeedingImports reports whether files still seeding are hardlinked or reflinked into the
+// library instead of copied (IMPORT_MODE=link)
+func (c *Config) LinkSeedingImports() bool {
+	return c.ImportMode == "link"
+}
+
+// RecycleBinEnabled reports whether replaced and deleted media goes to the recycle bin
+// instead of being deleted (RECYCLE_BIN_PATH)
+func (c *Config) RecycleBinEnabled() bool {
+	return c.RecycleBinPath != ""
+}
+
+// OIDCEnabled reports whether OpenID Connect login is configured
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != "" && c.OIDCClientID != "" && c.OIDCRedirectURL != ""
+}
+
+// RegistrationEnabled reports whether local accounts can be self-registered. Jellyfin sign-in
+// takes its accounts from Jellyfin, so registration is off there too.
+func (c *Config) RegistrationEnabled() bool {
+	return !c.DisableRegistration && !c.UseJellyfinAuth()
+}
@@ -0,0 +1,17 @@
+-- Per-subtitle-file sync results so bad syncs can be reviewed and reverted
+CREATE TABLE IF NOT EXISTS subtitle_syncs (
+    id SERIAL PRIMARY KEY,
+    media_type VARCHAR(20) NOT NULL,
+    media_id INTEGER NOT NULL,
+    subtitle_path TEXT UNIQUE NOT NULL,
+    backup_path TEXT,
+    status VARCHAR(50) NOT NULL,
+    offset_seconds DOUBLE PRECISION DEFAULT 0,
+    framerate_scale_factor DOUBLE PRECISION DEFAULT 0,
+    score DOUBLE PRECISION DEFAULT 0,
+    message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_subtitle_syncs_media ON subtitle_syncs(media_type, media_id);
@@ -0,0 +1,2 @@
+-- What each subtitle was aligned against ("audio", "stream:s:N", "subtitle:<file>")
+ALTER TABLE subtitle_syncs ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
@@ -0,0 +1,4 @@
+-- Priority for the subtitle quota scheduler (higher is processed first)
+ALTER TABLE subtitle_queue ADD COLUMN IF NOT EXISTS priority INTEGER DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_subtitle_queue_priority ON subtitle_queue(priority DESC, created_at);
@@ -0,0 +1,24 @@
+-- Per-user played state imported from Jellyfin
+CREATE TABLE IF NOT EXISTS watch_state (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    media_type VARCHAR(20) NOT NULL,
+    media_id INTEGER NOT NULL,
+    played BOOLEAN DEFAULT FALSE,
+    play_count INTEGER DEFAULT 0,
+    last_played_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, media_type, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_state_media ON watch_state(media_type, media_id);
+
+-- Seasons requested automatically after a user finished the previous one, so each is only requested once
+CREATE TABLE IF NOT EXISTS season_auto_requests (
+    id SERIAL PRIMARY KEY,
+    show_id INTEGER REFERENCES shows(id) ON DELETE CASCADE,
+    season_number INTEGER NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(show_id, season_number)
+);
@@ -0,0 +1,7 @@
+-- Cached mapping from TMDB/TVDB IDs to Jellyfin item IDs for targeted refreshes
+CREATE TABLE IF NOT EXISTS jellyfin_items (
+    provider_id VARCHAR(100) PRIMARY KEY,
+    jellyfin_id VARCHAR(64) NOT NULL,
+    path TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,19 @@
+-- Movie collections mirrored to Jellyfin as BoxSets. TMDB franchise collections have a
+-- tmdb_collection_id and derive their members from movies.raw_metadata; custom collections
+-- are defined by an admin and list their members in collection_movies.
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    tmdb_collection_id INTEGER UNIQUE,
+    poster_path TEXT,
+    uploaded_poster TEXT,
+    jellyfin_id VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS collection_movies (
+    collection_id INTEGER REFERENCES collections(id) ON DELETE CASCADE,
+    movie_id INTEGER REFERENCES movies(id) ON DELETE CASCADE,
+    PRIMARY KEY (collection_id, movie_id)
+);
@@ -0,0 +1,17 @@
+-- Item IDs are now cached per media server (Jellyfin or Plex). The old table only held
+-- lookups that are cheap to redo, so it is replaced rather than migrated.
+DROP TABLE IF EXISTS jellyfin_items;
+
+CREATE TABLE IF NOT EXISTS media_server_items (
+    server VARCHAR(20) NOT NULL,
+    provider_id VARCHAR(100) NOT NULL,
+    item_id VARCHAR(64) NOT NULL,
+    path TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (server, provider_id)
+);
+
+-- Collections remember which server their ID belongs to, so switching servers recreates them
+ALTER TABLE collections RENAME COLUMN jellyfin_id TO media_server_id;
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS media_server VARCHAR(20);
+UPDATE collections SET media_server = 'Jellyfin' WHERE media_server_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Links Arrgo accounts to the Jellyfin user they sign in as when AUTH_MODE=jellyfin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS jellyfin_id VARCHAR(64) UNIQUE;
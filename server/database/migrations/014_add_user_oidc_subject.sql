@@ -0,0 +1,2 @@
+-- Links Arrgo accounts to their OpenID Connect identity (the ID token's "sub" claim)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255) UNIQUE;
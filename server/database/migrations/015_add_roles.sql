@@ -0,0 +1,20 @@
+-- Roles grant permissions to non-admin users; admins (users.is_admin) have every permission.
+-- permissions is a comma-separated list of permission names. Users without a role_id get
+-- the default role.
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    permissions TEXT NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_single_default ON roles (is_default) WHERE is_default;
+
+-- Matches what every user could do before roles existed
+INSERT INTO roles (name, permissions, is_default)
+VALUES ('User', 'request_movies,request_shows,auto_approve', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role_id INTEGER REFERENCES roles(id) ON DELETE SET NULL;
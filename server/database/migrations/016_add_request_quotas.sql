@@ -0,0 +1,23 @@
+-- Request quotas over a rolling 7 days. NULL means unlimited; a user's own quota overrides
+-- their role's.
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS movie_quota INTEGER;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS season_quota INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS movie_quota INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS season_quota INTEGER;
+
+-- One row per request a user submits, including seasons added to an existing request, so
+-- quotas follow the requester rather than whoever opened the request. Denied requests are
+-- refunded by deleting their rows.
+CREATE TABLE IF NOT EXISTS request_usage (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    request_id INTEGER REFERENCES requests(id) ON DELETE SET NULL,
+    media_type VARCHAR(20) NOT NULL,
+    seasons INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_usage_user_created ON request_usage (user_id, created_at);
+
+-- Set once a request awaiting approval has been checked against the auto-approval size budget
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS budget_checked_at TIMESTAMP;
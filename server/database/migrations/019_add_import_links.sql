@@ -0,0 +1,10 @@
+-- How a file got from incoming into the library: 'move', or 'copy', 'hardlink' or 'reflink'
+-- when the original stayed behind for qBittorrent to keep seeding. source_path is that
+-- original, so scans don't pick it up again and seeding cleanup can delete it with the torrent.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS import_method VARCHAR(10);
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS source_path TEXT;
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS import_method VARCHAR(10);
+ALTER TABLE episodes ADD COLUMN IF NOT EXISTS source_path TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_movies_source_path ON movies (source_path);
+CREATE INDEX IF NOT EXISTS idx_episodes_source_path ON episodes (source_path);
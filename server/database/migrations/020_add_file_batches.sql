@@ -0,0 +1,29 @@
+-- Bulk renames and dedupes are planned first and applied after approval. Each operation is
+-- journaled so an applied batch can be undone: moves are reversed, and deleted files are
+-- held in the library's .arrgo-undo folder until the batch is finalized.
+CREATE TABLE IF NOT EXISTS file_batches (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'planned',
+    notes TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    applied_at TIMESTAMP,
+    undone_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS file_operations (
+    id SERIAL PRIMARY KEY,
+    batch_id INTEGER NOT NULL REFERENCES file_batches(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    source_path TEXT NOT NULL,
+    dest_path TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'planned',
+    error TEXT NOT NULL DEFAULT '',
+    -- Library rows removed by a delete, restored when it's undone
+    records JSONB,
+    UNIQUE (batch_id, position)
+);
@@ -0,0 +1,15 @@
+-- Media that's replaced or deleted is moved to the recycle bin (RECYCLE_BIN_PATH) instead of
+-- being removed, and can be restored to its original path until it's purged.
+CREATE TABLE IF NOT EXISTS recycle_bin (
+    id SERIAL PRIMARY KEY,
+    original_path TEXT NOT NULL,
+    recycled_path TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    is_dir BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Library rows removed with the item, put back when it's restored
+    records JSONB,
+    deleted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recycle_bin_deleted_at ON recycle_bin(deleted_at);
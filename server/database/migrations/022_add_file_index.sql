@@ -0,0 +1,14 @@
+-- Fingerprints of scanned media files, so library scans can skip files that haven't changed
+-- since they were last probed and follow files that were moved or renamed (same inode and size).
+CREATE TABLE IF NOT EXISTS file_index (
+    path TEXT PRIMARY KEY,
+    -- movie, episode, or show for show folders
+    media_type TEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    mtime TIMESTAMP NOT NULL,
+    -- 0 where the filesystem doesn't report one
+    inode BIGINT NOT NULL DEFAULT 0,
+    last_probed TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_index_inode ON file_index(inode, size);
@@ -0,0 +1,19 @@
+-- Named library folders per media type, so a library can span several disks. MOVIES_PATH and
+-- SHOWS_PATH are added at startup and stay the defaults until another root is made default.
+CREATE TABLE IF NOT EXISTS root_folders (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    media_type VARCHAR(20) NOT NULL, -- movie or show
+    path TEXT UNIQUE NOT NULL,
+    -- New media is placed in another root of the same type while this one has less free space
+    min_free_gb DOUBLE PRECISION NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_root_folders_single_default ON root_folders (media_type) WHERE is_default;
+
+-- Where a request's media goes; NULL uses the requester's role folder, then the default
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS root_folder_id INTEGER REFERENCES root_folders(id) ON DELETE SET NULL;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS movie_root_folder_id INTEGER REFERENCES root_folders(id) ON DELETE SET NULL;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS show_root_folder_id INTEGER REFERENCES root_folders(id) ON DELETE SET NULL;
@@ -0,0 +1,9 @@
+-- Optional separate 4K library. 4K root folders hold a second copy of movies and shows next
+-- to the standard library, with one default per media type and library.
+ALTER TABLE root_folders ADD COLUMN IF NOT EXISTS is_4k BOOLEAN NOT NULL DEFAULT FALSE;
+
+DROP INDEX IF EXISTS idx_root_folders_single_default;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_root_folders_single_default ON root_folders (media_type, is_4k) WHERE is_default;
+
+-- 4K requests are tracked separately from standard requests for the same media
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS is_4k BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- Movies can be kept in several editions, such as a director's cut next to the theatrical
+-- cut. The edition is parsed from the release name; empty is the regular edition. It's
+-- nullable so rows saved by undo journals before it existed can still be restored.
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS edition TEXT DEFAULT '';
+
+-- Requests can ask for a specific edition of a movie; empty means any
+ALTER TABLE requests ADD COLUMN IF NOT EXISTS edition TEXT NOT NULL DEFAULT '';
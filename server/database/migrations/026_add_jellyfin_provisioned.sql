@@ -0,0 +1,7 @@
+-- Marks accounts created by a Jellyfin sign-in. Only those follow Jellyfin's admin flag;
+-- local accounts linked to Jellyfin keep the admin status set in Arrgo. Accounts created
+-- before this column existed are recognised by their placeholder email.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS jellyfin_provisioned BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET jellyfin_provisioned = TRUE
+WHERE jellyfin_id IS NOT NULL AND email LIKE '%@jellyfin.local' AND NOT jellyfin_provisioned;
@@ -0,0 +1,12 @@
+-- Cached media server items are keyed by path instead of provider ID: the 4K and edition
+-- copies of a movie share a TMDB ID but are separate items. The cache is cheap to rebuild,
+-- so it is recreated rather than migrated.
+DROP TABLE IF EXISTS media_server_items;
+
+CREATE TABLE IF NOT EXISTS media_server_items (
+    server VARCHAR(20) NOT NULL,
+    path TEXT NOT NULL,
+    item_id VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (server, path)
+);
@@ -0,0 +1,4 @@
+-- Inode numbers are only unique within one filesystem, so the file index also records the
+-- device a file is on and only follows moves between files on the same one. 0 where the
+-- filesystem doesn't report one; existing entries fill it in on their next scan.
+ALTER TABLE file_index ADD COLUMN IF NOT EXISTS device BIGINT NOT NULL DEFAULT 0;
@@ -24,7 +24,7 @@ func ScanMoviesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermTriggerScans)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -48,7 +48,7 @@ func ScanShowsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermTriggerScans)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -72,7 +72,7 @@ func ScanIncomingMoviesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermTriggerScans)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -96,7 +96,7 @@ func ScanIncomingShowsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermTriggerScans)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -120,7 +120,7 @@ func StopScanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermTriggerScans)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -145,7 +145,7 @@ func ImportAllMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer importMoviesMutex.Unlock()
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -225,43 +225,6 @@ func ImportAllMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
-func RenameAllLibraryMoviesHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	_, err := RequireAdmin(r)
-	if err != nil {
-		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
-		return
-	}
-
-	cfg := config.Load()
-	allMovies, err := services.GetMovies()
-	if err != nil {
-		slog.Error("Error getting movies for renaming", "error", err)
-		http.Redirect(w, r, "/admin", http.StatusSeeOther)
-		return
-	}
-
-	slog.Info("Starting mass movie renaming", "count", len(allMovies))
-
-	count := 0
-	for _, m := range allMovies {
-		if m.Status == "matched" || m.Status == "ready" {
-			if err := services.RenameAndMoveMovie(cfg, m.ID); err != nil {
-				slog.Error("Error renaming movie", "movie_id", m.ID, "title", m.Title, "error", err)
-			} else {
-				count++
-			}
-		}
-	}
-
-	slog.Info("Mass movie renaming complete", "movies_renamed", count)
-	http.Redirect(w, r, "/admin", http.StatusSeeOther)
-}
-
 func ImportAllShowsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -275,7 +238,7 @@ func ImportAllShowsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer importShowsMutex.Unlock()
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -355,43 +318,6 @@ func ImportAllShowsHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/admin", http.StatusSeeOther)
 }
 
-func RenameAllLibraryShowsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	_, err := RequireAdmin(r)
-	if err != nil {
-		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
-		return
-	}
-
-	cfg := config.Load()
-	allShows, err := services.GetShows()
-	if err != nil {
-		slog.Error("Error getting shows for renaming", "error", err)
-		http.Redirect(w, r, "/admin", http.StatusSeeOther)
-		return
-	}
-
-	slog.Info("Starting mass show renaming", "count", len(allShows))
-
-	count := 0
-	for _, s := range allShows {
-		if s.Status == "matched" || s.Status == "ready" {
-			if err := services.RenameAndMoveShow(cfg, s.ID); err != nil {
-				slog.Error("Error renaming show", "show_id", s.ID, "title", s.Title, "error", err)
-			} else {
-				count++
-			}
-		}
-	}
-
-	slog.Info("Mass show renaming complete", "shows_renamed", count)
-	http.Redirect(w, r, "/admin", http.StatusSeeOther)
-}
-
 func RenameMovieHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -446,7 +372,7 @@ func (h *Handlers) DownloadSubtitlesHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -539,7 +465,7 @@ func NukeLibraryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := RequireAdmin(r)
+	user, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -566,9 +492,16 @@ func NukeLibraryHandler(w http.ResponseWriter, r *http.Request) {
 		movies, 
 		requests, 
 		subtitle_queue, 
+		subtitle_syncs, 
+		watch_state, 
+		season_auto_requests, 
+		media_server_items, 
+		collections, 
+		collection_movies, 
 		settings, 
 		downloads, 
-		tvdb_episodes 
+		tvdb_episodes, 
+		file_index 
 		RESTART IDENTITY CASCADE`
 
 	if _, err := tx.Exec(nukeQuery); err != nil {
@@ -578,6 +511,21 @@ func NukeLibraryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Recycled and batch-deleted files keep their files, but the library rows saved with them
+	// point at IDs that were just reset, so they can only be restored as plain files now
+	if _, err := tx.Exec("UPDATE recycle_bin SET records = NULL"); err != nil {
+		tx.Rollback()
+		slog.Error("Failed to clear recycle bin records", "error", err, "user", user.Username)
+		http.Error(w, "Failed to clear recycle bin records", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("UPDATE file_operations SET records = NULL"); err != nil {
+		tx.Rollback()
+		slog.Error("Failed to clear batch records", "error", err, "user", user.Username)
+		http.Error(w, "Failed to clear batch records", http.StatusInternalServerError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil {
 		slog.Error("Failed to commit nuke transaction", "error", err, "user", user.Username)
 		http.Error(w, "Failed to commit changes", http.StatusInternalServerError)
@@ -596,7 +544,7 @@ func (h *Handlers) GetMovieAlternativesHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -626,7 +574,7 @@ func (h *Handlers) GetShowAlternativesHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -656,7 +604,7 @@ func (h *Handlers) RematchMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
@@ -698,7 +646,7 @@ func (h *Handlers) RematchShowHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := RequireAdmin(r)
+	_, err := RequirePermission(r, models.PermManageLibrary)
 	if err != nil {
 		http.Error(w, "Unauthorized: Admin only", http.StatusUnauthorized)
 		return
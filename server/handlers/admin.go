@@ -29,10 +29,15 @@ func init() {
 		"templates/components/admin_library_management.html",
 		"templates/components/admin_subtitle_management.html",
 		"templates/components/admin_library_maintenance.html",
-		"templates/components/admin_jellyfin.html",
+		"templates/components/admin_media_server.html",
+		"templates/components/admin_collections.html",
+		"templates/components/admin_root_folders.html",
 		"templates/components/admin_user_info.html",
+		"templates/components/admin_roles.html",
 		"templates/components/admin_danger_zone.html",
 		"templates/components/admin_incoming_media.html",
+		"templates/components/admin_naming.html",
+		"templates/components/admin_file_batches.html",
 	)
 	if err != nil {
 		slog.Error("Failed to parse admin template", "error", err)
@@ -55,13 +60,22 @@ type IncomingShowWithSeasons struct {
 
 type AdminPageData struct {
 	Username       string
-	IsAdmin        bool
+	RoleName       string // "Administrator" for admins
+	AdminAccess    bool
 	CurrentPage    string
 	SearchQuery    string
 	IncomingMovies []IncomingMovieWithSeeding
 	IncomingShows  []IncomingShowWithSeasons
 	Users          []models.User
 
+	// Admin sections are shown by permission
+	CanManageLibrary bool
+	CanTriggerScans  bool
+	CanManageUsers   bool
+
+	MediaServerName  string // "Jellyfin", "Plex", or "" when no media server is configured
+	JellyfinUserSync bool   // pushing users to Jellyfin makes no sense when Jellyfin is the account source
+
 	ScanningIncomingMovies bool
 	ScanningIncomingShows  bool
 	ScanningMovieLibrary   bool
@@ -75,7 +89,7 @@ func AdminHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !user.IsAdmin {
+	if !user.CanAccessAdmin() {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -139,7 +153,7 @@ func AdminHandler(w http.ResponseWriter, r *http.Request) {
 	// We'll also build the downloading status map in the same pass
 	downloadingShowMap := make(map[int]bool)
 
-	if user.IsAdmin {
+	if user.Can(models.PermManageLibrary) {
 		query := `
 			SELECT s.show_id, s.season_number, e.torrent_hash
 			FROM episodes e
@@ -215,15 +229,28 @@ func AdminHandler(w http.ResponseWriter, r *http.Request) {
 		allUsers = []models.User{}
 	}
 
+	roleName := user.RoleName
+	if user.IsAdmin {
+		roleName = "Administrator"
+	}
+
 	data := AdminPageData{
 		Username:       user.Username,
-		IsAdmin:        user.IsAdmin,
+		RoleName:       roleName,
+		AdminAccess:    true,
 		CurrentPage:    "/admin",
 		SearchQuery:    "",
 		IncomingMovies: incomingMovies,
 		IncomingShows:  incomingShows,
 		Users:          allUsers,
 
+		CanManageLibrary: user.Can(models.PermManageLibrary),
+		CanTriggerScans:  user.Can(models.PermTriggerScans),
+		CanManageUsers:   user.Can(models.PermManageUsers),
+
+		MediaServerName:  services.MediaServerName(cfg),
+		JellyfinUserSync: cfg.JellyfinURL != "" && cfg.JellyfinAPIKey != "" && !cfg.UseJellyfinAuth(),
+
 		ScanningIncomingMovies: services.IsScanning(services.ScanIncomingMovies),
 		ScanningIncomingShows:  services.IsScanning(services.ScanIncomingShows),
 		ScanningMovieLibrary:   services.IsScanning(services.ScanMovieLibrary),
@@ -239,7 +266,7 @@ func AdminHandler(w http.ResponseWriter, r *http.Request) {
 
 func JellyfinSyncUsersHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -250,6 +277,11 @@ func JellyfinSyncUsersHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	cfg := config.Load()
+	if cfg.UseJellyfinAuth() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Users sign in with their Jellyfin accounts; nothing to sync."})
+		return
+	}
 	if err := services.SyncExistingUsersToJellyfin(cfg); err != nil {
 		slog.Error("Failed to sync users to Jellyfin", "error", err)
 		w.Header().Set("Content-Type", "application/json")
@@ -261,9 +293,9 @@ func JellyfinSyncUsersHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "User sync complete. New users created with temporary passwords."})
 }
 
-func JellyfinRefreshLibraryHandler(w http.ResponseWriter, r *http.Request) {
+func MediaServerSyncWatchedHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -273,36 +305,65 @@ func JellyfinRefreshLibraryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mediaType := r.URL.Query().Get("type") // "movies" or "shows" — informational only
-	if mediaType == "" {
-		mediaType = "all"
+	cfg := config.Load()
+	if err := services.SyncWatchState(cfg); err != nil {
+		slog.Error("Failed to sync watch state from media server", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Error: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Watched state imported from " + services.MediaServerName(cfg) + "."})
+}
+
+func MediaServerRefreshLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
+	// "movies" or "shows"; Plex scans just that library, Jellyfin always scans everything
+	mediaType := r.URL.Query().Get("type")
+
 	cfg := config.Load()
-	if err := services.RefreshJellyfinLibrary(cfg); err != nil {
-		slog.Error("Failed to refresh Jellyfin library", "type", mediaType, "error", err)
+	if err := services.RefreshMediaServerLibrary(cfg, mediaType); err != nil {
+		slog.Error("Failed to refresh media server library", "type", mediaType, "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"message": "Error: " + err.Error()})
 		return
 	}
 
-	slog.Info("Jellyfin library scan triggered manually", "type", mediaType)
+	slog.Info("Media server library scan triggered manually", "type", mediaType)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Jellyfin library scan triggered successfully."})
+	json.NewEncoder(w).Encode(map[string]string{"message": services.MediaServerName(cfg) + " library scan triggered successfully."})
 }
 
 func ScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.CanAny(models.PermTriggerScans, models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	status := map[string]bool{
+	status := map[string]interface{}{
 		"incoming_movies": services.IsScanning(services.ScanIncomingMovies),
 		"incoming_shows":  services.IsScanning(services.ScanIncomingShows),
 		"movie_library":   services.IsScanning(services.ScanMovieLibrary),
 		"show_library":    services.IsScanning(services.ScanShowLibrary),
+		// Files seen, skipped as unchanged, and updated by the running or last scan of each type
+		"progress": map[services.ScanType]services.ScanProgress{
+			services.ScanIncomingMovies: services.GetScanProgress(services.ScanIncomingMovies),
+			services.ScanIncomingShows:  services.GetScanProgress(services.ScanIncomingShows),
+			services.ScanMovieLibrary:   services.GetScanProgress(services.ScanMovieLibrary),
+			services.ScanShowLibrary:    services.GetScanProgress(services.ScanShowLibrary),
+		},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -311,7 +372,7 @@ func ScanStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) ScanSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -337,7 +398,7 @@ func (h *Handlers) ScanSubtitlesHandler(w http.ResponseWriter, r *http.Request)
 
 func (h *Handlers) QueueMissingSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -375,7 +436,7 @@ func (h *Handlers) QueueMissingSubtitlesHandler(w http.ResponseWriter, r *http.R
 
 func (h *Handlers) MovieSubtitlesSyncHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -403,7 +464,7 @@ func (h *Handlers) MovieSubtitlesSyncHandler(w http.ResponseWriter, r *http.Requ
 
 func (h *Handlers) EpisodeSubtitlesSyncHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -431,7 +492,7 @@ func (h *Handlers) EpisodeSubtitlesSyncHandler(w http.ResponseWriter, r *http.Re
 
 func (h *Handlers) SyncAllSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := GetCurrentUser(r)
-	if err != nil || user == nil || !user.IsAdmin {
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -496,3 +557,70 @@ func (h *Handlers) SyncAllSubtitlesHandler(w http.ResponseWriter, r *http.Reques
 		"count":   len(tasks),
 	})
 }
+
+// SubtitleSyncResultsHandler returns the most recent subtitle sync results with their quality metrics
+func (h *Handlers) SubtitleSyncResultsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	syncs, err := h.Subtitle.GetSubtitleSyncs(200)
+	if err != nil {
+		slog.Error("Failed to get subtitle sync results", "error", err)
+		http.Error(w, "Failed to get sync results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(syncs)
+}
+
+// RevertSubtitleSyncHandler restores the original subtitle for an applied sync
+func (h *Handlers) RevertSubtitleSyncHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid sync ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Subtitle.RevertSubtitleSync(id); err != nil {
+		slog.Error("Failed to revert subtitle sync", "id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Original subtitle restored"})
+}
+
+// SubtitleQueueStatusHandler returns the subtitle queue size, OpenSubtitles quota and projected completion
+func (h *Handlers) SubtitleQueueStatusHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.Subtitle.GetQueueStatus()
+	if err != nil {
+		slog.Error("Failed to get subtitle queue status", "error", err)
+		http.Error(w, "Failed to get queue status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,623 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIRoute describes an /api/v1 endpoint. The same list registers the routes in setupRoutes
+// and generates the OpenAPI spec in docs/openapi.json, so the two can't drift apart.
+type APIRoute struct {
+	Method      string
+	Path        string // relative to /api/v1, with {placeholders} for path parameters
+	Tag         string
+	Summary     string
+	Permissions []string // any one is enough; none means any valid key
+	Params      []APIParam
+	Body        any   // zero value of the JSON request body, nil if there is none
+	Status      int   // status on success
+	Response    any   // zero value of the JSON response, nil if there is no body
+	Also        []int // other statuses it replies with, besides the ones every route can
+	Handler     http.HandlerFunc
+}
+
+// APIParam is a path or query parameter of an APIRoute
+type APIParam struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // "integer", "string" or "boolean"
+	Description string
+	Enum        []string
+}
+
+// APIError is the body of every /api/v1 error response
+type APIError struct {
+	Error string `json:"error"`
+}
+
+// APIAccount is the user an API key belongs to
+type APIAccount struct {
+	ID          int64         `json:"id"`
+	Username    string        `json:"username"`
+	Email       string        `json:"email"`
+	IsAdmin     bool          `json:"is_admin"`
+	Role        string        `json:"role"`
+	Permissions []string      `json:"permissions"`
+	Quota       *models.Quota `json:"quota"`
+}
+
+// APIShow is a show with its seasons and episodes
+type APIShow struct {
+	models.Show
+	Seasons []services.SeasonWithEpisodes `json:"seasons"`
+}
+
+// APIEpisode is an episode with the season it belongs to
+type APIEpisode struct {
+	models.Episode
+	SeasonNumber int `json:"season_number"`
+}
+
+// APIRequestInput is the body for creating a request. Movies need tmdb_id and shows tvdb_id.
+type APIRequestInput struct {
+	MediaType     string `json:"media_type"`
+	Title         string `json:"title"`
+	OriginalTitle string `json:"original_title,omitempty"`
+	Year          int    `json:"year"`
+	TMDBID        string `json:"tmdb_id,omitempty"`
+	TVDBID        string `json:"tvdb_id,omitempty"`
+	IMDBID        string `json:"imdb_id,omitempty"`
+	PosterPath    string `json:"poster_path,omitempty"`
+	Overview      string `json:"overview,omitempty"`
+	Seasons       string `json:"seasons,omitempty"`  // comma-separated season numbers
+	Episodes      string `json:"episodes,omitempty"` // comma-separated, e.g. S01E01,S01E02
+	RootFolderID  *int64 `json:"root_folder_id,omitempty"`
+	Is4K          bool   `json:"is_4k,omitempty"`   // request for the 4K library
+	Edition       string `json:"edition,omitempty"` // movie edition, e.g. "Director's Cut"; any when empty
+}
+
+// APIScan reports whether a scan is running
+type APIScan struct {
+	Type    string `json:"type"`
+	Running bool   `json:"running"`
+}
+
+// APISettings is the server configuration, without secrets
+type APISettings struct {
+	MoviesPath           string  `json:"movies_path"`
+	ShowsPath            string  `json:"shows_path"`
+	IncomingMoviesPath   string  `json:"incoming_movies_path"`
+	IncomingShowsPath    string  `json:"incoming_shows_path"`
+	AuthMode             string  `json:"auth_mode"`
+	RegistrationEnabled  bool    `json:"registration_enabled"`
+	OIDCEnabled          bool    `json:"oidc_enabled"`
+	MediaServer          string  `json:"media_server"`
+	AutoRequestSeasons   bool    `json:"auto_request_seasons"`
+	SyncCollections      bool    `json:"sync_collections"`
+	AutoApprovePartial   bool    `json:"auto_approve_partial"`
+	AutoApproveMaxSizeGB float64 `json:"auto_approve_max_size_gb"`
+	SubtitleSync         bool    `json:"subtitle_sync"`
+}
+
+var (
+	paginationParams = []APIParam{
+		{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of items to return"},
+		{Name: "offset", In: "query", Type: "integer", Description: "Number of items to skip"},
+	}
+	idParam       = APIParam{Name: "id", In: "path", Type: "integer"}
+	scanTypeParam = APIParam{Name: "type", In: "path", Type: "string", Enum: []string{
+		string(services.ScanMovieLibrary), string(services.ScanShowLibrary),
+		string(services.ScanIncomingMovies), string(services.ScanIncomingShows),
+	}}
+)
+
+// APIV1Routes lists the /api/v1 endpoints
+func (h *Handlers) APIV1Routes() []APIRoute {
+	return []APIRoute{
+		{Method: http.MethodGet, Path: "/me", Tag: "Account", Summary: "The user the API key belongs to, with their permissions and request quota",
+			Status: http.StatusOK, Response: APIAccount{}, Handler: apiMeHandler},
+
+		{Method: http.MethodGet, Path: "/movies", Tag: "Movies", Summary: "List movies in the library, sorted by title",
+			Params: append([]APIParam{{Name: "q", In: "query", Type: "string", Description: "Only movies whose title matches"}}, paginationParams...),
+			Status: http.StatusOK, Response: []models.Movie{}, Handler: apiMoviesHandler},
+		{Method: http.MethodGet, Path: "/movies/{id}", Tag: "Movies", Summary: "Get a movie",
+			Params: []APIParam{idParam}, Status: http.StatusOK, Response: models.Movie{}, Handler: apiMovieHandler},
+
+		{Method: http.MethodGet, Path: "/shows", Tag: "Shows", Summary: "List shows in the library, sorted by title",
+			Params: append([]APIParam{{Name: "q", In: "query", Type: "string", Description: "Only shows whose title matches"}}, paginationParams...),
+			Status: http.StatusOK, Response: []models.Show{}, Handler: apiShowsHandler},
+		{Method: http.MethodGet, Path: "/shows/{id}", Tag: "Shows", Summary: "Get a show with its seasons and episodes",
+			Params: []APIParam{idParam}, Status: http.StatusOK, Response: APIShow{}, Handler: apiShowHandler},
+		{Method: http.MethodGet, Path: "/shows/{id}/episodes", Tag: "Shows", Summary: "List a show's episodes",
+			Params: []APIParam{idParam, {Name: "season", In: "query", Type: "integer", Description: "Only episodes of this season"}},
+			Status: http.StatusOK, Response: []APIEpisode{}, Handler: apiEpisodesHandler},
+
+		{Method: http.MethodGet, Path: "/requests", Tag: "Requests", Summary: "List requests, newest first",
+			Params: append([]APIParam{
+				{Name: "status", In: "query", Type: "string", Description: "Only requests with this status",
+					Enum: []string{"awaiting_approval", "pending", "downloading", "completed", "not_found", "cancelled"}},
+				{Name: "mine", In: "query", Type: "boolean", Description: "Only requests made by the API key's user"},
+			}, paginationParams...),
+			Status: http.StatusOK, Response: []models.Request{}, Handler: apiRequestsHandler},
+		{Method: http.MethodPost, Path: "/requests", Tag: "Requests",
+			Summary:     "Request a movie or show. Replies 201 when it will be downloaded and 202 when it awaits approval",
+			Permissions: []string{models.PermRequestMovies, models.PermRequestShows},
+			Body:        APIRequestInput{}, Status: http.StatusCreated, Response: models.Request{},
+			Also: []int{http.StatusAccepted, http.StatusConflict, http.StatusTooManyRequests}, Handler: h.apiCreateRequestHandler},
+		{Method: http.MethodGet, Path: "/requests/{id}", Tag: "Requests", Summary: "Get a request",
+			Params: []APIParam{idParam}, Status: http.StatusOK, Response: models.Request{}, Handler: apiRequestHandler},
+		{Method: http.MethodDelete, Path: "/requests/{id}", Tag: "Requests", Summary: "Delete a request and remove its torrents",
+			Permissions: []string{models.PermManageLibrary}, Params: []APIParam{idParam},
+			Status: http.StatusNoContent, Handler: apiDeleteRequestHandler},
+		{Method: http.MethodPost, Path: "/requests/{id}/approve", Tag: "Requests", Summary: "Approve a request, or retry one that wasn't found",
+			Permissions: []string{models.PermManageLibrary}, Params: []APIParam{idParam},
+			Status: http.StatusOK, Response: models.Request{}, Handler: h.apiApproveRequestHandler},
+		{Method: http.MethodPost, Path: "/requests/{id}/deny", Tag: "Requests", Summary: "Deny a request and refund its quota",
+			Permissions: []string{models.PermManageLibrary}, Params: []APIParam{idParam},
+			Status: http.StatusOK, Response: models.Request{}, Handler: apiDenyRequestHandler},
+
+		{Method: http.MethodGet, Path: "/downloads", Tag: "Downloads", Summary: "List torrents added for requests, newest first",
+			Params: []APIParam{{Name: "request_id", In: "query", Type: "integer", Description: "Only downloads of this request"}},
+			Status: http.StatusOK, Response: []models.Download{}, Handler: apiDownloadsHandler},
+
+		{Method: http.MethodGet, Path: "/scans", Tag: "Scans", Summary: "Which library and incoming folder scans are running",
+			Permissions: []string{models.PermTriggerScans, models.PermManageLibrary},
+			Status:      http.StatusOK, Response: []APIScan{}, Handler: apiScansHandler},
+		{Method: http.MethodPost, Path: "/scans/{type}", Tag: "Scans", Summary: "Start a scan",
+			Permissions: []string{models.PermTriggerScans}, Params: []APIParam{scanTypeParam},
+			Status: http.StatusAccepted, Response: APIScan{}, Also: []int{http.StatusConflict}, Handler: apiStartScanHandler},
+		{Method: http.MethodDelete, Path: "/scans/{type}", Tag: "Scans", Summary: "Stop a running scan",
+			Permissions: []string{models.PermTriggerScans}, Params: []APIParam{scanTypeParam},
+			Status: http.StatusOK, Response: APIScan{}, Handler: apiStopScanHandler},
+
+		{Method: http.MethodGet, Path: "/settings", Tag: "Settings", Summary: "Server configuration, without secrets",
+			Permissions: []string{models.PermManageLibrary},
+			Status:      http.StatusOK, Response: APISettings{}, Handler: apiSettingsHandler},
+	}
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding API response", "error", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, APIError{Error: message})
+}
+
+// apiUser returns the user of the request's API key. RequireAPIKey guarantees there is one.
+func apiUser(r *http.Request) *models.User {
+	return services.APIUser(r.Context())
+}
+
+// apiID parses the {id} path parameter, replying 400 if it isn't a number
+func apiID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// apiQueryInt parses an optional non-negative integer query parameter
+func apiQueryInt(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid " + name + " parameter")
+	}
+	return n, nil
+}
+
+// paginate applies the limit and offset query parameters to a list
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) ([]T, bool) {
+	limit, err := apiQueryInt(r, "limit")
+	if err == nil {
+		var offset int
+		if offset, err = apiQueryInt(r, "offset"); err == nil {
+			items = items[min(offset, len(items)):]
+			if limit > 0 {
+				items = items[:min(limit, len(items))]
+			}
+			return items, true
+		}
+	}
+	writeAPIError(w, http.StatusBadRequest, err.Error())
+	return nil, false
+}
+
+// notFoundOr replies 404 for missing rows and 500 for other errors
+func notFoundOr(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, what+" not found")
+		return
+	}
+	slog.Error("API error", "what", what, "error", err)
+	writeAPIError(w, http.StatusInternalServerError, "Failed to load "+what)
+}
+
+func apiMeHandler(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	quota, err := services.GetUserQuota(user)
+	if err != nil {
+		slog.Error("Error getting request quota", "user_id", user.ID, "error", err)
+	}
+
+	permissions := user.Permissions
+	if user.IsAdmin {
+		permissions = models.AllPermissions
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	writeAPIJSON(w, http.StatusOK, APIAccount{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		IsAdmin:     user.IsAdmin,
+		Role:        user.RoleName,
+		Permissions: permissions,
+		Quota:       quota,
+	})
+}
+
+func apiMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var movies []models.Movie
+	var err error
+	if q := r.URL.Query().Get("q"); q != "" {
+		movies, err = services.SearchMoviesLocal(q)
+	} else {
+		movies, err = services.GetMovies()
+	}
+	if err != nil {
+		notFoundOr(w, err, "movies")
+		return
+	}
+	if movies == nil {
+		movies = []models.Movie{}
+	}
+	if movies, ok := paginate(w, r, movies); ok {
+		writeAPIJSON(w, http.StatusOK, movies)
+	}
+}
+
+func apiMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+	movie, err := services.GetMovieByID(id)
+	if err != nil {
+		notFoundOr(w, err, "movie")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, movie)
+}
+
+func apiShowsHandler(w http.ResponseWriter, r *http.Request) {
+	var shows []models.Show
+	var err error
+	if q := r.URL.Query().Get("q"); q != "" {
+		shows, err = services.SearchShowsLocal(q)
+	} else {
+		shows, err = services.GetShows()
+	}
+	if err != nil {
+		notFoundOr(w, err, "shows")
+		return
+	}
+	if shows == nil {
+		shows = []models.Show{}
+	}
+	if shows, ok := paginate(w, r, shows); ok {
+		writeAPIJSON(w, http.StatusOK, shows)
+	}
+}
+
+func apiShowHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+	show, err := services.GetShowByID(id)
+	if err != nil {
+		notFoundOr(w, err, "show")
+		return
+	}
+	seasons, err := services.GetShowSeasons(id)
+	if err != nil {
+		notFoundOr(w, err, "seasons")
+		return
+	}
+	if seasons == nil {
+		seasons = []services.SeasonWithEpisodes{}
+	}
+	writeAPIJSON(w, http.StatusOK, APIShow{Show: *show, Seasons: seasons})
+}
+
+func apiEpisodesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+	season, err := apiQueryInt(r, "season")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := services.GetShowByID(id); err != nil {
+		notFoundOr(w, err, "show")
+		return
+	}
+	seasons, err := services.GetShowSeasons(id)
+	if err != nil {
+		notFoundOr(w, err, "episodes")
+		return
+	}
+
+	episodes := []APIEpisode{}
+	for _, s := range seasons {
+		if r.URL.Query().Has("season") && s.SeasonNumber != season {
+			continue
+		}
+		for _, e := range s.Episodes {
+			episodes = append(episodes, APIEpisode{Episode: e, SeasonNumber: s.SeasonNumber})
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, episodes)
+}
+
+func apiRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	requests, err := services.GetRequests()
+	if err != nil {
+		notFoundOr(w, err, "requests")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	mine := r.URL.Query().Get("mine") == "true"
+	userID := int(apiUser(r).ID)
+	filtered := []models.Request{}
+	for _, req := range requests {
+		if (status == "" || req.Status == status) && (!mine || req.UserID == userID) {
+			filtered = append(filtered, req)
+		}
+	}
+	if filtered, ok := paginate(w, r, filtered); ok {
+		writeAPIJSON(w, http.StatusOK, filtered)
+	}
+}
+
+func apiRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+	req, err := services.GetRequestByID(id)
+	if err != nil {
+		notFoundOr(w, err, "request")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, req)
+}
+
+func (h *Handlers) apiCreateRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var input APIRequestInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	switch {
+	case input.MediaType != "movie" && input.MediaType != "show":
+		writeAPIError(w, http.StatusBadRequest, "media_type must be movie or show")
+		return
+	case input.Title == "":
+		writeAPIError(w, http.StatusBadRequest, "title is required")
+		return
+	case input.MediaType == "movie" && input.TMDBID == "":
+		writeAPIError(w, http.StatusBadRequest, "tmdb_id is required for movies")
+		return
+	case input.MediaType == "show" && input.TVDBID == "":
+		writeAPIError(w, http.StatusBadRequest, "tvdb_id is required for shows")
+		return
+	case input.MediaType == "show" && input.Seasons == "" && input.Episodes == "":
+		writeAPIError(w, http.StatusBadRequest, "seasons or episodes are required for shows")
+		return
+	}
+
+	requestID, status, err := h.submitRequest(apiUser(r), models.Request{
+		MediaType:     input.MediaType,
+		Title:         input.Title,
+		OriginalTitle: input.OriginalTitle,
+		Year:          input.Year,
+		TMDBID:        input.TMDBID,
+		TVDBID:        input.TVDBID,
+		IMDBID:        input.IMDBID,
+		PosterPath:    input.PosterPath,
+		Overview:      input.Overview,
+		Seasons:       input.Seasons,
+		Episodes:      input.Episodes,
+		RootFolderID:  input.RootFolderID,
+		Is4K:          input.Is4K,
+		Edition:       input.Edition,
+	})
+	if err != nil {
+		writeAPIError(w, status, err.Error())
+		return
+	}
+
+	req, err := services.GetRequestByID(requestID)
+	if err != nil {
+		notFoundOr(w, err, "request")
+		return
+	}
+	writeAPIJSON(w, status, req)
+}
+
+func apiDeleteRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := services.GetRequestByID(id); err != nil {
+		notFoundOr(w, err, "request")
+		return
+	}
+
+	qb, _ := services.NewQBittorrentClient(config.Load())
+	if err := services.DeleteRequest(id, qb); err != nil {
+		slog.Error("Error deleting request", "error", err, "request_id", id, "user", apiUser(r).Username)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to delete request")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setAPIRequestStatus moves a request to a new status and replies with the updated request
+func setAPIRequestStatus(w http.ResponseWriter, r *http.Request, status string) (int, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return 0, false
+	}
+	if _, err := services.GetRequestByID(id); err != nil {
+		notFoundOr(w, err, "request")
+		return 0, false
+	}
+	if err := services.UpdateRequestStatus(id, status); err != nil {
+		slog.Error("Error updating request status", "error", err, "request_id", id, "status", status, "user", apiUser(r).Username)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to update request")
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handlers) apiApproveRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := setAPIRequestStatus(w, r, "pending"); !ok {
+		return
+	}
+	if automation := h.Automation; automation != nil {
+		processCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		go func() {
+			defer cancel()
+			automation.TriggerImmediateProcessing(processCtx)
+		}()
+	}
+	apiRequestHandler(w, r)
+}
+
+func apiDenyRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := setAPIRequestStatus(w, r, "cancelled")
+	if !ok {
+		return
+	}
+	if err := services.RefundRequestUsage(id); err != nil {
+		slog.Error("Error refunding quota for denied request", "error", err, "request_id", id)
+	}
+	apiRequestHandler(w, r)
+}
+
+func apiDownloadsHandler(w http.ResponseWriter, r *http.Request) {
+	requestID, err := apiQueryInt(r, "request_id")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	downloads, err := services.GetDownloads(requestID)
+	if err != nil {
+		notFoundOr(w, err, "downloads")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, downloads)
+}
+
+func apiScansHandler(w http.ResponseWriter, r *http.Request) {
+	scans := []APIScan{}
+	for _, t := range scanTypeParam.Enum {
+		scans = append(scans, APIScan{Type: t, Running: services.IsScanning(services.ScanType(t))})
+	}
+	writeAPIJSON(w, http.StatusOK, scans)
+}
+
+// apiScanType parses the {type} path parameter, replying 404 for unknown scans
+func apiScanType(w http.ResponseWriter, r *http.Request) (services.ScanType, bool) {
+	t := chi.URLParam(r, "type")
+	for _, known := range scanTypeParam.Enum {
+		if t == known {
+			return services.ScanType(t), true
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, "Unknown scan type")
+	return "", false
+}
+
+func apiStartScanHandler(w http.ResponseWriter, r *http.Request) {
+	scanType, ok := apiScanType(w, r)
+	if !ok {
+		return
+	}
+	ctx, _ := services.StartScan(scanType)
+	if ctx == nil {
+		writeAPIError(w, http.StatusConflict, "Scan already running")
+		return
+	}
+
+	cfg := config.Load()
+	switch scanType {
+	case services.ScanMovieLibrary:
+		go services.ScanMovies(ctx, cfg, false)
+	case services.ScanIncomingMovies:
+		go services.ScanMovies(ctx, cfg, true)
+	case services.ScanShowLibrary:
+		go services.ScanShows(ctx, cfg, false)
+	case services.ScanIncomingShows:
+		go services.ScanShows(ctx, cfg, true)
+	}
+	slog.Info("Started scan from API", "type", scanType, "user", apiUser(r).Username)
+	writeAPIJSON(w, http.StatusAccepted, APIScan{Type: string(scanType), Running: true})
+}
+
+func apiStopScanHandler(w http.ResponseWriter, r *http.Request) {
+	scanType, ok := apiScanType(w, r)
+	if !ok {
+		return
+	}
+	services.StopScan(scanType)
+	writeAPIJSON(w, http.StatusOK, APIScan{Type: string(scanType), Running: false})
+}
+
+func apiSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Load()
+	writeAPIJSON(w, http.StatusOK, APISettings{
+		MoviesPath:           cfg.MoviesPath,
+		ShowsPath:            cfg.ShowsPath,
+		IncomingMoviesPath:   cfg.IncomingMoviesPath,
+		IncomingShowsPath:    cfg.IncomingShowsPath,
+		AuthMode:             cfg.AuthMode,
+		RegistrationEnabled:  cfg.RegistrationEnabled(),
+		OIDCEnabled:          cfg.OIDCEnabled(),
+		MediaServer:          cfg.MediaServer,
+		AutoRequestSeasons:   cfg.AutoRequestSeasons,
+		SyncCollections:      cfg.SyncCollections,
+		AutoApprovePartial:   cfg.AutoApprovePartial,
+		AutoApproveMaxSizeGB: cfg.AutoApproveMaxSizeGB,
+		SubtitleSync:         cfg.EnableSubSync,
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	localmiddleware "Arrgo/middleware"
+	"Arrgo/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// testAPIKeys are the keys newAPIV1Server accepts and the users they belong to
+var testAPIKeys = map[string]*models.User{
+	"arrgo_admin":     {ID: 1, Username: "admin", IsAdmin: true},
+	"arrgo_manager":   {ID: 2, Username: "manager", Permissions: []string{models.PermManageLibrary}},
+	"arrgo_scanner":   {ID: 3, Username: "scanner", Permissions: []string{models.PermTriggerScans}},
+	"arrgo_requester": {ID: 4, Username: "requester", Permissions: []string{models.PermRequestMovies}},
+	"arrgo_viewer":    {ID: 5, Username: "viewer"},
+}
+
+// newAPIV1Server mounts the /api/v1 routes like setupRoutes does, with keys looked up in
+// testAPIKeys instead of the database
+func newAPIV1Server(t *testing.T) *httptest.Server {
+	t.Helper()
+	authenticate := func(key string) (*models.User, error) {
+		if user, ok := testAPIKeys[key]; ok {
+			return user, nil
+		}
+		return nil, errors.New("invalid API key")
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(localmiddleware.APIKeyAuth(authenticate))
+		for _, route := range (&Handlers{}).APIV1Routes() {
+			r.With(localmiddleware.RequireAPIPermission(route.Permissions...)).Method(route.Method, route.Path, route.Handler)
+		}
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func apiV1Request(t *testing.T, srv *httptest.Server, method, path string, header http.Header) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+"/api/v1"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body APIError
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body.Error
+}
+
+func TestAPIV1KeyAuth(t *testing.T) {
+	srv := newAPIV1Server(t)
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+		wantError  string
+	}{
+		{"no key", http.Header{}, http.StatusUnauthorized, "API key required"},
+		{"unknown key", http.Header{"X-Api-Key": {"arrgo_stolen"}}, http.StatusUnauthorized, "Invalid API key"},
+		{"unknown bearer token", http.Header{"Authorization": {"Bearer arrgo_stolen"}}, http.StatusUnauthorized, "Invalid API key"},
+		{"basic auth isn't a key", http.Header{"Authorization": {"Basic YWRtaW46YWRtaW4="}}, http.StatusUnauthorized, "Invalid API key"},
+		{"X-Api-Key header", http.Header{"X-Api-Key": {"arrgo_scanner"}}, http.StatusOK, ""},
+		{"bearer token", http.Header{"Authorization": {"Bearer arrgo_scanner"}}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, apiErr := apiV1Request(t, srv, http.MethodGet, "/scans", tt.header)
+			if status != tt.wantStatus || apiErr != tt.wantError {
+				t.Errorf("GET /scans = %d %q, want %d %q", status, apiErr, tt.wantStatus, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAPIV1Permissions(t *testing.T) {
+	t.Setenv("AUTH_MODE", "local")
+	srv := newAPIV1Server(t)
+	tests := []struct {
+		name       string
+		key        string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"settings need manage library", "arrgo_viewer", http.MethodGet, "/settings", http.StatusForbidden},
+		{"settings with manage library", "arrgo_manager", http.MethodGet, "/settings", http.StatusOK},
+		{"admins hold every permission", "arrgo_admin", http.MethodGet, "/settings", http.StatusOK},
+		{"scan status with trigger scans", "arrgo_scanner", http.MethodGet, "/scans", http.StatusOK},
+		{"scan status with manage library", "arrgo_manager", http.MethodGet, "/scans", http.StatusOK},
+		{"scan status without either", "arrgo_requester", http.MethodGet, "/scans", http.StatusForbidden},
+		{"starting scans needs trigger scans", "arrgo_manager", http.MethodPost, "/scans/library", http.StatusForbidden},
+		{"requesting needs a request permission", "arrgo_viewer", http.MethodPost, "/requests", http.StatusForbidden},
+		{"deleting requests needs manage library", "arrgo_requester", http.MethodDelete, "/requests/1", http.StatusForbidden},
+		{"approving requests needs manage library", "arrgo_scanner", http.MethodPost, "/requests/1/approve", http.StatusForbidden},
+		{"denying requests needs manage library", "arrgo_requester", http.MethodPost, "/requests/1/deny", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, apiErr := apiV1Request(t, srv, tt.method, tt.path, http.Header{"X-Api-Key": {tt.key}})
+			if status != tt.wantStatus {
+				t.Errorf("%s %s = %d %q, want %d", tt.method, tt.path, status, apiErr, tt.wantStatus)
+			}
+			if status == http.StatusForbidden && apiErr != "Forbidden" {
+				t.Errorf("403 body error = %q, want Forbidden", apiErr)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name       string
+		query      string
+		want       []int
+		wantStatus int
+	}{
+		{"no parameters", "", []int{1, 2, 3, 4, 5}, http.StatusOK},
+		{"limit", "?limit=2", []int{1, 2}, http.StatusOK},
+		{"offset", "?offset=3", []int{4, 5}, http.StatusOK},
+		{"limit and offset", "?limit=2&offset=1", []int{2, 3}, http.StatusOK},
+		{"limit past the end", "?limit=10&offset=4", []int{5}, http.StatusOK},
+		{"offset past the end", "?offset=9", []int{}, http.StatusOK},
+		{"zero limit means no limit", "?limit=0", []int{1, 2, 3, 4, 5}, http.StatusOK},
+		{"negative limit", "?limit=-1", nil, http.StatusBadRequest},
+		{"non-numeric offset", "?offset=two", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			got, ok := paginate(rec, httptest.NewRequest(http.MethodGet, "/api/v1/movies"+tt.query, nil), items)
+			if ok != (tt.wantStatus == http.StatusOK) {
+				t.Fatalf("paginate() ok = %v, want status %d", ok, tt.wantStatus)
+			}
+			if !ok {
+				if rec.Code != tt.wantStatus {
+					t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+				}
+				return
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("paginate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
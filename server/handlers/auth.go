@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"Arrgo/config"
+	"Arrgo/models"
 	"Arrgo/services"
+	"errors"
 	"html/template"
 	"log/slog"
 	"net/http"
@@ -33,12 +35,64 @@ func init() {
 	}
 }
 
+// LoginPageData tells the login page which account to ask for
+type LoginPageData struct {
+	JellyfinAuth        bool
+	RegistrationEnabled bool
+	OIDCName            string // set when OIDC sign-in is configured
+	Error               string
+}
+
+func newLoginPageData(cfg *config.Config) LoginPageData {
+	data := LoginPageData{
+		JellyfinAuth:        cfg.UseJellyfinAuth(),
+		RegistrationEnabled: cfg.RegistrationEnabled(),
+	}
+	if cfg.OIDCEnabled() {
+		data.OIDCName = cfg.OIDCProviderName
+	}
+	return data
+}
+
+// renderLoginError shows the login page with an error, for failures outside the login form
+func renderLoginError(w http.ResponseWriter, cfg *config.Config, message string) {
+	data := newLoginPageData(cfg)
+	data.Error = message
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := loginTmpl.ExecuteTemplate(w, "base", data); err != nil {
+		slog.Error("Error rendering login template", "error", err)
+	}
+}
+
+// RegisterPageData carries an invite through the registration form
+type RegisterPageData struct {
+	Invite string
+	Error  string
+}
+
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		if err := registerTmpl.ExecuteTemplate(w, "base", nil); err != nil {
+	// Invite links work even when open registration is off. Otherwise accounts come from
+	// Jellyfin in Jellyfin auth mode, and admins can turn registration off.
+	data := RegisterPageData{Invite: r.FormValue("invite")}
+	if data.Invite == "" && !config.Load().RegistrationEnabled() {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	render := func(status int) {
+		w.WriteHeader(status)
+		if err := registerTmpl.ExecuteTemplate(w, "base", data); err != nil {
 			slog.Error("Error rendering register template", "error", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
+	}
+
+	if data.Invite != "" && !services.ValidInvite(data.Invite) {
+		data.Error = "This invite link is invalid, expired or already used"
+		render(http.StatusGone)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		render(http.StatusOK)
 		return
 	}
 
@@ -52,14 +106,25 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	password := r.FormValue("password")
 
 	if username == "" || email == "" || password == "" {
-		http.Error(w, "Username, email and password are required", http.StatusBadRequest)
+		data.Error = "Username, email and password are required"
+		render(http.StatusBadRequest)
 		return
 	}
 
-	user, err := services.RegisterUser(username, email, password)
+	var user *models.User
+	var err error
+	if data.Invite != "" {
+		user, err = services.RegisterUserWithInvite(data.Invite, username, email, password)
+	} else {
+		user, err = services.RegisterUser(username, email, password)
+	}
 	if err != nil {
 		slog.Error("Registration failed", "username", username, "error", err)
-		http.Error(w, "Registration failed", http.StatusInternalServerError)
+		data.Error = "Registration failed"
+		if data.Invite != "" {
+			data.Error = err.Error()
+		}
+		render(http.StatusBadRequest)
 		return
 	}
 
@@ -83,13 +148,32 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
+// authenticateLogin checks a username and password against Jellyfin or the local accounts,
+// depending on the auth mode
+func authenticateLogin(cfg *config.Config, username, password string) (*models.User, error) {
+	if !cfg.UseJellyfinAuth() {
+		return services.AuthenticateUser(username, password)
+	}
+	user, err := services.AuthenticateJellyfinUser(cfg, username, password)
+	if err != nil {
+		// Local-only accounts (like the seeded admin) keep working, so a Jellyfin outage or
+		// an admin without a Jellyfin account can't lock everyone out
+		if localUser, localErr := services.AuthenticateUser(username, password); localErr == nil && localUser.JellyfinID == "" {
+			slog.Info("Jellyfin login failed, signed in with local account", "username", username, "error", err)
+			return localUser, nil
+		}
+	}
+	return user, err
+}
+
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("Login handler called",
 		"method", r.Method,
 		"path", r.URL.Path,
 		"htmx_request", r.Header.Get("HX-Request"))
+	cfg := config.Load()
 	if r.Method == http.MethodGet {
-		if err := loginTmpl.ExecuteTemplate(w, "base", nil); err != nil {
+		if err := loginTmpl.ExecuteTemplate(w, "base", newLoginPageData(cfg)); err != nil {
 			slog.Error("Error rendering login template", "error", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -112,7 +196,12 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := services.AuthenticateUser(username, password)
+	user, err := authenticateLogin(cfg, username, password)
+	if errors.Is(err, services.ErrAccountDisabled) {
+		slog.Warn("Login refused for disabled account", "username", username)
+		http.Error(w, "This account has been disabled", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		slog.Warn("Login failed", "username", username, "error", err)
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// CollectionsHandler lists collections along with the library movies custom ones can be built from
+func CollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collections, err := services.GetCollections()
+	if err != nil {
+		slog.Error("Error getting collections", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if collections == nil {
+		collections = []models.Collection{}
+	}
+
+	type movieOption struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+		Year  int    `json:"year"`
+	}
+	cfg := config.Load()
+	movies, _ := services.GetMovies()
+	options := make([]movieOption, 0, len(movies))
+	for _, m := range movies {
+		if m.TMDBID == "" || strings.HasPrefix(m.Path, cfg.IncomingMoviesPath) {
+			continue
+		}
+		options = append(options, movieOption{ID: m.ID, Title: m.Title, Year: m.Year})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"collections": collections,
+		"movies":      options,
+	})
+}
+
+// SaveCollectionHandler creates or updates a custom collection
+func SaveCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		PosterURL string `json:"poster_url"`
+		MovieIDs  []int  `json:"movie_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := services.SaveCustomCollection(req.ID, req.Name, strings.TrimSpace(req.PosterURL), req.MovieIDs)
+	if err != nil {
+		slog.Error("Error saving collection", "name", req.Name, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Saved custom collection", "collection_id", id, "name", req.Name, "movies", len(req.MovieIDs), "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Collection saved. It will appear in your media server on the next collection sync."})
+}
+
+// DeleteCollectionHandler removes a collection and its media server counterpart
+func DeleteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DeleteCollection(config.Load(), id); err != nil {
+		slog.Error("Error deleting collection", "collection_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collection deleted"})
+}
+
+func MediaServerSyncCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := config.Load()
+	if services.GetMediaServer(cfg) == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Error: no media server configured"})
+		return
+	}
+
+	go func() {
+		if err := services.SyncCollections(cfg); err != nil {
+			slog.Error("Failed to sync media server collections", "error", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collection sync started in the background."})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"Arrgo/config"
+	"Arrgo/models"
 	"Arrgo/services"
 	"html/template"
 	"log/slog"
@@ -30,7 +31,8 @@ func init() {
 
 type DashboardData struct {
 	Username           string
-	IsAdmin            bool
+	AdminAccess        bool
+	CanManageLibrary   bool
 	CurrentPage        string
 	SearchQuery        string
 	MovieCount         int
@@ -39,6 +41,7 @@ type DashboardData struct {
 	ShowCount          int
 	IncomingShowCount  int
 	ShowRequestCount   int
+	ContinueWatching   []models.SeriesSuggestion
 }
 
 func DashboardHandler(w http.ResponseWriter, r *http.Request) {
@@ -68,7 +71,7 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 	movieRequestCount := 0
 	showRequestCount := 0
 
-	if user.IsAdmin {
+	if user.Can(models.PermManageLibrary) {
 		// Total counts (including incoming)
 		totalMovies, _ := services.GetMovieCount("")
 		totalShows, _ := services.GetShowCount("")
@@ -80,9 +83,16 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 		movieRequestCount, showRequestCount, _ = services.GetPendingRequestCounts()
 	}
 
+	// Next episodes of shows the user is part way through, from media server watch state
+	continueWatching, err := services.GetContinueWatching(int(user.ID), 12)
+	if err != nil {
+		slog.Error("Error getting continue watching", "error", err)
+	}
+
 	data := DashboardData{
 		Username:           user.Username,
-		IsAdmin:            user.IsAdmin,
+		AdminAccess:        user.CanAccessAdmin(),
+		CanManageLibrary:   user.Can(models.PermManageLibrary),
 		CurrentPage:        "/dashboard",
 		SearchQuery:        "",
 		MovieCount:         movieCount,
@@ -91,6 +101,7 @@ func DashboardHandler(w http.ResponseWriter, r *http.Request) {
 		ShowCount:          showCount,
 		IncomingShowCount:  incomingShowCount,
 		ShowRequestCount:   showRequestCount,
+		ContinueWatching:   continueWatching,
 	}
 
 	if err := dashboardTmpl.ExecuteTemplate(w, "base", data); err != nil {
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+const fileBatchHistorySize = 20
+
+// fileBatchUser returns the current user if they can manage the library
+func fileBatchUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+// FileBatchesHandler lists recent bulk rename and dedupe batches, or returns one batch with
+// its operations when ?id= is given
+func FileBatchesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := fileBatchUser(w, r); !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("id") != "" {
+		id, err := ParseIDFromQuery(r, "id")
+		if err != nil {
+			http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+			return
+		}
+		batch, err := services.GetFileBatch(int64(id))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(batch)
+		return
+	}
+
+	batches, err := services.GetFileBatches(fileBatchHistorySize)
+	if err != nil {
+		slog.Error("Error loading file batches", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"batches": batches,
+	})
+}
+
+// PlanFileBatchHandler plans a bulk rename or dedupe without touching any files
+func PlanFileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := fileBatchUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	batch, err := services.PlanFileBatch(config.Load(), req.Kind, user.ID)
+	if err != nil {
+		slog.Error("Error planning file batch", "kind", req.Kind, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batch)
+}
+
+// ApplyFileBatchHandler starts applying an approved batch in the background
+func ApplyFileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	runFileBatch(w, r, "apply", services.ApplyFileBatch, "Applying changes")
+}
+
+// UndoFileBatchHandler starts undoing an applied batch in the background
+func UndoFileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	runFileBatch(w, r, "undo", services.UndoFileBatch, "Undoing changes")
+}
+
+func runFileBatch(w http.ResponseWriter, r *http.Request, action string, run func(*config.Config, int64) error, message string) {
+	user, ok := fileBatchUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("File batch requested", "action", action, "batch_id", id, "user", user.Username)
+	go func() {
+		if err := run(config.Load(), int64(id)); err != nil {
+			slog.Error("File batch failed", "action", action, "batch_id", id, "error", err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// FinalizeFileBatchHandler deletes the files an applied batch removed, through the recycle bin
+func FinalizeFileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := fileBatchUser(w, r)
+	if !ok {
+		return
+	}
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	cfg := config.Load()
+	if err := services.FinalizeFileBatch(cfg, int64(id)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	message := "Removed files deleted for good"
+	if cfg.RecycleBinEnabled() {
+		message = "Removed files moved to the recycle bin"
+	}
+	slog.Info("Finalized file batch", "batch_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// DiscardFileBatchHandler drops a plan that wasn't applied
+func DiscardFileBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := fileBatchUser(w, r); !ok {
+		return
+	}
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid batch ID", http.StatusBadRequest)
+		return
+	}
+	if err := services.DiscardFileBatch(int64(id)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Plan discarded"})
+}
@@ -0,0 +1,8 @@
+package handlers
+
+import "os"
+
+// Templates are parsed in init functions with paths relative to the server folder. Package
+// variables are initialized before any init function runs, so moving there first lets the
+// tests load them like the server does.
+var _ = os.Chdir("..")
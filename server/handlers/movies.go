@@ -43,7 +43,7 @@ func init() {
 
 type MoviesData struct {
 	Username        string
-	IsAdmin         bool
+	AdminAccess     bool
 	CurrentPage     string
 	SearchQuery     string
 	Movies          []models.Movie
@@ -82,13 +82,13 @@ func MoviesHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Separate incoming and library movies
 	var allTorrents []services.TorrentStatus
-	if user.IsAdmin {
+	if user.Can(models.PermManageLibrary) {
 		qb, err := services.NewQBittorrentClient(cfg)
 		if err == nil {
 			allTorrents, _ = qb.GetTorrentsDetailed(context.Background(), "")
 		}
 	}
-	libraryMovies, incomingMovies := SeparateIncomingMovies(allMovies, cfg, user.IsAdmin, allTorrents)
+	libraryMovies, incomingMovies := SeparateIncomingMovies(allMovies, cfg, user.Can(models.PermManageLibrary), allTorrents)
 
 	// Extract unique values for filters BEFORE filtering (so all options are available)
 	allGenres := ExtractGenresFromMovies(libraryMovies)
@@ -144,7 +144,7 @@ func MoviesHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := MoviesData{
 		Username:        user.Username,
-		IsAdmin:         user.IsAdmin,
+		AdminAccess:     user.CanAccessAdmin(),
 		CurrentPage:     "/movies",
 		SearchQuery:     "",
 		Movies:          libraryMovies,
@@ -235,22 +235,53 @@ func (h *Handlers) MovieDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	// Check library status
 	libStatus, _ := services.CheckLibraryStatus("movie", movie.TMDBID)
 
+	// The 4K library keeps its own copy, so a movie can be requested in 4K whatever its standard status
+	canRequest4K := user.Can(models.PermRequestMovies) && user.Can(models.PermRequest4K) && services.Has4KLibrary("movie")
+	var libStatus4K services.LibraryStatus
+	if canRequest4K {
+		libStatus4K, _ = services.CheckLibraryStatusFor("movie", movie.TMDBID, true)
+	}
+
+	var copies []models.Movie
+	if movie.ID > 0 && movie.TMDBID != "" {
+		if copies, err = services.GetMovieCopies(movie.TMDBID, movie.ID); err != nil {
+			slog.Error("Error getting other copies of movie", "error", err, "movie_id", movie.ID)
+		}
+	}
+
+	var watchState *models.WatchState
+	if movie.ID > 0 {
+		watchState, _ = services.GetMovieWatchState(int(user.ID), movie.ID)
+	}
+
 	data := struct {
-		Username      string
-		IsAdmin       bool
-		CurrentPage   string
-		SearchQuery   string
-		Movie         *models.Movie
-		HasSubtitles  bool
-		LibraryStatus services.LibraryStatus
+		Username         string
+		AdminAccess      bool
+		CanManageLibrary bool
+		CurrentPage      string
+		SearchQuery      string
+		Movie            *models.Movie
+		HasSubtitles     bool
+		WatchState       *models.WatchState
+		LibraryStatus    services.LibraryStatus
+		CanRequest4K     bool
+		LibraryStatus4K  services.LibraryStatus
+		Copies           []models.Movie // other editions and library copies
+		Editions         []string
 	}{
-		Username:      user.Username,
-		IsAdmin:       user.IsAdmin,
-		CurrentPage:   "/movies",
-		SearchQuery:   "",
-		Movie:         movie,
-		HasSubtitles:  services.HasSubtitles(movie.Path),
-		LibraryStatus: libStatus,
+		Username:         user.Username,
+		AdminAccess:      user.CanAccessAdmin(),
+		CanManageLibrary: user.Can(models.PermManageLibrary),
+		CurrentPage:      "/movies",
+		SearchQuery:      "",
+		Movie:            movie,
+		HasSubtitles:     services.HasSubtitles(movie.Path),
+		WatchState:       watchState,
+		LibraryStatus:    libStatus,
+		CanRequest4K:     canRequest4K,
+		LibraryStatus4K:  libStatus4K,
+		Copies:           copies,
+		Editions:         services.MovieEditions,
 	}
 
 	if err := movieDetailsTmpl.ExecuteTemplate(w, "base", data); err != nil {
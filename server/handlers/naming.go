@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// namingSampleSize is how many library items the live preview renders
+const namingSampleSize = 5
+
+// NamingTemplatesHandler returns the naming templates, the defaults and the available tokens
+func NamingTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"templates": services.GetNamingTemplates(),
+		"defaults":  services.DefaultNamingTemplates,
+		"tokens":    services.NamingTokens,
+	})
+}
+
+// SaveNamingTemplatesHandler saves the naming templates used by imports and renames
+func SaveNamingTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var templates services.NamingTemplates
+	if err := json.NewDecoder(r.Body).Decode(&templates); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SaveNamingTemplates(templates); err != nil {
+		slog.Warn("Error saving naming templates", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Saved naming templates", "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Naming templates saved"})
+}
+
+// NamingPreviewHandler renders unsaved templates against a few library movies and episodes,
+// for the live preview while editing
+func NamingPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var templates services.NamingTemplates
+	if err := json.NewDecoder(r.Body).Decode(&templates); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Invalid templates still render, so the preview keeps up while typing
+	validationError := ""
+	if err := services.ValidateNamingTemplates(templates); err != nil {
+		validationError = err.Error()
+	}
+
+	cfg := config.Load()
+	movies, err := services.PreviewMovieRenames(cfg, templates, namingSampleSize, false)
+	if err != nil {
+		slog.Error("Error previewing movie names", "error", err)
+	}
+	episodes, err := services.PreviewEpisodeRenames(cfg, templates, namingSampleSize, false)
+	if err != nil {
+		slog.Error("Error previewing episode names", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":    validationError,
+		"movies":   movies,
+		"episodes": episodes,
+	})
+}
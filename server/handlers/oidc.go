@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/services"
+	"log/slog"
+	"net/http"
+)
+
+// OIDCLoginHandler starts an OpenID Connect login by redirecting to the provider
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Load()
+	if !cfg.OIDCEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	authReq, err := services.NewOIDCAuthRequest(cfg)
+	if err != nil {
+		slog.Error("Failed to start OIDC login", "error", err)
+		renderLoginError(w, cfg, "Single sign-on is unavailable right now")
+		return
+	}
+
+	session, err := services.GetOrCreateSession(w, r)
+	if err != nil {
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+	session.Values["oidc_state"] = authReq.State
+	session.Values["oidc_nonce"] = authReq.Nonce
+	session.Values["oidc_verifier"] = authReq.Verifier
+	if err := services.SaveSession(w, r, session); err != nil {
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authReq.URL, http.StatusFound)
+}
+
+// OIDCCallbackHandler finishes an OpenID Connect login when the provider redirects back
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Load()
+	if !cfg.OIDCEnabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	session, err := services.GetOrCreateSession(w, r)
+	if err != nil {
+		http.Error(w, "Failed to get session", http.StatusInternalServerError)
+		return
+	}
+	state, _ := session.Values["oidc_state"].(string)
+	nonce, _ := session.Values["oidc_nonce"].(string)
+	verifier, _ := session.Values["oidc_verifier"].(string)
+	delete(session.Values, "oidc_state")
+	delete(session.Values, "oidc_nonce")
+	delete(session.Values, "oidc_verifier")
+	services.SaveSession(w, r, session)
+
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		slog.Warn("OIDC provider returned an error", "error", errParam, "description", r.URL.Query().Get("error_description"))
+		renderLoginError(w, cfg, "Single sign-on was cancelled or denied")
+		return
+	}
+	if state == "" || r.URL.Query().Get("state") != state {
+		slog.Warn("OIDC callback with missing or mismatched state")
+		renderLoginError(w, cfg, "Single sign-on session expired, please try again")
+		return
+	}
+
+	user, err := services.CompleteOIDCLogin(cfg, r.URL.Query().Get("code"), verifier, nonce)
+	if err != nil {
+		slog.Warn("OIDC login failed", "error", err)
+		renderLoginError(w, cfg, "Single sign-on failed: "+err.Error())
+		return
+	}
+
+	slog.Info("User authenticated via OIDC", "username", user.Username, "user_id", user.ID)
+	if err := SetupUserSession(w, r, user); err != nil {
+		slog.Error("Failed to setup session", "username", user.Username, "error", err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/services"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// startOIDCLogin runs the login handler against a stub provider's discovery document and
+// returns the session cookie and the state sent to the provider
+func startOIDCLogin(t *testing.T) (*http.Cookie, string) {
+	t.Helper()
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(idp.Close)
+
+	t.Setenv("SESSION_SECRET", "test-secret")
+	t.Setenv("OIDC_ISSUER", idp.URL)
+	t.Setenv("OIDC_CLIENT_ID", "arrgo")
+	t.Setenv("OIDC_REDIRECT_URL", "https://arrgo.test/auth/oidc/callback")
+	services.InitSessionStore(config.Load())
+
+	rec := httptest.NewRecorder()
+	OIDCLoginHandler(rec, httptest.NewRequest("GET", "/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), idp.URL+"/authorize") {
+		t.Fatalf("login redirected to %q", rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("login set no session cookie")
+	}
+	return cookies[0], location.Query().Get("state")
+}
+
+func TestOIDCCallbackRejectsBadState(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   func(state string) string
+		cookie  bool
+		wantMsg string
+	}{
+		{"mismatched state", func(string) string { return "state=forged&code=good-code" }, true, "session expired"},
+		{"missing state", func(string) string { return "code=good-code" }, true, "session expired"},
+		{"no session", func(state string) string { return "state=" + state + "&code=good-code" }, false, "session expired"},
+		{"provider error", func(state string) string { return "state=" + state + "&error=access_denied" }, true, "cancelled or denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, state := startOIDCLogin(t)
+
+			req := httptest.NewRequest("GET", "/auth/oidc/callback?"+tt.query(state), nil)
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			OIDCCallbackHandler(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantMsg) {
+				t.Errorf("body doesn't mention %q", tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackStateIsSingleUse(t *testing.T) {
+	cookie, state := startOIDCLogin(t)
+
+	// A failed callback clears the login from the session, so its state can't be replayed
+	req := httptest.NewRequest("GET", "/auth/oidc/callback?state=forged", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	OIDCCallbackHandler(rec, req)
+	cleared := rec.Result().Cookies()
+	if len(cleared) == 0 {
+		t.Fatal("callback didn't update the session cookie")
+	}
+
+	req = httptest.NewRequest("GET", "/auth/oidc/callback?state="+state+"&code=good-code", nil)
+	req.AddCookie(cleared[0])
+	rec = httptest.NewRecorder()
+	OIDCCallbackHandler(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "session expired") {
+		t.Errorf("replayed state: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// OpenAPISpec builds the OpenAPI 3.0 document for the /api/v1 routes. Schemas come from the
+// Go types of the request and response bodies, using their json tags.
+func OpenAPISpec(routes []APIRoute) map[string]any {
+	schemas := map[string]any{}
+	errorRef := schemaFor(reflect.TypeOf(APIError{}), schemas)
+	paths := map[string]any{}
+
+	for _, route := range routes {
+		op := map[string]any{
+			"tags":        []string{route.Tag},
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+		}
+		if len(route.Permissions) > 0 {
+			op["description"] = "Requires one of the permissions: " + strings.Join(route.Permissions, ", ") + "."
+		}
+
+		var params []map[string]any
+		for _, p := range route.Params {
+			schema := map[string]any{"type": p.Type}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			param := map[string]any{"name": p.Name, "in": p.In, "required": p.In == "path", "schema": schema}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if route.Body != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaFor(reflect.TypeOf(route.Body), schemas)),
+			}
+		}
+
+		responses := map[string]any{}
+		success := func(status int) {
+			response := map[string]any{"description": http.StatusText(status)}
+			if route.Response != nil {
+				response["content"] = jsonContent(schemaFor(reflect.TypeOf(route.Response), schemas))
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+		failure := func(status int) {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     jsonContent(errorRef),
+			}
+		}
+
+		success(route.Status)
+		failure(http.StatusUnauthorized)
+		if len(route.Permissions) > 0 {
+			failure(http.StatusForbidden)
+		}
+		if len(route.Params) > 0 || route.Body != nil {
+			failure(http.StatusBadRequest)
+		}
+		if strings.Contains(route.Path, "{") {
+			failure(http.StatusNotFound)
+		}
+		for _, status := range route.Also {
+			if status < 400 {
+				success(status)
+			} else {
+				failure(status)
+			}
+		}
+		op["responses"] = responses
+
+		item, _ := paths[route.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Arrgo API",
+			"version":     "1",
+			"description": "JSON API for Arrgo. Authenticate with an API key from the settings page, sent in the X-Api-Key header or as a bearer token. Keys act as their user, with the same permissions.",
+		},
+		"servers":  []map[string]any{{"url": "/api/v1"}},
+		"security": []map[string]any{{"apiKey": []string{}}, {"bearer": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// operationID names an operation after its method and path, e.g. GET /requests/{id} becomes
+// getRequestsById
+func operationID(route APIRoute) string {
+	id := strings.ToLower(route.Method)
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool { return r == '/' || r == '_' || r == '-' }) {
+		if m := pathParamPattern.FindStringSubmatch(part); m != nil {
+			part = "by_" + m[1]
+		}
+		for _, word := range strings.Split(part, "_") {
+			if word != "" {
+				id += strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+	}
+	return id
+}
+
+// schemaFor returns the schema for a Go type. Named structs are added to schemas and
+// referenced, so each appears once in the document.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := schemaFor(t.Elem(), schemas)
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, done := schemas[t.Name()]; done {
+			return ref
+		}
+		schemas[t.Name()] = nil // placeholder so recursive types terminate
+		properties := map[string]any{}
+		var required []string
+		addStructFields(t, properties, &required, schemas)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			slices.Sort(required)
+			schema["required"] = required
+		}
+		schemas[t.Name()] = schema
+		return ref
+	}
+	return map[string]any{}
+}
+
+// addStructFields adds the JSON fields of a struct to properties, flattening embedded structs
+// the way encoding/json does. Fields without omitempty are required.
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string, schemas map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, properties, required, schemas)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document for /api/v1
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(OpenAPISpec(h.APIV1Routes()))
+}
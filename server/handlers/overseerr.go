@@ -0,0 +1,1162 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Overseerr and its Jellyfin fork Jellyseerr are request managers with mobile apps and
+// integrations of their own. The handlers below implement the part of their API those clients
+// use to search, request and approve, so they can be pointed at Arrgo instead. It's mounted at
+// /overseerr/api/v1, apart from Arrgo's own /api/v1.
+
+// overseerrVersion is the Overseerr release whose API this mirrors. Clients check it when
+// connecting.
+const overseerrVersion = "1.33.2"
+
+// Overseerr permission bits, reported for users so clients know which buttons to show
+const (
+	overseerrPermAdmin          = 2
+	overseerrPermManageUsers    = 8
+	overseerrPermManageRequests = 16
+	overseerrPermRequest        = 32
+	overseerrPermAutoApprove    = 128
+	overseerrPermRequest4K      = 1024
+	overseerrPermRequest4KMovie = 2048
+	overseerrPermRequest4KTV    = 4096
+	overseerrPermRequestView    = 16384
+	overseerrPermRequestMovie   = 262144
+	overseerrPermRequestTV      = 524288
+)
+
+// Overseerr media statuses
+const (
+	overseerrMediaUnknown    = 1
+	overseerrMediaPending    = 2
+	overseerrMediaProcessing = 3
+	overseerrMediaPartial    = 4
+	overseerrMediaAvailable  = 5
+)
+
+// Overseerr request statuses
+const (
+	overseerrRequestPending  = 1
+	overseerrRequestApproved = 2
+	overseerrRequestDeclined = 3
+	overseerrRequestFailed   = 4
+)
+
+// Overseerr user types
+const (
+	overseerrUserLocal    = 2
+	overseerrUserJellyfin = 3
+)
+
+// OverseerrUser is a user as Overseerr describes them
+type OverseerrUser struct {
+	ID               int64     `json:"id"`
+	Email            string    `json:"email"`
+	Username         string    `json:"username"`
+	DisplayName      string    `json:"displayName"`
+	JellyfinUsername string    `json:"jellyfinUsername,omitempty"`
+	UserType         int       `json:"userType"`
+	Permissions      int       `json:"permissions"`
+	Avatar           string    `json:"avatar"`
+	RequestCount     int       `json:"requestCount"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// OverseerrMedia is what Overseerr knows about a movie or show: whether it's available and,
+// on search results and details, the requests for it
+type OverseerrMedia struct {
+	ID        int                     `json:"id"`
+	TMDBID    int                     `json:"tmdbId"`
+	TVDBID    int                     `json:"tvdbId,omitempty"`
+	MediaType string                  `json:"mediaType"` // "movie" or "tv"
+	Status    int                     `json:"status"`
+	Status4K  int                     `json:"status4k"`
+	Seasons   []OverseerrSeasonStatus `json:"seasons"`
+	Requests  []OverseerrRequest      `json:"requests,omitempty"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+// OverseerrSeasonStatus is the media status of one season of a show
+type OverseerrSeasonStatus struct {
+	ID           int `json:"id"`
+	SeasonNumber int `json:"seasonNumber"`
+	Status       int `json:"status"`
+	Status4K     int `json:"status4k"`
+}
+
+// OverseerrRequest is an Arrgo request as Overseerr describes it
+type OverseerrRequest struct {
+	ID          int                     `json:"id"`
+	Status      int                     `json:"status"`
+	Type        string                  `json:"type"` // "movie" or "tv"
+	Is4K        bool                    `json:"is4k"`
+	Media       OverseerrMedia          `json:"media"`
+	Seasons     []OverseerrSeasonStatus `json:"seasons"`
+	RequestedBy OverseerrUser           `json:"requestedBy"`
+	CreatedAt   time.Time               `json:"createdAt"`
+	UpdatedAt   time.Time               `json:"updatedAt"`
+}
+
+// OverseerrPageInfo describes one page of a list
+type OverseerrPageInfo struct {
+	Pages    int `json:"pages"`
+	PageSize int `json:"pageSize"`
+	Results  int `json:"results"`
+	Page     int `json:"page"`
+}
+
+// OverseerrRequestPage is one page of requests
+type OverseerrRequestPage struct {
+	PageInfo OverseerrPageInfo  `json:"pageInfo"`
+	Results  []OverseerrRequest `json:"results"`
+}
+
+// OverseerrRequestCount counts requests by type and status
+type OverseerrRequestCount struct {
+	Total      int `json:"total"`
+	Movie      int `json:"movie"`
+	TV         int `json:"tv"`
+	Pending    int `json:"pending"`
+	Approved   int `json:"approved"`
+	Declined   int `json:"declined"`
+	Processing int `json:"processing"`
+	Available  int `json:"available"`
+}
+
+// OverseerrSearchResult is a movie or show in search results. Movies fill the title fields
+// and shows the name fields.
+type OverseerrSearchResult struct {
+	ID               int             `json:"id"`
+	MediaType        string          `json:"mediaType"`
+	Title            string          `json:"title,omitempty"`
+	OriginalTitle    string          `json:"originalTitle,omitempty"`
+	ReleaseDate      string          `json:"releaseDate,omitempty"`
+	Name             string          `json:"name,omitempty"`
+	OriginalName     string          `json:"originalName,omitempty"`
+	FirstAirDate     string          `json:"firstAirDate,omitempty"`
+	Overview         string          `json:"overview"`
+	PosterPath       string          `json:"posterPath,omitempty"`
+	BackdropPath     string          `json:"backdropPath,omitempty"`
+	OriginalLanguage string          `json:"originalLanguage"`
+	VoteAverage      float64         `json:"voteAverage"`
+	VoteCount        int             `json:"voteCount"`
+	Popularity       float64         `json:"popularity"`
+	GenreIDs         []int           `json:"genreIds"`
+	Adult            bool            `json:"adult"`
+	MediaInfo        *OverseerrMedia `json:"mediaInfo,omitempty"`
+}
+
+// OverseerrSearchPage is one page of search results
+type OverseerrSearchPage struct {
+	Page         int                     `json:"page"`
+	TotalPages   int                     `json:"totalPages"`
+	TotalResults int                     `json:"totalResults"`
+	Results      []OverseerrSearchResult `json:"results"`
+}
+
+// OverseerrGenre is a TMDB genre
+type OverseerrGenre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// OverseerrMovie is a movie's details
+type OverseerrMovie struct {
+	ID            int              `json:"id"`
+	IMDBID        string           `json:"imdbId,omitempty"`
+	Title         string           `json:"title"`
+	OriginalTitle string           `json:"originalTitle"`
+	ReleaseDate   string           `json:"releaseDate"`
+	Overview      string           `json:"overview"`
+	PosterPath    string           `json:"posterPath,omitempty"`
+	BackdropPath  string           `json:"backdropPath,omitempty"`
+	Runtime       int              `json:"runtime"`
+	VoteAverage   float64          `json:"voteAverage"`
+	Genres        []OverseerrGenre `json:"genres"`
+	MediaInfo     *OverseerrMedia  `json:"mediaInfo,omitempty"`
+}
+
+// OverseerrSeason is one season of a show's details
+type OverseerrSeason struct {
+	ID           int    `json:"id"`
+	SeasonNumber int    `json:"seasonNumber"`
+	Name         string `json:"name"`
+	EpisodeCount int    `json:"episodeCount"`
+	AirDate      string `json:"airDate,omitempty"`
+	Overview     string `json:"overview"`
+	PosterPath   string `json:"posterPath,omitempty"`
+}
+
+// OverseerrShow is a show's details
+type OverseerrShow struct {
+	ID               int               `json:"id"`
+	Name             string            `json:"name"`
+	OriginalName     string            `json:"originalName"`
+	FirstAirDate     string            `json:"firstAirDate"`
+	LastAirDate      string            `json:"lastAirDate,omitempty"`
+	Status           string            `json:"status"`
+	Overview         string            `json:"overview"`
+	PosterPath       string            `json:"posterPath,omitempty"`
+	BackdropPath     string            `json:"backdropPath,omitempty"`
+	VoteAverage      float64           `json:"voteAverage"`
+	Genres           []OverseerrGenre  `json:"genres"`
+	NumberOfSeasons  int               `json:"numberOfSeasons"`
+	NumberOfEpisodes int               `json:"numberOfEpisodes"`
+	Seasons          []OverseerrSeason `json:"seasons"`
+	ExternalIDs      struct {
+		TVDBID int    `json:"tvdbId,omitempty"`
+		IMDBID string `json:"imdbId,omitempty"`
+	} `json:"externalIds"`
+	MediaInfo *OverseerrMedia `json:"mediaInfo,omitempty"`
+}
+
+func writeOverseerrError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"message": message})
+}
+
+// overseerrMapper converts Arrgo requests to Overseerr's shapes, caching the users it looks up
+type overseerrMapper struct {
+	h      *Handlers
+	users  map[int]OverseerrUser
+	counts map[int]int // requests per user
+}
+
+func (h *Handlers) newOverseerrMapper() *overseerrMapper {
+	return &overseerrMapper{h: h, users: map[int]OverseerrUser{}}
+}
+
+// overseerrUser describes an Arrgo user the way Overseerr does, with their role's permissions
+// as Overseerr permission bits
+func overseerrUser(user *models.User, requestCount int) OverseerrUser {
+	permissions := 0
+	if user.IsAdmin {
+		permissions |= overseerrPermAdmin
+	}
+	if user.Can(models.PermManageUsers) {
+		permissions |= overseerrPermManageUsers
+	}
+	if user.Can(models.PermManageLibrary) {
+		permissions |= overseerrPermManageRequests | overseerrPermRequestView
+	}
+	if user.Can(models.PermAutoApprove) {
+		permissions |= overseerrPermAutoApprove
+	}
+	if user.Can(models.PermRequestMovies) {
+		permissions |= overseerrPermRequestMovie
+	}
+	if user.Can(models.PermRequestShows) {
+		permissions |= overseerrPermRequestTV
+	}
+	if user.Can(models.PermRequestMovies) && user.Can(models.PermRequestShows) {
+		permissions |= overseerrPermRequest
+	}
+	if user.Can(models.PermRequest4K) {
+		permissions |= overseerrPermRequest4K | overseerrPermRequest4KMovie | overseerrPermRequest4KTV
+	}
+
+	out := OverseerrUser{
+		ID:           user.ID,
+		Email:        user.Email,
+		Username:     user.Username,
+		DisplayName:  user.Username,
+		UserType:     overseerrUserLocal,
+		Permissions:  permissions,
+		RequestCount: requestCount,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+	if user.JellyfinID != "" {
+		out.UserType = overseerrUserJellyfin
+		out.JellyfinUsername = user.Username
+	}
+	return out
+}
+
+// user returns the Overseerr description of a user, looking them up once per mapper
+func (m *overseerrMapper) user(id int) OverseerrUser {
+	if user, ok := m.users[id]; ok {
+		return user
+	}
+	if m.counts == nil {
+		m.counts = map[int]int{}
+		if requests, err := services.GetRequests(); err == nil {
+			for _, req := range requests {
+				m.counts[req.UserID]++
+			}
+		}
+	}
+
+	out := OverseerrUser{ID: int64(id), RequestCount: m.counts[id]}
+	if user, err := services.GetUserByID(int64(id)); err == nil {
+		out = overseerrUser(user, m.counts[id])
+	}
+	m.users[id] = out
+	return out
+}
+
+// overseerrRequestStatus maps an Arrgo request status to Overseerr's request status
+func overseerrRequestStatus(status string) int {
+	switch status {
+	case "awaiting_approval":
+		return overseerrRequestPending
+	case "cancelled":
+		return overseerrRequestDeclined
+	case "not_found":
+		return overseerrRequestFailed
+	}
+	return overseerrRequestApproved
+}
+
+// overseerrMediaStatus maps an Arrgo request status to the status of the media it asks for
+func overseerrMediaStatus(status string) int {
+	switch status {
+	case "awaiting_approval":
+		return overseerrMediaPending
+	case "pending", "downloading":
+		return overseerrMediaProcessing
+	case "completed":
+		return overseerrMediaAvailable
+	}
+	return overseerrMediaUnknown
+}
+
+// overseerrMediaType maps Arrgo's media types to Overseerr's
+func overseerrMediaType(mediaType string) string {
+	if mediaType == "show" {
+		return "tv"
+	}
+	return "movie"
+}
+
+// tmdbID returns the TMDB ID of a request. Show requests made from TVDB searches don't have
+// one, so it's looked up and saved the first time a client sees them.
+func (m *overseerrMapper) tmdbID(req models.Request) int {
+	if id, err := strconv.Atoi(req.TMDBID); err == nil {
+		return id
+	}
+	if req.MediaType != "show" || req.TVDBID == "" {
+		return 0
+	}
+	id, err := m.h.Metadata.FindTMDBShowID(req.TVDBID)
+	if err != nil || id == 0 {
+		slog.Debug("No TMDB ID found for show request", "request_id", req.ID, "tvdb_id", req.TVDBID, "error", err)
+		return 0
+	}
+	if err := services.SetRequestTMDBID(req.ID, strconv.Itoa(id)); err != nil {
+		slog.Error("Error saving TMDB ID of request", "request_id", req.ID, "error", err)
+	}
+	return id
+}
+
+// request describes an Arrgo request the way Overseerr does
+func (m *overseerrMapper) request(req models.Request) OverseerrRequest {
+	mediaType := overseerrMediaType(req.MediaType)
+	tmdbID := m.tmdbID(req)
+	tvdbID, _ := strconv.Atoi(req.TVDBID)
+	// A request only tells the status of the library it's for
+	mediaStatus, mediaStatus4K := overseerrMediaStatus(req.Status), overseerrMediaUnknown
+	if req.Is4K {
+		mediaStatus, mediaStatus4K = mediaStatus4K, mediaStatus
+	}
+
+	out := OverseerrRequest{
+		ID:     req.ID,
+		Status: overseerrRequestStatus(req.Status),
+		Type:   mediaType,
+		Is4K:   req.Is4K,
+		Media: OverseerrMedia{
+			ID:        tmdbID,
+			TMDBID:    tmdbID,
+			TVDBID:    tvdbID,
+			MediaType: mediaType,
+			Status:    mediaStatus,
+			Status4K:  mediaStatus4K,
+			Seasons:   []OverseerrSeasonStatus{},
+			CreatedAt: req.CreatedAt,
+			UpdatedAt: req.UpdatedAt,
+		},
+		Seasons:     []OverseerrSeasonStatus{},
+		RequestedBy: m.user(req.UserID),
+		CreatedAt:   req.CreatedAt,
+		UpdatedAt:   req.UpdatedAt,
+	}
+	for _, season := range requestSeasonNumbers(req) {
+		out.Seasons = append(out.Seasons, OverseerrSeasonStatus{
+			ID:           season,
+			SeasonNumber: season,
+			Status:       mediaStatus,
+			Status4K:     mediaStatus4K,
+		})
+	}
+	return out
+}
+
+// requestSeasonNumbers returns the seasons a show request asks for, whole or in part
+func requestSeasonNumbers(req models.Request) []int {
+	var seasons []int
+	for _, s := range strings.Split(req.Seasons, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && !slices.Contains(seasons, n) {
+			seasons = append(seasons, n)
+		}
+	}
+	for _, e := range strings.Split(req.Episodes, ",") {
+		var season, episode int
+		if _, err := fmt.Sscanf(strings.TrimSpace(e), "S%02dE%02d", &season, &episode); err == nil && !slices.Contains(seasons, season) {
+			seasons = append(seasons, season)
+		}
+	}
+	slices.Sort(seasons)
+	return seasons
+}
+
+// newMedia starts the media info for a movie or show from its requests, newest first
+func (m *overseerrMapper) newMedia(mediaType string, tmdbID, tvdbID int, requests []models.Request) *OverseerrMedia {
+	media := &OverseerrMedia{
+		ID:        tmdbID,
+		TMDBID:    tmdbID,
+		TVDBID:    tvdbID,
+		MediaType: mediaType,
+		Status:    overseerrMediaUnknown,
+		Status4K:  overseerrMediaUnknown,
+		Seasons:   []OverseerrSeasonStatus{},
+		Requests:  []OverseerrRequest{},
+	}
+	for _, req := range requests {
+		if req.TMDBID == "" {
+			req.TMDBID = strconv.Itoa(tmdbID)
+		}
+		media.Requests = append(media.Requests, m.request(req))
+	}
+	if len(requests) > 0 {
+		media.CreatedAt = requests[len(requests)-1].CreatedAt
+		media.UpdatedAt = requests[0].UpdatedAt
+	}
+	// Each library's status comes from its newest request
+	if i := slices.IndexFunc(requests, func(req models.Request) bool { return !req.Is4K }); i >= 0 {
+		media.Status = overseerrMediaStatus(requests[i].Status)
+	}
+	if i := slices.IndexFunc(requests, func(req models.Request) bool { return req.Is4K }); i >= 0 {
+		media.Status4K = overseerrMediaStatus(requests[i].Status)
+	}
+	return media
+}
+
+// overseerrLibraryStatus is the media status of a movie or show in a library
+func overseerrLibraryStatus(library services.LibraryStatus) int {
+	if library.Message == "Downloading/Processing" {
+		return overseerrMediaProcessing
+	}
+	return overseerrMediaAvailable
+}
+
+// movieMedia returns the media info of a movie, or nil if it's neither in the library nor
+// requested
+func (m *overseerrMapper) movieMedia(tmdbID int) *OverseerrMedia {
+	id := strconv.Itoa(tmdbID)
+	library, err := services.CheckLibraryStatus("movie", id)
+	if err != nil {
+		slog.Error("Error checking library status", "tmdb_id", id, "error", err)
+	}
+	library4K, err := services.CheckLibraryStatusFor("movie", id, true)
+	if err != nil {
+		slog.Error("Error checking 4K library status", "tmdb_id", id, "error", err)
+	}
+	requests, err := services.GetRequestsForMedia("movie", id)
+	if err != nil {
+		slog.Error("Error getting requests for movie", "tmdb_id", id, "error", err)
+	}
+	if !library.Exists && !library4K.Exists && len(requests) == 0 {
+		return nil
+	}
+
+	media := m.newMedia("movie", tmdbID, 0, requests)
+	if library.Exists {
+		media.Status = overseerrLibraryStatus(library)
+	}
+	if library4K.Exists {
+		media.Status4K = overseerrLibraryStatus(library4K)
+	}
+	return media
+}
+
+// showMedia returns the media info of a show, with the status of each season, or nil if it's
+// neither in the library nor requested
+func (m *overseerrMapper) showMedia(show *services.TMDBShowDetails) *OverseerrMedia {
+	tvdbID := show.ExternalIDs.TVDBID
+	if tvdbID == 0 {
+		return nil
+	}
+	id := strconv.Itoa(tvdbID)
+	library, err := services.CheckLibraryStatus("show", id)
+	if err != nil {
+		slog.Error("Error checking library status", "tvdb_id", id, "error", err)
+	}
+	library4K, err := services.CheckLibraryStatusFor("show", id, true)
+	if err != nil {
+		slog.Error("Error checking 4K library status", "tvdb_id", id, "error", err)
+	}
+	requests, err := services.GetRequestsForMedia("show", id)
+	if err != nil {
+		slog.Error("Error getting requests for show", "tvdb_id", id, "error", err)
+	}
+	if !library.Exists && !library4K.Exists && len(requests) == 0 {
+		return nil
+	}
+
+	seasonStatus := overseerrSeasonStatuses(library, requests, false)
+	seasonStatus4K := overseerrSeasonStatuses(library4K, requests, true)
+
+	media := m.newMedia("tv", show.ID, tvdbID, requests)
+	media.Status, media.Status4K = overseerrMediaUnknown, overseerrMediaUnknown
+	var aired, available, available4K int
+	for _, season := range show.Seasons {
+		if season.SeasonNumber == 0 {
+			continue
+		}
+		aired++
+		status, ok := seasonStatus[season.SeasonNumber]
+		status4K, ok4K := seasonStatus4K[season.SeasonNumber]
+		if !ok && !ok4K {
+			continue
+		}
+		media.Seasons = append(media.Seasons, OverseerrSeasonStatus{
+			ID:           season.ID,
+			SeasonNumber: season.SeasonNumber,
+			Status:       max(status, overseerrMediaUnknown),
+			Status4K:     max(status4K, overseerrMediaUnknown),
+		})
+		if status == overseerrMediaAvailable {
+			available++
+		} else {
+			media.Status = max(media.Status, status)
+		}
+		if status4K == overseerrMediaAvailable {
+			available4K++
+		} else {
+			media.Status4K = max(media.Status4K, status4K)
+		}
+	}
+	media.Status = overseerrShowStatus(media.Status, aired, available)
+	media.Status4K = overseerrShowStatus(media.Status4K, aired, available4K)
+	return media
+}
+
+// overseerrSeasonStatuses returns the status of each season of a show in one library. Seasons
+// of the library's unfinished requests are pending or processing, and seasons that are
+// complete in the library are available whatever their requests say.
+func overseerrSeasonStatuses(library services.LibraryStatus, requests []models.Request, is4K bool) map[int]int {
+	seasonStatus := map[int]int{}
+	for _, req := range requests {
+		status := overseerrMediaStatus(req.Status)
+		if req.Is4K != is4K || (status != overseerrMediaPending && status != overseerrMediaProcessing) {
+			continue
+		}
+		for _, season := range requestSeasonNumbers(req) {
+			seasonStatus[season] = max(seasonStatus[season], status)
+		}
+	}
+	for _, season := range library.Seasons {
+		seasonStatus[season] = overseerrMediaAvailable
+	}
+	return seasonStatus
+}
+
+// overseerrShowStatus is the status of a show from the most advanced status of its seasons
+// that aren't available yet, and how many of its aired seasons are
+func overseerrShowStatus(status, aired, available int) int {
+	switch {
+	case aired > 0 && available == aired:
+		return overseerrMediaAvailable
+	case available > 0 && status == overseerrMediaUnknown:
+		return overseerrMediaPartial
+	}
+	return status
+}
+
+// OverseerrStatusHandler reports the Overseerr version the API mirrors
+func OverseerrStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, map[string]any{
+		"version":         overseerrVersion,
+		"commitTag":       "arrgo",
+		"updateAvailable": false,
+		"commitsBehind":   0,
+		"restartRequired": false,
+	})
+}
+
+// OverseerrPublicSettingsHandler tells clients how to sign in
+func OverseerrPublicSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Load()
+	mediaServerType := 4 // not configured, so clients offer the local login
+	if cfg.UseJellyfinAuth() {
+		mediaServerType = 2
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]any{
+		"initialized":            true,
+		"applicationTitle":       "Arrgo",
+		"applicationUrl":         "",
+		"mediaServerType":        mediaServerType,
+		"jellyfinServerName":     "Jellyfin",
+		"localLogin":             true,
+		"movie4kEnabled":         services.Has4KLibrary("movie"),
+		"series4kEnabled":        services.Has4KLibrary("show"),
+		"partialRequestsEnabled": true,
+		"hideAvailable":          false,
+		"enablePushRegistration": false,
+		"region":                 "",
+		"originalLanguage":       "",
+		"locale":                 "en",
+	})
+}
+
+// OverseerrLoginHandler signs in with a username and password and starts a session. Local
+// logins send the username in the email field and Jellyfin logins in the username field;
+// both are checked the same way as the login page.
+func OverseerrLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOverseerrError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	username := req.Username
+	if username == "" {
+		username = req.Email
+	}
+	if username == "" || req.Password == "" {
+		writeOverseerrError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+
+	user, err := authenticateLogin(config.Load(), username, req.Password)
+	if errors.Is(err, services.ErrAccountDisabled) {
+		slog.Warn("Login refused for disabled account", "username", username)
+		writeOverseerrError(w, http.StatusForbidden, "This account has been disabled")
+		return
+	}
+	if err != nil {
+		slog.Warn("Login failed", "username", username, "error", err)
+		writeOverseerrError(w, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+	if err := SetupUserSession(w, r, user); err != nil {
+		slog.Error("Failed to setup session", "username", username, "error", err)
+		writeOverseerrError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+
+	slog.Info("User signed in through the Overseerr API", "username", user.Username, "user_id", user.ID)
+	writeAPIJSON(w, http.StatusOK, overseerrUser(user, 0))
+}
+
+// OverseerrLogoutHandler ends the session
+func OverseerrLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := services.GetSession(r)
+	if err == nil {
+		session.Values = make(map[any]any)
+		session.Options.MaxAge = -1
+		services.SaveSession(w, r, session)
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// OverseerrMeHandler returns the signed-in user
+func (h *Handlers) OverseerrMeHandler(w http.ResponseWriter, r *http.Request) {
+	user := apiUser(r)
+	writeAPIJSON(w, http.StatusOK, h.newOverseerrMapper().user(int(user.ID)))
+}
+
+// OverseerrSearchHandler searches TMDB for movies and shows. Movies in the results carry
+// their library and request status; shows only get it on their details, since Arrgo tracks
+// them by TVDB ID.
+func (h *Handlers) OverseerrSearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		writeOverseerrError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	page, err := apiQueryInt(r, "page")
+	if err != nil {
+		writeOverseerrError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := h.Metadata.SearchTMDBMulti(query, page)
+	if err != nil {
+		slog.Error("Error searching TMDB", "query", query, "error", err)
+		writeOverseerrError(w, http.StatusInternalServerError, "Search failed")
+		return
+	}
+
+	mapper := h.newOverseerrMapper()
+	out := OverseerrSearchPage{
+		Page:         results.Page,
+		TotalPages:   results.TotalPages,
+		TotalResults: results.TotalResults,
+		Results:      []OverseerrSearchResult{},
+	}
+	for _, result := range results.Results {
+		item := OverseerrSearchResult{
+			ID:               result.ID,
+			MediaType:        result.MediaType,
+			Title:            result.Title,
+			OriginalTitle:    result.OriginalTitle,
+			ReleaseDate:      result.ReleaseDate,
+			Name:             result.Name,
+			OriginalName:     result.OriginalName,
+			FirstAirDate:     result.FirstAirDate,
+			Overview:         result.Overview,
+			PosterPath:       result.PosterPath,
+			BackdropPath:     result.BackdropPath,
+			OriginalLanguage: result.OriginalLanguage,
+			VoteAverage:      result.VoteAverage,
+			VoteCount:        result.VoteCount,
+			Popularity:       result.Popularity,
+			GenreIDs:         result.GenreIDs,
+			Adult:            result.Adult,
+		}
+		if item.GenreIDs == nil {
+			item.GenreIDs = []int{}
+		}
+		if result.MediaType == "movie" {
+			item.MediaInfo = mapper.movieMedia(result.ID)
+		}
+		out.Results = append(out.Results, item)
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+// overseerrTMDBID parses the TMDB ID in the {id} path parameter
+func overseerrTMDBID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeOverseerrError(w, http.StatusBadRequest, "Invalid ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// OverseerrMovieHandler returns a movie's details by TMDB ID
+func (h *Handlers) OverseerrMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := overseerrTMDBID(w, r)
+	if !ok {
+		return
+	}
+	details, err := h.Metadata.GetTMDBMovieDetails(strconv.Itoa(id))
+	if err != nil {
+		slog.Error("Error getting TMDB movie details", "tmdb_id", id, "error", err)
+		writeOverseerrError(w, http.StatusNotFound, "Movie not found")
+		return
+	}
+
+	movie := OverseerrMovie{
+		ID:            details.ID,
+		IMDBID:        details.IMDBID,
+		Title:         details.Title,
+		OriginalTitle: details.OriginalTitle,
+		ReleaseDate:   details.ReleaseDate,
+		Overview:      details.Overview,
+		PosterPath:    details.PosterPath,
+		BackdropPath:  details.BackdropPath,
+		Runtime:       details.Runtime,
+		VoteAverage:   details.VoteAverage,
+		Genres:        []OverseerrGenre{},
+		MediaInfo:     h.newOverseerrMapper().movieMedia(details.ID),
+	}
+	for _, g := range details.Genres {
+		movie.Genres = append(movie.Genres, OverseerrGenre{ID: g.ID, Name: g.Name})
+	}
+	writeAPIJSON(w, http.StatusOK, movie)
+}
+
+// OverseerrShowHandler returns a show's details by TMDB ID
+func (h *Handlers) OverseerrShowHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := overseerrTMDBID(w, r)
+	if !ok {
+		return
+	}
+	details, err := h.Metadata.GetTMDBShowDetails(strconv.Itoa(id))
+	if err != nil {
+		slog.Error("Error getting TMDB show details", "tmdb_id", id, "error", err)
+		writeOverseerrError(w, http.StatusNotFound, "Show not found")
+		return
+	}
+
+	show := OverseerrShow{
+		ID:               details.ID,
+		Name:             details.Name,
+		OriginalName:     details.OriginalName,
+		FirstAirDate:     details.FirstAirDate,
+		LastAirDate:      details.LastAirDate,
+		Status:           details.Status,
+		Overview:         details.Overview,
+		PosterPath:       details.PosterPath,
+		BackdropPath:     details.BackdropPath,
+		VoteAverage:      details.VoteAverage,
+		Genres:           []OverseerrGenre{},
+		NumberOfSeasons:  details.NumberOfSeasons,
+		NumberOfEpisodes: details.NumberOfEpisodes,
+		Seasons:          []OverseerrSeason{},
+		MediaInfo:        h.newOverseerrMapper().showMedia(details),
+	}
+	show.ExternalIDs.TVDBID = details.ExternalIDs.TVDBID
+	show.ExternalIDs.IMDBID = details.ExternalIDs.IMDBID
+	for _, g := range details.Genres {
+		show.Genres = append(show.Genres, OverseerrGenre{ID: g.ID, Name: g.Name})
+	}
+	for _, s := range details.Seasons {
+		show.Seasons = append(show.Seasons, OverseerrSeason{
+			ID:           s.ID,
+			SeasonNumber: s.SeasonNumber,
+			Name:         s.Name,
+			EpisodeCount: s.EpisodeCount,
+			AirDate:      s.AirDate,
+			Overview:     s.Overview,
+			PosterPath:   s.PosterPath,
+		})
+	}
+	writeAPIJSON(w, http.StatusOK, show)
+}
+
+// overseerrFilters are the request list filters, by the Arrgo statuses they match
+var overseerrFilters = map[string][]string{
+	"approved":    {"pending", "downloading", "completed"},
+	"available":   {"completed"},
+	"pending":     {"awaiting_approval"},
+	"processing":  {"pending", "downloading"},
+	"unavailable": {"awaiting_approval", "pending", "downloading", "not_found"},
+	"failed":      {"not_found"},
+	"declined":    {"cancelled"},
+}
+
+// OverseerrRequestsHandler lists requests a page at a time, newest first
+func (h *Handlers) OverseerrRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	take, err := apiQueryInt(r, "take")
+	if err == nil {
+		var skip, requestedBy int
+		if skip, err = apiQueryInt(r, "skip"); err == nil {
+			if requestedBy, err = apiQueryInt(r, "requestedBy"); err == nil {
+				h.writeOverseerrRequests(w, r, take, skip, requestedBy)
+				return
+			}
+		}
+	}
+	writeOverseerrError(w, http.StatusBadRequest, err.Error())
+}
+
+func (h *Handlers) writeOverseerrRequests(w http.ResponseWriter, r *http.Request, take, skip, requestedBy int) {
+	requests, err := services.GetRequests()
+	if err != nil {
+		slog.Error("Error getting requests", "error", err)
+		writeOverseerrError(w, http.StatusInternalServerError, "Failed to load requests")
+		return
+	}
+
+	filter := r.URL.Query().Get("filter")
+	statuses, known := overseerrFilters[filter]
+	if filter != "" && filter != "all" && !known {
+		writeOverseerrError(w, http.StatusBadRequest, "Unknown filter")
+		return
+	}
+	var matching []models.Request
+	for _, req := range requests {
+		if (!known || slices.Contains(statuses, req.Status)) && (requestedBy == 0 || req.UserID == requestedBy) {
+			matching = append(matching, req)
+		}
+	}
+	if r.URL.Query().Get("sort") == "modified" {
+		slices.SortStableFunc(matching, func(a, b models.Request) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	}
+
+	if take <= 0 {
+		take = 10
+	}
+	page := matching[min(skip, len(matching)):]
+	page = page[:min(take, len(page))]
+
+	mapper := h.newOverseerrMapper()
+	out := OverseerrRequestPage{
+		PageInfo: OverseerrPageInfo{
+			Pages:    (len(matching) + take - 1) / take,
+			PageSize: take,
+			Results:  len(matching),
+			Page:     skip/take + 1,
+		},
+		Results: []OverseerrRequest{},
+	}
+	for _, req := range page {
+		out.Results = append(out.Results, mapper.request(req))
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+// OverseerrRequestCountHandler counts requests by type and status
+func OverseerrRequestCountHandler(w http.ResponseWriter, r *http.Request) {
+	requests, err := services.GetRequests()
+	if err != nil {
+		slog.Error("Error getting requests", "error", err)
+		writeOverseerrError(w, http.StatusInternalServerError, "Failed to load requests")
+		return
+	}
+
+	var count OverseerrRequestCount
+	for _, req := range requests {
+		count.Total++
+		if req.MediaType == "movie" {
+			count.Movie++
+		} else {
+			count.TV++
+		}
+		switch req.Status {
+		case "awaiting_approval":
+			count.Pending++
+		case "cancelled":
+			count.Declined++
+		case "pending", "downloading":
+			count.Approved++
+			count.Processing++
+		case "completed":
+			count.Approved++
+			count.Available++
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, count)
+}
+
+// OverseerrRequestHandler returns one request
+func (h *Handlers) OverseerrRequestHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := overseerrRequest(w, r)
+	if !ok {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, h.newOverseerrMapper().request(*req))
+}
+
+// overseerrRequest loads the request in the {id} path parameter
+func overseerrRequest(w http.ResponseWriter, r *http.Request) (*models.Request, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeOverseerrError(w, http.StatusBadRequest, "Invalid request ID")
+		return nil, false
+	}
+	req, err := services.GetRequestByID(id)
+	if err != nil {
+		writeOverseerrError(w, http.StatusNotFound, "Request not found")
+		return nil, false
+	}
+	return req, true
+}
+
+// OverseerrCreateRequestHandler requests a movie or show by TMDB ID. Shows need a TVDB ID,
+// which comes from the body or TMDB's external IDs; seasons may be a list of season numbers
+// or "all", which asks for every season not yet in the library or requested.
+func (h *Handlers) OverseerrCreateRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		MediaType string          `json:"mediaType"`
+		MediaID   int             `json:"mediaId"`
+		TVDBID    int             `json:"tvdbId"`
+		Seasons   json.RawMessage `json:"seasons"`
+		Is4K      bool            `json:"is4k"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeOverseerrError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if body.MediaID <= 0 {
+		writeOverseerrError(w, http.StatusBadRequest, "mediaId is required")
+		return
+	}
+	var req models.Request
+	switch body.MediaType {
+	case "movie":
+		details, err := h.Metadata.GetTMDBMovieDetails(strconv.Itoa(body.MediaID))
+		if err != nil {
+			slog.Error("Error getting TMDB movie details", "tmdb_id", body.MediaID, "error", err)
+			writeOverseerrError(w, http.StatusNotFound, "Movie not found")
+			return
+		}
+		req = models.Request{
+			MediaType:  "movie",
+			Title:      details.Title,
+			Year:       yearOf(details.ReleaseDate),
+			TMDBID:     strconv.Itoa(details.ID),
+			IMDBID:     details.IMDBID,
+			PosterPath: details.PosterPath,
+			Overview:   details.Overview,
+		}
+
+	case "tv":
+		details, err := h.Metadata.GetTMDBShowDetails(strconv.Itoa(body.MediaID))
+		if err != nil {
+			slog.Error("Error getting TMDB show details", "tmdb_id", body.MediaID, "error", err)
+			writeOverseerrError(w, http.StatusNotFound, "Show not found")
+			return
+		}
+		tvdbID := body.TVDBID
+		if tvdbID == 0 {
+			tvdbID = details.ExternalIDs.TVDBID
+		}
+		if tvdbID == 0 {
+			writeOverseerrError(w, http.StatusBadRequest, "This show has no TVDB ID, so it can't be requested")
+			return
+		}
+		seasons, err := overseerrSeasons(body.Seasons, details, strconv.Itoa(tvdbID), body.Is4K)
+		if err != nil {
+			writeOverseerrError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(seasons) == 0 {
+			writeOverseerrError(w, http.StatusConflict, "Every season is already available or requested")
+			return
+		}
+		req = models.Request{
+			MediaType:  "show",
+			Title:      details.Name,
+			Year:       yearOf(details.FirstAirDate),
+			TMDBID:     strconv.Itoa(details.ID),
+			TVDBID:     strconv.Itoa(tvdbID),
+			IMDBID:     details.ExternalIDs.IMDBID,
+			PosterPath: details.PosterPath,
+			Overview:   details.Overview,
+			Seasons:    strings.Join(seasons, ","),
+		}
+
+	default:
+		writeOverseerrError(w, http.StatusBadRequest, "mediaType must be movie or tv")
+		return
+	}
+
+	req.Is4K = body.Is4K
+	requestID, status, err := h.submitRequest(apiUser(r), req)
+	if err != nil {
+		writeOverseerrError(w, status, err.Error())
+		return
+	}
+	created, err := services.GetRequestByID(requestID)
+	if err != nil {
+		slog.Error("Error loading new request", "request_id", requestID, "error", err)
+		writeOverseerrError(w, http.StatusInternalServerError, "Failed to load request")
+		return
+	}
+	// Overseerr replies 201 whether or not the request needs approval; its status says which
+	writeAPIJSON(w, http.StatusCreated, h.newOverseerrMapper().request(*created))
+}
+
+// overseerrSeasons parses the seasons of a show request. "all" means every season after the
+// specials that isn't complete in the request's library or already requested for it.
+func overseerrSeasons(raw json.RawMessage, show *services.TMDBShowDetails, tvdbID string, is4K bool) ([]string, error) {
+	var numbers []int
+	var all string
+	if err := json.Unmarshal(raw, &all); err == nil && all == "all" {
+		library, err := services.CheckLibraryStatusFor("show", tvdbID, is4K)
+		if err != nil {
+			slog.Error("Error checking library status", "tvdb_id", tvdbID, "error", err)
+		}
+		taken := library.Seasons
+		if requests, err := services.GetRequestsForMedia("show", tvdbID); err == nil {
+			for _, req := range requests {
+				if req.Is4K == is4K && (req.Status == "awaiting_approval" || req.Status == "pending" || req.Status == "downloading") {
+					taken = append(taken, requestSeasonNumbers(req)...)
+				}
+			}
+		}
+		for _, season := range show.Seasons {
+			if season.SeasonNumber > 0 && !slices.Contains(taken, season.SeasonNumber) {
+				numbers = append(numbers, season.SeasonNumber)
+			}
+		}
+	} else if err := json.Unmarshal(raw, &numbers); err != nil {
+		return nil, errors.New(`seasons must be a list of season numbers or "all"`)
+	}
+
+	var seasons []string
+	for _, n := range numbers {
+		seasons = append(seasons, strconv.Itoa(n))
+	}
+	return seasons, nil
+}
+
+// yearOf returns the year of a YYYY-MM-DD date, or 0
+func yearOf(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(date[:4])
+	return year
+}
+
+// OverseerrRequestStatusHandler approves or declines a request
+func (h *Handlers) OverseerrRequestStatusHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := overseerrRequest(w, r)
+	if !ok {
+		return
+	}
+	user := apiUser(r)
+
+	switch chi.URLParam(r, "status") {
+	case "approve":
+		if err := services.UpdateRequestStatus(req.ID, "pending"); err != nil {
+			slog.Error("Error approving request", "error", err, "request_id", req.ID, "user", user.Username)
+			writeOverseerrError(w, http.StatusInternalServerError, "Failed to approve request")
+			return
+		}
+		if automation := h.Automation; automation != nil {
+			processCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			go func() {
+				defer cancel()
+				automation.TriggerImmediateProcessing(processCtx)
+			}()
+		}
+	case "decline":
+		if err := services.UpdateRequestStatus(req.ID, "cancelled"); err != nil {
+			slog.Error("Error denying request", "error", err, "request_id", req.ID, "user", user.Username)
+			writeOverseerrError(w, http.StatusInternalServerError, "Failed to decline request")
+			return
+		}
+		if err := services.RefundRequestUsage(req.ID); err != nil {
+			slog.Error("Error refunding quota for denied request", "error", err, "request_id", req.ID)
+		}
+	default:
+		writeOverseerrError(w, http.StatusNotFound, "Unknown request status")
+		return
+	}
+
+	h.OverseerrRequestHandler(w, r)
+}
+
+// OverseerrDeleteRequestHandler deletes a request and removes its torrents
+func OverseerrDeleteRequestHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := overseerrRequest(w, r)
+	if !ok {
+		return
+	}
+	qb, _ := services.NewQBittorrentClient(config.Load())
+	if err := services.DeleteRequest(req.ID, qb); err != nil {
+		slog.Error("Error deleting request", "error", err, "request_id", req.ID, "user", apiUser(r).Username)
+		writeOverseerrError(w, http.StatusInternalServerError, "Failed to delete request")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
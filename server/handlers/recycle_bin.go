@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+var recycleBinTmpl *template.Template
+
+func init() {
+	var err error
+	recycleBinTmpl, err = template.New("recycle_bin").Funcs(FuncMap()).ParseFiles(
+		"templates/layouts/base.html",
+		"templates/pages/recycle_bin.html",
+		"templates/components/navigation.html",
+	)
+	if err != nil {
+		slog.Error("Failed to parse recycle bin template", "error", err)
+		os.Exit(1)
+	}
+}
+
+type RecycleBinPageData struct {
+	Username    string
+	AdminAccess bool
+	CurrentPage string
+	SearchQuery string
+	Enabled     bool // RECYCLE_BIN_PATH is set
+	Path        string
+	Days        int
+	MinFreeGB   float64
+	Items       []models.RecycledItem
+	TotalSize   int64
+}
+
+// RecycleBinHandler shows the recycle bin page
+func RecycleBinHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	items, err := services.GetRecycledItems()
+	if err != nil {
+		slog.Error("Error getting recycle bin items", "error", err)
+	}
+	var total int64
+	for _, item := range items {
+		total += item.Size
+	}
+
+	cfg := config.Load()
+	data := RecycleBinPageData{
+		Username:    user.Username,
+		AdminAccess: user.CanAccessAdmin(),
+		CurrentPage: "/admin",
+		Enabled:     cfg.RecycleBinEnabled(),
+		Path:        cfg.RecycleBinPath,
+		Days:        cfg.RecycleBinDays,
+		MinFreeGB:   cfg.RecycleBinMinFreeGB,
+		Items:       items,
+		TotalSize:   total,
+	}
+
+	if err := recycleBinTmpl.ExecuteTemplate(w, "base", data); err != nil {
+		slog.Error("Error rendering recycle bin template", "error", err)
+	}
+}
+
+// RestoreRecycledItemHandler moves a recycled item back to where it was deleted from
+func RestoreRecycledItemHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+	if err := services.RestoreRecycledItem(config.Load(), int64(id)); err != nil {
+		slog.Error("Error restoring recycled item", "item_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Restored recycled item", "item_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Restored"})
+}
+
+// DeleteRecycledItemHandler permanently deletes one recycled item
+func DeleteRecycledItemHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid item ID", http.StatusBadRequest)
+		return
+	}
+	if err := services.DeleteRecycledItem(config.Load(), int64(id)); err != nil {
+		slog.Error("Error deleting recycled item", "item_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Deleted recycled item", "item_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Deleted"})
+}
+
+// EmptyRecycleBinHandler permanently deletes everything in the recycle bin
+func EmptyRecycleBinHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deleted, err := services.EmptyRecycleBin(config.Load())
+	if err != nil {
+		slog.Error("Error emptying recycle bin", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Emptied recycle bin", "items", deleted, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Deleted %d items", deleted)})
+}
@@ -6,6 +6,7 @@ import (
 	"Arrgo/services"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
@@ -36,11 +37,13 @@ func init() {
 }
 
 type RequestsData struct {
-	Username    string
-	IsAdmin     bool
-	CurrentPage string
-	SearchQuery string
-	Requests    []models.Request
+	Username         string
+	AdminAccess      bool
+	CanManageLibrary bool
+	CurrentPage      string
+	SearchQuery      string
+	Requests         []models.Request
+	RootFolders      []models.RootFolder // only loaded for library managers
 }
 
 func RequestsHandler(w http.ResponseWriter, r *http.Request) {
@@ -57,11 +60,17 @@ func RequestsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := RequestsData{
-		Username:    user.Username,
-		IsAdmin:     user.IsAdmin,
-		CurrentPage: "/requests",
-		SearchQuery: "",
-		Requests:    requests,
+		Username:         user.Username,
+		AdminAccess:      user.CanAccessAdmin(),
+		CanManageLibrary: user.Can(models.PermManageLibrary),
+		CurrentPage:      "/requests",
+		SearchQuery:      "",
+		Requests:         requests,
+	}
+	if data.CanManageLibrary {
+		if data.RootFolders, err = services.GetRootFolders(""); err != nil {
+			slog.Error("Error getting root folders", "error", err)
+		}
 	}
 
 	if err := requestsTmpl.ExecuteTemplate(w, "base", data); err != nil {
@@ -88,8 +97,61 @@ func (h *Handlers) CreateRequestHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	_, status, err := h.submitRequest(user, req)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// submitRequest checks a request against the user's permissions, quota and the library,
+// creates it and starts processing it. The returned status is 201 when the request will be
+// downloaded, 202 when it awaits approval, or the error status to reply with.
+func (h *Handlers) submitRequest(user *models.User, req models.Request) (int, int, error) {
 	req.UserID = int(user.ID)
 
+	if (req.MediaType == "movie" && !user.Can(models.PermRequestMovies)) || (req.MediaType == "show" && !user.Can(models.PermRequestShows)) {
+		return 0, http.StatusForbidden, errors.New("You don't have permission to request this")
+	}
+	if req.Edition != "" {
+		if req.MediaType != "movie" {
+			return 0, http.StatusBadRequest, errors.New("Only movies have editions")
+		}
+		edition := services.DetectEdition(req.Edition)
+		if edition == "" {
+			return 0, http.StatusBadRequest, fmt.Errorf("Unknown edition %q", req.Edition)
+		}
+		req.Edition = edition
+	}
+	if req.Is4K {
+		if !user.Can(models.PermRequest4K) {
+			return 0, http.StatusForbidden, errors.New("You don't have permission to request 4K")
+		}
+		if !services.Has4KLibrary(req.MediaType) {
+			return 0, http.StatusBadRequest, errors.New("There's no 4K library for this media type")
+		}
+	}
+
+	// Only people who manage the library pick where media goes; everyone else gets their role's folder
+	if req.RootFolderID != nil {
+		if !user.Can(models.PermManageLibrary) {
+			return 0, http.StatusForbidden, errors.New("You don't have permission to choose a root folder")
+		}
+		if err := services.ValidateRootFolder(*req.RootFolderID, req.MediaType, req.Is4K); err != nil {
+			return 0, http.StatusBadRequest, err
+		}
+	}
+
+	quota, err := services.GetUserQuota(user)
+	if err != nil {
+		slog.Error("Error checking request quota", "error", err, "user_id", user.ID)
+		return 0, http.StatusInternalServerError, errors.New("Failed to check request quota")
+	}
+	if !quota.Allows(req.MediaType, services.RequestSeasonCount(req)) {
+		return 0, http.StatusTooManyRequests, errors.New(quotaExceededMessage(quota, req))
+	}
+
 	// Final server-side check to prevent duplicate requests or requesting library items
 	var externalID string
 	if req.MediaType == "movie" {
@@ -98,17 +160,20 @@ func (h *Handlers) CreateRequestHandler(w http.ResponseWriter, r *http.Request)
 		externalID = req.TVDBID
 	}
 
-	status, err := services.CheckLibraryStatus(req.MediaType, externalID)
+	var status services.LibraryStatus
+	if req.Edition != "" {
+		status = services.CheckMovieEditionStatus(externalID, req.Edition, req.Is4K)
+	} else {
+		status, err = services.CheckLibraryStatusFor(req.MediaType, externalID, req.Is4K)
+	}
 	if err != nil {
 		slog.Error("Error checking library status", "error", err, "media_type", req.MediaType, "external_id", externalID)
-		http.Error(w, "Failed to verify media status", http.StatusInternalServerError)
-		return
+		return 0, http.StatusInternalServerError, errors.New("Failed to verify media status")
 	}
 
 	if req.MediaType == "movie" {
 		if status.Exists || strings.Contains(status.Message, "Already requested") {
-			http.Error(w, "Movie already exists or has been requested", http.StatusConflict)
-			return
+			return 0, http.StatusConflict, errors.New("Movie already exists or has been requested")
 		}
 	} else if req.MediaType == "show" {
 		// For shows, we check if the requested seasons are already in library or already requested
@@ -135,8 +200,7 @@ func (h *Handlers) CreateRequestHandler(w http.ResponseWriter, r *http.Request)
 
 		// Block if any seasons are duplicates
 		if len(duplicateSeasons) > 0 {
-			http.Error(w, fmt.Sprintf("Season(s) %s already exist(s) or have been requested", strings.Join(duplicateSeasons, ", ")), http.StatusConflict)
-			return
+			return 0, http.StatusConflict, fmt.Errorf("Season(s) %s already exist(s) or have been requested", strings.Join(duplicateSeasons, ", "))
 		}
 
 		// Also check episodes
@@ -150,19 +214,46 @@ func (h *Handlers) CreateRequestHandler(w http.ResponseWriter, r *http.Request)
 				}
 			}
 			if len(duplicateEpisodes) > 0 {
-				http.Error(w, fmt.Sprintf("Episode(s) %s already requested", strings.Join(duplicateEpisodes, ", ")), http.StatusConflict)
-				return
+				return 0, http.StatusConflict, fmt.Errorf("Episode(s) %s already requested", strings.Join(duplicateEpisodes, ", "))
 			}
 		}
 	}
 
-	if err := services.CreateRequest(req); err != nil {
+	// Requests from users without auto-approval wait for someone who can manage the library,
+	// unless they add seasons to a show that's already partly in the library
+	cfg := config.Load()
+	req.Status = "pending"
+	if !user.Can(models.PermAutoApprove) {
+		req.Status = "awaiting_approval"
+		if cfg.AutoApprovePartial && req.MediaType == "show" && len(status.Seasons) > 0 {
+			slog.Info("Auto-approving request for show already partly in library", "user_id", req.UserID, "title", req.Title)
+			req.Status = "pending"
+		}
+	}
+
+	requestID, err := services.CreateRequest(req)
+	if err != nil {
 		slog.Error("Error creating request", "error", err, "user_id", req.UserID, "title", req.Title, "media_type", req.MediaType)
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
+		return 0, http.StatusInternalServerError, errors.New("Failed to create request")
+	}
+	if err := services.RecordRequestUsage(user.ID, requestID, req); err != nil {
+		slog.Error("Error recording request against quota", "error", err, "user_id", user.ID, "request_id", requestID)
 	}
 
-	slog.Info("Request created successfully", "user_id", req.UserID, "title", req.Title, "media_type", req.MediaType, "seasons", req.Seasons)
+	slog.Info("Request created successfully", "request_id", requestID, "user_id", req.UserID, "title", req.Title, "media_type", req.MediaType, "seasons", req.Seasons, "status", req.Status)
+	if req.Status == "awaiting_approval" {
+		// Requests that fit the size budget are approved once the indexers have been checked
+		if automation := h.Automation; automation != nil && cfg.AutoApproveMaxSizeGB > 0 {
+			processCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			go func() {
+				defer cancel()
+				if automation.ApproveWithinSizeBudget(processCtx) > 0 {
+					automation.TriggerImmediateProcessing(processCtx)
+				}
+			}()
+		}
+		return requestID, http.StatusAccepted, nil
+	}
 
 	// Trigger immediate processing if automation service is available
 	if automation := h.Automation; automation != nil {
@@ -178,7 +269,54 @@ func (h *Handlers) CreateRequestHandler(w http.ResponseWriter, r *http.Request)
 		slog.Warn("Automation service not available, request will be processed on next scheduled check (1 hour)")
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	return requestID, http.StatusCreated, nil
+}
+
+// quotaExceededMessage explains which limit a request is over and when it frees up
+func quotaExceededMessage(quota *models.Quota, req models.Request) string {
+	var msg string
+	if req.MediaType == "movie" {
+		msg = fmt.Sprintf("You've used all %d of your movie requests for this week", *quota.MovieLimit)
+	} else {
+		left := max(*quota.SeasonLimit-quota.SeasonsUsed, 0)
+		msg = fmt.Sprintf("This request needs %d season(s) but you have %d of %d left this week",
+			services.RequestSeasonCount(req), left, *quota.SeasonLimit)
+	}
+	if quota.NextFreeAt != nil {
+		msg += fmt.Sprintf(". More requests free up on %s", quota.NextFreeAt.Format("Mon Jan 2 at 15:04"))
+	}
+	return msg
+}
+
+// SetRequestRootFolderHandler changes the root folder a request's media goes to
+func SetRequestRootFolderHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		RootFolderID *int64 `json:"root_folder_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SetRequestRootFolder(id, body.RootFolderID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Changed request root folder", "request_id", id, "root_folder_id", body.RootFolderID, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Root folder updated"})
 }
 
 func ApproveRequestHandler(w http.ResponseWriter, r *http.Request) {
@@ -193,7 +331,7 @@ func ApproveRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !user.IsAdmin {
+	if !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -225,7 +363,7 @@ func DenyRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !user.IsAdmin {
+	if !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -241,6 +379,9 @@ func DenyRequestHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to deny request", http.StatusInternalServerError)
 		return
 	}
+	if err := services.RefundRequestUsage(id); err != nil {
+		slog.Error("Error refunding quota for denied request", "error", err, "request_id", id)
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -257,7 +398,7 @@ func DeleteRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !user.IsAdmin {
+	if !user.Can(models.PermManageLibrary) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
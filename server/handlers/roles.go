@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// RolesHandler lists roles and the available permissions
+func RolesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roles, err := services.GetRoles()
+	if err != nil {
+		slog.Error("Error getting roles", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+
+	type permissionOption struct {
+		Name  string `json:"name"`
+		Label string `json:"label"`
+	}
+	permissions := make([]permissionOption, len(models.AllPermissions))
+	for i, p := range models.AllPermissions {
+		permissions[i] = permissionOption{Name: p, Label: models.PermissionLabels[p]}
+	}
+
+	rootFolders, err := services.GetRootFolders("")
+	if err != nil {
+		slog.Error("Error getting root folders", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rootFolders == nil {
+		rootFolders = []models.RootFolder{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"roles":        roles,
+		"permissions":  permissions,
+		"root_folders": rootFolders,
+	})
+}
+
+// permissionsBeyond returns the permissions the user doesn't hold. Admins hold them all.
+func permissionsBeyond(user *models.User, permissions []string) []string {
+	var beyond []string
+	for _, p := range permissions {
+		if !user.Can(p) {
+			beyond = append(beyond, p)
+		}
+	}
+	return beyond
+}
+
+// requirePermissionsWithin stops non-admins from handing out, or taking over, access they
+// don't have themselves. Writes a 403 and returns false when permissions go beyond the
+// user's own.
+func requirePermissionsWithin(w http.ResponseWriter, user *models.User, permissions []string) bool {
+	if beyond := permissionsBeyond(user, permissions); len(beyond) > 0 {
+		slog.Warn("Refused change beyond the user's own permissions", "permissions", beyond, "user", user.Username)
+		http.Error(w, "You can't grant permissions you don't have: "+strings.Join(beyond, ", "), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireRoleWithin checks a role (0 for the default role) only has permissions the user
+// holds, before the user edits, deletes, assigns or invites into it
+func requireRoleWithin(w http.ResponseWriter, user *models.User, roleID int64) bool {
+	if user.IsAdmin {
+		return true
+	}
+	permissions, err := services.GetRolePermissions(roleID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return requirePermissionsWithin(w, user, permissions)
+}
+
+// SaveRoleHandler creates or updates a role
+func SaveRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ID          int64    `json:"id"`
+		Name        string   `json:"name"`
+		Permissions []string `json:"permissions"`
+		IsDefault   bool     `json:"is_default"`
+		MovieQuota  *int     `json:"movie_quota"`
+		SeasonQuota *int     `json:"season_quota"`
+
+		MovieRootFolderID *int64 `json:"movie_root_folder_id"`
+		ShowRootFolderID  *int64 `json:"show_root_folder_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	// Both the role as it is and as it will be must be within the user's own permissions
+	if !requirePermissionsWithin(w, user, req.Permissions) || (req.ID != 0 && !requireRoleWithin(w, user, req.ID)) {
+		return
+	}
+
+	id, err := services.SaveRole(req.ID, req.Name, req.Permissions, req.IsDefault, req.MovieQuota, req.SeasonQuota, req.MovieRootFolderID, req.ShowRootFolderID)
+	if err != nil {
+		slog.Error("Error saving role", "name", req.Name, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Saved role", "role_id", id, "name", req.Name, "permissions", req.Permissions, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Role saved"})
+}
+
+// DeleteRoleHandler removes a role; its users fall back to the default role
+func DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+	if !requireRoleWithin(w, user, int64(id)) {
+		return
+	}
+
+	if err := services.DeleteRole(int64(id)); err != nil {
+		slog.Error("Error deleting role", "role_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Deleted role", "role_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role deleted"})
+}
+
+// SetUserRoleHandler assigns a role to a user. Only admins can grant or revoke admin.
+func SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		UserID  int64 `json:"user_id"`
+		RoleID  int64 `json:"role_id"`
+		IsAdmin bool  `json:"is_admin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	target, err := services.GetUserByID(req.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if target.IsAdmin != req.IsAdmin && !user.IsAdmin {
+		http.Error(w, "Only admins can grant or revoke admin", http.StatusForbidden)
+		return
+	}
+	if !requireRoleWithin(w, user, req.RoleID) {
+		return
+	}
+
+	if err := services.SetUserRole(req.UserID, req.RoleID, req.IsAdmin); err != nil {
+		slog.Error("Error setting user role", "target_user_id", req.UserID, "role_id", req.RoleID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Updated user role", "target_user", target.Username, "role_id", req.RoleID, "is_admin", req.IsAdmin, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role updated"})
+}
+
+// SetUserQuotaHandler sets a user's own request quota, overriding their role's. Null values
+// fall back to the role.
+func SetUserQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id"`
+		services.QuotaOverride
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SetUserQuota(req.UserID, req.QuotaOverride); err != nil {
+		slog.Error("Error setting user quota", "target_user_id", req.UserID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Updated user quota", "target_user_id", req.UserID, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Quota updated"})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"Arrgo/models"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestPermissionsBeyond(t *testing.T) {
+	manager := &models.User{Permissions: []string{models.PermManageUsers, models.PermRequestMovies}}
+	tests := []struct {
+		name        string
+		user        *models.User
+		permissions []string
+		want        []string
+	}{
+		{"within own", manager, []string{models.PermRequestMovies}, nil},
+		{"same as own", manager, []string{models.PermManageUsers, models.PermRequestMovies}, nil},
+		{"escalation", manager, []string{models.PermRequestMovies, models.PermManageLibrary, models.PermTriggerScans}, []string{models.PermManageLibrary, models.PermTriggerScans}},
+		{"admin", &models.User{IsAdmin: true}, []string{models.PermManageLibrary}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permissionsBeyond(tt.user, tt.permissions); !slices.Equal(got, tt.want) {
+				t.Errorf("permissionsBeyond() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionsWithin(t *testing.T) {
+	manager := &models.User{Permissions: []string{models.PermManageUsers}}
+
+	rec := httptest.NewRecorder()
+	if requirePermissionsWithin(rec, manager, []string{models.PermManageUsers, models.PermManageLibrary}) {
+		t.Fatal("granting manage_library was allowed")
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+
+	if !requirePermissionsWithin(httptest.NewRecorder(), manager, []string{models.PermManageUsers}) {
+		t.Error("granting an own permission was refused")
+	}
+}
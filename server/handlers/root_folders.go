@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// RootFoldersHandler lists the root folders with their free space
+func RootFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roots, err := services.GetRootFolders(r.URL.Query().Get("media_type"))
+	if err != nil {
+		slog.Error("Error getting root folders", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if roots == nil {
+		roots = []models.RootFolder{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roots)
+}
+
+// CreateRootFolderHandler adds a root folder
+func CreateRootFolderHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name      string  `json:"name"`
+		MediaType string  `json:"media_type"`
+		Path      string  `json:"path"`
+		MinFreeGB float64 `json:"min_free_gb"`
+		Is4K      bool    `json:"is_4k"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := services.CreateRootFolder(config.Load(), req.Name, req.MediaType, req.Path, req.MinFreeGB, req.Is4K)
+	if err != nil {
+		slog.Warn("Error adding root folder", "path", req.Path, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "message": "Root folder added"})
+}
+
+// UpdateRootFolderHandler renames a root folder and changes its minimum free space
+func UpdateRootFolderHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid root folder ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Name      string  `json:"name"`
+		MinFreeGB float64 `json:"min_free_gb"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.UpdateRootFolder(int64(id), req.Name, req.MinFreeGB); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Root folder updated"})
+}
+
+// SetDefaultRootFolderHandler makes a root folder the default of its media type and library
+func SetDefaultRootFolderHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid root folder ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SetDefaultRootFolder(int64(id)); err != nil {
+		slog.Error("Error setting default root folder", "root_folder_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Changed default root folder", "root_folder_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Default root folder changed"})
+}
+
+// DeleteRootFolderHandler removes an empty root folder
+func DeleteRootFolderHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageLibrary) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid root folder ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.DeleteRootFolder(config.Load(), int64(id)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Removed root folder", "root_folder_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Root folder removed"})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"Arrgo/config"
+	"Arrgo/models"
 	"Arrgo/services"
 	"html/template"
 	"log/slog"
@@ -40,11 +41,12 @@ type UnifiedSearchResult struct {
 
 type SearchPageData struct {
 	Username    string
-	IsAdmin     bool
+	AdminAccess bool
 	CurrentPage string
 	SearchQuery string
 	Movies      []UnifiedSearchResult
 	Shows       []UnifiedSearchResult
+	Quota       *models.Quota // nil if it couldn't be loaded
 }
 
 func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
@@ -181,13 +183,19 @@ func (h *Handlers) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	quota, err := services.GetUserQuota(user)
+	if err != nil {
+		slog.Error("Error loading request quota", "error", err, "user_id", user.ID)
+	}
+
 	data := SearchPageData{
 		Username:    user.Username,
-		IsAdmin:     user.IsAdmin,
+		AdminAccess: user.CanAccessAdmin(),
 		CurrentPage: "/search",
 		SearchQuery: query,
 		Movies:      movies,
 		Shows:       shows,
+		Quota:       quota,
 	}
 
 	if err := searchTmpl.ExecuteTemplate(w, "base", data); err != nil {
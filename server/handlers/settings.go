@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"Arrgo/models"
 	"Arrgo/services"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 )
 
 var settingsTmpl *template.Template
@@ -25,11 +27,38 @@ func init() {
 
 type SettingsData struct {
 	Username    string
-	IsAdmin     bool
+	AdminAccess bool
 	CurrentPage string
 	SearchQuery string
 	Error       string
 	Success     string
+
+	// Jellyfin-linked accounts change their password in Jellyfin
+	JellyfinManaged bool
+
+	APIKeys   []models.APIKey
+	NewAPIKey string // shown once, right after it's created
+}
+
+func newSettingsData(user *models.User) SettingsData {
+	keys, err := services.GetAPIKeys(user.ID)
+	if err != nil {
+		slog.Error("Error getting API keys", "user_id", user.ID, "error", err)
+	}
+	return SettingsData{
+		Username:    user.Username,
+		AdminAccess: user.CanAccessAdmin(),
+		CurrentPage: "/settings",
+
+		JellyfinManaged: user.JellyfinID != "",
+		APIKeys:         keys,
+	}
+}
+
+func renderSettings(w http.ResponseWriter, data SettingsData) {
+	if err := settingsTmpl.ExecuteTemplate(w, "base", data); err != nil {
+		slog.Error("Error rendering settings template", "error", err)
+	}
 }
 
 func SettingsHandler(w http.ResponseWriter, r *http.Request) {
@@ -39,13 +68,11 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := SettingsData{
-		Username:    user.Username,
-		IsAdmin:     user.IsAdmin,
-		CurrentPage: "/settings",
-	}
+	data := newSettingsData(user)
 
-	if r.Method == http.MethodPost {
+	if r.Method == http.MethodPost && data.JellyfinManaged {
+		data.Error = "Your password is managed by Jellyfin"
+	} else if r.Method == http.MethodPost {
 		currentPassword := r.FormValue("current_password")
 		newPassword := r.FormValue("new_password")
 		confirmPassword := r.FormValue("confirm_password")
@@ -63,7 +90,48 @@ func SettingsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := settingsTmpl.ExecuteTemplate(w, "base", data); err != nil {
-		slog.Error("Error rendering settings template", "error", err)
+	renderSettings(w, data)
+}
+
+// CreateAPIKeyHandler creates an API key for the signed-in user and shows it once
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
 	}
+
+	key, err := services.CreateAPIKey(user.ID, r.FormValue("name"))
+	data := newSettingsData(user)
+	if err != nil {
+		slog.Warn("API key creation failed", "username", user.Username, "error", err)
+		data.Error = err.Error()
+	} else {
+		slog.Info("Created API key", "username", user.Username, "name", r.FormValue("name"))
+		data.NewAPIKey = key
+	}
+	renderSettings(w, data)
+}
+
+// DeleteAPIKeyHandler revokes one of the signed-in user's API keys
+func DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err == nil {
+		err = services.DeleteAPIKey(user.ID, id)
+	}
+	if err != nil {
+		data := newSettingsData(user)
+		data.Error = err.Error()
+		renderSettings(w, data)
+		return
+	}
+
+	slog.Info("Revoked API key", "username", user.Username, "key_id", id)
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
 }
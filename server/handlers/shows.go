@@ -44,7 +44,7 @@ func init() {
 
 type ShowsData struct {
 	Username       string
-	IsAdmin        bool
+	AdminAccess    bool
 	CurrentPage    string
 	SearchQuery    string
 	Shows          []models.Show
@@ -77,13 +77,13 @@ func ShowsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Separate incoming and library shows
 	var allTorrents []services.TorrentStatus
-	if user.IsAdmin {
+	if user.Can(models.PermManageLibrary) {
 		qb, err := services.NewQBittorrentClient(cfg)
 		if err == nil {
 			allTorrents, _ = qb.GetTorrentsDetailed(context.Background(), "")
 		}
 	}
-	libraryShows, incomingShows := SeparateIncomingShows(allShows, cfg, user.IsAdmin, allTorrents)
+	libraryShows, incomingShows := SeparateIncomingShows(allShows, cfg, user.Can(models.PermManageLibrary), allTorrents)
 
 	// Extract unique values for filters BEFORE filtering (so all options are available)
 	allGenres := ExtractGenresFromShows(libraryShows)
@@ -116,7 +116,7 @@ func ShowsHandler(w http.ResponseWriter, r *http.Request) {
 
 	data := ShowsData{
 		Username:       user.Username,
-		IsAdmin:        user.IsAdmin,
+		AdminAccess:    user.CanAccessAdmin(),
 		CurrentPage:    "/shows",
 		SearchQuery:    "",
 		Shows:          libraryShows,
@@ -214,6 +214,13 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	// Check library status
 	libStatus, _ := services.CheckLibraryStatus("show", show.TVDBID)
 
+	// The 4K library keeps its own copy, so seasons can be requested in 4K whatever their standard status
+	canRequest4K := user.Can(models.PermRequestShows) && user.Can(models.PermRequest4K) && services.Has4KLibrary("show")
+	var libStatus4K services.LibraryStatus
+	if canRequest4K {
+		libStatus4K, _ = services.CheckLibraryStatusFor("show", show.TVDBID, true)
+	}
+
 	// Prepare data for template
 	type EnhancedSeason struct {
 		SeasonNumber int
@@ -225,9 +232,17 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 			Quality      string
 			Size         int64
 			HasSubtitles bool
+			Watched      bool
+			PlayCount    int
 		}
 	}
 
+	// Per-user watched state imported from the media server, keyed by local episode ID
+	var watchStates map[int]models.WatchState
+	if show.ID > 0 {
+		watchStates, _ = services.GetShowWatchStates(int(user.ID), show.ID)
+	}
+
 	var enhancedSeasons []EnhancedSeason
 	if len(allEpisodes) > 0 {
 		// Group by season
@@ -272,6 +287,8 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 				Quality      string
 				Size         int64
 				HasSubtitles bool
+				Watched      bool
+				PlayCount    int
 			}{
 				ID:           localID,
 				Number:       te.Number,
@@ -280,6 +297,8 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 				Quality:      quality,
 				Size:         size,
 				HasSubtitles: hasSubtitles,
+				Watched:      watchStates[localID].Played,
+				PlayCount:    watchStates[localID].PlayCount,
 			})
 		}
 
@@ -309,6 +328,8 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 					Quality      string
 					Size         int64
 					HasSubtitles bool
+					Watched      bool
+					PlayCount    int
 				}{
 					ID:           e.ID,
 					Number:       e.EpisodeNumber,
@@ -317,6 +338,8 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 					Quality:      e.Quality,
 					Size:         e.Size,
 					HasSubtitles: hasSubtitles,
+					Watched:      watchStates[e.ID].Played,
+					PlayCount:    watchStates[e.ID].PlayCount,
 				})
 			}
 			enhancedSeasons = append(enhancedSeasons, es)
@@ -324,21 +347,27 @@ func (h *Handlers) ShowDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Username      string
-		IsAdmin       bool
-		CurrentPage   string
-		SearchQuery   string
-		Show          *models.Show
-		Seasons       []EnhancedSeason
-		LibraryStatus services.LibraryStatus
+		Username         string
+		AdminAccess      bool
+		CanManageLibrary bool
+		CurrentPage      string
+		SearchQuery      string
+		Show             *models.Show
+		Seasons          []EnhancedSeason
+		LibraryStatus    services.LibraryStatus
+		CanRequest4K     bool
+		LibraryStatus4K  services.LibraryStatus
 	}{
-		Username:      user.Username,
-		IsAdmin:       user.IsAdmin,
-		CurrentPage:   "/shows",
-		SearchQuery:   "",
-		Show:          show,
-		Seasons:       enhancedSeasons,
-		LibraryStatus: libStatus,
+		Username:         user.Username,
+		AdminAccess:      user.CanAccessAdmin(),
+		CanManageLibrary: user.Can(models.PermManageLibrary),
+		CurrentPage:      "/shows",
+		SearchQuery:      "",
+		Show:             show,
+		Seasons:          enhancedSeasons,
+		LibraryStatus:    libStatus,
+		CanRequest4K:     canRequest4K,
+		LibraryStatus4K:  libStatus4K,
 	}
 
 	if err := showDetailsTmpl.ExecuteTemplate(w, "base", data); err != nil {
@@ -9,6 +9,11 @@ import (
 )
 
 func GetCurrentUser(r *http.Request) (*models.User, error) {
+	// /api/v1 requests are authenticated by API key rather than a session
+	if user := services.APIUser(r.Context()); user != nil {
+		return user, nil
+	}
+
 	session, err := services.GetSession(r)
 	if err != nil {
 		return nil, err
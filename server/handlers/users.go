@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"Arrgo/config"
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+var adminUsersTmpl *template.Template
+var resetPasswordTmpl *template.Template
+
+func init() {
+	var err error
+	adminUsersTmpl, err = template.New("admin_users").Funcs(FuncMap()).ParseFiles(
+		"templates/layouts/base.html",
+		"templates/pages/admin_users.html",
+		"templates/components/navigation.html",
+	)
+	if err != nil {
+		slog.Error("Failed to parse admin users template", "error", err)
+		os.Exit(1)
+	}
+
+	resetPasswordTmpl, err = template.ParseFiles(
+		"templates/layouts/base.html",
+		"templates/pages/reset_password.html",
+	)
+	if err != nil {
+		slog.Error("Failed to parse reset password template", "error", err)
+		os.Exit(1)
+	}
+}
+
+type AdminUsersPageData struct {
+	Username      string
+	AdminAccess   bool
+	CurrentPage   string
+	SearchQuery   string
+	CurrentUserID int64
+	CanGrantAdmin bool // only admins can grant admin or manage admin accounts
+	JellyfinSync  bool // account changes are mirrored to Jellyfin
+	Users         []models.User
+	Roles         []models.Role
+	Invites       []models.Invite
+	Quotas        map[int64]services.QuotaOverride
+}
+
+// AdminUsersHandler shows the user management page
+func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !user.Can(models.PermManageUsers) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	users, err := services.GetAllUsers()
+	if err != nil {
+		slog.Error("Error getting users", "error", err)
+	}
+	roles, err := services.GetRoles()
+	if err != nil {
+		slog.Error("Error getting roles", "error", err)
+	}
+	invites, err := services.GetInvites()
+	if err != nil {
+		slog.Error("Error getting invites", "error", err)
+	}
+	quotas, err := services.GetUserQuotaOverrides()
+	if err != nil {
+		slog.Error("Error getting user quotas", "error", err)
+	}
+
+	cfg := config.Load()
+	data := AdminUsersPageData{
+		Username:      user.Username,
+		AdminAccess:   user.CanAccessAdmin(),
+		CurrentPage:   "/admin",
+		CurrentUserID: user.ID,
+		CanGrantAdmin: user.IsAdmin,
+		JellyfinSync:  cfg.JellyfinURL != "" && cfg.JellyfinAPIKey != "",
+		Users:         users,
+		Roles:         roles,
+		Invites:       invites,
+		Quotas:        quotas,
+	}
+
+	if err := adminUsersTmpl.ExecuteTemplate(w, "base", data); err != nil {
+		slog.Error("Error rendering admin users template", "error", err)
+	}
+}
+
+// userByID loads the target of a user management action; tests replace it
+var userByID = services.GetUserByID
+
+// manageableUser loads the target of a user management action. Admin accounts can only be
+// changed by admins, and other accounts only by users holding all of their permissions, so
+// users with just manage_users can't take over accounts with more access than their own.
+func manageableUser(w http.ResponseWriter, r *http.Request, targetID int64) (actor, target *models.User, ok bool) {
+	actor, err := GetCurrentUser(r)
+	if err != nil || actor == nil || !actor.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	target, err = userByID(targetID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if target.IsAdmin && !actor.IsAdmin {
+		http.Error(w, "Only admins can manage admin accounts", http.StatusForbidden)
+		return nil, nil, false
+	}
+	if !requirePermissionsWithin(w, actor, target.Permissions) {
+		return nil, nil, false
+	}
+	return actor, target, true
+}
+
+// mirrorToJellyfin applies an account change to Jellyfin in the background when Jellyfin is
+// configured. Arrgo stays the source of truth if it fails.
+func mirrorToJellyfin(action string, target *models.User, apply func(cfg *config.Config) error) {
+	cfg := config.Load()
+	if cfg.JellyfinURL == "" || cfg.JellyfinAPIKey == "" {
+		return
+	}
+	go func() {
+		if err := apply(cfg); err != nil {
+			slog.Error("Failed to mirror user change to Jellyfin", "action", action, "username", target.Username, "error", err)
+		}
+	}()
+}
+
+// CreateInviteHandler issues a single-use registration link
+func CreateInviteHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		RoleID int64 `json:"role_id"`
+		Days   int   `json:"days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !requireRoleWithin(w, user, req.RoleID) {
+		return
+	}
+	ttl := services.DefaultInviteTTL
+	if req.Days > 0 {
+		ttl = time.Duration(min(req.Days, 90)) * 24 * time.Hour
+	}
+
+	token, err := services.CreateInvite(user.ID, req.RoleID, ttl)
+	if err != nil {
+		slog.Error("Error creating invite", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Created invite", "role_id", req.RoleID, "expires_in", ttl, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"path":    "/register?invite=" + token,
+		"message": fmt.Sprintf("Invite created. The link works once and expires in %d days.", int(ttl.Hours()/24)),
+	})
+}
+
+// DeleteInviteHandler revokes an invite
+func DeleteInviteHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := GetCurrentUser(r)
+	if err != nil || user == nil || !user.Can(models.PermManageUsers) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := ParseIDFromQuery(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid invite ID", http.StatusBadRequest)
+		return
+	}
+	if err := services.DeleteInvite(int64(id)); err != nil {
+		slog.Error("Error deleting invite", "invite_id", id, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Revoked invite", "invite_id", id, "user", user.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invite revoked"})
+}
+
+// SetUserDisabledHandler disables or re-enables an account
+func SetUserDisabledHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID   int64 `json:"user_id"`
+		Disabled bool  `json:"disabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	actor, target, ok := manageableUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if target.ID == actor.ID {
+		http.Error(w, "You can't disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	if err := services.SetUserDisabled(target.ID, req.Disabled); err != nil {
+		slog.Error("Error updating user", "target_user", target.Username, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mirrorToJellyfin("disable", target, func(cfg *config.Config) error {
+		return services.SetJellyfinUserDisabled(cfg, target, req.Disabled)
+	})
+
+	slog.Info("Updated user", "target_user", target.Username, "disabled", req.Disabled, "user", actor.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User updated"})
+}
+
+// PasswordResetLinkHandler issues a single-use password reset link for a user
+func PasswordResetLinkHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	actor, target, ok := manageableUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
+	token, err := services.CreatePasswordReset(target.ID, actor.ID)
+	if err != nil {
+		slog.Error("Error creating password reset", "target_user", target.Username, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Created password reset link", "target_user", target.Username, "user", actor.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"path":    "/reset-password?token=" + token,
+		"message": fmt.Sprintf("Reset link for %s created. It works once and expires in %d hours.", target.Username, int(services.PasswordResetTTL.Hours())),
+	})
+}
+
+// DeleteUserHandler deletes an account, reassigning their requests to another user or
+// cancelling them
+func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID     int64 `json:"user_id"`
+		ReassignTo int64 `json:"reassign_to"` // 0 cancels the user's requests
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	actor, target, ok := manageableUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if target.ID == actor.ID {
+		http.Error(w, "You can't delete your own account", http.StatusBadRequest)
+		return
+	}
+
+	qb, _ := services.NewQBittorrentClient(config.Load())
+	if err := services.DeleteUser(target.ID, req.ReassignTo, qb); err != nil {
+		slog.Error("Error deleting user", "target_user", target.Username, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mirrorToJellyfin("delete", target, func(cfg *config.Config) error {
+		return services.DeleteJellyfinUser(cfg, target)
+	})
+
+	slog.Info("Deleted user", "target_user", target.Username, "reassigned_to", req.ReassignTo, "user", actor.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted"})
+}
+
+type ResetPasswordPageData struct {
+	Token    string
+	Username string
+	Error    string
+	Success  bool
+}
+
+// ResetPasswordHandler lets someone with a reset link choose a new password
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	data := ResetPasswordPageData{Token: r.FormValue("token")}
+	render := func(status int) {
+		w.WriteHeader(status)
+		if err := resetPasswordTmpl.ExecuteTemplate(w, "base", data); err != nil {
+			slog.Error("Error rendering reset password template", "error", err)
+		}
+	}
+
+	target, err := services.GetPasswordResetUser(data.Token)
+	if err != nil {
+		data.Error = err.Error()
+		render(http.StatusGone)
+		return
+	}
+	data.Username = target.Username
+
+	if r.Method != http.MethodPost {
+		render(http.StatusOK)
+		return
+	}
+
+	newPassword := r.FormValue("new_password")
+	if newPassword != r.FormValue("confirm_password") {
+		data.Error = "Passwords do not match"
+		render(http.StatusBadRequest)
+		return
+	}
+	if len(newPassword) < 8 {
+		data.Error = "Password must be at least 8 characters"
+		render(http.StatusBadRequest)
+		return
+	}
+
+	user, err := services.ResetPassword(data.Token, newPassword)
+	if err != nil {
+		slog.Warn("Password reset failed", "username", target.Username, "error", err)
+		data.Error = err.Error()
+		render(http.StatusBadRequest)
+		return
+	}
+	mirrorToJellyfin("reset password", user, func(cfg *config.Config) error {
+		return services.SetJellyfinPassword(cfg, user, newPassword)
+	})
+
+	slog.Info("Password reset with reset link", "username", user.Username)
+	data.Success = true
+	render(http.StatusOK)
+}
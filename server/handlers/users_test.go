@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"Arrgo/models"
+	"Arrgo/services"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// useTestUsers makes user management handlers load their targets from users
+func useTestUsers(t *testing.T, users ...*models.User) {
+	t.Helper()
+	userByID = func(id int64) (*models.User, error) {
+		for _, u := range users {
+			if u.ID == id {
+				return u, nil
+			}
+		}
+		return nil, errors.New("user not found")
+	}
+	t.Cleanup(func() { userByID = services.GetUserByID })
+}
+
+func TestUserManagementStaysWithinOwnPermissions(t *testing.T) {
+	userManager := &models.User{ID: 1, Username: "manager", Permissions: []string{models.PermManageUsers}}
+	librarian := &models.User{ID: 2, Username: "librarian", Permissions: []string{models.PermManageLibrary}}
+	admin := &models.User{ID: 3, Username: "admin", IsAdmin: true}
+	useTestUsers(t, userManager, librarian, admin)
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"reset link for a user with more permissions", PasswordResetLinkHandler, `{"user_id": 2}`, http.StatusForbidden, models.PermManageLibrary},
+		{"disabling a user with more permissions", SetUserDisabledHandler, `{"user_id": 2, "disabled": true}`, http.StatusForbidden, models.PermManageLibrary},
+		{"deleting a user with more permissions", DeleteUserHandler, `{"user_id": 2}`, http.StatusForbidden, models.PermManageLibrary},
+		{"reset link for an admin", PasswordResetLinkHandler, `{"user_id": 3}`, http.StatusForbidden, "Only admins"},
+		{"unknown user", PasswordResetLinkHandler, `{"user_id": 9}`, http.StatusNotFound, "User not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/users", strings.NewReader(tt.body))
+			req = req.WithContext(services.WithAPIUser(req.Context(), userManager))
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to mention %q", rec.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(rec.Body.String(), "token=") {
+				t.Error("response contains a reset link")
+			}
+		})
+	}
+}
@@ -302,22 +302,22 @@ func SeparateIncomingShows(allShows []models.Show, cfg *config.Config, isAdmin b
 	return libraryShows, incomingShows
 }
 
-// RequireAdmin checks if the current user is an admin, returns error if not
-func RequireAdmin(r *http.Request) (*models.User, error) {
+// RequirePermission checks that the current user has a permission, returns error if not
+func RequirePermission(r *http.Request, permission string) (*models.User, error) {
 	user, err := GetCurrentUser(r)
 	if err != nil || user == nil {
 		return nil, fmt.Errorf("unauthorized")
 	}
-	if !user.IsAdmin {
+	if !user.Can(permission) {
 		return nil, fmt.Errorf("forbidden")
 	}
 	return user, nil
 }
 
-// RequireAdminHandler wraps a handler to require admin access
-func RequireAdminHandler(handler http.HandlerFunc) http.HandlerFunc {
+// RequirePermissionHandler wraps a handler to require a permission
+func RequirePermissionHandler(permission string, handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := RequireAdmin(r)
+		_, err := RequirePermission(r, permission)
 		if err != nil {
 			if err.Error() == "unauthorized" {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -5,6 +5,7 @@ import (
 	"Arrgo/database"
 	"Arrgo/handlers"
 	localmiddleware "Arrgo/middleware"
+	"Arrgo/models"
 	"Arrgo/services"
 	"context"
 	"fmt"
@@ -23,6 +24,8 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+//go:generate go run ./cmd/openapi ../docs/openapi.json
+
 // setupRoutes configures all HTTP routes
 func setupRoutes(h *handlers.Handlers) *chi.Mux {
 	r := chi.NewRouter()
@@ -48,61 +51,139 @@ func setupRoutes(h *handlers.Handlers) *chi.Mux {
 	})
 	r.HandleFunc("/login", handlers.LoginHandler)
 	r.HandleFunc("/register", handlers.RegisterHandler)
+	r.HandleFunc("/reset-password", handlers.ResetPasswordHandler)
 	r.HandleFunc("/logout", handlers.LogoutHandler)
+	r.Get("/auth/oidc/login", handlers.OIDCLoginHandler)
+	r.Get("/auth/oidc/callback", handlers.OIDCCallbackHandler)
+
+	// --- JSON API (API keys) ---
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/openapi.json", h.OpenAPIHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(localmiddleware.RequireAPIKey)
+			for _, route := range h.APIV1Routes() {
+				r.With(localmiddleware.RequireAPIPermission(route.Permissions...)).Method(route.Method, route.Path, route.Handler)
+			}
+		})
+	})
+
+	// --- Overseerr-compatible API (API keys or session cookie) ---
+	r.Route("/overseerr/api/v1", func(r chi.Router) {
+		r.Get("/status", handlers.OverseerrStatusHandler)
+		r.Get("/settings/public", handlers.OverseerrPublicSettingsHandler)
+		r.Post("/auth/local", handlers.OverseerrLoginHandler)
+		r.Post("/auth/jellyfin", handlers.OverseerrLoginHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(localmiddleware.RequireOverseerrAuth)
+			can := localmiddleware.RequireOverseerrPermission
+
+			r.Post("/auth/logout", handlers.OverseerrLogoutHandler)
+			r.Get("/auth/me", h.OverseerrMeHandler)
+			r.Get("/search", h.OverseerrSearchHandler)
+			r.Get("/movie/{id}", h.OverseerrMovieHandler)
+			r.Get("/tv/{id}", h.OverseerrShowHandler)
+			r.Get("/request", h.OverseerrRequestsHandler)
+			r.Get("/request/count", handlers.OverseerrRequestCountHandler)
+			r.Get("/request/{id}", h.OverseerrRequestHandler)
+			r.With(can(models.PermRequestMovies, models.PermRequestShows)).Post("/request", h.OverseerrCreateRequestHandler)
+			r.With(can(models.PermManageLibrary)).Post("/request/{id}/{status}", h.OverseerrRequestStatusHandler)
+			r.With(can(models.PermManageLibrary)).Delete("/request/{id}", handlers.OverseerrDeleteRequestHandler)
+		})
+	})
 
 	// --- Protected Routes ---
 	r.Group(func(r chi.Router) {
 		r.Use(localmiddleware.RequireAuth)
 
+		// Routes that need more than a signed-in user declare the permissions that allow them
+		// (any one is enough); admins have every permission
+		can := localmiddleware.RequirePermission
+
 		// UI / General
 		r.Get("/dashboard", handlers.DashboardHandler)
 			r.HandleFunc("/settings", handlers.SettingsHandler)
+		r.Post("/settings/api-keys", handlers.CreateAPIKeyHandler)
+		r.Post("/settings/api-keys/delete", handlers.DeleteAPIKeyHandler)
 		r.Get("/search", h.SearchHandler)
 		r.Get("/requests", handlers.RequestsHandler)
-		r.Post("/requests/create", h.CreateRequestHandler)
-		r.Post("/requests/delete", handlers.DeleteRequestHandler)
+		r.With(can(models.PermRequestMovies, models.PermRequestShows)).Post("/requests/create", h.CreateRequestHandler)
+		r.With(can(models.PermManageLibrary)).Post("/requests/delete", handlers.DeleteRequestHandler)
+		r.With(can(models.PermManageLibrary)).Post("/requests/approve", handlers.ApproveRequestHandler)
+		r.With(can(models.PermManageLibrary)).Post("/requests/deny", handlers.DenyRequestHandler)
+		r.With(can(models.PermManageLibrary)).Post("/requests/root-folder", handlers.SetRequestRootFolderHandler)
 
 		// Movies
 		r.Get("/movies", handlers.MoviesHandler)
 		r.Get("/movies/details", h.MovieDetailsHandler)
-		r.Post("/scan/movies", handlers.ScanMoviesHandler)
-		r.Post("/scan/incoming/movies", handlers.ScanIncomingMoviesHandler)
-		r.Post("/import/movies/all", handlers.ImportAllMoviesHandler)
-		r.Post("/rename/movie", handlers.RenameMovieHandler)
-		r.Post("/rename/library/movies", handlers.RenameAllLibraryMoviesHandler)
-		r.Get("/api/movies/alternatives", h.GetMovieAlternativesHandler)
-		r.Post("/api/movies/rematch", h.RematchMovieHandler)
+		r.With(can(models.PermTriggerScans)).Post("/scan/movies", handlers.ScanMoviesHandler)
+		r.With(can(models.PermTriggerScans)).Post("/scan/incoming/movies", handlers.ScanIncomingMoviesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/import/movies/all", handlers.ImportAllMoviesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/rename/movie", handlers.RenameMovieHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/movies/alternatives", h.GetMovieAlternativesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/movies/rematch", h.RematchMovieHandler)
 
 		// Shows
 		r.Get("/shows", handlers.ShowsHandler)
 		r.Get("/shows/details", h.ShowDetailsHandler)
-		r.Post("/scan/shows", handlers.ScanShowsHandler)
-		r.Post("/scan/incoming/shows", handlers.ScanIncomingShowsHandler)
-		r.Post("/import/shows/all", handlers.ImportAllShowsHandler)
-		r.Post("/rename/show", handlers.RenameShowHandler)
-		r.Post("/rename/library/shows", handlers.RenameAllLibraryShowsHandler)
-		r.Get("/api/shows/alternatives", h.GetShowAlternativesHandler)
-		r.Post("/api/shows/rematch", h.RematchShowHandler)
+		r.With(can(models.PermTriggerScans)).Post("/scan/shows", handlers.ScanShowsHandler)
+		r.With(can(models.PermTriggerScans)).Post("/scan/incoming/shows", handlers.ScanIncomingShowsHandler)
+		r.With(can(models.PermManageLibrary)).Post("/import/shows/all", handlers.ImportAllShowsHandler)
+		r.With(can(models.PermManageLibrary)).Post("/rename/show", handlers.RenameShowHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/shows/alternatives", h.GetShowAlternativesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/shows/rematch", h.RematchShowHandler)
 
 		// Subtitles
-		r.Post("/subtitles/download", h.DownloadSubtitlesHandler)
-		r.Post("/admin/subtitles/scan", h.ScanSubtitlesHandler)
-		r.Post("/admin/subtitles/queue", h.QueueMissingSubtitlesHandler)
-		r.Post("/api/admin/subtitles/sync/movie", h.MovieSubtitlesSyncHandler)
-		r.Post("/api/admin/subtitles/sync/episode", h.EpisodeSubtitlesSyncHandler)
-		r.Post("/api/admin/subtitles/sync/all", h.SyncAllSubtitlesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/subtitles/download", h.DownloadSubtitlesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/admin/subtitles/scan", h.ScanSubtitlesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/admin/subtitles/queue", h.QueueMissingSubtitlesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/subtitles/sync/movie", h.MovieSubtitlesSyncHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/subtitles/sync/episode", h.EpisodeSubtitlesSyncHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/subtitles/sync/all", h.SyncAllSubtitlesHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/subtitles/queue/status", h.SubtitleQueueStatusHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/subtitles/syncs", h.SubtitleSyncResultsHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/subtitles/syncs/revert", h.RevertSubtitleSyncHandler)
 
 		// Admin & System
-		r.Get("/admin", handlers.AdminHandler)
-		r.Post("/admin/nuke", handlers.NukeLibraryHandler)
-		r.Post("/scan/stop", handlers.StopScanHandler)
-		r.Get("/api/scan/status", handlers.ScanStatusHandler)
-		r.Post("/api/admin/dedupe/movies", handlers.DeduplicateMoviesHandler)
-		r.Post("/api/admin/dedupe/shows", handlers.DeduplicateShowsHandler)
-		r.Post("/api/admin/jellyfin/sync-users", handlers.JellyfinSyncUsersHandler)
-		r.Post("/api/admin/jellyfin/refresh-library", handlers.JellyfinRefreshLibraryHandler)
-		r.Post("/requests/approve", handlers.ApproveRequestHandler)
-		r.Post("/requests/deny", handlers.DenyRequestHandler)
+		r.With(can(models.PermManageLibrary, models.PermTriggerScans, models.PermManageUsers)).Get("/admin", handlers.AdminHandler)
+		r.With(can(models.PermManageLibrary)).Post("/admin/nuke", handlers.NukeLibraryHandler)
+		r.With(can(models.PermTriggerScans)).Post("/scan/stop", handlers.StopScanHandler)
+		r.With(can(models.PermTriggerScans, models.PermManageLibrary)).Get("/api/scan/status", handlers.ScanStatusHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/batches", handlers.FileBatchesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/batches/plan", handlers.PlanFileBatchHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/batches/apply", handlers.ApplyFileBatchHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/batches/undo", handlers.UndoFileBatchHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/batches/finalize", handlers.FinalizeFileBatchHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/batches/discard", handlers.DiscardFileBatchHandler)
+		r.With(can(models.PermManageLibrary)).Get("/admin/recycle-bin", handlers.RecycleBinHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/recycle-bin/restore", handlers.RestoreRecycledItemHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/recycle-bin/delete", handlers.DeleteRecycledItemHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/recycle-bin/empty", handlers.EmptyRecycleBinHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/jellyfin/sync-users", handlers.JellyfinSyncUsersHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/media-server/refresh-library", handlers.MediaServerRefreshLibraryHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/media-server/sync-watched", handlers.MediaServerSyncWatchedHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/media-server/sync-collections", handlers.MediaServerSyncCollectionsHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/collections", handlers.CollectionsHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/collections", handlers.SaveCollectionHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/collections/delete", handlers.DeleteCollectionHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/naming", handlers.NamingTemplatesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/naming", handlers.SaveNamingTemplatesHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/naming/preview", handlers.NamingPreviewHandler)
+		r.With(can(models.PermManageLibrary)).Get("/api/admin/root-folders", handlers.RootFoldersHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/root-folders", handlers.CreateRootFolderHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/root-folders/update", handlers.UpdateRootFolderHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/root-folders/default", handlers.SetDefaultRootFolderHandler)
+		r.With(can(models.PermManageLibrary)).Post("/api/admin/root-folders/delete", handlers.DeleteRootFolderHandler)
+		r.With(can(models.PermManageUsers)).Get("/api/admin/roles", handlers.RolesHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/roles", handlers.SaveRoleHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/roles/delete", handlers.DeleteRoleHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/users/role", handlers.SetUserRoleHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/users/quota", handlers.SetUserQuotaHandler)
+		r.With(can(models.PermManageUsers)).Get("/admin/users", handlers.AdminUsersHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/users/disable", handlers.SetUserDisabledHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/users/reset-link", handlers.PasswordResetLinkHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/users/delete", handlers.DeleteUserHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/invites", handlers.CreateInviteHandler)
+		r.With(can(models.PermManageUsers)).Post("/api/admin/invites/delete", handlers.DeleteInviteHandler)
 	})
 
 	// Root redirect
@@ -151,6 +232,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := services.EnsureRootFolders(cfg); err != nil {
+		slog.Error("Failed to set up root folders", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize subtitle service
 	subtitleSvc := services.NewSubtitleService(cfg, database.DB)
 
@@ -163,6 +249,15 @@ func main() {
 	// Start completed requests cleanup worker (doesn't require qBittorrent)
 	services.StartCompletedRequestsCleanupWorker()
 
+	// Start watch state sync worker (no-op without a media server configured)
+	services.StartWatchStateSyncWorker(cfg)
+
+	// Start collections sync worker (only when MEDIA_SERVER_COLLECTIONS is enabled)
+	services.StartCollectionsSyncWorker(cfg)
+
+	// Start recycle bin purge worker (only when RECYCLE_BIN_PATH is set)
+	services.StartRecycleBinWorker(cfg)
+
 	// Start Automation Service
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -63,7 +63,7 @@ func RequireAuth(next http.Handler) http.Handler {
 		}
 
 		// Verify user still exists
-		_, err = services.GetUserByID(userIDInt)
+		user, err := services.GetUserByID(userIDInt)
 		if err != nil {
 			slog.Warn("User not found in database, redirecting to login",
 				"user_id", userIDInt,
@@ -71,7 +71,46 @@ func RequireAuth(next http.Handler) http.Handler {
 			redirectToLogin(w, r, "User not found in database")
 			return
 		}
+		if user.Disabled {
+			redirectToLogin(w, r, "User is disabled")
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequirePermission allows the request through when the signed-in user has any of the given
+// permissions. It must run after RequireAuth.
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, err := services.GetSession(r)
+			if err != nil {
+				redirectToLogin(w, r, "No session found")
+				return
+			}
+			userID, err := parseUserID(session.Values["user_id"])
+			if err != nil {
+				redirectToLogin(w, r, "Invalid user_id in session")
+				return
+			}
+			user, err := services.GetUserByID(userID)
+			if err != nil {
+				redirectToLogin(w, r, "User not found in database")
+				return
+			}
+
+			if !user.CanAny(permissions...) {
+				slog.Warn("Permission denied",
+					"username", user.Username,
+					"path", r.URL.Path,
+					"permissions", permissions)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
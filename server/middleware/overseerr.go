@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"Arrgo/models"
+	"Arrgo/services"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// overseerrError replies with an error in the shape Overseerr clients expect
+func overseerrError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// RequireOverseerrAuth authenticates requests to the Overseerr-compatible API. Integrations
+// send an API key in the X-Api-Key header, like they do to Overseerr, while mobile apps sign
+// in through /auth/local and send the session cookie. Either way the user becomes the
+// current user for handlers.
+func RequireOverseerrAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *models.User
+		if key := r.Header.Get("X-Api-Key"); key != "" {
+			var err error
+			user, err = services.AuthenticateAPIKey(key)
+			if err != nil {
+				slog.Warn("API key authentication failed", "path", r.URL.Path, "error", err)
+				overseerrError(w, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+		} else {
+			user = sessionUser(r)
+			if user == nil {
+				overseerrError(w, http.StatusUnauthorized, "You must be logged in")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(services.WithAPIUser(r.Context(), user)))
+	})
+}
+
+// sessionUser returns the enabled user signed in with the session cookie, or nil
+func sessionUser(r *http.Request) *models.User {
+	session, err := services.GetSession(r)
+	if err != nil {
+		return nil
+	}
+	userID, ok := session.Values["user_id"]
+	if !ok {
+		return nil
+	}
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil
+	}
+	user, err := services.GetUserByID(id)
+	if err != nil || user.Disabled {
+		return nil
+	}
+	return user
+}
+
+// RequireOverseerrPermission is RequireAPIPermission for the Overseerr-compatible API. It
+// must run after RequireOverseerrAuth.
+func RequireOverseerrPermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := services.APIUser(r.Context())
+			if user == nil {
+				overseerrError(w, http.StatusUnauthorized, "You must be logged in")
+				return
+			}
+			if !user.CanAny(permissions...) {
+				slog.Warn("Permission denied",
+					"username", user.Username,
+					"path", r.URL.Path,
+					"permissions", permissions)
+				overseerrError(w, http.StatusForbidden, "You do not have permission to do this")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
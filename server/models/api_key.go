@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// APIKey authenticates requests to the /api/v1 JSON API as its user. The key itself is only
+// known when it's created.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the key, for telling keys apart
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Collection struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	TMDBCollectionID int       `json:"tmdb_collection_id,omitempty"` // 0 for custom collections
+	PosterPath       string    `json:"poster_path,omitempty"`        // TMDB poster path, or an image URL for custom collections
+	MediaServer      string    `json:"media_server,omitempty"`       // server MediaServerID belongs to
+	MediaServerID    string    `json:"media_server_id,omitempty"`
+	MovieIDs         []int     `json:"movie_ids"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// IsCustom reports whether the collection was defined by an admin rather than TMDB
+func (c Collection) IsCustom() bool {
+	return c.TMDBCollectionID == 0
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Download is a torrent added to qBittorrent for a request
+type Download struct {
+	ID          int       `json:"id"`
+	RequestID   int       `json:"request_id"`
+	TorrentHash string    `json:"torrent_hash"`
+	Title       string    `json:"title"`
+	Size        int64     `json:"size"`
+	Status      string    `json:"status"`
+	Progress    float64   `json:"progress"` // 0 to 1
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
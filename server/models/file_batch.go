@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// FileBatch is a planned bulk rename or dedupe, and after it's applied, the journal for undoing it
+type FileBatch struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`   // rename_movies, rename_shows, dedupe_movies or dedupe_shows
+	Status    string     `json:"status"` // planned, applying, applied, undoing, undone or finalized
+	Notes     []string   `json:"notes"`  // items left out of the plan and why
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	UndoneAt  *time.Time `json:"undone_at,omitempty"`
+
+	Moves   int   `json:"moves"`
+	Deletes int   `json:"deletes"`
+	Failed  int   `json:"failed"`
+	Bytes   int64 `json:"bytes"` // size of the files the batch deletes
+
+	Operations []FileOperation `json:"operations,omitempty"`
+}
+
+// FileOperation is one move or delete in a batch. Deletes move the file to DestPath, in the
+// library's undo folder, so they can be undone until the batch is finalized.
+type FileOperation struct {
+	ID         int64  `json:"id"`
+	Position   int    `json:"position"`
+	Action     string `json:"action"` // move or delete
+	SourcePath string `json:"source_path"`
+	DestPath   string `json:"dest_path"`
+	Reason     string `json:"reason"`
+	Size       int64  `json:"size"`
+	Status     string `json:"status"` // planned, done, failed or undone
+	Error      string `json:"error,omitempty"`
+}
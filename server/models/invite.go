@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Invite is a single-use registration link. The token is only known when it's created.
+type Invite struct {
+	ID        int64      `json:"id"`
+	RoleID    int64      `json:"role_id"` // 0 means the default role
+	RoleName  string     `json:"role_name"`
+	CreatedBy string     `json:"created_by"`
+	UsedBy    string     `json:"used_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the invite can still be used to register
+func (i Invite) Usable() bool {
+	return i.UsedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -10,12 +10,13 @@ type Movie struct {
 	IMDBID          string     `json:"imdb_id"`
 	Path            string     `json:"path"`
 	Quality         string     `json:"quality"`
+	Edition         string     `json:"edition,omitempty"` // e.g. "Director's Cut"; empty for the regular edition
 	Size            int64      `json:"size"`
 	Overview        string     `json:"overview"`
 	PosterPath      string     `json:"poster_path"`
 	Genres          string     `json:"genres"`
 	Status          string     `json:"status"` // e.g., "discovered", "matching", "ready"
-	RawMetadata     []byte     `json:"raw_metadata"`
+	RawMetadata     []byte     `json:"-"`
 	TorrentHash     string     `json:"torrent_hash,omitempty"` // Torrent hash for seeding status
 	ImportedAt      *time.Time `json:"imported_at,omitempty"`  // Timestamp when imported to library
 	SubtitlesSynced bool       `json:"subtitles_synced"`       // Whether subtitles have been synced
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// QuotaWindow is the rolling period request quotas are counted over
+const QuotaWindow = 7 * 24 * time.Hour
+
+// Quota is a user's request allowance and what they've used of it in the current window.
+// A nil limit means unlimited.
+type Quota struct {
+	MovieLimit  *int       `json:"movie_limit"`
+	SeasonLimit *int       `json:"season_limit"`
+	MoviesUsed  int        `json:"movies_used"`
+	SeasonsUsed int        `json:"seasons_used"`
+	NextFreeAt  *time.Time `json:"next_free_at,omitempty"` // when the oldest counted request leaves the window
+}
+
+// Limited reports whether any limit applies
+func (q Quota) Limited() bool {
+	return q.MovieLimit != nil || q.SeasonLimit != nil
+}
+
+// MoviesExhausted reports whether the user can't request another movie
+func (q Quota) MoviesExhausted() bool {
+	return q.MovieLimit != nil && q.MoviesUsed >= *q.MovieLimit
+}
+
+// SeasonsExhausted reports whether the user can't request another season
+func (q Quota) SeasonsExhausted() bool {
+	return q.SeasonLimit != nil && q.SeasonsUsed >= *q.SeasonLimit
+}
+
+// Allows reports whether a request for mediaType covering the given number of seasons fits
+// in what's left
+func (q Quota) Allows(mediaType string, seasons int) bool {
+	if mediaType == "movie" {
+		return !q.MoviesExhausted()
+	}
+	return q.SeasonLimit == nil || q.SeasonsUsed+seasons <= *q.SeasonLimit
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RecycledItem is a file or folder in the recycle bin
+type RecycledItem struct {
+	ID           int64     `json:"id"`
+	OriginalPath string    `json:"original_path"`
+	RecycledPath string    `json:"recycled_path"`
+	Reason       string    `json:"reason"`
+	Size         int64     `json:"size"`
+	IsDir        bool      `json:"is_dir"`
+	DeletedAt    time.Time `json:"deleted_at"`
+}
@@ -15,11 +15,19 @@ type Request struct {
 	Year          int        `json:"year"`
 	PosterPath    string     `json:"poster_path"`
 	Overview      string     `json:"overview"`
-	Seasons       string     `json:"seasons,omitempty"`  // Comma-separated list of season numbers
-	Episodes      string     `json:"episodes,omitempty"` // Comma-separated list of episode identifiers (e.g., S01E01,S01E02)
-	Status        string     `json:"status"`             // "pending", "downloading", "completed", "cancelled", "not_found"
+	Seasons       string     `json:"seasons,omitempty"`        // Comma-separated list of season numbers
+	Episodes      string     `json:"episodes,omitempty"`       // Comma-separated list of episode identifiers (e.g., S01E01,S01E02)
+	Status        string     `json:"status"`                   // "awaiting_approval", "pending", "downloading", "completed", "cancelled", "not_found"
+	RootFolderID  *int64     `json:"root_folder_id,omitempty"` // nil uses the requester's role folder, then the default
+	Is4K          bool       `json:"is_4k"`                    // for the 4K library rather than the standard one
+	Edition       string     `json:"edition,omitempty"`        // movie edition to get, e.g. "Director's Cut"; empty for any
 	RetryCount    int        `json:"retry_count"`
 	LastSearchAt  *time.Time `json:"last_search_at,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
+
+// UsesRootFolder reports whether the request places its media in the given root folder
+func (r Request) UsesRootFolder(id int64) bool {
+	return r.RootFolderID != nil && *r.RootFolderID == id
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Permissions a role can grant. Admins have all of them.
+const (
+	PermRequestMovies = "request_movies"
+	PermRequestShows  = "request_shows"
+	PermRequest4K     = "request_4k"     // requests for the separate 4K library
+	PermAutoApprove   = "auto_approve"   // requests skip the approval queue
+	PermManageLibrary = "manage_library" // imports, renames, rematches, dedupes, subtitles, collections and requests
+	PermTriggerScans  = "trigger_scans"
+	PermManageUsers   = "manage_users"
+)
+
+// AllPermissions lists every permission in display order
+var AllPermissions = []string{
+	PermRequestMovies,
+	PermRequestShows,
+	PermRequest4K,
+	PermAutoApprove,
+	PermManageLibrary,
+	PermTriggerScans,
+	PermManageUsers,
+}
+
+// PermissionLabels are the names shown in the admin UI
+var PermissionLabels = map[string]string{
+	PermRequestMovies: "Request movies",
+	PermRequestShows:  "Request shows",
+	PermRequest4K:     "Request 4K",
+	PermAutoApprove:   "Requests auto-approved",
+	PermManageLibrary: "Manage library",
+	PermTriggerScans:  "Trigger scans",
+	PermManageUsers:   "Manage users",
+}
+
+// Role is a named set of permissions assigned to users. Users without a role get the default role.
+type Role struct {
+	ID                int64     `json:"id"`
+	Name              string    `json:"name"`
+	Permissions       []string  `json:"permissions"`
+	IsDefault         bool      `json:"is_default"`
+	MovieQuota        *int      `json:"movie_quota"`          // movies per QuotaWindow; nil is unlimited
+	SeasonQuota       *int      `json:"season_quota"`         // seasons per QuotaWindow; nil is unlimited
+	MovieRootFolderID *int64    `json:"movie_root_folder_id"` // where the role's requested movies go; nil is the default
+	ShowRootFolderID  *int64    `json:"show_root_folder_id"`
+	UserCount         int       `json:"user_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Has reports whether the role grants a permission
+func (r Role) Has(permission string) bool {
+	return slices.Contains(r.Permissions, permission)
+}
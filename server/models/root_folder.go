@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RootFolder is a library folder movies or shows are kept in
+type RootFolder struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	MediaType string    `json:"media_type"` // "movie" or "show"
+	Path      string    `json:"path"`
+	MinFreeGB float64   `json:"min_free_gb"` // new media goes elsewhere below this; 0 is no minimum
+	IsDefault bool      `json:"is_default"`
+	Is4K      bool      `json:"is_4k"`                // part of the separate 4K library
+	Available bool      `json:"available"`            // the folder exists, e.g. its disk is mounted
+	FreeBytes *uint64   `json:"free_bytes,omitempty"` // nil when unknown
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -14,7 +14,7 @@ type Show struct {
 	PosterPath  string    `json:"poster_path"`
 	Genres      string    `json:"genres"`
 	Status      string    `json:"status"`
-	RawMetadata []byte    `json:"raw_metadata"`
+	RawMetadata []byte    `json:"-"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SubtitleSync is the outcome of a subtitle sync. Metrics are nil when ffsubsync didn't report them.
+type SubtitleSync struct {
+	ID                   int       `json:"id"`
+	MediaType            string    `json:"media_type"` // "movie" or "episode"
+	MediaID              int       `json:"media_id"`
+	MediaTitle           string    `json:"media_title,omitempty"` // For display
+	SubtitlePath         string    `json:"subtitle_path"`
+	BackupPath           string    `json:"backup_path,omitempty"`
+	Status               string    `json:"status"` // "applied", "rejected", "failed", "reverted"
+	OffsetSeconds        *float64  `json:"offset_seconds"`
+	FramerateScaleFactor *float64  `json:"framerate_scale_factor"`
+	Score                *float64  `json:"score"`
+	Message              string    `json:"message,omitempty"`
+	Reference            string    `json:"reference,omitempty"` // "audio", "stream:s:N" or "subtitle:<file>"
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type User struct {
 	ID           int64     `db:"id"`
@@ -8,6 +11,26 @@ type User struct {
 	Email        string    `db:"email"`
 	PasswordHash string    `db:"password_hash"`
 	IsAdmin      bool      `db:"is_admin"`
+	Disabled     bool      `db:"disabled"`
+	JellyfinID   string    `db:"jellyfin_id"` // set for accounts that sign in through Jellyfin
+	RoleID       int64     `db:"role_id"`     // 0 means the default role
+	RoleName     string    `db:"-"`
+	Permissions  []string  `db:"-"` // from the user's role; ignored for admins
 	CreatedAt    time.Time `db:"created_at"`
 	UpdatedAt    time.Time `db:"updated_at"`
 }
+
+// Can reports whether the user has a permission. Admins can do everything.
+func (u *User) Can(permission string) bool {
+	return u.IsAdmin || slices.Contains(u.Permissions, permission)
+}
+
+// CanAny reports whether the user has at least one of the permissions
+func (u *User) CanAny(permissions ...string) bool {
+	return slices.ContainsFunc(permissions, u.Can)
+}
+
+// CanAccessAdmin reports whether the user can open the admin page
+func (u *User) CanAccessAdmin() bool {
+	return u.CanAny(PermManageLibrary, PermTriggerScans, PermManageUsers)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type WatchState struct {
+	UserID       int        `json:"user_id"`
+	MediaType    string     `json:"media_type"` // "movie" or "episode"
+	MediaID      int        `json:"media_id"`
+	Played       bool       `json:"played"`
+	PlayCount    int        `json:"play_count"`
+	LastPlayedAt *time.Time `json:"last_played_at,omitempty"`
+}
+
+// SeriesSuggestion is the next unwatched episode of a show the user has been watching
+type SeriesSuggestion struct {
+	ShowID        int        `json:"show_id"`
+	ShowTitle     string     `json:"show_title"`
+	PosterPath    string     `json:"poster_path"`
+	EpisodeID     int        `json:"episode_id"`
+	SeasonNumber  int        `json:"season_number"`
+	EpisodeNumber int        `json:"episode_number"`
+	EpisodeTitle  string     `json:"episode_title"`
+	LastPlayedAt  *time.Time `json:"last_played_at,omitempty"`
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognise
+const APIKeyPrefix = "arrgo_"
+
+// CreateAPIKey creates a key for the user and returns it. It can't be recovered later.
+func CreateAPIKey(userID int64, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if len(name) > 100 {
+		return "", fmt.Errorf("name is too long")
+	}
+
+	token, hash, err := newLinkToken()
+	if err != nil {
+		return "", err
+	}
+	key := APIKeyPrefix + token
+	_, err = database.DB.Exec("INSERT INTO api_keys (user_id, name, key_prefix, key_hash) VALUES ($1, $2, $3, $4)",
+		userID, name, key[:len(APIKeyPrefix)+6], hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, nil
+}
+
+// GetAPIKeys returns a user's API keys, newest first
+func GetAPIKeys(userID int64) ([]models.APIKey, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, name, key_prefix, last_used_at, created_at
+		FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &lastUsed, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		if lastUsed.Valid {
+			key.LastUsedAt = &lastUsed.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey revokes one of the user's API keys
+func DeleteAPIKey(userID, keyID int64) error {
+	res, err := database.DB.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("API key not found")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the user an API key belongs to. Keys of disabled users don't work.
+func AuthenticateAPIKey(key string) (*models.User, error) {
+	token, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok || token == "" {
+		return nil, fmt.Errorf("invalid API key")
+	}
+
+	var userID int64
+	err := database.DB.QueryRow("UPDATE api_keys SET last_used_at = NOW() WHERE key_hash = $1 RETURNING user_id",
+		hashLinkToken(token)).Scan(&userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	return activeUser(GetUserByID(userID))
+}
+
+type apiUserKey struct{}
+
+// WithAPIUser attaches the user an API key belongs to to a request context
+func WithAPIUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, apiUserKey{}, user)
+}
+
+// APIUser returns the user a request was authenticated as by API key, or nil for session
+// requests
+func APIUser(ctx context.Context) *models.User {
+	user, _ := ctx.Value(apiUserKey{}).(*models.User)
+	return user
+}
@@ -9,10 +9,21 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrAccountDisabled is returned when a disabled user tries to sign in
+var ErrAccountDisabled = fmt.Errorf("account disabled")
+
+// userColumns and userWithRole select a user along with the permissions of their role, or of
+// the default role when they don't have one
+const userColumns = `u.id, u.username, u.email, u.password_hash, u.is_admin, u.disabled, COALESCE(u.jellyfin_id, ''),
+	COALESCE(u.role_id, 0), COALESCE(r.name, ''), COALESCE(r.permissions, ''), u.created_at, u.updated_at`
+
+const userWithRole = `users u LEFT JOIN roles r ON r.id = COALESCE(u.role_id, (SELECT id FROM roles WHERE is_default))`
+
 func AuthenticateUser(username, password string) (*models.User, error) {
 	var user models.User
+	var permissions string
 	err := database.DB.QueryRow(
-		"SELECT id, username, email, password_hash, is_admin, created_at, updated_at FROM users WHERE username = $1",
+		"SELECT "+userColumns+" FROM "+userWithRole+" WHERE u.username = $1",
 		username,
 	).Scan(
 		&user.ID,
@@ -20,6 +31,11 @@ func AuthenticateUser(username, password string) (*models.User, error) {
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Disabled,
+		&user.JellyfinID,
+		&user.RoleID,
+		&user.RoleName,
+		&permissions,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -36,7 +52,11 @@ func AuthenticateUser(username, password string) (*models.User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
 
+	user.Permissions = splitPermissions(permissions)
 	return &user, nil
 }
 
@@ -68,10 +88,22 @@ func RegisterUser(username, email, password string) (*models.User, error) {
 	return &user, nil
 }
 
+// activeUser wraps a user lookup for sign-in, refusing disabled accounts
+func activeUser(user *models.User, err error) (*models.User, error) {
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	return user, nil
+}
+
 func GetUserByID(userID int64) (*models.User, error) {
 	var user models.User
+	var permissions string
 	err := database.DB.QueryRow(
-		"SELECT id, username, email, password_hash, is_admin, created_at, updated_at FROM users WHERE id = $1",
+		"SELECT "+userColumns+" FROM "+userWithRole+" WHERE u.id = $1",
 		userID,
 	).Scan(
 		&user.ID,
@@ -79,6 +111,11 @@ func GetUserByID(userID int64) (*models.User, error) {
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Disabled,
+		&user.JellyfinID,
+		&user.RoleID,
+		&user.RoleName,
+		&permissions,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -90,6 +127,7 @@ func GetUserByID(userID int64) (*models.User, error) {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
+	user.Permissions = splitPermissions(permissions)
 	return &user, nil
 }
 
@@ -124,7 +162,7 @@ func ChangePassword(userID int64, currentPassword, newPassword string) error {
 }
 
 func GetAllUsers() ([]models.User, error) {
-	rows, err := database.DB.Query("SELECT id, username, email, is_admin, created_at, updated_at FROM users ORDER BY username ASC")
+	rows, err := database.DB.Query("SELECT " + userColumns + " FROM " + userWithRole + " ORDER BY u.username ASC")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -133,17 +171,25 @@ func GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
+		var permissions string
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
 			&user.Email,
+			&user.PasswordHash,
 			&user.IsAdmin,
+			&user.Disabled,
+			&user.JellyfinID,
+			&user.RoleID,
+			&user.RoleName,
+			&permissions,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		user.Permissions = splitPermissions(permissions)
 		users = append(users, user)
 	}
 
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/justbri/arrgo/shared/format"
+)
+
+// ApproveWithinSizeBudget approves requests awaiting approval when the torrents automation
+// would pick for them add up to no more than AUTO_APPROVE_MAX_SIZE_GB. Each request is
+// checked once (again if seasons are added to it); anything over budget or without search
+// results stays in the queue for a manual decision. Returns how many requests were approved.
+func (s *AutomationService) ApproveWithinSizeBudget(ctx context.Context) int {
+	if s.cfg.AutoApproveMaxSizeGB <= 0 {
+		return 0
+	}
+	budget := int64(s.cfg.AutoApproveMaxSizeGB * 1024 * 1024 * 1024)
+
+	rows, err := database.DB.Query(`
+		SELECT id, title, media_type, COALESCE(year, 0), COALESCE(seasons, ''), COALESCE(episodes, ''), is_4k, edition
+		FROM requests WHERE status = 'awaiting_approval' AND budget_checked_at IS NULL`)
+	if err != nil {
+		slog.Error("Error querying requests awaiting approval", "error", err)
+		return 0
+	}
+	var requests []models.Request
+	for rows.Next() {
+		var r models.Request
+		if err := rows.Scan(&r.ID, &r.Title, &r.MediaType, &r.Year, &r.Seasons, &r.Episodes, &r.Is4K, &r.Edition); err != nil {
+			slog.Error("Error scanning request awaiting approval", "error", err)
+			continue
+		}
+		r.Title = decodeUnicodeEscapes(r.Title)
+		requests = append(requests, r)
+	}
+	rows.Close()
+
+	approved := 0
+	for _, r := range requests {
+		// Claim the request so concurrent checks don't search the indexers twice
+		res, err := database.DB.Exec("UPDATE requests SET budget_checked_at = NOW() WHERE id = $1 AND budget_checked_at IS NULL", r.ID)
+		if err != nil {
+			slog.Error("Error claiming request for size check", "request_id", r.ID, "error", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		size, err := s.estimateRequestSize(ctx, r)
+		if err != nil {
+			slog.Info("Request left for manual approval", "request_id", r.ID, "title", r.Title, "reason", err)
+			continue
+		}
+		if size > budget {
+			slog.Info("Request is over the auto-approval size budget", "request_id", r.ID, "title", r.Title,
+				"size", format.Bytes(size), "budget", format.Bytes(budget))
+			continue
+		}
+
+		res, err = database.DB.Exec("UPDATE requests SET status = 'pending', updated_at = NOW() WHERE id = $1 AND status = 'awaiting_approval'", r.ID)
+		if err != nil {
+			slog.Error("Error auto-approving request", "request_id", r.ID, "error", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			approved++
+			slog.Info("Auto-approved request within size budget", "request_id", r.ID, "title", r.Title,
+				"size", format.Bytes(size), "budget", format.Bytes(budget))
+		}
+	}
+	return approved
+}
+
+// estimateRequestSize searches the indexers the way processing would and adds up the size
+// of the best torrent for the movie, or for each requested season and episode
+func (s *AutomationService) estimateRequestSize(ctx context.Context, r models.Request) (int64, error) {
+	parts := []models.Request{r}
+	if r.MediaType == "show" {
+		parts = nil
+		for _, season := range strings.Split(r.Seasons, ",") {
+			if season = strings.TrimSpace(season); season != "" {
+				part := r
+				part.Seasons, part.Episodes = season, ""
+				parts = append(parts, part)
+			}
+		}
+		for _, episode := range strings.Split(r.Episodes, ",") {
+			if episode = strings.TrimSpace(episode); episode != "" {
+				part := r
+				part.Seasons, part.Episodes = "", episode
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) == 0 {
+			return 0, fmt.Errorf("no seasons or episodes requested")
+		}
+	}
+
+	var total int64
+	for _, part := range parts {
+		query := part.Title
+		if part.MediaType == "movie" && part.Year > 0 {
+			query = fmt.Sprintf("%s %d", part.Title, part.Year)
+		}
+
+		searchResults, err := SearchTorrents(ctx, query, part.MediaType, part.Seasons, part.Episodes)
+		if err != nil {
+			return 0, fmt.Errorf("indexer search failed: %w", err)
+		}
+		results := make([]TorrentSearchResult, 0, len(searchResults))
+		for _, result := range searchResults {
+			results = append(results, TorrentSearchResult{
+				Title:      result.Title,
+				Size:       result.Size,
+				Seeds:      result.Seeds,
+				Peers:      result.Peers,
+				MagnetLink: result.MagnetLink,
+				InfoHash:   result.InfoHash,
+				Source:     result.Source,
+				Resolution: result.Resolution,
+				Quality:    result.Quality,
+			})
+		}
+
+		results = editionResults(libraryResults(results, part.MediaType, part.Is4K), part.Edition)
+		best := selectBestResult(results, part.MediaType, part.Seasons, part.Episodes, part.Title, part.Year)
+		if best == nil {
+			return 0, fmt.Errorf("no suitable torrent found for %q (seasons %q, episodes %q)", part.Title, part.Seasons, part.Episodes)
+		}
+		size := format.ParseBytes(best.Size)
+		if size == 0 {
+			return 0, fmt.Errorf("unknown size for torrent %q", best.Title)
+		}
+		total += size
+	}
+	return total, nil
+}
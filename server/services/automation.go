@@ -79,7 +79,8 @@ func (s *AutomationService) Start(ctx context.Context) {
 		slog.Info("qBittorrent is available, processing all pending requests on startup")
 		// First, check and fix any "downloading" requests that don't actually have active torrents
 		s.ValidateDownloadingRequests(ctx)
-		// Then process all pending requests
+		// Approve anything waiting that fits the size budget, then process all pending requests
+		s.ApproveWithinSizeBudget(ctx)
 		s.ProcessPendingRequestsOnStartup(ctx)
 		// Also update download status immediately to pick up any manual torrents
 		s.UpdateDownloadStatus(ctx)
@@ -93,6 +94,7 @@ func (s *AutomationService) Start(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			s.ApproveWithinSizeBudget(ctx)
 			s.ProcessPendingRequests(ctx)
 		case <-updateTicker.C:
 			s.UpdateDownloadStatus(ctx)
@@ -326,7 +328,7 @@ func (s *AutomationService) ValidateDownloadingRequests(ctx context.Context) {
 // ProcessPendingRequestsOnStartup processes all pending requests on startup, ignoring retry timing
 func (s *AutomationService) ProcessPendingRequestsOnStartup(ctx context.Context) {
 	var requests []models.Request
-	query := `SELECT id, title, media_type, year, tmdb_id, tvdb_id, imdb_id, seasons, retry_count, last_search_at FROM requests WHERE status = 'pending'`
+	query := `SELECT id, title, media_type, year, tmdb_id, tvdb_id, imdb_id, seasons, is_4k, edition, retry_count, last_search_at FROM requests WHERE status = 'pending'`
 
 	slog.Debug("Checking for pending requests to process on startup")
 	rows, err := database.DB.Query(query)
@@ -340,7 +342,7 @@ func (s *AutomationService) ProcessPendingRequestsOnStartup(ctx context.Context)
 		var r models.Request
 		var tmdbID, tvdbID, imdbID, seasons sql.NullString
 		var lastSearchAt sql.NullTime
-		if err := rows.Scan(&r.ID, &r.Title, &r.MediaType, &r.Year, &tmdbID, &tvdbID, &imdbID, &seasons, &r.RetryCount, &lastSearchAt); err != nil {
+		if err := rows.Scan(&r.ID, &r.Title, &r.MediaType, &r.Year, &tmdbID, &tvdbID, &imdbID, &seasons, &r.Is4K, &r.Edition, &r.RetryCount, &lastSearchAt); err != nil {
 			slog.Error("Error scanning request", "error", err)
 			continue
 		}
@@ -381,7 +383,7 @@ func (s *AutomationService) ProcessPendingRequestsOnStartup(ctx context.Context)
 
 func (s *AutomationService) ProcessPendingRequests(ctx context.Context) {
 	var requests []models.Request
-	query := `SELECT id, title, media_type, year, tmdb_id, tvdb_id, imdb_id, seasons, retry_count, last_search_at FROM requests WHERE status = 'pending'`
+	query := `SELECT id, title, media_type, year, tmdb_id, tvdb_id, imdb_id, seasons, is_4k, edition, retry_count, last_search_at FROM requests WHERE status = 'pending'`
 
 	slog.Debug("Checking for pending requests to process")
 	rows, err := database.DB.Query(query)
@@ -396,7 +398,7 @@ func (s *AutomationService) ProcessPendingRequests(ctx context.Context) {
 		var r models.Request
 		var tmdbID, tvdbID, imdbID, seasons sql.NullString
 		var lastSearchAt sql.NullTime
-		if err := rows.Scan(&r.ID, &r.Title, &r.MediaType, &r.Year, &tmdbID, &tvdbID, &imdbID, &seasons, &r.RetryCount, &lastSearchAt); err != nil {
+		if err := rows.Scan(&r.ID, &r.Title, &r.MediaType, &r.Year, &tmdbID, &tvdbID, &imdbID, &seasons, &r.Is4K, &r.Edition, &r.RetryCount, &lastSearchAt); err != nil {
 			slog.Error("Error scanning request", "error", err)
 			continue
 		}
@@ -1102,8 +1104,9 @@ func (s *AutomationService) processSingleSeason(ctx context.Context, r models.Re
 		}
 	}
 
-	results := allResults
-	slog.Info("Indexer search completed", "request_id", r.ID, "results_count", len(results), "variants_searched", len(variants))
+	// Only keep releases the request's library takes, of the edition it asks for
+	results := editionResults(libraryResults(allResults, r.MediaType, r.Is4K), r.Edition)
+	slog.Info("Indexer search completed", "request_id", r.ID, "results_count", len(results), "variants_searched", len(variants), "4k", r.Is4K, "edition", r.Edition)
 
 	if len(results) == 0 {
 		slog.Info("No results found for request", "request_id", r.ID, "title", r.Title, "retry_count", r.RetryCount)
@@ -1620,73 +1623,9 @@ func (s *AutomationService) UpdateDownloadStatus(ctx context.Context) {
 	}
 }
 
+// ProcessSubtitleQueue hands the subtitle queue to the quota scheduler
 func (s *AutomationService) ProcessSubtitleQueue(ctx context.Context) {
-	// 1. Check if we are still in quota lockdown
-	var resetStr string
-	err := database.DB.QueryRow("SELECT value FROM settings WHERE key = 'opensubtitles_quota_reset'").Scan(&resetStr)
-	if err == nil {
-		if t, err := time.Parse(time.RFC3339, resetStr); err == nil {
-			if time.Now().Before(t.Add(5 * time.Minute)) {
-				// Still in lockdown
-				slog.Debug("Still in OpenSubtitles quota lockdown", "reset_time", t.Add(5*time.Minute))
-				return
-			}
-		}
-	}
-
-	// 2. Fetch pending jobs that are ready for retry
-	rows, err := database.DB.Query("SELECT id, media_type, media_id FROM subtitle_queue WHERE next_retry <= CURRENT_TIMESTAMP")
-	if err != nil {
-		slog.Error("Error querying subtitle queue", "error", err)
-		return
-	}
-	defer rows.Close()
-
-	type job struct {
-		id    int
-		mType string
-		mID   int
-	}
-	var jobs []job
-	for rows.Next() {
-		var j job
-		if err := rows.Scan(&j.id, &j.mType, &j.mID); err == nil {
-			jobs = append(jobs, j)
-		}
-	}
-
-	for _, j := range jobs {
-		slog.Info("Retrying subtitle download", "media_type", j.mType, "media_id", j.mID)
-		var err error
-		if j.mType == "movie" {
-			err = globalSubtitle.DownloadSubtitlesForMovie(j.mID)
-		} else {
-			err = globalSubtitle.DownloadSubtitlesForEpisode(j.mID)
-		}
-
-		if err == nil {
-			// Success! Remove from queue
-			database.DB.Exec("DELETE FROM subtitle_queue WHERE id = $1", j.id)
-			slog.Info("Successfully downloaded subtitles on retry", "media_type", j.mType, "media_id", j.mID)
-		} else {
-			// Check if it was a quota error again
-			if strings.Contains(err.Error(), "406") {
-				// Quota hit again, next_retry was updated by QueueSubtitleDownload called inside DownloadSubtitlesForX
-				slog.Warn("Hit quota again while retrying subtitle download", "media_type", j.mType, "media_id", j.mID)
-				break // Stop processing queue for now
-			} else {
-				// Some other error, increment retry count and back off
-				database.DB.Exec("UPDATE subtitle_queue SET retry_count = retry_count + 1, next_retry = CURRENT_TIMESTAMP + interval '1 hour' WHERE id = $1", j.id)
-
-				var retries int
-				database.DB.QueryRow("SELECT retry_count FROM subtitle_queue WHERE id = $1", j.id).Scan(&retries)
-				if retries > 5 {
-					slog.Warn("Giving up on subtitles after 5 retries", "media_type", j.mType, "media_id", j.mID, "retries", retries)
-					database.DB.Exec("DELETE FROM subtitle_queue WHERE id = $1", j.id)
-				}
-			}
-		}
-	}
+	globalSubtitle.ProcessQueue()
 }
 
 // selectBestResult selects the best torrent result based on seeds, quality, season matching, title/year matching, and minimum requirements
@@ -41,7 +41,7 @@ func CleanupEmptyDirs(root string) error {
 		".jpeg": true, ".gif": true, ".svg": true, ".webp": true,
 		".sfv": true, ".srr": true, ".xml": true, ".html": true,
 		".htm": true, ".info": true, ".srt": true, ".sub": true,
-		".idx": true, ".m3u": true, ".m3u8": true, ".parts": true,
+		".idx": true, ".ass": true, ".ssa": true, ".vtt": true, ".m3u": true, ".m3u8": true, ".parts": true,
 		".sample": true, ".tbn": true, ".ico": true, ".desktop": true,
 		".ini": true, ".ds_store": true, "thumbs.db": true, ".torrent": true,
 	}
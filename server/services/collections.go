@@ -0,0 +1,414 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minCollectionMovies is how many movies of a TMDB collection must be in the library
+	// before it gets a media server collection; a "collection" of one is just noise
+	minCollectionMovies = 2
+
+	// collectionBackfillBatch caps TMDB lookups per sync for movies matched before
+	// collection info was stored
+	collectionBackfillBatch = 100
+)
+
+// collectionMember is a library movie that belongs in a collection
+type collectionMember struct {
+	TMDBID string
+	Path   string
+}
+
+// SyncCollections creates and maintains media server collections for TMDB franchise
+// collections with enough movies in the library, plus any custom collections defined by an
+// admin. Members are matched to server items by TMDB ID and path, so collections survive renames.
+func SyncCollections(cfg *config.Config) error {
+	server := GetMediaServer(cfg)
+	if server == nil {
+		return fmt.Errorf("no media server configured")
+	}
+
+	backfillMovieCollections()
+
+	if err := refreshTMDBCollections(cfg); err != nil {
+		return err
+	}
+
+	collections, err := GetCollections()
+	if err != nil {
+		return err
+	}
+
+	synced := 0
+	for _, c := range collections {
+		members, err := getCollectionMembers(cfg, c)
+		if err != nil {
+			slog.Error("Failed to load collection members", "collection", c.Name, "error", err)
+			continue
+		}
+		if err := syncMediaServerCollection(server, c, members); err != nil {
+			slog.Error("Failed to sync media server collection", "server", server.Name(), "collection", c.Name, "error", err)
+			continue
+		}
+		synced++
+	}
+
+	slog.Info("Collection sync complete", "server", server.Name(), "collections", synced)
+	return nil
+}
+
+// backfillMovieCollections fetches TMDB details for movies whose stored metadata predates
+// collection info. Movies that belong to no collection end up with an explicit null, so
+// each movie is only looked up once.
+func backfillMovieCollections() {
+	if globalMetadata == nil || globalMetadata.cfg.TMDBAPIKey == "" {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, tmdb_id FROM movies
+		WHERE tmdb_id IS NOT NULL AND tmdb_id != ''
+			AND (raw_metadata IS NULL OR raw_metadata->'belongs_to_collection' IS NULL)
+		LIMIT $1`, collectionBackfillBatch)
+	if err != nil {
+		slog.Error("Failed to find movies missing collection info", "error", err)
+		return
+	}
+
+	type pending struct {
+		id     int
+		tmdbID string
+	}
+	var movies []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.tmdbID); err == nil {
+			movies = append(movies, p)
+		}
+	}
+	rows.Close()
+
+	for _, m := range movies {
+		details, err := globalMetadata.GetTMDBMovieDetails(m.tmdbID)
+		if err != nil {
+			slog.Debug("Failed to fetch TMDB details for collection backfill", "movie_id", m.id, "error", err)
+			continue
+		}
+		rawMetadata, _ := json.Marshal(details)
+		database.DB.Exec("UPDATE movies SET raw_metadata = $1 WHERE id = $2", rawMetadata, m.id)
+	}
+
+	if len(movies) > 0 {
+		slog.Info("Backfilled TMDB collection info", "movies", len(movies))
+	}
+}
+
+// refreshTMDBCollections brings the collections table in line with the library: franchises
+// that reached the minimum size are added, ones that dropped below it are removed (along
+// with their media server collection).
+func refreshTMDBCollections(cfg *config.Config) error {
+	rows, err := database.DB.Query(`
+		SELECT (raw_metadata->'belongs_to_collection'->>'id')::int,
+			MAX(raw_metadata->'belongs_to_collection'->>'name'),
+			COALESCE(MAX(raw_metadata->'belongs_to_collection'->>'poster_path'), '')
+		FROM movies
+		WHERE jsonb_typeof(raw_metadata->'belongs_to_collection') = 'object'
+			AND path NOT LIKE $1 || '%'
+		GROUP BY 1
+		HAVING COUNT(*) >= $2`, cfg.IncomingMoviesPath, minCollectionMovies)
+	if err != nil {
+		return fmt.Errorf("failed to query tmdb collections: %w", err)
+	}
+
+	active := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		var name, posterPath string
+		if err := rows.Scan(&id, &name, &posterPath); err != nil {
+			continue
+		}
+		active[id] = true
+		_, err := database.DB.Exec(`
+			INSERT INTO collections (name, tmdb_collection_id, poster_path) VALUES ($1, $2, $3)
+			ON CONFLICT (tmdb_collection_id) DO UPDATE SET name = $1, poster_path = $3, updated_at = CURRENT_TIMESTAMP`,
+			name, id, posterPath)
+		if err != nil {
+			slog.Error("Failed to upsert TMDB collection", "collection", name, "error", err)
+		}
+	}
+	rows.Close()
+
+	collections, err := GetCollections()
+	if err != nil {
+		return err
+	}
+	for _, c := range collections {
+		if !c.IsCustom() && !active[c.TMDBCollectionID] {
+			slog.Info("TMDB collection no longer has enough movies in library, removing", "collection", c.Name)
+			if err := DeleteCollection(cfg, c.ID); err != nil {
+				slog.Error("Failed to remove collection", "collection", c.Name, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetCollections returns all collections, with member movie IDs for custom ones
+func GetCollections() ([]models.Collection, error) {
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.name, COALESCE(c.tmdb_collection_id, 0), COALESCE(c.poster_path, ''),
+			COALESCE(c.media_server, ''), COALESCE(c.media_server_id, ''),
+			c.created_at, c.updated_at,
+			COALESCE(STRING_AGG(cm.movie_id::text, ','), '')
+		FROM collections c
+		LEFT JOIN collection_movies cm ON cm.collection_id = c.id
+		GROUP BY c.id
+		ORDER BY c.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collections: %w", err)
+	}
+	defer rows.Close()
+
+	var collections []models.Collection
+	for rows.Next() {
+		var c models.Collection
+		var movieIDs string
+		if err := rows.Scan(&c.ID, &c.Name, &c.TMDBCollectionID, &c.PosterPath, &c.MediaServer, &c.MediaServerID, &c.CreatedAt, &c.UpdatedAt, &movieIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		c.MovieIDs = []int{}
+		for _, idStr := range strings.Split(movieIDs, ",") {
+			if id, err := strconv.Atoi(idStr); err == nil {
+				c.MovieIDs = append(c.MovieIDs, id)
+			}
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// SaveCustomCollection creates a custom collection, or replaces the name, poster and members
+// of an existing one when id is non-zero. Returns the collection ID.
+func SaveCustomCollection(id int, name, posterURL string, movieIDs []int) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("collection name is required")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if id == 0 {
+		err = tx.QueryRow("INSERT INTO collections (name, poster_path) VALUES ($1, $2) RETURNING id", name, posterURL).Scan(&id)
+	} else {
+		var res sql.Result
+		res, err = tx.Exec(`
+			UPDATE collections SET name = $1, poster_path = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3 AND tmdb_collection_id IS NULL`, name, posterURL, id)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				err = fmt.Errorf("custom collection %d not found", id)
+			}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM collection_movies WHERE collection_id = $1", id); err != nil {
+		return 0, err
+	}
+	for _, movieID := range movieIDs {
+		if _, err := tx.Exec("INSERT INTO collection_movies (collection_id, movie_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, movieID); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// DeleteCollection removes a collection and its media server counterpart
+func DeleteCollection(cfg *config.Config, id int) error {
+	var serverName, serverID sql.NullString
+	if err := database.DB.QueryRow("SELECT media_server, media_server_id FROM collections WHERE id = $1", id).Scan(&serverName, &serverID); err != nil {
+		return fmt.Errorf("collection not found: %w", err)
+	}
+
+	if server := GetMediaServer(cfg); server != nil && serverID.String != "" && serverName.String == server.Name() {
+		if err := server.DeleteCollection(serverID.String); err != nil && err != errMediaItemNotFound {
+			return fmt.Errorf("failed to delete %s collection: %w", server.Name(), err)
+		}
+	}
+
+	_, err := database.DB.Exec("DELETE FROM collections WHERE id = $1", id)
+	return err
+}
+
+// getCollectionMembers returns the library movies that belong in a collection
+func getCollectionMembers(cfg *config.Config, c models.Collection) ([]collectionMember, error) {
+	var rows *sql.Rows
+	var err error
+	if c.IsCustom() {
+		rows, err = database.DB.Query(`
+			SELECT m.tmdb_id, m.path FROM movies m
+			JOIN collection_movies cm ON cm.movie_id = m.id
+			WHERE cm.collection_id = $1 AND m.tmdb_id IS NOT NULL AND m.tmdb_id != '' AND m.path NOT LIKE $2 || '%'`,
+			c.ID, cfg.IncomingMoviesPath)
+	} else {
+		rows, err = database.DB.Query(`
+			SELECT tmdb_id, path FROM movies
+			WHERE (raw_metadata->'belongs_to_collection'->>'id')::int = $1
+				AND jsonb_typeof(raw_metadata->'belongs_to_collection') = 'object'
+				AND tmdb_id IS NOT NULL AND tmdb_id != '' AND path NOT LIKE $2 || '%'`,
+			c.TMDBCollectionID, cfg.IncomingMoviesPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []collectionMember
+	for rows.Next() {
+		var m collectionMember
+		if err := rows.Scan(&m.TMDBID, &m.Path); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// syncMediaServerCollection makes the server collection contain exactly its library members,
+// creating it if needed, and uploads the poster when it changed
+func syncMediaServerCollection(server MediaServer, c models.Collection, members []collectionMember) error {
+	want := make(map[string]bool)
+	var wantIDs []string
+	for _, m := range members {
+		item, err := resolveMediaServerItem(server, mediaServerRefreshTarget{MediaType: "movie", Source: "tmdb", ID: m.TMDBID, Path: m.Path})
+		if err != nil {
+			return err
+		}
+		if item == nil || want[item.ID] {
+			// Not picked up by the server yet; it'll be added on a later sync
+			continue
+		}
+		want[item.ID] = true
+		wantIDs = append(wantIDs, item.ID)
+	}
+	if len(wantIDs) == 0 {
+		return nil
+	}
+
+	// IDs from another server are useless after switching MEDIA_SERVER; recreate instead
+	collectionID := c.MediaServerID
+	if c.MediaServer != server.Name() {
+		collectionID = ""
+	}
+
+	var current []string
+	if collectionID != "" {
+		var err error
+		current, err = server.GetCollectionItems(collectionID)
+		if err == errMediaItemNotFound {
+			// Deleted on the server; recreate it
+			collectionID = ""
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if collectionID == "" {
+		var err error
+		collectionID, err = server.CreateCollection(c.Name, wantIDs)
+		if err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+		database.DB.Exec(`
+			UPDATE collections SET media_server = $1, media_server_id = $2, uploaded_poster = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3`, server.Name(), collectionID, c.ID)
+		slog.Info("Created media server collection", "server", server.Name(), "collection", c.Name, "movies", len(wantIDs))
+	} else {
+		have := make(map[string]bool)
+		var remove []string
+		for _, id := range current {
+			have[id] = true
+			if !want[id] {
+				remove = append(remove, id)
+			}
+		}
+		var add []string
+		for _, id := range wantIDs {
+			if !have[id] {
+				add = append(add, id)
+			}
+		}
+
+		if len(add) > 0 {
+			if err := server.AddCollectionItems(collectionID, add); err != nil {
+				return fmt.Errorf("failed to add collection items: %w", err)
+			}
+		}
+		if len(remove) > 0 {
+			if err := server.RemoveCollectionItems(collectionID, remove); err != nil {
+				return fmt.Errorf("failed to remove collection items: %w", err)
+			}
+		}
+		if len(add) > 0 || len(remove) > 0 {
+			slog.Info("Updated media server collection", "server", server.Name(), "collection", c.Name, "added", len(add), "removed", len(remove))
+		}
+	}
+
+	var uploaded sql.NullString
+	database.DB.QueryRow("SELECT uploaded_poster FROM collections WHERE id = $1", c.ID).Scan(&uploaded)
+	if c.PosterPath != "" && uploaded.String != c.PosterPath {
+		if err := server.SetCollectionPoster(collectionID, collectionPosterURL(c.PosterPath)); err != nil {
+			slog.Warn("Failed to upload collection poster", "collection", c.Name, "error", err)
+		} else {
+			database.DB.Exec("UPDATE collections SET uploaded_poster = $1 WHERE id = $2", c.PosterPath, c.ID)
+		}
+	}
+	return nil
+}
+
+// collectionPosterURL turns a TMDB poster path into a full image URL; custom collections
+// already store a full URL
+func collectionPosterURL(posterPath string) string {
+	if strings.HasPrefix(posterPath, "http://") || strings.HasPrefix(posterPath, "https://") {
+		return posterPath
+	}
+	return "https://image.tmdb.org/t/p/original/" + strings.TrimPrefix(posterPath, "/")
+}
+
+// StartCollectionsSyncWorker periodically syncs media server collections when enabled
+func StartCollectionsSyncWorker(cfg *config.Config) {
+	server := GetMediaServer(cfg)
+	if !cfg.SyncCollections || server == nil {
+		return
+	}
+
+	slog.Info("Starting collections sync background worker", "server", server.Name())
+
+	go func() {
+		ticker := time.NewTicker(6 * time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := SyncCollections(cfg); err != nil {
+				slog.Error("Error during collections sync", "error", err)
+			}
+		}
+	}()
+}
@@ -10,334 +10,331 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/justbri/arrgo/shared/format"
 )
 
-// DedupeResult contains statistics about the deduplication process
-type DedupeResult struct {
-	ItemsScanned   int      `json:"items_scanned"`
-	ItemsRemoved   int      `json:"items_removed"`
-	FoldersRemoved int      `json:"folders_removed"`
-	BytesFreed     int64    `json:"bytes_freed"`
-	Messages       []string `json:"messages"`
-}
+var seasonEpisodeRegex = regexp.MustCompile(`(?i)(S\d{2,}E\d{2,})`)
 
-// DeduplicateMovies scans the movies directory for duplicates based on TMDB/IMDB IDs
-func DeduplicateMovies(cfg *config.Config) (*DedupeResult, error) {
-	result := &DedupeResult{}
-	moviesMap := make(map[string][]string) // ID -> list of paths
+// dedupeFile is a video considered by a dedupe
+type dedupeFile struct {
+	folder string
+	path   string
+	size   int64
+}
 
-	// Read the movies directory
-	entries, err := os.ReadDir(cfg.MoviesPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read movies directory: %w", err)
+// libraryFoldersByID groups the folders of the library roots by the TMDB/TVDB/IMDB ID in their
+// names, trying the ID sources in order. Hidden folders, like the undo folder, are skipped, and
+// so are roots that can't be read unless none can.
+func libraryFoldersByID(roots []string, sources ...string) (map[string][]string, error) {
+	folders := make(map[string][]string) // ID -> list of paths
+	var readErr error
+	read := 0
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			slog.Warn("Skipping unreadable root folder", "path", root, "error", err)
+			readErr = fmt.Errorf("failed to read %s: %w", root, err)
+			continue
+		}
+		read++
+		addFoldersByID(folders, root, entries, sources)
+	}
+	if read == 0 && readErr != nil {
+		return nil, readErr
 	}
+	return folders, nil
+}
 
+func addFoldersByID(folders map[string][]string, root string, entries []os.DirEntry, sources []string) {
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue // Movies should be in their own folders
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
 		}
 
-		folderPath := filepath.Join(cfg.MoviesPath, entry.Name())
-		result.ItemsScanned++
-
 		// Extract ID from folder name using existing parser logic
-		_, _, tmdbID, _, imdbID := ParseMediaName(entry.Name())
-
-		idKey := ""
-		if tmdbID != "" {
-			idKey = "tmdb-" + tmdbID
-		} else if imdbID != "" {
-			idKey = "imdb-" + imdbID
+		_, _, tmdbID, tvdbID, imdbID := ParseMediaName(entry.Name())
+		ids := map[string]string{"tmdb": tmdbID, "tvdb": tvdbID, "imdb": imdbID}
+		for _, source := range sources {
+			if ids[source] != "" {
+				key := source + "-" + ids[source]
+				folders[key] = append(folders[key], filepath.Join(root, entry.Name()))
+				break
+			}
 		}
+	}
+}
 
-		if idKey != "" {
-			moviesMap[idKey] = append(moviesMap[idKey], folderPath)
-		}
+// planMovieDedupe plans removing duplicate movie folders (by TMDB/IMDB ID and edition), across
+// the movie root folders of each library, and extra copies, keeping the largest video file
+func planMovieDedupe(cfg *config.Config) (*filePlan, error) {
+	plan := newFilePlan(BatchDedupeMovies)
+	moviesMap, err := libraryFoldersByID(LibraryRoots(cfg, "movie"), "tmdb", "imdb")
+	if err != nil {
+		return nil, err
 	}
 
-	// Process duplicates
-	for id, paths := range moviesMap {
-		if len(paths) <= 1 {
-			continue // No duplicates
+	ids := make([]string, 0, len(moviesMap))
+	for id := range moviesMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// The standard and 4K libraries each keep their own copy of every edition, so only copies
+	// of the same edition within one library are duplicates
+	fourKRoots := libraryRoots(cfg, "movie", true)
+	for _, id := range ids {
+		copies := make(map[string][]string)
+		var labels []string
+		for _, p := range moviesMap[id] {
+			// Editions spelled differently, like "Extended" and "Extended Cut", are the same
+			label := editionKey(DetectEdition(filepath.Base(p)))
+			if label == "" {
+				label = "regular"
+			}
+			if rootOf(fourKRoots, p) != "" {
+				label = "4K " + label
+			}
+			if _, ok := copies[label]; !ok {
+				labels = append(labels, label)
+			}
+			copies[label] = append(copies[label], p)
+		}
+		sort.Strings(labels)
+		if len(labels) > 1 {
+			plan.note("%s: %s editions, one copy of each is kept", id, strings.Join(labels, ", "))
 		}
+		for _, label := range labels {
+			planMovieFolders(plan, id, copies[label])
+		}
+	}
 
-		slog.Info("Found duplicate movie folders", "id", id, "count", len(paths))
-		result.Messages = append(result.Messages, fmt.Sprintf("Found %d folders for %s", len(paths), id))
+	return plan, nil
+}
 
-		// Find the best video file across all duplicate folders
-		type videoFile struct {
-			folder string
-			path   string
-			size   int64
-		}
+// planMovieFolders plans removing the duplicate folders of one edition of a movie in a library
+// and extra copies, keeping the largest video file. Other editions in the kept folder stay.
+func planMovieFolders(plan *filePlan, id string, paths []string) {
+	if len(paths) <= 1 {
+		return // No duplicates
+	}
+	slog.Info("Found duplicate movie folders", "id", id, "count", len(paths))
 
-		var allVideos []videoFile
+	// Find the best video file across all duplicate folders
+	var allVideos []dedupeFile
+	for _, folder := range paths {
+		files, err := os.ReadDir(folder)
+		if err != nil {
+			slog.Error("Failed to read duplicate movie folder", "folder", folder, "error", err)
+			continue
+		}
 
-		for _, folder := range paths {
-			files, err := os.ReadDir(folder)
-			if err != nil {
-				slog.Error("Failed to read duplicate movie folder", "folder", folder, "error", err)
+		for _, file := range files {
+			if file.IsDir() || !MovieExtensions[strings.ToLower(filepath.Ext(file.Name()))] {
 				continue
 			}
-
-			for _, file := range files {
-				if file.IsDir() {
-					continue
-				}
-
-				ext := strings.ToLower(filepath.Ext(file.Name()))
-				if MovieExtensions[ext] {
-					fullPath := filepath.Join(folder, file.Name())
-					info, err := file.Info()
-					if err == nil {
-						allVideos = append(allVideos, videoFile{
-							folder: folder,
-							path:   fullPath,
-							size:   info.Size(),
-						})
-					}
-				}
+			if info, err := file.Info(); err == nil {
+				allVideos = append(allVideos, dedupeFile{folder: folder, path: filepath.Join(folder, file.Name()), size: info.Size()})
 			}
 		}
+	}
 
-		if len(allVideos) <= 1 {
-			continue // No duplicate video files to resolve
-		}
-
-		// Sort by size descending
-		sort.Slice(allVideos, func(i, j int) bool {
-			return allVideos[i].size > allVideos[j].size
-		})
-
-		bestVideo := allVideos[0]
+	if len(allVideos) <= 1 {
+		plan.note("%s: %d folders but only one video, left alone", id, len(paths))
+		return
+	}
 
-		// Map of folders to keep vs folders to delete
-		foldersToDelete := make(map[string]bool)
-		for _, p := range paths {
-			if p != bestVideo.folder {
-				foldersToDelete[p] = true
-			}
-		}
+	// Sort by size descending
+	sort.Slice(allVideos, func(i, j int) bool {
+		return allVideos[i].size > allVideos[j].size
+	})
+	best := allVideos[0]
 
-		// Delete the duplicate video files and their DB entries
-		for _, vid := range allVideos[1:] {
-			slog.Info("Deleting duplicate movie file", "path", vid.path, "size", vid.size)
-			if err := os.Remove(vid.path); err == nil {
-				result.ItemsRemoved++
-				result.BytesFreed += vid.size
-				result.Messages = append(result.Messages, fmt.Sprintf("Removed duplicate: %s", filepath.Base(vid.path)))
-
-				// Optional: Delete from database if it exists
-				database.DB.Exec("DELETE FROM movies WHERE path = $1", vid.path)
-			} else {
-				slog.Error("Failed to delete duplicate movie file", "path", vid.path, "error", err)
-			}
+	for _, folder := range paths {
+		if folder != best.folder {
+			plan.remove(folder, fmt.Sprintf("Duplicate of %s, which has the largest copy (%s)",
+				filepath.Base(best.folder), format.Bytes(best.size)), pathSize(folder))
 		}
-
-		// Try to delete the empty duplicate folders
-		for folder := range foldersToDelete {
-			// Only remove if it's empty (or only contains nfos/posters which we can delete)
-			if err := os.RemoveAll(folder); err == nil {
-				result.FoldersRemoved++
-			}
+	}
+	bestEdition := movieEdition(best.path)
+	for _, vid := range allVideos[1:] {
+		if vid.folder == best.folder && SameEdition(movieEdition(vid.path), bestEdition) {
+			plan.remove(vid.path, fmt.Sprintf("Smaller copy (%s) of %s", format.Bytes(vid.size), filepath.Base(best.path)), vid.size)
 		}
 	}
-
-	return result, nil
 }
 
-// DeduplicateShows scans the shows directory, merges duplicate show folders, and dedupes episodes
-func DeduplicateShows(cfg *config.Config) (*DedupeResult, error) {
-	result := &DedupeResult{}
-	showsMap := make(map[string][]string) // ID -> list of paths
-
-	// Read the shows directory
-	entries, err := os.ReadDir(cfg.ShowsPath)
+// planShowDedupe plans merging duplicate show folders (by TVDB/TMDB ID) and removing
+// duplicate episodes, keeping the largest file of each. Folders are only merged within a root
+// folder, so merges stay renames on one disk.
+func planShowDedupe(cfg *config.Config) (*filePlan, error) {
+	plan := newFilePlan(BatchDedupeShows)
+	roots := LibraryRoots(cfg, "show")
+	showsMap, err := libraryFoldersByID(roots, "tvdb", "tmdb")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read shows directory: %w", err)
+		return nil, err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	ids := make([]string, 0, len(showsMap))
+	for id := range showsMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		byRoot := make(map[string][]string)
+		var rootOrder []string
+		for _, p := range showsMap[id] {
+			root := rootOf(roots, p)
+			if _, ok := byRoot[root]; !ok {
+				rootOrder = append(rootOrder, root)
+			}
+			byRoot[root] = append(byRoot[root], p)
 		}
-
-		folderPath := filepath.Join(cfg.ShowsPath, entry.Name())
-		result.ItemsScanned++
-
-		_, _, tmdbID, tvdbID, _ := ParseMediaName(entry.Name())
-
-		idKey := ""
-		if tvdbID != "" {
-			idKey = "tvdb-" + tvdbID
-		} else if tmdbID != "" {
-			idKey = "tmdb-" + tmdbID
+		if len(rootOrder) > 1 {
+			plan.note("%s: folders in %d root folders, only merged within each", id, len(rootOrder))
 		}
-
-		if idKey != "" {
-			showsMap[idKey] = append(showsMap[idKey], folderPath)
+		for _, root := range rootOrder {
+			planShowFolders(plan, id, byRoot[root])
 		}
 	}
 
-	// 1. Merge duplicate show folders
-	for id, paths := range showsMap {
-		if len(paths) <= 1 {
-			// Single folder, just dedupe episodes inside it
-			dedupeEpisodesInShow(paths[0], result)
-			continue
-		}
+	return plan, nil
+}
 
-		slog.Info("Found duplicate show folders", "id", id, "count", len(paths))
-		result.Messages = append(result.Messages, fmt.Sprintf("Merging %d folders for %s", len(paths), id))
-
-		// Pick the primary folder: prefer the one the DB already points to for this show,
-		// so we don't needlessly move files that are already in the correct location.
-		// Fall back to the longest path (canonical names with proper punctuation tend to be longer).
-		primaryFolder := paths[0]
-		var dbPath string
-		idParts := strings.SplitN(id, "-", 2)
-		if len(idParts) == 2 {
-			_ = database.DB.QueryRow("SELECT path FROM shows WHERE "+idParts[0]+"_id = $1 LIMIT 1", idParts[1]).Scan(&dbPath)
-		}
-		if dbPath != "" {
-			for _, p := range paths {
-				if p == dbPath {
-					primaryFolder = p
-					break
-				}
-			}
-		} else {
-			for _, p := range paths {
-				if len(p) > len(primaryFolder) {
-					primaryFolder = p
-				}
+// planShowFolders plans merging the folders of one show in a root folder and deduping its episodes
+func planShowFolders(plan *filePlan, id string, paths []string) {
+	if len(paths) <= 1 {
+		// Single folder, just dedupe episodes inside it
+		planEpisodeDedupe(plan, paths[0], nil)
+		return
+	}
+	slog.Info("Found duplicate show folders", "id", id, "count", len(paths))
+
+	// Pick the primary folder: prefer the one the DB already points to for this show,
+	// so we don't needlessly move files that are already in the correct location.
+	// Fall back to the longest path (canonical names with proper punctuation tend to be longer).
+	primaryFolder := paths[0]
+	var dbPath string
+	idParts := strings.SplitN(id, "-", 2)
+	if len(idParts) == 2 {
+		_ = database.DB.QueryRow("SELECT path FROM shows WHERE "+idParts[0]+"_id = $1 LIMIT 1", idParts[1]).Scan(&dbPath)
+	}
+	if dbPath != "" {
+		for _, p := range paths {
+			if p == dbPath {
+				primaryFolder = p
+				break
 			}
 		}
-
+	} else {
 		for _, p := range paths {
-			if p == primaryFolder {
-				continue
+			if len(p) > len(primaryFolder) {
+				primaryFolder = p
 			}
-			mergeShowFolders(p, primaryFolder, result)
 		}
+	}
 
-		// Dedupe the newly consolidated primary folder
-		dedupeEpisodesInShow(primaryFolder, result)
+	var merged []dedupeFile
+	for _, p := range paths {
+		if p != primaryFolder {
+			merged = append(merged, planShowFolderMerge(plan, p, primaryFolder)...)
+		}
 	}
 
-	return result, nil
+	// Dedupe the consolidated primary folder
+	planEpisodeDedupe(plan, primaryFolder, merged)
 }
 
-// dedupeEpisodesInShow finds and removes duplicate SXXEXX video files in a show directory
-func dedupeEpisodesInShow(showPath string, result *DedupeResult) {
-	// Map of S01E01 -> list of video files
-	episodesMap := make(map[string][]struct {
-		path string
-		size int64
-	})
-
-	seasonEpRegex := regexp.MustCompile(`(?i)(S\d{2,}E\d{2,})`)
+// planShowFolderMerge plans moving the files of srcShowPath missing from destShowPath into it,
+// then removing srcShowPath with whatever is left. Returns the videos it moves.
+func planShowFolderMerge(plan *filePlan, srcShowPath, destShowPath string) []dedupeFile {
+	var moved []dedupeFile
+	var leftover int64
 
-	err := filepath.Walk(showPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	err := filepath.Walk(srcShowPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
 			return nil
 		}
-		if info.IsDir() {
+		relPath, err := filepath.Rel(srcShowPath, path)
+		if err != nil {
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if MovieExtensions[ext] {
-			match := seasonEpRegex.FindString(path)
-			if match != "" {
-				key := strings.ToUpper(match)
-				episodesMap[key] = append(episodesMap[key], struct {
-					path string
-					size int64
-				}{path, info.Size()})
-			}
+		destPath := filepath.Join(destShowPath, relPath)
+		if !plan.free(destPath) {
+			leftover += info.Size()
+			return nil
+		}
+		plan.move(path, destPath, "Merge into "+filepath.Base(destShowPath), info.Size())
+		if MovieExtensions[strings.ToLower(filepath.Ext(path))] {
+			moved = append(moved, dedupeFile{folder: destShowPath, path: destPath, size: info.Size()})
 		}
 		return nil
 	})
-
 	if err != nil {
-		slog.Error("Failed to walk show directory for dedupe", "path", showPath, "error", err)
-		return
+		slog.Error("Failed to walk show folder for merge", "path", srcShowPath, "error", err)
+		plan.note("%s: couldn't be read, left alone", srcShowPath)
+		return moved
 	}
 
-	for _, files := range episodesMap {
-		if len(files) <= 1 {
-			continue
-		}
-
-		// Sort by size descending
-		sort.Slice(files, func(i, j int) bool {
-			return files[i].size > files[j].size
-		})
-
-		// Keep files[0], delete the rest
-		for _, f := range files[1:] {
-			slog.Info("Deleting duplicate episode file", "path", f.path, "size", f.size)
-			if err := os.Remove(f.path); err == nil {
-				result.ItemsRemoved++
-				result.BytesFreed += f.size
-				result.Messages = append(result.Messages, fmt.Sprintf("Removed duplicate episode: %s", filepath.Base(f.path)))
+	plan.remove(srcShowPath, fmt.Sprintf("Duplicate folder of %s; what's left already exists there", filepath.Base(destShowPath)), leftover)
+	return moved
+}
 
-				database.DB.Exec("DELETE FROM episodes WHERE file_path = $1", f.path)
-			}
+// planEpisodeDedupe plans removing duplicate SXXEXX video files in a show folder, including
+// videos earlier operations of the plan move into it
+func planEpisodeDedupe(plan *filePlan, showPath string, incoming []dedupeFile) {
+	// Map of S01E01 -> list of video files
+	episodesMap := make(map[string][]dedupeFile)
+	add := func(f dedupeFile) {
+		if match := seasonEpisodeRegex.FindString(f.path); match != "" {
+			key := strings.ToUpper(match)
+			episodesMap[key] = append(episodesMap[key], f)
 		}
 	}
-}
 
-// mergeShowFolders moves everything from srcShowPath to destShowPath and deletes src
-func mergeShowFolders(srcShowPath, destShowPath string, result *DedupeResult) {
-	slog.Info("Merging show folders", "from", srcShowPath, "to", destShowPath)
-
-	err := filepath.Walk(srcShowPath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(showPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
-
-		relPath, err := filepath.Rel(srcShowPath, path)
-		if err != nil || relPath == "." {
-			return nil
-		}
-
-		destPath := filepath.Join(destShowPath, relPath)
-
 		if info.IsDir() {
-			os.MkdirAll(destPath, 0755)
+			if strings.HasPrefix(info.Name(), ".") && path != showPath {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-
-		// It's a file. Let's move it over if it doesn't exist, else leave it to be cleaned up
-		if _, err := os.Stat(destPath); os.IsNotExist(err) {
-			slog.Info("Moving file to primary show folder", "from", path, "to", destPath)
-			if err := os.Rename(path, destPath); err != nil {
-				// Fallback to copy+delete if cross-device
-				if copyErr := copyFile(path, destPath); copyErr != nil {
-					slog.Error("Failed to copy file during show folder merge, leaving original in place", "from", path, "to", destPath, "error", copyErr)
-					return nil
-				}
-				os.Remove(path)
-			}
-
-			// Update database just in case
-			database.DB.Exec("UPDATE episodes SET file_path = $1 WHERE file_path = $2", destPath, path)
+		if MovieExtensions[strings.ToLower(filepath.Ext(path))] && !plan.gone[path] {
+			add(dedupeFile{folder: showPath, path: path, size: info.Size()})
 		}
-
 		return nil
 	})
-
 	if err != nil {
-		slog.Error("Failed to merge show folders", "src", srcShowPath, "error", err)
+		slog.Error("Failed to walk show directory for dedupe", "path", showPath, "error", err)
 		return
 	}
+	for _, f := range incoming {
+		add(f)
+	}
 
-	// Now try to delete the source folder
-	if err := os.RemoveAll(srcShowPath); err == nil {
-		result.FoldersRemoved++
-		result.Messages = append(result.Messages, fmt.Sprintf("Merged duplicate folder: %s", filepath.Base(srcShowPath)))
-		database.DB.Exec("DELETE FROM shows WHERE path = $1", srcShowPath)
+	keys := make([]string, 0, len(episodesMap))
+	for key := range episodesMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		files := episodesMap[key]
+		if len(files) <= 1 {
+			continue
+		}
+
+		// Sort by size descending, keep files[0] and remove the rest
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].size > files[j].size
+		})
+		for _, f := range files[1:] {
+			plan.remove(f.path, fmt.Sprintf("Smaller copy (%s) of %s, keeping %s", format.Bytes(f.size), key, filepath.Base(files[0].path)), f.size)
+		}
 	}
 }
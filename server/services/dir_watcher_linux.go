@@ -0,0 +1,127 @@
+//go:build linux
+
+package services
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO |
+	syscall.IN_MOVED_FROM | syscall.IN_DELETE | syscall.IN_MODIFY
+
+// dirWatcher reports changed paths under folders watched recursively with inotify. When the
+// kernel drops events, the watched root itself is reported, so the caller can rescan it.
+type dirWatcher struct {
+	fd     int
+	mu     sync.Mutex
+	paths  map[int32]string // watch descriptor -> folder
+	roots  []string
+	events chan string
+}
+
+func newDirWatcher() (*dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	w := &dirWatcher{fd: fd, paths: map[int32]string{}, events: make(chan string, 256)}
+	go w.readEvents()
+	return w, nil
+}
+
+// Events returns the changed paths
+func (w *dirWatcher) Events() <-chan string {
+	return w.events
+}
+
+// AddRecursive watches a folder and every non-hidden folder below it
+func (w *dirWatcher) AddRecursive(root string) error {
+	w.mu.Lock()
+	w.roots = append(w.roots, root)
+	w.mu.Unlock()
+	return w.addTree(root)
+}
+
+func (w *dirWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Removed while walking
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			// Usually fs.inotify.max_user_watches; the hourly scan still covers this folder
+			return err
+		}
+		w.mu.Lock()
+		w.paths[int32(wd)] = path
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *dirWatcher) readEvents() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			slog.Error("Stopped watching incoming folders", "error", err)
+			close(w.events)
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(bytes.TrimRight(buf[nameStart:nameStart+int(event.Len)], "\x00"))
+			offset = nameStart + int(event.Len)
+			w.handleEvent(event.Wd, event.Mask, name)
+		}
+	}
+}
+
+func (w *dirWatcher) handleEvent(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		w.mu.Lock()
+		roots := append([]string(nil), w.roots...)
+		w.mu.Unlock()
+		for _, root := range roots {
+			w.events <- root
+		}
+		return
+	}
+
+	w.mu.Lock()
+	dir, ok := w.paths[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.paths, wd)
+	}
+	w.mu.Unlock()
+	if !ok || name == "" {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	// New folders need watches of their own
+	if mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !strings.HasPrefix(name, ".") {
+		if err := w.addTree(path); err != nil {
+			slog.Warn("Failed to watch new incoming folder", "path", path, "error", err)
+		}
+	}
+	w.events <- path
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package services
+
+import "errors"
+
+// dirWatcher is only implemented on Linux; elsewhere incoming folders are polled
+type dirWatcher struct{}
+
+func newDirWatcher() (*dirWatcher, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (w *dirWatcher) Events() <-chan string {
+	return nil
+}
+
+func (w *dirWatcher) AddRecursive(root string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package services
+
+import "errors"
+
+// diskFree is only implemented on Linux
+func diskFree(path string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
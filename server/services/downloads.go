@@ -0,0 +1,32 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"fmt"
+)
+
+// GetDownloads returns the torrents added for requests, newest first. A requestID other than
+// 0 limits them to that request.
+func GetDownloads(requestID int) ([]models.Download, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, COALESCE(request_id, 0), torrent_hash, title, COALESCE(size, 0), COALESCE(status, ''),
+			COALESCE(progress, 0), created_at, updated_at
+		FROM downloads WHERE $1 = 0 OR request_id = $1
+		ORDER BY created_at DESC`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query downloads: %w", err)
+	}
+	defer rows.Close()
+
+	downloads := []models.Download{}
+	for rows.Next() {
+		var d models.Download
+		if err := rows.Scan(&d.ID, &d.RequestID, &d.TorrentHash, &d.Title, &d.Size, &d.Status,
+			&d.Progress, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan download: %w", err)
+		}
+		downloads = append(downloads, d)
+	}
+	return downloads, rows.Err()
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"Arrgo/config"
+	sharedhttp "github.com/justbri/arrgo/shared/http"
+)
+
+// extraSample marks a release sample, which is discarded rather than imported
+const extraSample = "sample"
+
+// extraFolders maps the folder names releases use for extras to the subfolders Jellyfin and
+// Plex both recognise
+var extraFolders = map[string]string{
+	"trailers": "Trailers", "trailer": "Trailers",
+	"featurettes": "Featurettes", "featurette": "Featurettes",
+	"deleted scenes": "Deleted Scenes", "deleted": "Deleted Scenes",
+	"behind the scenes": "Behind The Scenes", "behind": "Behind The Scenes",
+	"interviews": "Interviews", "interview": "Interviews",
+	"scenes": "Scenes", "shorts": "Shorts",
+	"extras": "Other", "extra": "Other", "bonus": "Other", "bonuses": "Other",
+	"bonus content": "Other", "other": "Other",
+	"samples": extraSample, "sample": extraSample,
+}
+
+// extraKeywords classifies extras by file name, most specific first. Jellyfin's "-trailer"
+// style suffixes are covered by the word boundaries.
+var extraKeywords = []struct {
+	pattern *regexp.Regexp
+	folder  string
+}{
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])sample([^a-z0-9]|$)`), extraSample},
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])(trailer|teaser)s?([^a-z0-9]|$)`), "Trailers"},
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])(featurettes?|making[ ._-]of)([^a-z0-9]|$)`), "Featurettes"},
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])deleted([ ._-]?scenes?)?([^a-z0-9]|$)`), "Deleted Scenes"},
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])(behind[ ._-]?the[ ._-]?scenes|bts)([^a-z0-9]|$)`), "Behind The Scenes"},
+	{regexp.MustCompile(`(?i)(^|[^a-z0-9])interviews?([^a-z0-9]|$)`), "Interviews"},
+}
+
+// isExtraFolder reports whether a folder name is one releases and media servers use for extras
+func isExtraFolder(name string) bool {
+	_, ok := extraFolders[strings.ToLower(strings.TrimSpace(name))]
+	return ok
+}
+
+// extraType classifies a video inside a release folder by its name, then by the folders
+// between it and the release root. Returns the extras subfolder it belongs in, extraSample
+// for samples, or "" when it isn't an extra.
+func extraType(releaseDir, path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, k := range extraKeywords {
+		if k.pattern.MatchString(name) {
+			return k.folder
+		}
+	}
+
+	rel, err := filepath.Rel(releaseDir, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return ""
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := len(parts) - 1; i >= 0; i-- {
+		if folder, ok := extraFolders[strings.ToLower(strings.TrimSpace(parts[i]))]; ok {
+			return folder
+		}
+	}
+	return ""
+}
+
+// importReleaseExtras carries the extras shipped in a movie's release folder into the
+// extras subfolders next to the imported movie. Samples are deleted, and videos that aren't
+// recognisably extras are left alone. When the release is still seeding the extras are
+// copied or linked like the movie itself and samples stay put. Returns how many it imports.
+func importReleaseExtras(cfg *config.Config, mainVideo, destDir string, keepOriginal bool) int {
+	// Loose files in the incoming root share no folder, so nothing there belongs to this movie
+	rel, err := filepath.Rel(cfg.IncomingMoviesPath, mainVideo)
+	if err != nil || !strings.Contains(rel, string(filepath.Separator)) {
+		return 0
+	}
+	releaseDir := filepath.Join(cfg.IncomingMoviesPath, strings.Split(rel, string(filepath.Separator))[0])
+
+	imported := 0
+	filepath.WalkDir(releaseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path == mainVideo || !MovieExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		folder := extraType(releaseDir, path)
+		switch folder {
+		case "":
+			return nil
+		case extraSample:
+			if !keepOriginal {
+				if err := os.Remove(path); err == nil {
+					slog.Debug("Discarded release sample", "path", path)
+				}
+			}
+			return nil
+		}
+
+		destPath := filepath.Join(destDir, folder, filepath.Base(path))
+		if _, err := os.Stat(destPath); err == nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			slog.Error("Failed to create extras folder", "path", filepath.Dir(destPath), "error", err)
+			return nil
+		}
+		if keepOriginal {
+			_, err = importSeedingFile(cfg, path, destPath)
+		} else {
+			err = safeRename(path, destPath)
+		}
+		if err != nil {
+			slog.Error("Failed to import extra", "from", path, "to", destPath, "error", err)
+			return nil
+		}
+		slog.Info("Imported extra", "from", path, "to", destPath)
+		imported++
+		return nil
+	})
+	return imported
+}
+
+// tmdbVideo is a video TMDB lists for a movie
+type tmdbVideo struct {
+	Key      string `json:"key"`
+	Site     string `json:"site"`
+	Type     string `json:"type"`
+	Official bool   `json:"official"`
+	Size     int    `json:"size"`
+}
+
+// movieTrailerKey picks the YouTube key of a movie's trailer from TMDB, preferring official
+// trailers and then the highest resolution. Returns "" when TMDB has none.
+func movieTrailerKey(cfg *config.Config, tmdbID string) (string, error) {
+	if cfg.TMDBAPIKey == "" {
+		return "", fmt.Errorf("TMDB_API_KEY is not set")
+	}
+
+	apiURL := sharedhttp.BuildQueryURL("https://api.themoviedb.org/3/movie/"+tmdbID+"/videos", map[string]string{
+		"api_key":  cfg.TMDBAPIKey,
+		"language": "en-US",
+	})
+	resp, err := sharedhttp.MakeRequest(context.Background(), apiURL, sharedhttp.DefaultClient)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Results []tmdbVideo `json:"results"`
+	}
+	if err := sharedhttp.DecodeJSONResponse(resp, &result); err != nil {
+		return "", err
+	}
+
+	var best *tmdbVideo
+	for i, v := range result.Results {
+		if v.Site != "YouTube" || v.Type != "Trailer" || v.Key == "" {
+			continue
+		}
+		if best == nil || (v.Official && !best.Official) || (v.Official == best.Official && v.Size > best.Size) {
+			best = &result.Results[i]
+		}
+	}
+	if best == nil {
+		return "", nil
+	}
+	return best.Key, nil
+}
+
+// hasTrailer reports whether a movie folder already has a trailer video
+func hasTrailer(movieDir string) bool {
+	entries, err := os.ReadDir(filepath.Join(movieDir, "Trailers"))
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && MovieExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchMovieTrailer fetches a movie's trailer from TMDB's video metadata when FETCH_TRAILERS
+// is on and the movie has none. With yt-dlp installed the trailer is downloaded into the
+// Trailers subfolder; otherwise it's linked from movie.nfo, which Jellyfin plays remotely.
+func FetchMovieTrailer(cfg *config.Config, tmdbID, movieDir, baseName string) {
+	if !cfg.FetchTrailers || tmdbID == "" || hasTrailer(movieDir) {
+		return
+	}
+
+	key, err := movieTrailerKey(cfg, tmdbID)
+	if err != nil {
+		slog.Warn("Failed to look up movie trailer", "tmdb_id", tmdbID, "error", err)
+		return
+	}
+	if key == "" {
+		slog.Debug("No trailer on TMDB", "tmdb_id", tmdbID)
+		return
+	}
+	trailerURL := "https://www.youtube.com/watch?v=" + key
+
+	if ytdlp, err := exec.LookPath("yt-dlp"); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		out := filepath.Join(movieDir, "Trailers", baseName+"-trailer.%(ext)s")
+		cmd := exec.CommandContext(ctx, ytdlp, "--quiet", "--no-playlist",
+			"-f", "bestvideo[height<=1080]+bestaudio/best[height<=1080]",
+			"--merge-output-format", "mkv", "-o", out, trailerURL)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			slog.Warn("Failed to download trailer, linking it instead", "tmdb_id", tmdbID, "error", err, "output", strings.TrimSpace(string(output)))
+		} else {
+			slog.Info("Downloaded movie trailer", "tmdb_id", tmdbID, "path", filepath.Dir(out))
+			return
+		}
+	}
+
+	setMovieNFOTrailer(movieDir, tmdbID, key)
+}
@@ -0,0 +1,631 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Kinds of file batches
+const (
+	BatchRenameMovies = "rename_movies"
+	BatchRenameShows  = "rename_shows"
+	BatchDedupeMovies = "dedupe_movies"
+	BatchDedupeShows  = "dedupe_shows"
+)
+
+// File batch statuses
+const (
+	BatchPlanned   = "planned"
+	BatchApplying  = "applying"
+	BatchApplied   = "applied"
+	BatchUndoing   = "undoing"
+	BatchUndone    = "undone"
+	BatchFinalized = "finalized"
+)
+
+// File operation actions and statuses
+const (
+	FileOpMove    = "move"
+	FileOpDelete  = "delete"
+	FileOpPlanned = "planned"
+	FileOpDone    = "done"
+	FileOpFailed  = "failed"
+	FileOpUndone  = "undone"
+)
+
+// UndoDirName is the folder in each library root holding deleted files until their batch is
+// finalized. Scans and dedupes skip it like any hidden folder.
+const UndoDirName = ".arrgo-undo"
+
+// fileBatchMu lets one batch be applied or undone at a time
+var fileBatchMu sync.Mutex
+
+// filePlan collects the operations of a batch while it's planned. Nothing on disk changes
+// until the batch is applied.
+type filePlan struct {
+	kind    string
+	ops     []models.FileOperation
+	notes   []string
+	taken   map[string]bool   // destinations of planned moves
+	gone    map[string]bool   // sources of planned moves and deletes
+	folders map[string]string // planned folder moves, new path -> old path
+}
+
+func newFilePlan(kind string) *filePlan {
+	return &filePlan{kind: kind, taken: map[string]bool{}, gone: map[string]bool{}, folders: map[string]string{}}
+}
+
+// free reports whether a path will be free for a move once the operations planned so far ran
+func (p *filePlan) free(path string) bool {
+	if p.taken[path] {
+		return false
+	}
+	if p.gone[path] {
+		return true
+	}
+	_, err := os.Stat(p.onDisk(path))
+	return os.IsNotExist(err)
+}
+
+// current returns where a path will be once the folder moves planned so far ran
+func (p *filePlan) current(path string) string {
+	for dst, src := range p.folders {
+		if path == src || strings.HasPrefix(path, src+string(filepath.Separator)) {
+			return dst + path[len(src):]
+		}
+	}
+	return path
+}
+
+// onDisk is the reverse of current: where a planned path is on disk right now
+func (p *filePlan) onDisk(path string) string {
+	for dst, src := range p.folders {
+		if path == dst || strings.HasPrefix(path, dst+string(filepath.Separator)) {
+			return src + path[len(dst):]
+		}
+	}
+	return path
+}
+
+func (p *filePlan) moveFolder(src, dst, reason string) {
+	p.move(src, dst, reason, pathSize(src))
+	p.folders[dst] = src
+}
+
+func (p *filePlan) move(src, dst, reason string, size int64) {
+	p.ops = append(p.ops, models.FileOperation{Action: FileOpMove, SourcePath: src, DestPath: dst, Reason: reason, Size: size})
+	p.taken[dst] = true
+	p.gone[src] = true
+	delete(p.taken, src)
+}
+
+func (p *filePlan) remove(path, reason string, size int64) {
+	p.ops = append(p.ops, models.FileOperation{Action: FileOpDelete, SourcePath: path, Reason: reason, Size: size})
+	p.gone[path] = true
+	delete(p.taken, path)
+}
+
+func (p *filePlan) note(format string, args ...any) {
+	p.notes = append(p.notes, fmt.Sprintf(format, args...))
+}
+
+// pathSize returns the size of a file, or of everything in a folder
+func pathSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// PlanFileBatch plans a bulk rename or dedupe and saves it for approval
+func PlanFileBatch(cfg *config.Config, kind string, userID int64) (*models.FileBatch, error) {
+	var plan *filePlan
+	var err error
+	switch kind {
+	case BatchRenameMovies:
+		plan, err = planMovieRenames(cfg)
+	case BatchRenameShows:
+		plan, err = planShowRenames(cfg)
+	case BatchDedupeMovies:
+		plan, err = planMovieDedupe(cfg)
+	case BatchDedupeShows:
+		plan, err = planShowDedupe(cfg)
+	default:
+		return nil, fmt.Errorf("unknown batch kind %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return saveFilePlan(cfg, plan, userID)
+}
+
+func saveFilePlan(cfg *config.Config, plan *filePlan, userID int64) (*models.FileBatch, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("INSERT INTO file_batches (kind, notes, created_by) VALUES ($1, $2, $3) RETURNING id",
+		plan.kind, strings.Join(plan.notes, "\n"), sql.NullInt64{Int64: userID, Valid: userID > 0}).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save batch: %w", err)
+	}
+
+	for i, op := range plan.ops {
+		if op.Action == FileOpDelete {
+			op.DestPath = undoHoldPath(cfg, id, op.SourcePath)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO file_operations (batch_id, position, action, source_path, dest_path, reason, size)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			id, i+1, op.Action, op.SourcePath, op.DestPath, op.Reason, op.Size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save batch operation: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Planned file batch", "batch_id", id, "kind", plan.kind, "operations", len(plan.ops), "notes", len(plan.notes))
+	return GetFileBatch(id)
+}
+
+// libraryRoot returns the root folder a path is in, or ""
+func libraryRoot(cfg *config.Config, path string) string {
+	return rootOf(allLibraryRoots(cfg), path)
+}
+
+// undoHoldPath is where a deleted file waits in its library's undo folder, so the move stays
+// on one filesystem
+func undoHoldPath(cfg *config.Config, batchID int64, path string) string {
+	root := libraryRoot(cfg, path)
+	if root == "" {
+		root = filepath.Dir(path)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.Join(root, UndoDirName, fmt.Sprintf("batch-%d", batchID), rel)
+}
+
+const fileBatchColumns = `
+	SELECT b.id, b.kind, b.status, b.notes, COALESCE(u.username, ''), b.created_at, b.applied_at, b.undone_at,
+		COUNT(o.id) FILTER (WHERE o.action = 'move'),
+		COUNT(o.id) FILTER (WHERE o.action = 'delete'),
+		COUNT(o.id) FILTER (WHERE o.status = 'failed'),
+		COALESCE(SUM(o.size) FILTER (WHERE o.action = 'delete'), 0)
+	FROM file_batches b
+	LEFT JOIN users u ON u.id = b.created_by
+	LEFT JOIN file_operations o ON o.batch_id = b.id`
+
+func scanFileBatch(row interface{ Scan(...any) error }) (*models.FileBatch, error) {
+	var b models.FileBatch
+	var notes string
+	var appliedAt, undoneAt sql.NullTime
+	err := row.Scan(&b.ID, &b.Kind, &b.Status, &notes, &b.CreatedBy, &b.CreatedAt, &appliedAt, &undoneAt,
+		&b.Moves, &b.Deletes, &b.Failed, &b.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	b.Notes = []string{}
+	if notes != "" {
+		b.Notes = strings.Split(notes, "\n")
+	}
+	if appliedAt.Valid {
+		b.AppliedAt = &appliedAt.Time
+	}
+	if undoneAt.Valid {
+		b.UndoneAt = &undoneAt.Time
+	}
+	return &b, nil
+}
+
+// GetFileBatches returns the most recent batches, without their operations
+func GetFileBatches(limit int) ([]models.FileBatch, error) {
+	rows, err := database.DB.Query(fileBatchColumns+`
+		GROUP BY b.id, u.username
+		ORDER BY b.created_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file batches: %w", err)
+	}
+	defer rows.Close()
+
+	batches := []models.FileBatch{}
+	for rows.Next() {
+		b, err := scanFileBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan file batch: %w", err)
+		}
+		batches = append(batches, *b)
+	}
+	return batches, rows.Err()
+}
+
+// GetFileBatch returns a batch with its operations
+func GetFileBatch(id int64) (*models.FileBatch, error) {
+	b, err := scanFileBatch(database.DB.QueryRow(fileBatchColumns+`
+		WHERE b.id = $1
+		GROUP BY b.id, u.username`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("batch not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.Operations, err = getFileOperations(id)
+	return b, err
+}
+
+func getFileOperations(batchID int64) ([]models.FileOperation, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, position, action, source_path, dest_path, reason, size, status, error
+		FROM file_operations WHERE batch_id = $1 ORDER BY position`, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch operations: %w", err)
+	}
+	defer rows.Close()
+
+	ops := []models.FileOperation{}
+	for rows.Next() {
+		var op models.FileOperation
+		if err := rows.Scan(&op.ID, &op.Position, &op.Action, &op.SourcePath, &op.DestPath, &op.Reason, &op.Size, &op.Status, &op.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan batch operation: %w", err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+// setFileBatchStatus moves a batch from one of the given statuses to a new one
+func setFileBatchStatus(id int64, status string, from ...string) error {
+	res, err := database.DB.Exec(`
+		UPDATE file_batches SET status = $2,
+			applied_at = CASE WHEN $2 = 'applied' THEN CURRENT_TIMESTAMP ELSE applied_at END,
+			undone_at = CASE WHEN $2 = 'undone' THEN CURRENT_TIMESTAMP ELSE undone_at END
+		WHERE id = $1 AND status = ANY($3)`, id, status, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("batch can't be %s now", status)
+	}
+	return nil
+}
+
+func setFileOperationStatus(op models.FileOperation, status string, opErr error) {
+	message := ""
+	if opErr != nil {
+		message = opErr.Error()
+	}
+	if _, err := database.DB.Exec("UPDATE file_operations SET status = $2, error = $3 WHERE id = $1", op.ID, status, message); err != nil {
+		slog.Error("Failed to record batch operation status", "operation_id", op.ID, "error", err)
+	}
+}
+
+// ApplyFileBatch runs a planned batch. Every operation is journaled as it runs; ones whose
+// files changed since planning fail on their own without stopping the rest.
+func ApplyFileBatch(cfg *config.Config, id int64) error {
+	if !fileBatchMu.TryLock() {
+		return fmt.Errorf("another batch is being applied or undone")
+	}
+	defer fileBatchMu.Unlock()
+
+	if err := setFileBatchStatus(id, BatchApplying, BatchPlanned); err != nil {
+		return err
+	}
+	ops, err := getFileOperations(id)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Applying file batch", "batch_id", id, "operations", len(ops))
+	failed := 0
+	var emptied []string
+	for _, op := range ops {
+		if op.Status != FileOpPlanned {
+			continue
+		}
+		if err := applyFileOperation(op); err != nil {
+			slog.Warn("Batch operation failed", "batch_id", id, "action", op.Action, "source", op.SourcePath, "error", err)
+			setFileOperationStatus(op, FileOpFailed, err)
+			failed++
+			continue
+		}
+		setFileOperationStatus(op, FileOpDone, nil)
+		emptied = append(emptied, filepath.Dir(op.SourcePath))
+	}
+	removeEmptyDirs(cfg, emptied)
+
+	QueueMediaServerLibraryRefresh(cfg)
+	slog.Info("Applied file batch", "batch_id", id, "operations", len(ops), "failed", failed)
+	return setFileBatchStatus(id, BatchApplied, BatchApplying)
+}
+
+func applyFileOperation(op models.FileOperation) error {
+	unlockSrc := lockPath(op.SourcePath)
+	defer unlockSrc()
+	unlockDst := lockPath(op.DestPath)
+	defer unlockDst()
+
+	info, err := os.Stat(op.SourcePath)
+	if err != nil {
+		return fmt.Errorf("source no longer exists")
+	}
+	if _, err := os.Stat(op.DestPath); err == nil {
+		return fmt.Errorf("destination already exists")
+	}
+	if err := os.MkdirAll(filepath.Dir(op.DestPath), 0755); err != nil {
+		return err
+	}
+
+	if op.Action == FileOpDelete {
+		records, err := snapshotLibraryRows(op.SourcePath)
+		if err != nil {
+			return fmt.Errorf("failed to save library rows: %w", err)
+		}
+		if err := os.Rename(op.SourcePath, op.DestPath); err != nil {
+			return err
+		}
+		if _, err := database.DB.Exec("UPDATE file_operations SET records = $2 WHERE id = $1", op.ID, records); err != nil {
+			slog.Error("Failed to journal library rows of deleted path", "path", op.SourcePath, "error", err)
+		}
+		forgetLibraryRows(op.SourcePath)
+		return nil
+	}
+
+	if info.IsDir() {
+		err = os.Rename(op.SourcePath, op.DestPath)
+	} else {
+		err = safeRename(op.SourcePath, op.DestPath)
+	}
+	if err != nil {
+		return err
+	}
+	repointLibraryPaths(op.SourcePath, op.DestPath)
+	return nil
+}
+
+// UndoFileBatch reverses an applied batch, newest operation first. A batch left half applied
+// by a restart can be undone too.
+func UndoFileBatch(cfg *config.Config, id int64) error {
+	if !fileBatchMu.TryLock() {
+		return fmt.Errorf("another batch is being applied or undone")
+	}
+	defer fileBatchMu.Unlock()
+
+	if err := setFileBatchStatus(id, BatchUndoing, BatchApplied, BatchApplying); err != nil {
+		return err
+	}
+	ops, err := getFileOperations(id)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Undoing file batch", "batch_id", id)
+	failed := 0
+	var emptied []string
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Status != FileOpDone {
+			continue
+		}
+		if err := undoFileOperation(op); err != nil {
+			slog.Warn("Undoing batch operation failed", "batch_id", id, "action", op.Action, "source", op.SourcePath, "error", err)
+			setFileOperationStatus(op, FileOpFailed, fmt.Errorf("undo failed: %w", err))
+			failed++
+			continue
+		}
+		setFileOperationStatus(op, FileOpUndone, nil)
+		emptied = append(emptied, filepath.Dir(op.DestPath))
+	}
+	removeEmptyDirs(cfg, emptied)
+
+	QueueMediaServerLibraryRefresh(cfg)
+	slog.Info("Undid file batch", "batch_id", id, "failed", failed)
+	return setFileBatchStatus(id, BatchUndone, BatchUndoing)
+}
+
+func undoFileOperation(op models.FileOperation) error {
+	unlockSrc := lockPath(op.SourcePath)
+	defer unlockSrc()
+	unlockDst := lockPath(op.DestPath)
+	defer unlockDst()
+
+	info, err := os.Stat(op.DestPath)
+	if err != nil {
+		return fmt.Errorf("%s no longer exists", op.DestPath)
+	}
+	if _, err := os.Stat(op.SourcePath); err == nil {
+		return fmt.Errorf("%s exists again", op.SourcePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(op.SourcePath), 0755); err != nil {
+		return err
+	}
+
+	if info.IsDir() || op.Action == FileOpDelete {
+		err = os.Rename(op.DestPath, op.SourcePath)
+	} else {
+		err = safeRename(op.DestPath, op.SourcePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if op.Action == FileOpDelete {
+		return restoreLibraryRows(op.ID)
+	}
+	repointLibraryPaths(op.DestPath, op.SourcePath)
+	return nil
+}
+
+// FinalizeFileBatch permanently deletes the files an applied batch removed, or moves them to
+// the recycle bin when there is one. The batch can't be undone afterwards.
+func FinalizeFileBatch(cfg *config.Config, id int64) error {
+	if err := setFileBatchStatus(id, BatchFinalized, BatchApplied); err != nil {
+		return err
+	}
+
+	if cfg.RecycleBinEnabled() {
+		rows, err := database.DB.Query(`
+			SELECT source_path, dest_path, reason, records FROM file_operations
+			WHERE batch_id = $1 AND action = $2 AND status = $3 ORDER BY position`, id, FileOpDelete, FileOpDone)
+		if err != nil {
+			return fmt.Errorf("failed to query deleted files of batch: %w", err)
+		}
+		type held struct {
+			source, dest, reason string
+			records              []byte
+		}
+		var items []held
+		for rows.Next() {
+			var h held
+			if err := rows.Scan(&h.source, &h.dest, &h.reason, &h.records); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan deleted file of batch: %w", err)
+			}
+			items = append(items, h)
+		}
+		rows.Close()
+
+		for _, h := range items {
+			if err := recycle(cfg, h.dest, h.source, h.reason, h.records); err != nil {
+				slog.Error("Failed to move deleted file of batch to recycle bin", "batch_id", id, "path", h.source, "error", err)
+			}
+		}
+	}
+
+	for _, root := range allLibraryRoots(cfg) {
+		hold := filepath.Join(root, UndoDirName, fmt.Sprintf("batch-%d", id))
+		if err := os.RemoveAll(hold); err != nil {
+			slog.Error("Failed to remove deleted files of batch", "batch_id", id, "path", hold, "error", err)
+		}
+		os.Remove(filepath.Join(root, UndoDirName))
+	}
+	slog.Info("Finalized file batch", "batch_id", id)
+	return nil
+}
+
+// DiscardFileBatch deletes a batch that was planned but never applied
+func DiscardFileBatch(id int64) error {
+	res, err := database.DB.Exec("DELETE FROM file_batches WHERE id = $1 AND status = $2", id, BatchPlanned)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("only planned batches can be discarded")
+	}
+	return nil
+}
+
+// removeEmptyDirs removes folders left empty by a batch, and their empty parents, up to the
+// library root
+func removeEmptyDirs(cfg *config.Config, dirs []string) {
+	for _, dir := range dirs {
+		root := libraryRoot(cfg, dir)
+		for root != "" && dir != root && strings.HasPrefix(dir, root) {
+			if os.Remove(dir) != nil {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+}
+
+// repointLibraryPaths updates library rows for a file or folder that moved, keeping their
+// IDs and everything attached to them
+func repointLibraryPaths(src, dst string) {
+	columns := []struct{ table, column string }{
+		{"movies", "path"}, {"movies", "poster_path"},
+		{"shows", "path"}, {"shows", "poster_path"},
+		{"episodes", "file_path"}, {"file_index", "path"},
+	}
+	for _, c := range columns {
+		query := fmt.Sprintf(`UPDATE %[1]s SET %[2]s = $2 || substr(%[2]s, length($1) + 1)
+			WHERE %[2]s = $1 OR left(%[2]s, length($1) + 1) = $1 || '/'`, c.table, c.column)
+		if _, err := database.DB.Exec(query, src, dst); err != nil {
+			slog.Error("Failed to update library paths", "table", c.table, "from", src, "to", dst, "error", err)
+		}
+	}
+}
+
+// snapshotLibraryRows returns the library rows for a file or folder as JSON, so a delete can
+// put them back. Shows come with their seasons and episodes.
+func snapshotLibraryRows(path string) ([]byte, error) {
+	var records []byte
+	err := database.DB.QueryRow(`
+		SELECT json_build_object(
+			'movies', (SELECT COALESCE(json_agg(m), '[]') FROM movies m
+				WHERE m.path = $1 OR left(m.path, length($1) + 1) = $1 || '/'),
+			'shows', (SELECT COALESCE(json_agg(sh), '[]') FROM shows sh WHERE sh.path = $1),
+			'seasons', (SELECT COALESCE(json_agg(s), '[]') FROM seasons s
+				JOIN shows sh ON s.show_id = sh.id WHERE sh.path = $1),
+			'episodes', (SELECT COALESCE(json_agg(e), '[]') FROM episodes e
+				WHERE e.file_path = $1 OR left(e.file_path, length($1) + 1) = $1 || '/'
+				OR e.season_id IN (SELECT s.id FROM seasons s JOIN shows sh ON s.show_id = sh.id WHERE sh.path = $1))
+		)`, path).Scan(&records)
+	return records, err
+}
+
+// forgetLibraryRows removes the library rows for a deleted file or folder
+func forgetLibraryRows(path string) {
+	queries := []string{
+		"DELETE FROM movies WHERE path = $1 OR left(path, length($1) + 1) = $1 || '/'",
+		"DELETE FROM episodes WHERE file_path = $1 OR left(file_path, length($1) + 1) = $1 || '/'",
+		"DELETE FROM shows WHERE path = $1",
+	}
+	for _, query := range queries {
+		if _, err := database.DB.Exec(query, path); err != nil {
+			slog.Error("Failed to remove library rows of deleted path", "path", path, "error", err)
+		}
+	}
+}
+
+// restoreLibraryRows puts back the library rows a delete removed. Rows that came back some
+// other way, such as a rescan, are left alone.
+func restoreLibraryRows(opID int64) error {
+	var records sql.NullString
+	if err := database.DB.QueryRow("SELECT records FROM file_operations WHERE id = $1", opID).Scan(&records); err != nil {
+		return err
+	}
+	if !records.Valid {
+		return nil
+	}
+	return restoreLibraryRecords(records.String)
+}
+
+// restoreLibraryRecords inserts library rows saved by snapshotLibraryRows
+func restoreLibraryRecords(records string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Parents first, so seasons and episodes find their show
+	for _, table := range []string{"shows", "seasons", "episodes", "movies"} {
+		query := fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM json_populate_recordset(NULL::%[1]s, $1::json -> '%[1]s')
+			ON CONFLICT DO NOTHING`, table)
+		if _, err := tx.Exec(query, records); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileFingerprint is what the file index remembers about a media file, or a show folder, to
+// tell whether it changed since it was last probed
+type fileFingerprint struct {
+	size   int64
+	mtime  time.Time
+	inode  uint64
+	device uint64
+}
+
+// fingerprintOf fingerprints a file. Times are kept in UTC at the database's precision so
+// they compare equal after a round trip.
+func fingerprintOf(info os.FileInfo) fileFingerprint {
+	return fileFingerprint{
+		size:   info.Size(),
+		mtime:  info.ModTime().UTC().Truncate(time.Microsecond),
+		inode:  fileInode(info),
+		device: fileDevice(info),
+	}
+}
+
+// showFingerprint fingerprints a show folder by its inode and device, the total size of its videos, and
+// the newest change to any video or folder in it, so added, removed, replaced and renamed
+// episodes all change it. Also returns how many videos it holds.
+func showFingerprint(showPath string) (fp fileFingerprint, videos int, err error) {
+	info, err := os.Stat(showPath)
+	if err != nil {
+		return fileFingerprint{}, 0, err
+	}
+	fp.inode, fp.device = fileInode(info), fileDevice(info)
+	err = filepath.Walk(showPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && path != showPath && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() {
+			if !MovieExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			fp.size += info.Size()
+			videos++
+		}
+		if mtime := info.ModTime().UTC().Truncate(time.Microsecond); mtime.After(fp.mtime) {
+			fp.mtime = mtime
+		}
+		return nil
+	})
+	return fp, videos, err
+}
+
+// indexedColumns maps the media types in the file index to the library column holding their path
+var indexedColumns = map[string]struct{ table, column string }{
+	"movie":   {"movies", "path"},
+	"episode": {"episodes", "file_path"},
+	"show":    {"shows", "path"},
+}
+
+// fileIndexStore holds the fingerprints of the file index; tests use an in-memory one
+type fileIndexStore interface {
+	// lookup returns the fingerprint indexed for a path that still has its library row
+	lookup(path, mediaType string) (fileFingerprint, bool)
+	store(path, mediaType string, fp fileFingerprint)
+	forget(path string)
+}
+
+// dbFileIndex is the file_index table
+type dbFileIndex struct{}
+
+func (dbFileIndex) lookup(path, mediaType string) (fileFingerprint, bool) {
+	c := indexedColumns[mediaType]
+	var size, inode, device int64
+	var mtime time.Time
+	err := database.DB.QueryRow(fmt.Sprintf(`
+		SELECT fi.size, fi.mtime, fi.inode, fi.device FROM file_index fi
+		WHERE fi.path = $1 AND fi.media_type = $2
+			AND EXISTS (SELECT 1 FROM %s WHERE %s = $1)`, c.table, c.column),
+		path, mediaType).Scan(&size, &mtime, &inode, &device)
+	if err != nil {
+		return fileFingerprint{}, false
+	}
+	return fileFingerprint{size: size, mtime: mtime, inode: uint64(inode), device: uint64(device)}, true
+}
+
+func (dbFileIndex) store(path, mediaType string, fp fileFingerprint) {
+	_, err := database.DB.Exec(`
+		INSERT INTO file_index (path, media_type, size, mtime, inode, device, last_probed)
+		VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+		ON CONFLICT (path) DO UPDATE SET
+			media_type = EXCLUDED.media_type,
+			size = EXCLUDED.size,
+			mtime = EXCLUDED.mtime,
+			inode = EXCLUDED.inode,
+			device = EXCLUDED.device,
+			last_probed = CURRENT_TIMESTAMP`,
+		path, mediaType, fp.size, fp.mtime, int64(fp.inode), int64(fp.device))
+	if err != nil {
+		slog.Warn("Failed to update file index", "path", path, "error", err)
+	}
+}
+
+func (dbFileIndex) forget(path string) {
+	if _, err := database.DB.Exec("DELETE FROM file_index WHERE path = $1", path); err != nil {
+		slog.Warn("Failed to remove file index entry", "path", path, "error", err)
+	}
+}
+
+var fileIndex fileIndexStore = dbFileIndex{}
+
+// fileUnchanged reports whether a path was indexed with the same fingerprint and still has
+// its library row, in which case a scan doesn't need to probe it again. The device isn't
+// part of the comparison, but is brought up to date for entries indexed before it was kept.
+func fileUnchanged(path string, mediaType string, fp fileFingerprint) bool {
+	indexed, ok := fileIndex.lookup(path, mediaType)
+	if !ok || indexed.size != fp.size || !indexed.mtime.Equal(fp.mtime) || indexed.inode != fp.inode {
+		return false
+	}
+	if indexed.device != fp.device {
+		fileIndex.store(path, mediaType, fp)
+	}
+	return true
+}
+
+// indexFile records the fingerprint of a path that was just probed
+func indexFile(path string, mediaType string, fp fileFingerprint) {
+	fileIndex.store(path, mediaType, fp)
+}
+
+// scannedFile is a file a scan probed, waiting to be indexed
+type scannedFile struct {
+	path string
+	fp   fileFingerprint
+}
+
+// renameScannedMovie and renameScannedShow put a scanned library movie or show where its
+// metadata says it belongs and return its path afterwards; tests replace them
+var (
+	renameScannedMovie = func(cfg *config.Config, movieID int) (string, error) {
+		if err := RenameAndMoveMovie(cfg, movieID); err != nil {
+			return "", err
+		}
+		var path string
+		err := database.DB.QueryRow("SELECT path FROM movies WHERE id = $1", movieID).Scan(&path)
+		return path, err
+	}
+	renameScannedShow = func(cfg *config.Config, showID int) (string, error) {
+		if err := RenameAndMoveShow(cfg, showID); err != nil {
+			return "", err
+		}
+		var path string
+		err := database.DB.QueryRow("SELECT path FROM shows WHERE id = $1", showID).Scan(&path)
+		return path, err
+	}
+)
+
+// settleScannedMovie renames a scanned library movie into place after its metadata match,
+// then indexes it under the path it ended up at. A movie whose match or rename failed is
+// left out of the index, so the next scan probes it again and retries.
+func settleScannedMovie(cfg *config.Config, movieID int, path string, fp fileFingerprint, matchErr error) {
+	if matchErr != nil {
+		slog.Debug("Error matching movie metadata, retrying on the next scan", "movie_id", movieID, "error", matchErr)
+		fileIndex.forget(path)
+		return
+	}
+	if !strings.HasPrefix(path, cfg.IncomingMoviesPath) {
+		newPath, err := renameScannedMovie(cfg, movieID)
+		if err != nil {
+			slog.Debug("Error moving/renaming movie during scan, retrying on the next scan", "movie_id", movieID, "error", err)
+			fileIndex.forget(path)
+			return
+		}
+		if newPath != path {
+			info, err := os.Stat(newPath)
+			if err != nil {
+				return
+			}
+			path, fp = newPath, fingerprintOf(info)
+		}
+	}
+	indexFile(path, "movie", fp)
+}
+
+// settleScannedShow renames a scanned library show into place after its metadata match,
+// then indexes the show folder and the episodes probed in it. When the match or rename
+// failed nothing is indexed, so the next scan probes the show and its episodes again and
+// retries. A renamed show is indexed by the scan that finds it at its new path.
+func settleScannedShow(cfg *config.Config, showID int, showPath string, showFP fileFingerprint, episodes []scannedFile, matchErr error) {
+	if matchErr != nil {
+		slog.Debug("Error matching show metadata, retrying on the next scan", "show_id", showID, "error", matchErr)
+		fileIndex.forget(showPath)
+		return
+	}
+	if !strings.HasPrefix(showPath, cfg.IncomingShowsPath) {
+		newPath, err := renameScannedShow(cfg, showID)
+		if err != nil {
+			slog.Debug("Error moving/renaming show during scan, retrying on the next scan", "show_id", showID, "error", err)
+			fileIndex.forget(showPath)
+			return
+		}
+		if newPath != showPath {
+			return
+		}
+	}
+	indexScannedEpisodes(episodes)
+	indexFile(showPath, "show", showFP)
+}
+
+// indexScannedEpisodes indexes episode files of a show that's matched and in place
+func indexScannedEpisodes(episodes []scannedFile) {
+	for _, e := range episodes {
+		indexFile(e.path, "episode", e.fp)
+	}
+}
+
+// findMovedFile returns the indexed path a file was moved or renamed from: one with the same
+// device, inode and size that's gone from disk but still has its library row. Inodes are
+// reused across filesystems, so entries on another device, or indexed before devices were
+// kept, never match. Returns "" when there's none, or when the filesystem doesn't report
+// inodes and devices.
+func findMovedFile(path string, mediaType string, fp fileFingerprint) string {
+	if fp.inode == 0 || fp.device == 0 {
+		return ""
+	}
+	c := indexedColumns[mediaType]
+	rows, err := database.DB.Query(fmt.Sprintf(`
+		SELECT fi.path FROM file_index fi
+		WHERE fi.device = $1 AND fi.inode = $2 AND fi.size = $3 AND fi.media_type = $4 AND fi.path <> $5
+			AND EXISTS (SELECT 1 FROM %s WHERE %s = fi.path)`, c.table, c.column),
+		int64(fp.device), int64(fp.inode), fp.size, mediaType, path)
+	if err != nil {
+		return ""
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oldPath string
+		if err := rows.Scan(&oldPath); err != nil {
+			continue
+		}
+		if _, err := os.Stat(oldPath); os.IsNotExist(err) {
+			return oldPath
+		}
+	}
+	return ""
+}
+
+// followMovedFile points the library rows and index entries of a moved file or folder at its
+// new path, keeping their IDs, metadata and watch history
+func followMovedFile(oldPath, newPath string) {
+	slog.Info("Following moved media", "from", oldPath, "to", newPath)
+	// Stale entries at the new path would block moving the old ones there
+	database.DB.Exec("DELETE FROM file_index WHERE path = $1 OR left(path, length($1) + 1) = $1 || '/'", newPath)
+	repointLibraryPaths(oldPath, newPath)
+}
+
+// purgeFileIndex drops index entries whose library row is gone
+func purgeFileIndex(mediaTypes ...string) {
+	for _, mediaType := range mediaTypes {
+		c := indexedColumns[mediaType]
+		_, err := database.DB.Exec(fmt.Sprintf(`
+			DELETE FROM file_index fi WHERE fi.media_type = $1
+				AND NOT EXISTS (SELECT 1 FROM %s WHERE %s = fi.path)`, c.table, c.column), mediaType)
+		if err != nil {
+			slog.Warn("Failed to purge file index", "media_type", mediaType, "error", err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"Arrgo/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// memoryFileIndex stands in for file_index. Every path counts as having its library row.
+type memoryFileIndex map[string]fileFingerprint
+
+func (idx memoryFileIndex) lookup(path, mediaType string) (fileFingerprint, bool) {
+	fp, ok := idx[path]
+	return fp, ok
+}
+func (idx memoryFileIndex) store(path, mediaType string, fp fileFingerprint) { idx[path] = fp }
+func (idx memoryFileIndex) forget(path string)                               { delete(idx, path) }
+
+// useMemoryFileIndex gives the test an empty in-memory file index and renames that report
+// renameErr, or leave paths as they are, and puts the real ones back afterwards
+func useMemoryFileIndex(t *testing.T, renameErr *error) memoryFileIndex {
+	idx := memoryFileIndex{}
+	origIndex, origMovie, origShow := fileIndex, renameScannedMovie, renameScannedShow
+	fileIndex = idx
+	renameScannedMovie = func(cfg *config.Config, movieID int) (string, error) {
+		return filepath.Join(cfg.MoviesPath, "Movie (2020)", "Movie (2020).mkv"), *renameErr
+	}
+	renameScannedShow = func(cfg *config.Config, showID int) (string, error) {
+		return filepath.Join(cfg.ShowsPath, "Show (2020)"), *renameErr
+	}
+	t.Cleanup(func() {
+		fileIndex, renameScannedMovie, renameScannedShow = origIndex, origMovie, origShow
+	})
+	return idx
+}
+
+func TestScanRetriesUnsettledMovies(t *testing.T) {
+	errTMDB := errors.New("TMDB is down")
+	errRename := errors.New("destination is read-only")
+
+	tests := []struct {
+		name       string
+		incoming   bool
+		matchErrs  []error // the match result of each scan
+		renameErr  error
+		wantProbed []bool // whether each scan probed the file rather than skipping it
+	}{
+		{
+			name:       "failed match is retried on the next scan",
+			matchErrs:  []error{errTMDB, nil, nil},
+			wantProbed: []bool{true, true, false},
+		},
+		{
+			name:       "matched movie is skipped by later scans",
+			matchErrs:  []error{nil, nil},
+			wantProbed: []bool{true, false},
+		},
+		{
+			name:       "failed rename is retried on every scan",
+			matchErrs:  []error{nil, nil},
+			renameErr:  errRename,
+			wantProbed: []bool{true, true},
+		},
+		{
+			name:       "incoming movies aren't renamed",
+			incoming:   true,
+			matchErrs:  []error{nil, nil},
+			renameErr:  errRename,
+			wantProbed: []bool{true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MoviesPath: t.TempDir(), IncomingMoviesPath: t.TempDir()}
+			root := cfg.MoviesPath
+			if tt.incoming {
+				root = cfg.IncomingMoviesPath
+			}
+			path := filepath.Join(root, "Movie (2020)", "Movie (2020).mkv")
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, _ := os.Stat(path)
+			fp := fingerprintOf(info)
+			renameErr := tt.renameErr
+			useMemoryFileIndex(t, &renameErr)
+
+			for i, matchErr := range tt.matchErrs {
+				// What processMovieDir does with a file: skip it when unchanged, otherwise
+				// probe it, match it and settle it
+				probed := !fileUnchanged(path, "movie", fp)
+				if probed {
+					settleScannedMovie(cfg, 1, path, fp, matchErr)
+				}
+				if probed != tt.wantProbed[i] {
+					t.Errorf("scan %d probed = %v, want %v", i+1, probed, tt.wantProbed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSettleScannedShow(t *testing.T) {
+	fp := fileFingerprint{size: 100, mtime: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), inode: 7, device: 1}
+	tests := []struct {
+		name        string
+		showPath    string
+		matchErr    error
+		renameErr   error
+		stale       bool // the show was indexed by an earlier scan
+		wantIndexed bool
+	}{
+		{name: "matched and in place", showPath: "/tv/Show (2020)", wantIndexed: true},
+		{name: "failed match", showPath: "/tv/Show (2020)", matchErr: errors.New("TVDB is down")},
+		{name: "failed match forgets the earlier entry", showPath: "/tv/Show (2020)", matchErr: errors.New("TVDB is down"), stale: true},
+		{name: "failed rename", showPath: "/tv/Show (2020)", renameErr: errors.New("destination is read-only")},
+		{name: "renamed show waits for the scan of its new folder", showPath: "/tv/show.2020"},
+		{name: "incoming shows aren't renamed", showPath: "/incoming/show.2020", renameErr: errors.New("not called"), wantIndexed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ShowsPath: "/tv", IncomingShowsPath: "/incoming"}
+			renameErr := tt.renameErr
+			idx := useMemoryFileIndex(t, &renameErr)
+			if tt.stale {
+				idx.store(tt.showPath, "show", fp)
+			}
+			episode := scannedFile{path: tt.showPath + "/Season 01/S01E01.mkv", fp: fp}
+
+			settleScannedShow(cfg, 1, tt.showPath, fp, []scannedFile{episode}, tt.matchErr)
+
+			if _, ok := idx[tt.showPath]; ok != tt.wantIndexed {
+				t.Errorf("show indexed = %v, want %v", ok, tt.wantIndexed)
+			}
+			if _, ok := idx[episode.path]; ok != tt.wantIndexed {
+				t.Errorf("episode indexed = %v, want %v", ok, tt.wantIndexed)
+			}
+		})
+	}
+}
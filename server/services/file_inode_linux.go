@@ -0,0 +1,25 @@
+//go:build linux
+
+package services
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of a file, which survives renames and moves within a filesystem
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+	return 0
+}
+
+// fileDevice returns the ID of the filesystem a file is on. Inode numbers are only unique
+// within one filesystem, so moves are only followed between files on the same device.
+func fileDevice(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package services
+
+import "os"
+
+// fileInode is only implemented on Linux; without it, moved files are probed again like new ones
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
+
+// fileDevice is only implemented on Linux, alongside fileInode
+func fileDevice(info os.FileInfo) uint64 {
+	return 0
+}
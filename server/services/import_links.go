@@ -0,0 +1,91 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// How a file got from incoming into the library, recorded in import_method
+const (
+	ImportMoved    = "move"
+	ImportCopied   = "copy"
+	ImportHardlink = "hardlink"
+	ImportReflink  = "reflink"
+)
+
+// importSeedingFile puts a file qBittorrent is still seeding into the library and leaves the
+// original where it is. With IMPORT_MODE=link it tries a hardlink, then a reflink (which works
+// across btrfs subvolumes, where hardlinks don't), so the library shares the torrent's disk
+// space; anything else, or a library on another filesystem, gets a copy. Returns the method
+// used.
+func importSeedingFile(cfg *config.Config, src, dst string) (string, error) {
+	if cfg.LinkSeedingImports() {
+		linkErr := os.Link(src, dst)
+		if linkErr == nil {
+			return ImportHardlink, nil
+		}
+		reflinkErr := reflinkFile(src, dst)
+		if reflinkErr == nil {
+			return ImportReflink, nil
+		}
+		slog.Info("Can't link file into the library, copying instead", "src", src, "dst", dst, "hardlink_error", linkErr, "reflink_error", reflinkErr)
+	}
+	if err := copyFile(src, dst); err != nil {
+		return "", err
+	}
+	return ImportCopied, nil
+}
+
+// recordImportMethod notes how a movie or episode came out of incoming. Copies and links
+// remember the original so scans and seeding cleanup know it belongs to a library file.
+func recordImportMethod(table string, id int, method, sourcePath string) {
+	source := sql.NullString{String: sourcePath, Valid: method != ImportMoved}
+	query := fmt.Sprintf("UPDATE %s SET import_method = $1, source_path = $2 WHERE id = $3", table)
+	if _, err := database.DB.Exec(query, method, source, id); err != nil {
+		slog.Warn("Failed to record import method", "table", table, "id", id, "error", err)
+	}
+}
+
+// IsImportSource reports whether a file in incoming is the original of a library file that
+// was copied or linked while it kept seeding, so scans leave it alone
+func IsImportSource(path string) bool {
+	var found bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM movies WHERE source_path = $1)
+			OR EXISTS (SELECT 1 FROM episodes WHERE source_path = $1)`, path).Scan(&found)
+	return err == nil && found
+}
+
+// sourceOnlyTorrent reports whether every library file from a torrent was copied or linked,
+// and still exists. The torrent's own files are then just seeding copies and can be deleted
+// along with it.
+func sourceOnlyTorrent(hash string) bool {
+	rows, err := database.DB.Query(`
+		SELECT path, COALESCE(import_method, '') FROM movies WHERE LOWER(torrent_hash) = $1
+		UNION ALL
+		SELECT file_path, COALESCE(import_method, '') FROM episodes WHERE LOWER(torrent_hash) = $1`, hash)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var path, method string
+		if err := rows.Scan(&path, &method); err != nil {
+			return false
+		}
+		if method != ImportCopied && method != ImportHardlink && method != ImportReflink {
+			return false
+		}
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+		count++
+	}
+	return rows.Err() == nil && count > 0
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"Arrgo/config"
+	"Arrgo/models"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -86,32 +88,6 @@ func CreateJellyfinUser(cfg *config.Config, username, password string) (string,
 	return result.ID, nil
 }
 
-// RefreshJellyfinLibrary triggers a library scan in Jellyfin.
-func RefreshJellyfinLibrary(cfg *config.Config) error {
-	if cfg.JellyfinURL == "" || cfg.JellyfinAPIKey == "" {
-		return nil
-	}
-
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/Library/Refresh", cfg.JellyfinURL), nil)
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", cfg.JellyfinAPIKey))
-
-	resp, err := jellyfinClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("jellyfin library refresh failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("jellyfin library refresh returned status %d", resp.StatusCode)
-	}
-
-	slog.Info("Triggered Jellyfin library refresh")
-	return nil
-}
-
 // SyncExistingUsersToJellyfin creates Jellyfin accounts for all existing Arrgo users
 // that don't already have one in Jellyfin. Since we can't recover passwords, new
 // Jellyfin users are created with a temporary password they'll need to change.
@@ -186,3 +162,125 @@ func getJellyfinUsers(cfg *config.Config) ([]jellyfinUser, error) {
 	}
 	return users, nil
 }
+
+// jellyfinIDForUser returns the Jellyfin account matching an Arrgo user: the linked account
+// for users who sign in through Jellyfin, otherwise the one with the same username. Returns
+// "" when Jellyfin isn't configured or has no such user.
+func jellyfinIDForUser(cfg *config.Config, user *models.User) (string, error) {
+	if cfg.JellyfinURL == "" || cfg.JellyfinAPIKey == "" || skipJellyfinSync(user.Username) {
+		return "", nil
+	}
+	if user.JellyfinID != "" {
+		return user.JellyfinID, nil
+	}
+	users, err := getJellyfinUsers(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get jellyfin users: %w", err)
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Name, user.Username) {
+			return u.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// jellyfinAdminRequest sends an API-key authenticated request to Jellyfin and checks the status
+func jellyfinAdminRequest(cfg *config.Config, method, path string, payload any) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, cfg.JellyfinURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", cfg.JellyfinAPIKey))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := jellyfinClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jellyfin request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("jellyfin returned status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// SetJellyfinUserDisabled mirrors disabling or enabling an Arrgo account to the matching
+// Jellyfin account, if there is one
+func SetJellyfinUserDisabled(cfg *config.Config, user *models.User, disabled bool) error {
+	jellyfinID, err := jellyfinIDForUser(cfg, user)
+	if err != nil || jellyfinID == "" {
+		return err
+	}
+
+	resp, err := jellyfinAdminRequest(cfg, "GET", "/Users/"+jellyfinID, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Keep the rest of the policy as it is; Jellyfin replaces the whole policy on update
+	var result struct {
+		Policy map[string]any `json:"Policy"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode jellyfin user: %w", err)
+	}
+	if result.Policy == nil {
+		return fmt.Errorf("jellyfin user has no policy")
+	}
+	result.Policy["IsDisabled"] = disabled
+
+	policyResp, err := jellyfinAdminRequest(cfg, "POST", "/Users/"+jellyfinID+"/Policy", result.Policy)
+	if err != nil {
+		return err
+	}
+	policyResp.Body.Close()
+
+	slog.Info("Updated Jellyfin user", "username", user.Username, "jellyfin_id", jellyfinID, "disabled", disabled)
+	return nil
+}
+
+// SetJellyfinPassword mirrors a password reset to the matching Jellyfin account, if there is one
+func SetJellyfinPassword(cfg *config.Config, user *models.User, password string) error {
+	jellyfinID, err := jellyfinIDForUser(cfg, user)
+	if err != nil || jellyfinID == "" {
+		return err
+	}
+	resp, err := jellyfinAdminRequest(cfg, "POST", "/Users/"+jellyfinID+"/Password", map[string]any{
+		"NewPw": password,
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	slog.Info("Reset Jellyfin password", "username", user.Username, "jellyfin_id", jellyfinID)
+	return nil
+}
+
+// DeleteJellyfinUser mirrors deleting an Arrgo account to the matching Jellyfin account, if
+// there is one
+func DeleteJellyfinUser(cfg *config.Config, user *models.User) error {
+	jellyfinID, err := jellyfinIDForUser(cfg, user)
+	if err != nil || jellyfinID == "" {
+		return err
+	}
+	resp, err := jellyfinAdminRequest(cfg, "DELETE", "/Users/"+jellyfinID, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	slog.Info("Deleted Jellyfin user", "username", user.Username, "jellyfin_id", jellyfinID)
+	return nil
+}
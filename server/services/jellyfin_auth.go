@@ -0,0 +1,200 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// jellyfinAuthHeader identifies Arrgo as a client when signing in on a user's behalf. Logins
+// don't use the API key; Jellyfin only accepts them with client details.
+const jellyfinAuthHeader = `MediaBrowser Client="Arrgo", Device="Arrgo", DeviceId="arrgo-sso", Version="1.0"`
+
+// ErrJellyfinUnavailable means Jellyfin couldn't be reached, as opposed to rejecting the login
+var ErrJellyfinUnavailable = fmt.Errorf("jellyfin unavailable")
+
+type jellyfinAuthResult struct {
+	User struct {
+		ID     string `json:"Id"`
+		Name   string `json:"Name"`
+		Policy struct {
+			IsAdministrator bool `json:"IsAdministrator"`
+		} `json:"Policy"`
+	} `json:"User"`
+	AccessToken string `json:"AccessToken"`
+}
+
+// AuthenticateJellyfinUser validates credentials with Jellyfin's AuthenticateByName and returns
+// the matching Arrgo user, provisioning it on first login. Accounts created this way are
+// admins when the Jellyfin user is, and their admin status follows Jellyfin on every login.
+func AuthenticateJellyfinUser(cfg *config.Config, username, password string) (*models.User, error) {
+	body, _ := json.Marshal(map[string]string{
+		"Username": username,
+		"Pw":       password,
+	})
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/Users/AuthenticateByName", cfg.JellyfinURL), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", jellyfinAuthHeader)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := jellyfinClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJellyfinUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("invalid credentials")
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("%w: status %d", ErrJellyfinUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("jellyfin returned status %d", resp.StatusCode)
+	}
+
+	var result jellyfinAuthResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode jellyfin response: %w", err)
+	}
+	if result.User.ID == "" {
+		return nil, fmt.Errorf("jellyfin returned no user")
+	}
+
+	// Arrgo only needed the credentials checked; don't leave a session behind in Jellyfin
+	go endJellyfinSession(cfg, result.AccessToken)
+
+	return provisionJellyfinUser(result.User.ID, result.User.Name, password, result.User.Policy.IsAdministrator)
+}
+
+// provisionJellyfinUser finds the Arrgo account for a Jellyfin user. An existing local account
+// with the same username is only linked when the password also matches it, since a Jellyfin
+// user of the same name isn't necessarily the same person. Otherwise a new account is created
+// with a random password, so it can only sign in through Jellyfin.
+func provisionJellyfinUser(jellyfinID, name, password string, isAdmin bool) (*models.User, error) {
+	var userID int64
+	err := database.DB.QueryRow("SELECT id FROM users WHERE jellyfin_id = $1", jellyfinID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		userID, err = linkLocalJellyfinUser(jellyfinID, name, password)
+	}
+	if err == sql.ErrNoRows {
+		userID, err = createJellyfinUser(jellyfinID, name, isAdmin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision jellyfin user: %w", err)
+	}
+
+	// Local accounts linked to Jellyfin keep the admin status set in Arrgo, so a Jellyfin
+	// user can't promote or demote them
+	if _, err := database.DB.Exec("UPDATE users SET is_admin = $1 WHERE id = $2 AND jellyfin_provisioned AND is_admin != $1", isAdmin, userID); err != nil {
+		return nil, fmt.Errorf("failed to update admin status: %w", err)
+	}
+
+	return activeUser(GetUserByID(userID))
+}
+
+// linkLocalJellyfinUser links the unlinked local account named name to a Jellyfin user when
+// password is also that account's Arrgo password. Returns sql.ErrNoRows when there's nothing
+// to link.
+func linkLocalJellyfinUser(jellyfinID, name, password string) (int64, error) {
+	var userID int64
+	var hash string
+	err := database.DB.QueryRow("SELECT id, password_hash FROM users WHERE LOWER(username) = LOWER($1) AND jellyfin_id IS NULL", name).Scan(&userID, &hash)
+	if err != nil {
+		return 0, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		slog.Warn("Jellyfin user matches a local account with a different password, not linking", "username", name, "jellyfin_id", jellyfinID)
+		return 0, sql.ErrNoRows
+	}
+	res, err := database.DB.Exec("UPDATE users SET jellyfin_id = $1, updated_at = NOW() WHERE id = $2 AND jellyfin_id IS NULL", jellyfinID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	slog.Info("Linked existing user to Jellyfin", "username", name, "jellyfin_id", jellyfinID)
+	return userID, nil
+}
+
+// createJellyfinUser creates the account for a Jellyfin user, with a numbered username when
+// the name is taken locally. The placeholder email uses the Jellyfin ID, which is unique.
+func createJellyfinUser(jellyfinID, name string, isAdmin bool) (int64, error) {
+	username, err := availableUsername(name)
+	if err != nil {
+		return 0, err
+	}
+	hash, err := randomPasswordHash()
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int64
+	err = database.DB.QueryRow(
+		"INSERT INTO users (username, email, password_hash, is_admin, jellyfin_id, jellyfin_provisioned) VALUES ($1, $2, $3, $4, $5, TRUE) RETURNING id",
+		username, fmt.Sprintf("%s@jellyfin.local", strings.ToLower(jellyfinID)), hash, isAdmin, jellyfinID,
+	).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("Provisioned user from Jellyfin", "username", username, "jellyfin_id", jellyfinID, "is_admin", isAdmin)
+	return userID, nil
+}
+
+// availableUsername returns base, or base with a number suffixed when that username is
+// already taken. Usernames are unique regardless of case.
+func availableUsername(base string) (string, error) {
+	username := base
+	for i := 2; ; i++ {
+		var exists bool
+		if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func randomPasswordHash() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(buf)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func endJellyfinSession(cfg *config.Config, accessToken string) {
+	if accessToken == "" {
+		return
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/Sessions/Logout", cfg.JellyfinURL), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("%s, Token=%q", jellyfinAuthHeader, accessToken))
+	resp, err := jellyfinClient.Do(req)
+	if err != nil {
+		slog.Debug("Failed to end Jellyfin session", "error", err)
+		return
+	}
+	resp.Body.Close()
+}
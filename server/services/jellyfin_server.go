@@ -0,0 +1,273 @@
+package services
+
+import (
+	"Arrgo/config"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sharedhttp "github.com/justbri/arrgo/shared/http"
+)
+
+// jellyfinServer is the Jellyfin MediaServer backend, authenticated with an API key
+type jellyfinServer struct {
+	cfg *config.Config
+}
+
+// jellyfinItem is an item as returned by Jellyfin's /Items endpoints
+type jellyfinItem struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+	Path string `json:"Path"`
+}
+
+func (j *jellyfinServer) Name() string {
+	return "Jellyfin"
+}
+
+// RefreshLibrary triggers a scan of every Jellyfin library. Jellyfin has no per-library scan
+// endpoint, so section is ignored.
+func (j *jellyfinServer) RefreshLibrary(section string) error {
+	if err := j.request("POST", "/Library/Refresh", nil, nil); err != nil {
+		return fmt.Errorf("jellyfin library refresh failed: %w", err)
+	}
+	return nil
+}
+
+// RefreshItem asks Jellyfin to rescan a single item and its children
+func (j *jellyfinServer) RefreshItem(mediaType string, item MediaServerItem) error {
+	params := url.Values{}
+	params.Set("Recursive", "true")
+	params.Set("MetadataRefreshMode", "Default")
+	params.Set("ImageRefreshMode", "Default")
+	params.Set("ReplaceAllMetadata", "false")
+	params.Set("ReplaceAllImages", "false")
+	return j.request("POST", "/Items/"+url.PathEscape(item.ID)+"/Refresh?"+params.Encode(), nil, nil)
+}
+
+func (j *jellyfinServer) FindItemsByProvider(mediaType, source, id string) ([]MediaServerItem, error) {
+	var provider string
+	switch source {
+	case "tmdb":
+		provider = "Tmdb." + id
+	case "tvdb":
+		provider = "Tvdb." + id
+	case "imdb":
+		provider = "Imdb." + id
+	default:
+		return nil, nil
+	}
+	params := jellyfinItemParams(mediaType)
+	params.Set("AnyProviderIdEquals", provider)
+	return j.queryItems(params)
+}
+
+func (j *jellyfinServer) SearchItems(mediaType, title string) ([]MediaServerItem, error) {
+	params := jellyfinItemParams(mediaType)
+	params.Set("SearchTerm", title)
+	return j.queryItems(params)
+}
+
+func jellyfinItemParams(mediaType string) url.Values {
+	itemType := "Movie"
+	if mediaType == "show" {
+		itemType = "Series"
+	}
+	params := url.Values{}
+	params.Set("Recursive", "true")
+	params.Set("IncludeItemTypes", itemType)
+	params.Set("Fields", "Path")
+	return params
+}
+
+func (j *jellyfinServer) queryItems(params url.Values) ([]MediaServerItem, error) {
+	var result struct {
+		Items []jellyfinItem `json:"Items"`
+	}
+	if err := j.request("GET", "/Items?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	items := make([]MediaServerItem, len(result.Items))
+	for i, item := range result.Items {
+		items[i] = MediaServerItem{ID: item.ID, Name: item.Name, Path: item.Path}
+	}
+	return items, nil
+}
+
+func (j *jellyfinServer) GetUsers() ([]MediaServerUser, error) {
+	jellyfinUsers, err := getJellyfinUsers(j.cfg)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]MediaServerUser, len(jellyfinUsers))
+	for i, u := range jellyfinUsers {
+		users[i] = MediaServerUser{ID: u.ID, Name: u.Name}
+	}
+	return users, nil
+}
+
+// GetPlayedItems pages through every played movie and episode for a Jellyfin user
+func (j *jellyfinServer) GetPlayedItems(userID string) ([]PlayedItem, error) {
+	const pageSize = 500
+	var items []PlayedItem
+	for start := 0; ; start += pageSize {
+		params := url.Values{}
+		params.Set("Recursive", "true")
+		params.Set("IncludeItemTypes", "Movie,Episode")
+		params.Set("Filters", "IsPlayed")
+		params.Set("Fields", "Path")
+		params.Set("StartIndex", strconv.Itoa(start))
+		params.Set("Limit", strconv.Itoa(pageSize))
+
+		var result struct {
+			Items []struct {
+				Path     string `json:"Path"`
+				UserData struct {
+					PlayCount      int        `json:"PlayCount"`
+					LastPlayedDate *time.Time `json:"LastPlayedDate"`
+				} `json:"UserData"`
+			} `json:"Items"`
+			TotalRecordCount int `json:"TotalRecordCount"`
+		}
+		if err := j.request("GET", fmt.Sprintf("/Users/%s/Items?%s", url.PathEscape(userID), params.Encode()), nil, &result); err != nil {
+			return nil, err
+		}
+
+		for _, item := range result.Items {
+			items = append(items, PlayedItem{Path: item.Path, PlayCount: item.UserData.PlayCount, LastPlayed: item.UserData.LastPlayedDate})
+		}
+		if len(result.Items) < pageSize || len(items) >= result.TotalRecordCount {
+			return items, nil
+		}
+	}
+}
+
+func (j *jellyfinServer) GetCollectionItems(collectionID string) ([]string, error) {
+	params := url.Values{}
+	params.Set("Ids", collectionID)
+	existing, err := j.queryItems(params)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return nil, errMediaItemNotFound
+	}
+
+	params = url.Values{}
+	params.Set("ParentId", collectionID)
+	children, err := j.queryItems(params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(children))
+	for i, item := range children {
+		ids[i] = item.ID
+	}
+	return ids, nil
+}
+
+func (j *jellyfinServer) CreateCollection(name string, itemIDs []string) (string, error) {
+	params := url.Values{}
+	params.Set("Name", name)
+	params.Set("Ids", strings.Join(itemIDs, ","))
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := j.request("POST", "/Collections?"+params.Encode(), nil, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (j *jellyfinServer) AddCollectionItems(collectionID string, itemIDs []string) error {
+	return j.request("POST", "/Collections/"+url.PathEscape(collectionID)+"/Items?Ids="+url.QueryEscape(strings.Join(itemIDs, ",")), nil, nil)
+}
+
+func (j *jellyfinServer) RemoveCollectionItems(collectionID string, itemIDs []string) error {
+	return j.request("DELETE", "/Collections/"+url.PathEscape(collectionID)+"/Items?Ids="+url.QueryEscape(strings.Join(itemIDs, ",")), nil, nil)
+}
+
+func (j *jellyfinServer) DeleteCollection(collectionID string) error {
+	return j.request("DELETE", "/Items/"+url.PathEscape(collectionID), nil, nil)
+}
+
+// SetCollectionPoster downloads an image and sets it as the collection's primary image.
+// Jellyfin expects the image body base64 encoded.
+func (j *jellyfinServer) SetCollectionPoster(collectionID, imageURL string) error {
+	resp, err := sharedhttp.LongTimeoutClient.Get(imageURL)
+	if err != nil {
+		return fmt.Errorf("failed to download poster: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("poster download returned status %d", resp.StatusCode)
+	}
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read poster: %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(image)
+	}
+
+	body := []byte(base64.StdEncoding.EncodeToString(image))
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/Items/%s/Images/Primary", j.cfg.JellyfinURL, url.PathEscape(collectionID)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", j.cfg.JellyfinAPIKey))
+	req.Header.Set("Content-Type", contentType)
+
+	uploadResp, err := jellyfinClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jellyfin image upload failed: %w", err)
+	}
+	defer uploadResp.Body.Close()
+
+	if uploadResp.StatusCode != http.StatusNoContent && uploadResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jellyfin image upload returned status %d", uploadResp.StatusCode)
+	}
+	return nil
+}
+
+// request sends an authenticated request to Jellyfin and decodes the JSON response into out
+// when it's non-nil. A 404 is reported as errMediaItemNotFound.
+func (j *jellyfinServer) request(method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, j.cfg.JellyfinURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", j.cfg.JellyfinAPIKey))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := jellyfinClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jellyfin request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errMediaItemNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("jellyfin returned status %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode jellyfin response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"Arrgo/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestJellyfinGetPlayedItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int // status for the user's items, 0 for 200
+		played    int
+		wantItems int
+		wantErr   error // checked with errors.Is when set
+		wantFail  bool
+	}{
+		{name: "single page", played: 3, wantItems: 3},
+		{name: "several pages", played: 1203, wantItems: 1203},
+		{name: "nothing played", played: 0, wantItems: 0},
+		{name: "server error", status: http.StatusInternalServerError, wantFail: true},
+		{name: "bad api key", status: http.StatusUnauthorized, wantFail: true},
+		{name: "unknown user", status: http.StatusNotFound, wantErr: errMediaItemNotFound, wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/Users/user-1/Items" || r.URL.Query().Get("Filters") != "IsPlayed" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				start, _ := strconv.Atoi(r.URL.Query().Get("StartIndex"))
+				limit, _ := strconv.Atoi(r.URL.Query().Get("Limit"))
+				var items []map[string]interface{}
+				for i := start; i < min(start+limit, tt.played); i++ {
+					items = append(items, map[string]interface{}{
+						"Path":     fmt.Sprintf("/mnt/movies/Movie %d.mkv", i),
+						"UserData": map[string]interface{}{"PlayCount": 1, "LastPlayedDate": "2026-10-01T20:00:00Z"},
+					})
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"Items": items, "TotalRecordCount": tt.played})
+			}))
+			defer srv.Close()
+
+			server := &jellyfinServer{cfg: &config.Config{JellyfinURL: srv.URL, JellyfinAPIKey: "test"}}
+			items, err := server.GetPlayedItems("user-1")
+			if tt.wantFail {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("GetPlayedItems() error = %v, want an error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetPlayedItems() error = %v", err)
+			}
+			if len(items) != tt.wantItems {
+				t.Errorf("GetPlayedItems() returned %d items, want %d", len(items), tt.wantItems)
+			}
+			if len(items) > 0 && (items[0].LastPlayed == nil || items[0].PlayCount != 1) {
+				t.Errorf("GetPlayedItems() first item = %+v", items[0])
+			}
+		})
+	}
+}
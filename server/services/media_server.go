@@ -0,0 +1,133 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MediaServerItem is the subset of a media server item needed to match it against the library.
+// Path is the movie file or show folder as the server sees it, which matches ours since both
+// mount the same media volume.
+type MediaServerItem struct {
+	ID   string
+	Name string
+	Path string
+}
+
+// MediaServerUser is an account on the media server, matched to Arrgo users by username
+type MediaServerUser struct {
+	ID   string
+	Name string
+}
+
+// PlayedItem is a movie or episode a media server user has played
+type PlayedItem struct {
+	Path       string
+	PlayCount  int
+	LastPlayed *time.Time
+}
+
+// MediaServer is the common interface implemented by every supported media server
+type MediaServer interface {
+	Name() string
+
+	// RefreshLibrary scans the "movies" or "shows" library, or everything when section is empty
+	RefreshLibrary(section string) error
+	// RefreshItem rescans one movie or show after its files changed. Returns
+	// errMediaItemNotFound when the item no longer exists.
+	RefreshItem(mediaType string, item MediaServerItem) error
+	// FindItemsByProvider looks up movies or shows by external ID (source "tmdb", "tvdb" or "imdb")
+	FindItemsByProvider(mediaType, source, id string) ([]MediaServerItem, error)
+	// SearchItems looks up movies or shows by title, for items the server couldn't identify
+	SearchItems(mediaType, title string) ([]MediaServerItem, error)
+
+	GetUsers() ([]MediaServerUser, error)
+	GetPlayedItems(userID string) ([]PlayedItem, error)
+
+	// GetCollectionItems returns the item IDs in a movie collection, or errMediaItemNotFound
+	GetCollectionItems(collectionID string) ([]string, error)
+	CreateCollection(name string, itemIDs []string) (string, error)
+	AddCollectionItems(collectionID string, itemIDs []string) error
+	RemoveCollectionItems(collectionID string, itemIDs []string) error
+	DeleteCollection(collectionID string) error
+	SetCollectionPoster(collectionID, imageURL string) error
+}
+
+var errMediaItemNotFound = fmt.Errorf("media server item not found")
+
+// GetMediaServer returns the media server selected by MEDIA_SERVER, or nil when it isn't configured
+func GetMediaServer(cfg *config.Config) MediaServer {
+	switch cfg.MediaServer {
+	case "plex":
+		if cfg.PlexURL != "" && cfg.PlexToken != "" {
+			return &plexServer{cfg: cfg}
+		}
+	case "jellyfin", "":
+		if cfg.JellyfinURL != "" && cfg.JellyfinAPIKey != "" {
+			return &jellyfinServer{cfg: cfg}
+		}
+	default:
+		slog.Warn("Unknown MEDIA_SERVER, media server integration disabled", "media_server", cfg.MediaServer)
+	}
+	return nil
+}
+
+// MediaServerName returns the display name of the configured media server, or "" if none
+func MediaServerName(cfg *config.Config) string {
+	if server := GetMediaServer(cfg); server != nil {
+		return server.Name()
+	}
+	return ""
+}
+
+// RefreshMediaServerLibrary triggers a scan of the "movies" or "shows" library, or all of them
+func RefreshMediaServerLibrary(cfg *config.Config, section string) error {
+	server := GetMediaServer(cfg)
+	if server == nil {
+		return fmt.Errorf("no media server configured")
+	}
+	if err := server.RefreshLibrary(section); err != nil {
+		return err
+	}
+	slog.Info("Triggered media server library refresh", "server", server.Name(), "section", section)
+	return nil
+}
+
+// GetRecentlyPlayedPaths returns the file paths of movies and episodes any user played in the
+// last week, most recent first, based on the watch state imported from the media server
+func GetRecentlyPlayedPaths(cfg *config.Config) ([]string, error) {
+	if GetMediaServer(cfg) == nil {
+		return nil, nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT path FROM (
+			SELECT m.path, MAX(ws.last_played_at) AS last_played
+			FROM watch_state ws JOIN movies m ON m.id = ws.media_id
+			WHERE ws.media_type = 'movie' AND ws.last_played_at > NOW() - INTERVAL '7 days'
+			GROUP BY m.path
+			UNION ALL
+			SELECT e.file_path, MAX(ws.last_played_at)
+			FROM watch_state ws JOIN episodes e ON e.id = ws.media_id
+			WHERE ws.media_type = 'episode' AND ws.last_played_at > NOW() - INTERVAL '7 days'
+			GROUP BY e.file_path
+		) recent
+		ORDER BY last_played DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently played items: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// mediaServerRefreshDelay is how long queued refreshes are collected before being sent, so a
+// season pack or a bulk rename turns into one batch instead of dozens of requests.
+var mediaServerRefreshDelay = 30 * time.Second
+
+// mediaServerRefreshAttempts is how many batches a target is tried in before a failing
+// lookup or refresh is given up on
+const mediaServerRefreshAttempts = 3
+
+// mediaServerRefreshTarget is a movie or show Arrgo changed on disk. Source and ID are the
+// external ID the media server knows it by (e.g. "tmdb"/"603", "tvdb"/"81189") and Path is the
+// movie file or show folder the server should have for it.
+type mediaServerRefreshTarget struct {
+	MediaType string // "movie" or "show"
+	Source    string
+	ID        string
+	Path      string
+}
+
+// providerID is the external ID in the media server's terms, for logging
+func (t mediaServerRefreshTarget) providerID() string {
+	return t.Source + "." + t.ID
+}
+
+type mediaServerRefreshState struct {
+	sync.Mutex
+	cfg     *config.Config
+	pending map[mediaServerRefreshTarget]int // failed attempts so far
+	full    bool                             // a top-level folder was removed, which only a library scan notices
+	timer   *time.Timer
+}
+
+var mediaServerRefreshQueue = mediaServerRefreshState{pending: make(map[mediaServerRefreshTarget]int)}
+
+// mediaServerItemCache remembers which media server item is at a path. It's keyed by path
+// rather than provider ID because the 4K and edition copies of a movie share their TMDB ID
+// but are separate items.
+type mediaServerItemCache interface {
+	lookup(server, path string) (itemID string, ok bool)
+	store(server, path, itemID string)
+	forget(server, path string)
+}
+
+// dbMediaServerItemCache keeps the item cache in media_server_items
+type dbMediaServerItemCache struct{}
+
+func (dbMediaServerItemCache) lookup(server, path string) (string, bool) {
+	var itemID string
+	err := database.DB.QueryRow("SELECT item_id FROM media_server_items WHERE server = $1 AND path = $2", server, path).Scan(&itemID)
+	return itemID, err == nil
+}
+
+func (dbMediaServerItemCache) store(server, path, itemID string) {
+	_, err := database.DB.Exec(`
+		INSERT INTO media_server_items (server, path, item_id, updated_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (server, path) DO UPDATE SET item_id = $3, updated_at = CURRENT_TIMESTAMP`,
+		server, path, itemID)
+	if err != nil {
+		slog.Debug("Failed to cache media server item ID", "path", path, "error", err)
+	}
+}
+
+func (dbMediaServerItemCache) forget(server, path string) {
+	database.DB.Exec("DELETE FROM media_server_items WHERE server = $1 AND path = $2", server, path)
+}
+
+var mediaServerItems mediaServerItemCache = dbMediaServerItemCache{}
+
+// QueueMediaServerMovieRefresh schedules a refresh of a movie after its file changed
+func QueueMediaServerMovieRefresh(cfg *config.Config, tmdbID, moviePath string) {
+	if tmdbID == "" {
+		return
+	}
+	queueMediaServerRefresh(cfg, mediaServerRefreshTarget{MediaType: "movie", Source: "tmdb", ID: tmdbID, Path: moviePath})
+}
+
+// QueueMediaServerShowRefresh schedules a recursive refresh of a show after episodes in its folder changed
+func QueueMediaServerShowRefresh(cfg *config.Config, tvdbID, showPath string) {
+	if tvdbID == "" {
+		return
+	}
+	queueMediaServerRefresh(cfg, mediaServerRefreshTarget{MediaType: "show", Source: "tvdb", ID: tvdbID, Path: showPath})
+}
+
+// QueueMediaServerLibraryRefresh schedules a full library refresh with the next batch, for
+// changes like removed top-level folders that an item refresh can't pick up
+func QueueMediaServerLibraryRefresh(cfg *config.Config) {
+	if GetMediaServer(cfg) == nil {
+		return
+	}
+	scheduleMediaServerRefresh(cfg, func(q *mediaServerRefreshState) { q.full = true })
+}
+
+func queueMediaServerRefresh(cfg *config.Config, target mediaServerRefreshTarget) {
+	if GetMediaServer(cfg) == nil || target.Source == "" || target.ID == "" {
+		return
+	}
+	scheduleMediaServerRefresh(cfg, func(q *mediaServerRefreshState) {
+		if _, queued := q.pending[target]; !queued {
+			q.pending[target] = 0
+		}
+	})
+}
+
+// retryMediaServerRefresh puts a target whose lookup or refresh failed back in the queue for
+// the next batch, until it has failed mediaServerRefreshAttempts times
+func retryMediaServerRefresh(cfg *config.Config, server MediaServer, target mediaServerRefreshTarget, attempts int, err error) {
+	attempts++
+	if attempts >= mediaServerRefreshAttempts {
+		slog.Error("Giving up on media server refresh", "server", server.Name(), "provider_id", target.providerID(), "path", target.Path, "attempts", attempts, "error", err)
+		return
+	}
+	slog.Warn("Media server refresh failed, retrying with the next batch", "server", server.Name(), "provider_id", target.providerID(), "path", target.Path, "attempt", attempts, "error", err)
+	scheduleMediaServerRefresh(cfg, func(q *mediaServerRefreshState) {
+		q.pending[target] = max(q.pending[target], attempts)
+	})
+}
+
+func scheduleMediaServerRefresh(cfg *config.Config, add func(q *mediaServerRefreshState)) {
+	q := &mediaServerRefreshQueue
+	q.Lock()
+	defer q.Unlock()
+
+	q.cfg = cfg
+	add(q)
+	if q.timer == nil {
+		q.timer = time.AfterFunc(mediaServerRefreshDelay, flushMediaServerRefreshes)
+	}
+}
+
+// flushMediaServerRefreshes sends the queued batch. Items the server already has at the
+// expected path get a targeted refresh. A target the server has no item for is a folder it
+// hasn't seen yet, which only a library scan picks up, so one full refresh is issued for the
+// whole batch. Lookups and refreshes that fail are retried with a later batch instead.
+func flushMediaServerRefreshes() {
+	q := &mediaServerRefreshQueue
+	q.Lock()
+	cfg := q.cfg
+	pending := q.pending
+	needsFullRefresh := q.full
+	q.pending = make(map[mediaServerRefreshTarget]int)
+	q.full = false
+	q.timer = nil
+	q.Unlock()
+
+	server := GetMediaServer(cfg)
+	if server == nil {
+		return
+	}
+
+	refreshed := 0
+	for t, attempts := range pending {
+		item, err := resolveMediaServerItem(server, t)
+		if err == nil && item != nil {
+			err = server.RefreshItem(t.MediaType, *item)
+			if err == errMediaItemNotFound {
+				// Stale cache entry; resolve from scratch once
+				mediaServerItems.forget(server.Name(), t.Path)
+				if item, err = resolveMediaServerItem(server, t); err == nil && item != nil {
+					err = server.RefreshItem(t.MediaType, *item)
+				}
+			}
+		}
+		if err != nil {
+			retryMediaServerRefresh(cfg, server, t, attempts, err)
+			continue
+		}
+		if item == nil {
+			slog.Debug("Media server doesn't know this folder yet, falling back to full refresh", "server", server.Name(), "provider_id", t.providerID(), "path", t.Path)
+			needsFullRefresh = true
+			continue
+		}
+		refreshed++
+	}
+
+	slog.Info("Sent media server refreshes", "server", server.Name(), "targeted", refreshed, "full_refresh", needsFullRefresh)
+	if needsFullRefresh {
+		if err := server.RefreshLibrary(""); err != nil {
+			slog.Error("Failed to trigger media server library refresh", "server", server.Name(), "error", err)
+		}
+	}
+}
+
+// resolveMediaServerItem returns the media server item for a target, or nil if the server
+// has no item at the target's path. The cached item for the path is tried first, then a
+// provider ID lookup, then a title search, both matched on path so copies of the same title
+// in other folders aren't mistaken for it.
+func resolveMediaServerItem(server MediaServer, t mediaServerRefreshTarget) (*MediaServerItem, error) {
+	if itemID, ok := mediaServerItems.lookup(server.Name(), t.Path); ok {
+		return &MediaServerItem{ID: itemID, Path: t.Path}, nil
+	}
+
+	items, err := server.FindItemsByProvider(t.MediaType, t.Source, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	item := matchMediaServerItemPath(items, t.Path)
+	if item == nil {
+		title, _, _, _, _ := ParseMediaName(filepath.Base(showOrMovieDir(t)))
+		if title != "" {
+			if items, err = server.SearchItems(t.MediaType, title); err != nil {
+				return nil, err
+			}
+			item = matchMediaServerItemPath(items, t.Path)
+		}
+	}
+	if item == nil {
+		return nil, nil
+	}
+
+	mediaServerItems.store(server.Name(), t.Path, item.ID)
+	return item, nil
+}
+
+// showOrMovieDir returns the top-level library folder of a target
+func showOrMovieDir(t mediaServerRefreshTarget) string {
+	if t.MediaType == "movie" {
+		return filepath.Dir(t.Path)
+	}
+	return t.Path
+}
+
+func matchMediaServerItemPath(items []MediaServerItem, path string) *MediaServerItem {
+	for i := range items {
+		if filepath.Clean(items[i].Path) == filepath.Clean(path) {
+			return &items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"Arrgo/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryMediaServerItemCache stands in for media_server_items
+type memoryMediaServerItemCache map[string]string
+
+func (c memoryMediaServerItemCache) lookup(server, path string) (string, bool) {
+	id, ok := c[server+"|"+path]
+	return id, ok
+}
+func (c memoryMediaServerItemCache) store(server, path, itemID string) { c[server+"|"+path] = itemID }
+func (c memoryMediaServerItemCache) forget(server, path string)        { delete(c, server+"|"+path) }
+
+// fakeJellyfin serves the Jellyfin endpoints refreshes use: item lookups by provider ID or
+// title, item refreshes and library refreshes. It records every refresh it receives.
+type fakeJellyfin struct {
+	sync.Mutex
+	items      []jellyfinItem    // every item the server has
+	providers  map[string]string // item ID -> provider ID, like "Tmdb.603"
+	itemStatus int               // status for /Items lookups, 0 for 200
+	refreshed  []string
+	libraryRun int
+}
+
+func newFakeJellyfin(t *testing.T, f *fakeJellyfin) *config.Config {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/Items":
+			if f.itemStatus != 0 {
+				w.WriteHeader(f.itemStatus)
+				return
+			}
+			var found []jellyfinItem
+			for _, item := range f.items {
+				provider, search := r.URL.Query().Get("AnyProviderIdEquals"), r.URL.Query().Get("SearchTerm")
+				if (provider != "" && f.providers[item.ID] == provider) || (search != "" && strings.Contains(item.Name, search)) {
+					found = append(found, item)
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Items": found})
+		case r.Method == "POST" && r.URL.Path == "/Library/Refresh":
+			f.libraryRun++
+		case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/Items/") && strings.HasSuffix(r.URL.Path, "/Refresh"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/Items/"), "/Refresh")
+			if !slices.ContainsFunc(f.items, func(item jellyfinItem) bool { return item.ID == id }) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			f.refreshed = append(f.refreshed, id)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &config.Config{MediaServer: "jellyfin", JellyfinURL: srv.URL, JellyfinAPIKey: "test"}
+}
+
+// useMemoryMediaServerRefreshes gives the test an empty refresh queue and an in-memory item
+// cache, and puts the real ones back afterwards
+func useMemoryMediaServerRefreshes(t *testing.T) memoryMediaServerItemCache {
+	cache := memoryMediaServerItemCache{}
+	mediaServerItems = cache
+	mediaServerRefreshDelay = time.Hour
+	mediaServerRefreshQueue = mediaServerRefreshState{pending: make(map[mediaServerRefreshTarget]int)}
+	t.Cleanup(func() {
+		mediaServerItems = dbMediaServerItemCache{}
+		mediaServerRefreshDelay = 30 * time.Second
+		if timer := mediaServerRefreshQueue.timer; timer != nil {
+			timer.Stop()
+		}
+	})
+	return cache
+}
+
+// flushMediaServerRefreshesNow sends the queued batch without waiting for the timer
+func flushMediaServerRefreshesNow() {
+	q := &mediaServerRefreshQueue
+	q.Lock()
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.Unlock()
+	flushMediaServerRefreshes()
+}
+
+func TestFlushMediaServerRefreshes(t *testing.T) {
+	const (
+		movie   = "/mnt/movies/The Matrix (1999) {tmdb-603} [1080p]/The Matrix (1999) {tmdb-603} [1080p].mkv"
+		movie4K = "/mnt/movies-4k/The Matrix (1999) {tmdb-603} [2160p]/The Matrix (1999) {tmdb-603} [2160p].mkv"
+		show    = "/mnt/shows/Firefly (2002) {tvdb-78874}"
+	)
+	matrix := []jellyfinItem{{ID: "hd", Name: "The Matrix", Path: movie}, {ID: "uhd", Name: "The Matrix", Path: movie4K}}
+	matrixIDs := map[string]string{"hd": "Tmdb.603", "uhd": "Tmdb.603"}
+
+	tests := []struct {
+		name          string
+		server        *fakeJellyfin
+		cached        map[string]string // path -> item ID
+		queue         []mediaServerRefreshTarget
+		wantRefreshed []string
+		wantLibrary   int
+		wantRetries   int
+	}{
+		{
+			name:          "known item gets a targeted refresh",
+			server:        &fakeJellyfin{items: matrix[:1], providers: matrixIDs},
+			queue:         []mediaServerRefreshTarget{{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie}},
+			wantRefreshed: []string{"hd"},
+		},
+		{
+			name:   "4K and standard copies refresh their own items",
+			server: &fakeJellyfin{items: matrix, providers: matrixIDs},
+			queue: []mediaServerRefreshTarget{
+				{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie4K},
+				{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie},
+			},
+			wantRefreshed: []string{"hd", "uhd"},
+		},
+		{
+			name:          "cached copy isn't used for the other copy",
+			server:        &fakeJellyfin{items: matrix, providers: matrixIDs},
+			cached:        map[string]string{movie: "hd"},
+			queue:         []mediaServerRefreshTarget{{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie4K}},
+			wantRefreshed: []string{"uhd"},
+		},
+		{
+			name:          "unidentified item is found by title and path",
+			server:        &fakeJellyfin{items: []jellyfinItem{{ID: "ff", Name: "Firefly", Path: show}}},
+			queue:         []mediaServerRefreshTarget{{MediaType: "show", Source: "tvdb", ID: "78874", Path: show}},
+			wantRefreshed: []string{"ff"},
+		},
+		{
+			name:          "stale cached item is resolved again",
+			server:        &fakeJellyfin{items: matrix[:1], providers: matrixIDs},
+			cached:        map[string]string{movie: "deleted"},
+			queue:         []mediaServerRefreshTarget{{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie}},
+			wantRefreshed: []string{"hd"},
+		},
+		{
+			name:        "new folder falls back to a library refresh",
+			server:      &fakeJellyfin{},
+			queue:       []mediaServerRefreshTarget{{MediaType: "show", Source: "tvdb", ID: "78874", Path: show}},
+			wantLibrary: 1,
+		},
+		{
+			name:        "lookup errors are retried, not turned into a library refresh",
+			server:      &fakeJellyfin{items: matrix, providers: matrixIDs, itemStatus: http.StatusInternalServerError},
+			queue:       []mediaServerRefreshTarget{{MediaType: "movie", Source: "tmdb", ID: "603", Path: movie}},
+			wantRetries: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := useMemoryMediaServerRefreshes(t)
+			cfg := newFakeJellyfin(t, tt.server)
+			for path, id := range tt.cached {
+				cache.store("Jellyfin", path, id)
+			}
+			for _, target := range tt.queue {
+				queueMediaServerRefresh(cfg, target)
+			}
+			flushMediaServerRefreshesNow()
+
+			slices.Sort(tt.server.refreshed)
+			if !slices.Equal(tt.server.refreshed, tt.wantRefreshed) {
+				t.Errorf("refreshed items = %v, want %v", tt.server.refreshed, tt.wantRefreshed)
+			}
+			if tt.server.libraryRun != tt.wantLibrary {
+				t.Errorf("library refreshes = %d, want %d", tt.server.libraryRun, tt.wantLibrary)
+			}
+			if got := len(mediaServerRefreshQueue.pending); got != tt.wantRetries {
+				t.Errorf("targets queued for retry = %d, want %d", got, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestMediaServerRefreshGivesUp(t *testing.T) {
+	useMemoryMediaServerRefreshes(t)
+
+	server := &fakeJellyfin{itemStatus: http.StatusInternalServerError}
+	cfg := newFakeJellyfin(t, server)
+	queueMediaServerRefresh(cfg, mediaServerRefreshTarget{MediaType: "show", Source: "tvdb", ID: "78874", Path: "/mnt/shows/Firefly"})
+
+	for i := 0; i < mediaServerRefreshAttempts; i++ {
+		flushMediaServerRefreshesNow()
+	}
+	if n := len(mediaServerRefreshQueue.pending); n != 0 {
+		t.Errorf("target still queued after %d failed attempts", mediaServerRefreshAttempts)
+	}
+	if server.libraryRun != 0 {
+		t.Errorf("library refreshes = %d, want 0", server.libraryRun)
+	}
+}
@@ -60,18 +60,29 @@ var (
 )
 
 type TMDBMovieDetails struct {
-	ID          int     `json:"id"`
-	IMDBID      string  `json:"imdb_id"`
-	Title       string  `json:"title"`
-	ReleaseDate string  `json:"release_date"`
-	Overview    string  `json:"overview"`
-	PosterPath  string  `json:"poster_path"`
-	VoteAverage float64 `json:"vote_average"`
-	Genres      []struct {
+	ID            int     `json:"id"`
+	IMDBID        string  `json:"imdb_id"`
+	Title         string  `json:"title"`
+	OriginalTitle string  `json:"original_title"`
+	ReleaseDate   string  `json:"release_date"`
+	Overview      string  `json:"overview"`
+	PosterPath    string  `json:"poster_path"`
+	BackdropPath  string  `json:"backdrop_path"`
+	VoteAverage   float64 `json:"vote_average"`
+	Genres        []struct {
 		ID   int    `json:"id"`
 		Name string `json:"name"`
 	} `json:"genres"`
-	Runtime int `json:"runtime"`
+	Runtime             int                `json:"runtime"`
+	BelongsToCollection *TMDBCollectionRef `json:"belongs_to_collection"`
+}
+
+// TMDBCollectionRef is the franchise a TMDB movie belongs to
+type TMDBCollectionRef struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	PosterPath   string `json:"poster_path"`
+	BackdropPath string `json:"backdrop_path"`
 }
 
 type TVDBShowDetails struct {
@@ -145,6 +156,93 @@ func (s *MetadataService) GetTMDBMovieDetails(tmdbID string) (*TMDBMovieDetails,
 	return &details, nil
 }
 
+// TMDBShowDetails is a TV show from TMDB, with its TVDB and IMDb IDs
+type TMDBShowDetails struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	OriginalName string  `json:"original_name"`
+	FirstAirDate string  `json:"first_air_date"`
+	LastAirDate  string  `json:"last_air_date"`
+	Status       string  `json:"status"`
+	Overview     string  `json:"overview"`
+	PosterPath   string  `json:"poster_path"`
+	BackdropPath string  `json:"backdrop_path"`
+	VoteAverage  float64 `json:"vote_average"`
+	Genres       []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"genres"`
+	NumberOfSeasons  int `json:"number_of_seasons"`
+	NumberOfEpisodes int `json:"number_of_episodes"`
+	Seasons          []struct {
+		ID           int    `json:"id"`
+		SeasonNumber int    `json:"season_number"`
+		Name         string `json:"name"`
+		EpisodeCount int    `json:"episode_count"`
+		AirDate      string `json:"air_date"`
+		Overview     string `json:"overview"`
+		PosterPath   string `json:"poster_path"`
+	} `json:"seasons"`
+	ExternalIDs struct {
+		TVDBID int    `json:"tvdb_id"`
+		IMDBID string `json:"imdb_id"`
+	} `json:"external_ids"`
+}
+
+// GetTMDBShowDetails fetches a TV show from TMDB. Arrgo keys shows on TVDB IDs, so clients
+// that only know the TMDB ID use this to find the TVDB one.
+func (s *MetadataService) GetTMDBShowDetails(tmdbID string) (*TMDBShowDetails, error) {
+	if s.cfg.TMDBAPIKey == "" {
+		return nil, fmt.Errorf("TMDB_API_KEY is not set")
+	}
+
+	s.throttle()
+	apiURL := fmt.Sprintf("https://api.themoviedb.org/3/tv/%s?api_key=%s&language=en-US&append_to_response=external_ids", tmdbID, s.cfg.TMDBAPIKey)
+
+	resp, err := sharedhttp.MakeRequest(context.Background(), apiURL, sharedhttp.LongTimeoutClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var details TMDBShowDetails
+	if err := sharedhttp.DecodeJSONResponse(resp, &details); err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
+
+// FindTMDBShowID returns the TMDB ID of the show with a TVDB ID, or 0 if TMDB doesn't know it
+func (s *MetadataService) FindTMDBShowID(tvdbID string) (int, error) {
+	if s.cfg.TMDBAPIKey == "" {
+		return 0, fmt.Errorf("TMDB_API_KEY is not set")
+	}
+
+	s.throttle()
+	apiURL := sharedhttp.BuildQueryURL("https://api.themoviedb.org/3/find/"+tvdbID, map[string]string{
+		"api_key":         s.cfg.TMDBAPIKey,
+		"external_source": "tvdb_id",
+	})
+
+	resp, err := sharedhttp.MakeRequest(context.Background(), apiURL, sharedhttp.LongTimeoutClient)
+	if err != nil {
+		return 0, err
+	}
+
+	var found struct {
+		TVResults []struct {
+			ID int `json:"id"`
+		} `json:"tv_results"`
+	}
+	if err := sharedhttp.DecodeJSONResponse(resp, &found); err != nil {
+		return 0, err
+	}
+	if len(found.TVResults) == 0 {
+		return 0, nil
+	}
+	return found.TVResults[0].ID, nil
+}
+
 func (s *MetadataService) GetTVDBShowDetails(tvdbID string) (*TVDBShowDetails, error) {
 	if s.cfg.TVDBAPIKey == "" {
 		return nil, fmt.Errorf("TVDB_API_KEY is not set")
@@ -253,6 +351,71 @@ type TMDBMovieSearchResponse struct {
 	} `json:"results"`
 }
 
+// TMDBSearchPage is one page of a TMDB search across movies and TV shows
+type TMDBSearchPage struct {
+	Page         int                `json:"page"`
+	TotalPages   int                `json:"total_pages"`
+	TotalResults int                `json:"total_results"`
+	Results      []TMDBSearchResult `json:"results"`
+}
+
+// TMDBSearchResult is a movie or show in a TMDB search. Movies fill the title fields and
+// shows the name fields.
+type TMDBSearchResult struct {
+	ID               int     `json:"id"`
+	MediaType        string  `json:"media_type"` // "movie" or "tv"
+	Title            string  `json:"title"`
+	OriginalTitle    string  `json:"original_title"`
+	ReleaseDate      string  `json:"release_date"`
+	Name             string  `json:"name"`
+	OriginalName     string  `json:"original_name"`
+	FirstAirDate     string  `json:"first_air_date"`
+	Overview         string  `json:"overview"`
+	PosterPath       string  `json:"poster_path"`
+	BackdropPath     string  `json:"backdrop_path"`
+	OriginalLanguage string  `json:"original_language"`
+	VoteAverage      float64 `json:"vote_average"`
+	VoteCount        int     `json:"vote_count"`
+	Popularity       float64 `json:"popularity"`
+	GenreIDs         []int   `json:"genre_ids"`
+	Adult            bool    `json:"adult"`
+}
+
+// SearchTMDBMulti searches TMDB for movies and TV shows at once, one page at a time. People
+// in the results are dropped, so a page can hold fewer than TMDB's 20 results.
+func (s *MetadataService) SearchTMDBMulti(query string, page int) (*TMDBSearchPage, error) {
+	if s.cfg.TMDBAPIKey == "" {
+		return nil, fmt.Errorf("TMDB_API_KEY is not set")
+	}
+
+	s.throttle()
+	searchURL := sharedhttp.BuildQueryURL("https://api.themoviedb.org/3/search/multi", map[string]string{
+		"api_key":  s.cfg.TMDBAPIKey,
+		"query":    query,
+		"page":     strconv.Itoa(max(page, 1)),
+		"language": "en-US",
+	})
+
+	resp, err := sharedhttp.MakeRequest(context.Background(), searchURL, sharedhttp.LongTimeoutClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var results TMDBSearchPage
+	if err := sharedhttp.DecodeJSONResponse(resp, &results); err != nil {
+		return nil, err
+	}
+
+	media := results.Results[:0]
+	for _, r := range results.Results {
+		if r.MediaType == "movie" || r.MediaType == "tv" {
+			media = append(media, r)
+		}
+	}
+	results.Results = media
+	return &results, nil
+}
+
 type TVDBAuthResponse struct {
 	Data struct {
 		Token string `json:"token"`
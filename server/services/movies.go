@@ -35,9 +35,13 @@ func ScanMovies(ctx context.Context, cfg *config.Config, onlyIncoming bool) erro
 	}()
 
 	slog.Info("Starting movie scan", "scan_type", scanType, "workers", DefaultWorkerCount)
+	resetScanProgress(scanType)
 
-	// Clean up missing files first
-	PurgeMissingMovies()
+	// Clean up missing files first. Library scans do it afterwards instead, so movies that were
+	// moved or renamed are followed to their new path rather than removed and added again.
+	if onlyIncoming {
+		PurgeMissingMovies()
+	}
 
 	type movieTask struct {
 		root string
@@ -50,14 +54,7 @@ func ScanMovies(ctx context.Context, cfg *config.Config, onlyIncoming bool) erro
 	// Fetch torrents once for incoming scans so we can do hash linking without per-file QB calls
 	var cachedTorrents []TorrentStatus
 	if onlyIncoming {
-		if qb, err := NewQBittorrentClient(cfg); err == nil {
-			if torrents, err := qb.GetTorrentsDetailed(context.Background(), ""); err == nil {
-				cachedTorrents = torrents
-				slog.Info("Fetched torrent list for incoming movie scan", "count", len(cachedTorrents))
-			} else {
-				slog.Debug("Failed to fetch torrent list for incoming scan, hash linking will be skipped", "error", err)
-			}
-		}
+		cachedTorrents = fetchIncomingTorrents(cfg, "movie")
 	}
 
 	// Start workers
@@ -84,7 +81,7 @@ func ScanMovies(ctx context.Context, cfg *config.Config, onlyIncoming bool) erro
 	if onlyIncoming {
 		paths = []string{cfg.IncomingMoviesPath}
 	} else {
-		paths = []string{cfg.MoviesPath}
+		paths = LibraryRoots(cfg, "movie")
 	}
 
 	for _, p := range paths {
@@ -111,7 +108,7 @@ func ScanMovies(ctx context.Context, cfg *config.Config, onlyIncoming bool) erro
 			case <-ctx.Done():
 				stopped = true
 			default:
-				if entry.IsDir() {
+				if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 					dirCount++
 					slog.Debug("Found directory to process", "path", p, "folder", entry.Name())
 					taskChan <- movieTask{root: p, name: entry.Name()}
@@ -127,10 +124,15 @@ func ScanMovies(ctx context.Context, cfg *config.Config, onlyIncoming bool) erro
 	close(taskChan)
 	wg.Wait()
 
+	if !onlyIncoming {
+		PurgeMissingMovies()
+	}
+
+	progress := GetScanProgress(scanType)
 	if ctx.Err() == context.Canceled {
-		slog.Info("Movie scan cancelled", "scan_type", scanType)
+		slog.Info("Movie scan cancelled", "scan_type", scanType, "seen", progress.Seen, "skipped", progress.Skipped, "updated", progress.Updated)
 	} else {
-		slog.Info("Movie scan complete", "scan_type", scanType)
+		slog.Info("Movie scan complete", "scan_type", scanType, "seen", progress.Seen, "skipped", progress.Skipped, "updated", progress.Updated)
 	}
 
 	return nil
@@ -175,8 +177,37 @@ func processMovieDir(cfg *config.Config, root string, folderName string, cachedT
 		return
 	}
 
+	if strings.HasPrefix(mainMovieFile, cfg.IncomingMoviesPath) && IsImportSource(mainMovieFile) {
+		slog.Debug("Skipping seeding original of an imported movie", "file", mainMovieFile)
+		return
+	}
+
 	slog.Debug("Found main movie file", "folder", folderName, "file", mainMovieFile)
 
+	info, err := os.Stat(mainMovieFile)
+	if err != nil {
+		return
+	}
+	fp := fingerprintOf(info)
+	progress := progressFor(mainMovieFile, cfg.IncomingMoviesPath, ScanIncomingMovies, ScanMovieLibrary)
+	if fileUnchanged(mainMovieFile, "movie", fp) {
+		slog.Debug("Movie file unchanged since last scan, skipping", "file", mainMovieFile)
+		progress.skip(1)
+		return
+	}
+	if oldPath := findMovedFile(mainMovieFile, "movie", fp); oldPath != "" {
+		followMovedFile(oldPath, mainMovieFile)
+		if posterPath := findLocalPoster(folderPath); posterPath != "" {
+			database.DB.Exec("UPDATE movies SET poster_path = $1, updated_at = CURRENT_TIMESTAMP WHERE path = $2", posterPath, mainMovieFile)
+		}
+		progress.update(1)
+		// It keeps its metadata, but may need renaming for its new folder
+		var id int
+		err := database.DB.QueryRow("SELECT id FROM movies WHERE path = $1", mainMovieFile).Scan(&id)
+		settleScannedMovie(cfg, id, mainMovieFile, fp, err)
+		return
+	}
+
 	// Fallback to parsing folder name for movie info and IDs
 	folderTitle, folderYear, tmdbID, _, imdbID := ParseMediaName(folderName)
 
@@ -222,10 +253,6 @@ func processMovieDir(cfg *config.Config, root string, folderName string, cachedT
 	// Previously, we skipped movies that already existed in the library.
 	// We now process them anyway to ensure metadata is fully extracted and folders are correctly renamed.
 
-	info, err := os.Stat(mainMovieFile)
-	if err != nil {
-		return
-	}
 	size := info.Size()
 	
 	if quality == "" {
@@ -242,6 +269,7 @@ func processMovieDir(cfg *config.Config, root string, folderName string, cachedT
 		IMDBID:     imdbID,
 		Path:       mainMovieFile,
 		Quality:    quality,
+		Edition:    movieEdition(mainMovieFile),
 		Size:       size,
 		PosterPath: posterPath,
 		Status:     "discovered",
@@ -250,8 +278,10 @@ func processMovieDir(cfg *config.Config, root string, folderName string, cachedT
 	id, err := upsertMovie(movie)
 	if err != nil {
 		slog.Error("Error upserting movie", "title", title, "folder", folderName, "error", err)
+		progress.seen.Add(1)
 		return
 	}
+	progress.update(1)
 
 	slog.Info("Upserted movie", "movie_id", id, "title", title, "year", year, "folder", folderName, "path", mainMovieFile)
 
@@ -259,18 +289,10 @@ func processMovieDir(cfg *config.Config, root string, folderName string, cachedT
 	if strings.HasPrefix(mainMovieFile, cfg.IncomingMoviesPath) {
 		LinkTorrentHashToFile(cfg, cachedTorrents, mainMovieFile, "movie")
 	}
-	// Fetch metadata immediately
-	if err := globalMetadata.MatchMovie(id); err != nil {
-		slog.Debug("Error matching movie metadata", "movie_id", id, "title", title, "error", err)
-	}
-
-	// Force rename the movie if its metadata or quality dictates a new path.
-	// Only do this for library movies — incoming movies should stay put until the user manually imports them.
-	if !strings.HasPrefix(mainMovieFile, cfg.IncomingMoviesPath) {
-		if err := RenameAndMoveMovie(cfg, id); err != nil {
-			slog.Debug("Error moving/renaming movie during scan", "movie_id", id, "title", title, "error", err)
-		}
-	}
+	// Fetch metadata immediately, then force rename the movie if its metadata or quality
+	// dictates a new path. Only library movies are renamed — incoming movies should stay put
+	// until the user manually imports them. It's indexed once both succeeded.
+	settleScannedMovie(cfg, id, mainMovieFile, fp, globalMetadata.MatchMovie(id))
 }
 
 // findMainMovieFile finds the main movie file in a folder, skipping extras
@@ -315,7 +337,7 @@ func findMainMovieFile(folderPath string, folderName string) string {
 				"trailers": true, "trailer": true, "samples": true, "sample": true,
 				"plex versions": true, "plex optimized": true,
 			}
-			if skipDirs[subDirName] {
+			if skipDirs[subDirName] || isExtraFolder(subDirName) {
 				slog.Debug("Skipping subdirectory", "subdir", subDirName, "path", path)
 				return filepath.SkipDir
 			}
@@ -457,26 +479,27 @@ func findMainMovieFile(folderPath string, folderName string) string {
 func upsertMovie(movie models.Movie) (int, error) {
 	var id int
 	query := `
-		INSERT INTO movies (title, year, tmdb_id, imdb_id, path, quality, size, poster_path, status, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)
+		INSERT INTO movies (title, year, tmdb_id, imdb_id, path, quality, edition, size, poster_path, status, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP)
 		ON CONFLICT (path) DO UPDATE SET
 			title = EXCLUDED.title,
 			year = EXCLUDED.year,
 			tmdb_id = COALESCE(NULLIF(EXCLUDED.tmdb_id, ''), movies.tmdb_id),
 			imdb_id = COALESCE(NULLIF(EXCLUDED.imdb_id, ''), movies.imdb_id),
 			quality = EXCLUDED.quality,
+			edition = EXCLUDED.edition,
 			size = EXCLUDED.size,
 			poster_path = COALESCE(NULLIF(EXCLUDED.poster_path, ''), movies.poster_path),
 			status = movies.status, -- Keep existing status if it was already matched
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING id
 	`
-	err := database.DB.QueryRow(query, movie.Title, movie.Year, movie.TMDBID, movie.IMDBID, movie.Path, movie.Quality, movie.Size, movie.PosterPath, movie.Status).Scan(&id)
+	err := database.DB.QueryRow(query, movie.Title, movie.Year, movie.TMDBID, movie.IMDBID, movie.Path, movie.Quality, movie.Edition, movie.Size, movie.PosterPath, movie.Status).Scan(&id)
 	return id, err
 }
 
 func GetMovies() ([]models.Movie, error) {
-	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, size, overview, poster_path, genres, status, imported_at, torrent_hash, subtitles_synced, created_at, updated_at FROM movies ORDER BY title ASC`
+	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, COALESCE(edition, ''), size, overview, poster_path, genres, status, imported_at, torrent_hash, subtitles_synced, created_at, updated_at FROM movies ORDER BY title ASC`
 	rows, err := database.DB.Query(query)
 	if err != nil {
 		return nil, err
@@ -488,7 +511,7 @@ func GetMovies() ([]models.Movie, error) {
 		var m models.Movie
 		var tmdbID, imdbID, overview, posterPath, quality, genres, torrentHash sql.NullString
 		var importedAt sql.NullTime
-		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &torrentHash, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
+		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Edition, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &torrentHash, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -509,7 +532,7 @@ func GetMovies() ([]models.Movie, error) {
 
 func GetIncomingMovies(incomingPath string) ([]models.Movie, error) {
 	query := `
-		SELECT id, title, year, tmdb_id, imdb_id, path, quality, size, overview, poster_path, genres, status, imported_at, torrent_hash, subtitles_synced, created_at, updated_at 
+		SELECT id, title, year, tmdb_id, imdb_id, path, quality, COALESCE(edition, ''), size, overview, poster_path, genres, status, imported_at, torrent_hash, subtitles_synced, created_at, updated_at 
 		FROM movies 
 		WHERE path LIKE $1 || '%' AND imported_at IS NULL
 		ORDER BY created_at DESC`
@@ -524,7 +547,7 @@ func GetIncomingMovies(incomingPath string) ([]models.Movie, error) {
 		var m models.Movie
 		var tmdbID, imdbID, overview, posterPath, quality, genres, torrentHash sql.NullString
 		var importedAt sql.NullTime
-		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &torrentHash, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
+		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Edition, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &torrentHash, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -544,11 +567,11 @@ func GetIncomingMovies(incomingPath string) ([]models.Movie, error) {
 }
 
 func GetMovieByID(id int) (*models.Movie, error) {
-	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, size, overview, poster_path, genres, status, imported_at, subtitles_synced, created_at, updated_at FROM movies WHERE id = $1`
+	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, COALESCE(edition, ''), size, overview, poster_path, genres, status, imported_at, subtitles_synced, created_at, updated_at FROM movies WHERE id = $1`
 	var m models.Movie
 	var tmdbID, imdbID, overview, posterPath, quality, genres sql.NullString
 	var importedAt sql.NullTime
-	err := database.DB.QueryRow(query, id).Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
+	err := database.DB.QueryRow(query, id).Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Edition, &m.Size, &overview, &posterPath, &genres, &m.Status, &importedAt, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -564,6 +587,30 @@ func GetMovieByID(id int) (*models.Movie, error) {
 	return &m, nil
 }
 
+// GetMovieCopies returns the other library copies of a movie, such as its other editions or
+// its 4K copy
+func GetMovieCopies(tmdbID string, excludeID int) ([]models.Movie, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, title, year, path, COALESCE(quality, ''), COALESCE(edition, '')
+		FROM movies
+		WHERE tmdb_id = $1 AND id != $2
+		ORDER BY edition, quality`, tmdbID, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var copies []models.Movie
+	for rows.Next() {
+		var m models.Movie
+		if err := rows.Scan(&m.ID, &m.Title, &m.Year, &m.Path, &m.Quality, &m.Edition); err != nil {
+			return nil, err
+		}
+		copies = append(copies, m)
+	}
+	return copies, rows.Err()
+}
+
 func GetMovieCount(excludeIncomingPath string) (int, error) {
 	var count int
 	var err error
@@ -577,6 +624,13 @@ func GetMovieCount(excludeIncomingPath string) (int, error) {
 
 func PurgeMissingMovies() {
 	slog.Debug("Checking for missing movies")
+
+	// Movies on a root folder whose disk isn't mounted are unreachable, not gone
+	offline := unavailableRoots("movie")
+	if len(offline) > 0 {
+		slog.Warn("Root folders are unavailable, keeping their movies", "paths", offline)
+	}
+
 	rows, err := database.DB.Query("SELECT id, path FROM movies")
 	if err != nil {
 		return
@@ -589,7 +643,7 @@ func PurgeMissingMovies() {
 		if err := rows.Scan(&id, &path); err != nil {
 			continue
 		}
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(path); os.IsNotExist(err) && rootOf(offline, path) == "" {
 			toDelete = append(toDelete, id)
 		}
 	}
@@ -599,6 +653,7 @@ func PurgeMissingMovies() {
 		slog.Info("Removing missing movie from DB", "movie_id", id)
 		database.DB.Exec("DELETE FROM movies WHERE id = $1", id)
 	}
+	purgeFileIndex("movie")
 }
 
 func SearchMoviesLocal(query string) ([]models.Movie, error) {
@@ -614,7 +669,7 @@ func SearchMoviesLocal(query string) ([]models.Movie, error) {
 	}
 
 	dbQuery := fmt.Sprintf(`
-		SELECT id, title, year, tmdb_id, imdb_id, path, quality, size, overview, poster_path, genres, status, subtitles_synced, created_at, updated_at
+		SELECT id, title, year, tmdb_id, imdb_id, path, quality, COALESCE(edition, ''), size, overview, poster_path, genres, status, subtitles_synced, created_at, updated_at
 		FROM movies
 		WHERE %s
 		ORDER BY title ASC
@@ -630,7 +685,7 @@ func SearchMoviesLocal(query string) ([]models.Movie, error) {
 	for rows.Next() {
 		var m models.Movie
 		var tmdbID, imdbID, overview, posterPath, quality, genres sql.NullString
-		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Size, &overview, &posterPath, &genres, &m.Status, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
+		err := rows.Scan(&m.ID, &m.Title, &m.Year, &tmdbID, &imdbID, &m.Path, &quality, &m.Edition, &m.Size, &overview, &posterPath, &genres, &m.Status, &m.SubtitlesSynced, &m.CreatedAt, &m.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,622 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const namingTemplatesKey = "naming_templates"
+
+// NamingTemplates are the folder and file name templates used when importing and renaming.
+// Tokens like {Title} or {Season:00} are replaced with the item's values; anything else is
+// kept as written, so "{tmdb-{TmdbId}}" becomes "{tmdb-603}".
+type NamingTemplates struct {
+	MovieFolder  string `json:"movie_folder"`
+	MovieFile    string `json:"movie_file"`
+	ShowFolder   string `json:"show_folder"`
+	SeasonFolder string `json:"season_folder"`
+	EpisodeFile  string `json:"episode_file"`
+}
+
+// DefaultNamingTemplates match the names Arrgo has always used
+var DefaultNamingTemplates = NamingTemplates{
+	MovieFolder:  "{Title} ({Year}) {edition-{Edition}} {tmdb-{TmdbId}} [{Quality}]",
+	MovieFile:    "{Title} ({Year}) {edition-{Edition}} {tmdb-{TmdbId}} [{Quality}]",
+	ShowFolder:   "{Title} ({Year}) {tvdb-{TvdbId}}",
+	SeasonFolder: "Season {Season:00}",
+	EpisodeFile:  "{Title} - S{Season:00}E{Episode:00} - {EpisodeTitle}",
+}
+
+// NamingTokens lists the tokens templates can use, for the settings UI
+var NamingTokens = []string{
+	"Title", "Year", "TmdbId", "TvdbId", "ImdbId", "Quality", "Codec", "ReleaseGroup",
+	"Edition", "Season", "Episode", "EpisodeTitle",
+}
+
+// NamingValues are the values of one movie or episode for rendering a template. Title is
+// the show's title for episodes.
+type NamingValues struct {
+	Title        string
+	Year         int
+	TmdbID       string
+	TvdbID       string
+	ImdbID       string
+	Quality      string
+	Codec        string
+	ReleaseGroup string
+	Edition      string
+	Season       int
+	Episode      int
+	EpisodeTitle string
+}
+
+var (
+	namingTokenRegex = regexp.MustCompile(`\{(\w+)(?::(0+))?\}`)
+	// A bracketed group holding a token, e.g. " [{Quality}]" or " {tmdb-{TmdbId}}", dropped
+	// with its leading space when all of its tokens are empty
+	namingGroupRegex = regexp.MustCompile(`\s*(\[[^\[\]]*\{\w+(?::0+)?\}[^\[\]]*\]|\([^()]*\{\w+(?::0+)?\}[^()]*\)|\{[^{}]*\{\w+(?::0+)?\}[^{}]*\})`)
+	namingSpaceRegex = regexp.MustCompile(`\s{2,}`)
+	namingDashRegex  = regexp.MustCompile(`(\s-)+\s`)
+
+	codecRegex        = regexp.MustCompile(`(?i)\b(x265|h\.?265|hevc|x264|h\.?264|avc|av1|vp9|xvid|divx)\b`)
+	editionRegex      = regexp.MustCompile(`(?i)\b(director'?s\.?\s?cut|extended(?:\.?\s?(?:cut|edition))?|unrated|uncut|theatrical(?:\.?\s?cut)?|remastered|imax|criterion|ultimate\.?\s?(?:cut|edition)|special\.?\s?edition)\b`)
+	yearRegex         = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	editionTagRegex   = regexp.MustCompile(`(?i)\{edition-([^}]+)\}`)
+	releaseGroupRegex = regexp.MustCompile(`\S-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+	sceneTagRegex     = regexp.MustCompile(`(?i)\b(1080p|720p|2160p|480p|WEB-DL|WEBRip|BluRay|BDRip|x264|x265|HEVC|H264|H265)\b`)
+)
+
+// GetNamingTemplates returns the saved naming templates, with defaults for any not set
+func GetNamingTemplates() NamingTemplates {
+	templates := DefaultNamingTemplates
+
+	var value string
+	err := database.DB.QueryRow("SELECT value FROM settings WHERE key = $1", namingTemplatesKey).Scan(&value)
+	if err != nil {
+		return templates
+	}
+
+	var saved NamingTemplates
+	if err := json.Unmarshal([]byte(value), &saved); err != nil {
+		return templates
+	}
+	if saved.MovieFolder != "" {
+		templates.MovieFolder = saved.MovieFolder
+	}
+	if saved.MovieFile != "" {
+		templates.MovieFile = saved.MovieFile
+	}
+	if saved.ShowFolder != "" {
+		templates.ShowFolder = saved.ShowFolder
+	}
+	if saved.SeasonFolder != "" {
+		templates.SeasonFolder = saved.SeasonFolder
+	}
+	if saved.EpisodeFile != "" {
+		templates.EpisodeFile = saved.EpisodeFile
+	}
+	return templates
+}
+
+// SaveNamingTemplates validates and saves naming templates. Existing files keep their names
+// until they are renamed.
+func SaveNamingTemplates(templates NamingTemplates) error {
+	if err := ValidateNamingTemplates(templates); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(templates)
+	if err != nil {
+		return err
+	}
+	_, err = database.DB.Exec(`
+		INSERT INTO settings (key, value, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET value = $2, updated_at = CURRENT_TIMESTAMP`,
+		namingTemplatesKey, string(value))
+	return err
+}
+
+// ValidateNamingTemplates checks templates only use known tokens and keep names unique: every
+// template needs the title, and episodes need their season and episode numbers
+func ValidateNamingTemplates(templates NamingTemplates) error {
+	fields := []struct {
+		name     string
+		template string
+		required []string
+	}{
+		{"Movie folder", templates.MovieFolder, []string{"Title"}},
+		{"Movie file", templates.MovieFile, []string{"Title"}},
+		{"Show folder", templates.ShowFolder, []string{"Title"}},
+		{"Season folder", templates.SeasonFolder, []string{"Season"}},
+		{"Episode file", templates.EpisodeFile, []string{"Season", "Episode"}},
+	}
+
+	for _, f := range fields {
+		if strings.TrimSpace(f.template) == "" {
+			return fmt.Errorf("%s template is empty", f.name)
+		}
+		if strings.ContainsAny(f.template, `/\`) {
+			return fmt.Errorf("%s template can't contain path separators", f.name)
+		}
+
+		used := map[string]bool{}
+		for _, match := range namingTokenRegex.FindAllStringSubmatch(f.template, -1) {
+			token, ok := namingToken(match[1])
+			if !ok {
+				return fmt.Errorf("%s template has unknown token %s", f.name, match[0])
+			}
+			used[token] = true
+		}
+		for _, token := range f.required {
+			if !used[token] {
+				return fmt.Errorf("%s template must include {%s}", f.name, token)
+			}
+		}
+	}
+	return nil
+}
+
+// namingToken returns the canonical name of a token, matched case-insensitively
+func namingToken(name string) (string, bool) {
+	for _, token := range NamingTokens {
+		if strings.EqualFold(token, name) {
+			return token, true
+		}
+	}
+	return name, false
+}
+
+func (v NamingValues) token(name, padding string) string {
+	number := func(n int) string {
+		if n <= 0 && name != "Season" {
+			return ""
+		}
+		if padding != "" {
+			return fmt.Sprintf("%0*d", len(padding), n)
+		}
+		return strconv.Itoa(n)
+	}
+
+	switch name {
+	case "Title":
+		return v.Title
+	case "Year":
+		return number(v.Year)
+	case "TmdbId":
+		return v.TmdbID
+	case "TvdbId":
+		return v.TvdbID
+	case "ImdbId":
+		return v.ImdbID
+	case "Quality":
+		return v.Quality
+	case "Codec":
+		return v.Codec
+	case "ReleaseGroup":
+		return v.ReleaseGroup
+	case "Edition":
+		return v.Edition
+	case "Season":
+		return number(v.Season)
+	case "Episode":
+		return number(v.Episode)
+	case "EpisodeTitle":
+		return v.EpisodeTitle
+	}
+	return ""
+}
+
+// RenderName fills a template with an item's values. Values are made safe for file names,
+// and brackets left empty by missing values are dropped along with extra spaces and dashes.
+func RenderName(template string, v NamingValues) string {
+	replace := func(s string) (string, bool) {
+		empty := true
+		out := namingTokenRegex.ReplaceAllStringFunc(s, func(match string) string {
+			parts := namingTokenRegex.FindStringSubmatch(match)
+			token, ok := namingToken(parts[1])
+			if !ok {
+				return match
+			}
+			value := sanitizePath(strings.TrimSpace(v.token(token, parts[2])))
+			if value != "" {
+				empty = false
+			}
+			return value
+		})
+		return out, empty
+	}
+
+	name := namingGroupRegex.ReplaceAllStringFunc(template, func(group string) string {
+		out, empty := replace(group)
+		if empty {
+			return ""
+		}
+		return out
+	})
+	name, _ = replace(name)
+
+	// Spaces first, so an empty token between dashes leaves " - - " for the dashes to collapse
+	name = namingSpaceRegex.ReplaceAllString(name, " ")
+	name = namingDashRegex.ReplaceAllString(name, " - ")
+	return strings.Trim(name, " -.")
+}
+
+// mediaFileNamingValues fills the values taken from a media file's name: codec, release group
+// and edition. The codec comes from ffprobe when the name doesn't have one and the templates
+// use it.
+func mediaFileNamingValues(v *NamingValues, path string, probeCodec bool) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	v.Codec = DetectCodec(name)
+	if v.Codec == "" && probeCodec {
+		if meta, err := ProbeVideo(context.Background(), path); err == nil {
+			v.Codec = meta.GetCodec()
+		}
+	}
+	v.Edition = DetectEdition(name)
+	if sceneTagRegex.MatchString(name) {
+		if m := releaseGroupRegex.FindStringSubmatch(name); m != nil && !strings.EqualFold(m[1], "DL") {
+			v.ReleaseGroup = m[1]
+		}
+	}
+}
+
+// DetectCodec returns the video codec named in a release or file name, or ""
+func DetectCodec(name string) string {
+	m := codecRegex.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	switch strings.ReplaceAll(strings.ToLower(m[1]), ".", "") {
+	case "x265", "h265", "hevc":
+		return "HEVC"
+	case "x264", "h264", "avc":
+		return "H264"
+	case "av1":
+		return "AV1"
+	case "vp9":
+		return "VP9"
+	}
+	return "XviD"
+}
+
+// MovieEditions are the editions DetectEdition recognizes, offered when requesting a movie
+var MovieEditions = []string{
+	"Director's Cut", "Extended", "Theatrical", "Unrated", "Uncut", "Ultimate Cut",
+	"Special Edition", "IMAX", "Remastered", "Criterion",
+}
+
+// movieEdition returns the edition of a movie file, from its name or else its folder's
+func movieEdition(path string) string {
+	if edition := DetectEdition(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))); edition != "" {
+		return edition
+	}
+	return DetectEdition(filepath.Base(filepath.Dir(path)))
+}
+
+// SameEdition reports whether two editions are the same. They're compared by their first word,
+// so "Extended" matches "Extended Cut" and "Extended Edition".
+func SameEdition(a, b string) bool {
+	return editionKey(a) == editionKey(b)
+}
+
+func editionKey(edition string) string {
+	words := strings.Fields(strings.ToLower(edition))
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// DetectEdition returns the edition named in a release or file name, such as
+// "Director's Cut", or ""
+func DetectEdition(name string) string {
+	if m := editionTagRegex.FindStringSubmatch(name); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	// Look after the year, so titles like "Uncut Gems" aren't taken for editions
+	if years := yearRegex.FindAllStringIndex(name, -1); years != nil {
+		name = name[years[len(years)-1][1]:]
+	}
+	m := editionRegex.FindString(name)
+	if m == "" {
+		return ""
+	}
+
+	words := strings.Fields(strings.NewReplacer(".", " ", "'", "").Replace(strings.ToLower(m)))
+	for i, word := range words {
+		if word == "directors" {
+			word = "director's"
+		}
+		if word == "imax" {
+			words[i] = "IMAX"
+			continue
+		}
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// usesToken reports whether any of the templates use a token
+func usesToken(token string, templates ...string) bool {
+	for _, template := range templates {
+		for _, match := range namingTokenRegex.FindAllStringSubmatch(template, -1) {
+			if name, _ := namingToken(match[1]); name == token {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RenamePreview is where a movie or episode is now and where renaming it would put it
+type RenamePreview struct {
+	ID      int    `json:"id"`
+	Label   string `json:"label"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+// movieDestination returns the library folder and file name (without extension) for a movie
+func movieDestination(moviesPath string, m models.Movie, templates NamingTemplates) (string, string) {
+	v := NamingValues{
+		Title:   m.Title,
+		Year:    m.Year,
+		TmdbID:  m.TMDBID,
+		ImdbID:  m.IMDBID,
+		Quality: m.Quality,
+	}
+	mediaFileNamingValues(&v, m.Path, usesToken("Codec", templates.MovieFolder, templates.MovieFile))
+	if m.Edition != "" {
+		v.Edition = m.Edition
+	}
+
+	// Each edition has its own folder and file, so templates without the edition still get its
+	// tag rather than putting two editions in the same place
+	folderTemplate, fileTemplate := templates.MovieFolder, templates.MovieFile
+	if !usesToken("Edition", folderTemplate) {
+		folderTemplate += " {edition-{Edition}}"
+	}
+	if !usesToken("Edition", fileTemplate) {
+		fileTemplate += " {edition-{Edition}}"
+	}
+	return filepath.Join(moviesPath, RenderName(folderTemplate, v)), RenderName(fileTemplate, v)
+}
+
+// showFolderName returns the library folder name for a show
+func showFolderName(sh models.Show, templates NamingTemplates) string {
+	return RenderName(templates.ShowFolder, NamingValues{
+		Title:  sh.Title,
+		Year:   sh.Year,
+		TvdbID: sh.TVDBID,
+		ImdbID: sh.IMDBID,
+	})
+}
+
+// episodeDestination returns the show folder name, season folder name and file name (without
+// extension) for an episode
+func episodeDestination(sh models.Show, seasonNumber int, e models.Episode, templates NamingTemplates) (string, string, string) {
+	v := NamingValues{
+		Title:        sh.Title,
+		Year:         sh.Year,
+		TvdbID:       sh.TVDBID,
+		ImdbID:       sh.IMDBID,
+		Quality:      e.Quality,
+		Season:       seasonNumber,
+		Episode:      e.EpisodeNumber,
+		EpisodeTitle: episodeNamingTitle(e, sh.Title),
+	}
+	mediaFileNamingValues(&v, e.FilePath, usesToken("Codec", templates.EpisodeFile))
+	return showFolderName(sh, templates), RenderName(templates.SeasonFolder, v), RenderName(templates.EpisodeFile, v)
+}
+
+// episodeNamingTitle cleans up an episode's title for its file name
+func episodeNamingTitle(e models.Episode, showTitle string) string {
+	epTitle := e.Title
+	// Only run the scene-name cleaner if the title actually looks like a raw scene filename:
+	// dots-as-separators OR explicit quality/codec tags. Hyphens alone are not a signal
+	// (many real episode titles contain them, e.g. "Spider-Man", "Step-by-Step").
+	if strings.Contains(epTitle, ".") || sceneTagRegex.MatchString(epTitle) {
+		// Clean the episode title using the shared parser
+		epTitle, _, _, _, _ = ParseMediaName(epTitle)
+	}
+
+	if epTitle == "" || strings.EqualFold(epTitle, showTitle) {
+		epTitle = fmt.Sprintf("Episode %d", e.EpisodeNumber)
+	}
+
+	// Strip show title prefix from episode title to avoid recursive and redundant naming
+	return StripShowTitlePrefix(epTitle, showTitle)
+}
+
+// PreviewMovieRenames returns where renaming would move library movies with the given
+// templates, up to limit when limit > 0. With changedOnly, movies already named that way
+// are left out.
+func PreviewMovieRenames(cfg *config.Config, templates NamingTemplates, limit int, changedOnly bool) ([]RenamePreview, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, title, year, tmdb_id, COALESCE(imdb_id, ''), path, COALESCE(quality, ''), COALESCE(edition, '')
+		FROM movies
+		WHERE status IN ('matched', 'ready') AND tmdb_id IS NOT NULL AND tmdb_id != ''
+		ORDER BY title`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roots := LibraryRoots(cfg, "movie")
+	previews := []RenamePreview{}
+	for rows.Next() {
+		var m models.Movie
+		if err := rows.Scan(&m.ID, &m.Title, &m.Year, &m.TMDBID, &m.IMDBID, &m.Path, &m.Quality, &m.Edition); err != nil {
+			return nil, err
+		}
+		dir, name := movieDestination(rootOrDefault(roots, m.Path), m, templates)
+		newPath := filepath.Join(findExistingDirCaseInsensitive(dir), name+filepath.Ext(m.Path))
+		if changedOnly && newPath == m.Path {
+			continue
+		}
+		previews = append(previews, RenamePreview{
+			ID:      m.ID,
+			Label:   fmt.Sprintf("%s (%d)", m.Title, m.Year),
+			OldPath: m.Path,
+			NewPath: newPath,
+		})
+		if limit > 0 && len(previews) >= limit {
+			break
+		}
+	}
+	return previews, rows.Err()
+}
+
+// PreviewEpisodeRenames is PreviewMovieRenames for episodes
+func PreviewEpisodeRenames(cfg *config.Config, templates NamingTemplates, limit int, changedOnly bool) ([]RenamePreview, error) {
+	rows, err := database.DB.Query(`
+		SELECT e.id, e.episode_number, COALESCE(e.title, ''), e.file_path, COALESCE(e.quality, ''), s.season_number,
+			sh.title, COALESCE(sh.year, 0), COALESCE(sh.tvdb_id, ''), COALESCE(sh.imdb_id, '')
+		FROM episodes e
+		JOIN seasons s ON e.season_id = s.id
+		JOIN shows sh ON s.show_id = sh.id
+		WHERE sh.status IN ('matched', 'ready') AND e.file_path IS NOT NULL AND e.file_path != ''
+		ORDER BY sh.title, s.season_number, e.episode_number`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roots := LibraryRoots(cfg, "show")
+	previews := []RenamePreview{}
+	for rows.Next() {
+		var e models.Episode
+		var sh models.Show
+		var seasonNumber int
+		if err := rows.Scan(&e.ID, &e.EpisodeNumber, &e.Title, &e.FilePath, &e.Quality, &seasonNumber,
+			&sh.Title, &sh.Year, &sh.TVDBID, &sh.IMDBID); err != nil {
+			return nil, err
+		}
+		showDir, seasonDir, name := episodeDestination(sh, seasonNumber, e, templates)
+		showPath := findExistingDirCaseInsensitive(filepath.Join(rootOrDefault(roots, e.FilePath), showDir))
+		newPath := filepath.Join(showPath, seasonDir, name+filepath.Ext(e.FilePath))
+		if changedOnly && newPath == e.FilePath {
+			continue
+		}
+		previews = append(previews, RenamePreview{
+			ID:      e.ID,
+			Label:   fmt.Sprintf("%s S%02dE%02d", sh.Title, seasonNumber, e.EpisodeNumber),
+			OldPath: e.FilePath,
+			NewPath: newPath,
+		})
+		if limit > 0 && len(previews) >= limit {
+			break
+		}
+	}
+	return previews, rows.Err()
+}
+
+// planMovieRenames plans renaming every library movie that doesn't follow the saved templates.
+// A movie folder is renamed as a whole when it can be, so posters and extras come along.
+func planMovieRenames(cfg *config.Config) (*filePlan, error) {
+	plan := newFilePlan(BatchRenameMovies)
+	previews, err := PreviewMovieRenames(cfg, GetNamingTemplates(), 0, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list movie renames: %w", err)
+	}
+
+	roots := LibraryRoots(cfg, "movie")
+	for _, p := range previews {
+		root := rootOf(roots, p.OldPath)
+		if root == "" {
+			plan.note("%s: not in a movie root folder, left alone", p.Label)
+			continue
+		}
+		planLibraryFolderRename(root, plan, p)
+		planFileRename(plan, plan.current(p.OldPath), p.NewPath, p.Label)
+	}
+	return plan, nil
+}
+
+// planShowRenames plans renaming every library episode, and show folder, that doesn't follow
+// the saved templates
+func planShowRenames(cfg *config.Config) (*filePlan, error) {
+	plan := newFilePlan(BatchRenameShows)
+	previews, err := PreviewEpisodeRenames(cfg, GetNamingTemplates(), 0, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episode renames: %w", err)
+	}
+
+	roots := LibraryRoots(cfg, "show")
+	for _, p := range previews {
+		root := rootOf(roots, p.OldPath)
+		if root == "" {
+			plan.note("%s: not in a show root folder, left alone", p.Label)
+			continue
+		}
+		planLibraryFolderRename(root, plan, p)
+		planFileRename(plan, plan.current(p.OldPath), p.NewPath, p.Label)
+	}
+	return plan, nil
+}
+
+// planLibraryFolderRename plans renaming the top folder a rename moves a file out of, when the
+// file lands in a top folder of another name that doesn't exist yet
+func planLibraryFolderRename(root string, plan *filePlan, p RenamePreview) {
+	topFolder := func(path string) string {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(root, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+	}
+
+	from, to := topFolder(p.OldPath), topFolder(p.NewPath)
+	if from == "" || to == "" || from == to || from == p.OldPath || plan.gone[from] || !plan.free(to) {
+		return
+	}
+	plan.moveFolder(from, to, "Rename folder of "+p.Label)
+}
+
+// planFileRename plans moving a video to its new name along with its sidecar files, like
+// subtitles named after it
+func planFileRename(plan *filePlan, from, to, label string) {
+	if from == to {
+		return
+	}
+	if !plan.free(to) {
+		plan.note("%s: %s already exists, left alone", label, to)
+		return
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(from), filepath.Ext(from))
+	newStem := strings.TrimSuffix(filepath.Base(to), filepath.Ext(to))
+	plan.move(from, to, "Rename "+label, pathSize(plan.onDisk(from)))
+
+	entries, err := os.ReadDir(plan.onDisk(filepath.Dir(from)))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasPrefix(name, stem+".") || strings.HasPrefix(name, stem+"-")) {
+			continue
+		}
+		src := filepath.Join(filepath.Dir(from), name)
+		if src == from || plan.gone[src] {
+			continue
+		}
+		dst := filepath.Join(filepath.Dir(to), newStem+name[len(stem):])
+		if !plan.free(dst) {
+			plan.note("%s: %s already exists, left alone", label, dst)
+			continue
+		}
+		plan.move(src, dst, "Rename "+label+" sidecar", pathSize(plan.onDisk(src)))
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderName(t *testing.T) {
+	movie := NamingValues{Title: "The Matrix", Year: 1999, TmdbID: "603", Quality: "1080p"}
+	episode := NamingValues{Title: "Show", Year: 2020, TvdbID: "81189", Quality: "1080p", Season: 1, Episode: 2, EpisodeTitle: "Pilot"}
+	untitled := episode
+	untitled.EpisodeTitle = ""
+
+	tests := []struct {
+		name     string
+		template string
+		values   NamingValues
+		want     string
+	}{
+		{"default movie folder", DefaultNamingTemplates.MovieFolder, movie, "The Matrix (1999) {tmdb-603} [1080p]"},
+		{"default movie file with edition", DefaultNamingTemplates.MovieFile, NamingValues{Title: "Blade Runner", Year: 1982, TmdbID: "78", Quality: "2160p", Edition: "Final Cut"}, "Blade Runner (1982) {edition-Final Cut} {tmdb-78} [2160p]"},
+		{"default show folder", DefaultNamingTemplates.ShowFolder, episode, "Show (2020) {tvdb-81189}"},
+		{"default season folder", DefaultNamingTemplates.SeasonFolder, episode, "Season 01"},
+		{"default episode file", DefaultNamingTemplates.EpisodeFile, episode, "Show - S01E02 - Pilot"},
+		{"empty groups are dropped", DefaultNamingTemplates.MovieFolder, NamingValues{Title: "The Matrix", Year: 1999}, "The Matrix (1999)"},
+		{"empty trailing token", DefaultNamingTemplates.EpisodeFile, untitled, "Show - S01E02"},
+		{"empty token between dashes", "{Title} - S{Season:00}E{Episode:00} - {EpisodeTitle} - {Quality}", untitled, "Show - S01E02 - 1080p"},
+		{"empty token after the title", "{Title} - {EpisodeTitle} - {Quality}", untitled, "Show - 1080p"},
+		{"adjacent empty tokens", "{Title} - {EpisodeTitle} - {Codec} - {Quality}", untitled, "Show - 1080p"},
+		{"season zero is padded", "Season {Season:00}", NamingValues{Season: 0}, "Season 00"},
+		{"wider padding", "S{Season:000}E{Episode:000}", episode, "S001E002"},
+		{"unpadded numbers", "{Season}x{Episode}", NamingValues{Season: 3, Episode: 12}, "3x12"},
+		{"tokens are case-insensitive", "{title} ({YEAR})", movie, "The Matrix (1999)"},
+		{"unknown tokens are kept", "{Title} {Foo}", movie, "The Matrix {Foo}"},
+		{"values are made safe for file names", "{Title}", NamingValues{Title: "Mission: Impossible?"}, "Mission - Impossible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderName(tt.template, tt.values); got != tt.want {
+				t.Errorf("RenderName(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNamingTemplates(t *testing.T) {
+	with := func(change func(*NamingTemplates)) NamingTemplates {
+		templates := DefaultNamingTemplates
+		change(&templates)
+		return templates
+	}
+	tests := []struct {
+		name      string
+		templates NamingTemplates
+		wantErr   string
+	}{
+		{"defaults", DefaultNamingTemplates, ""},
+		{"case-insensitive tokens", with(func(n *NamingTemplates) { n.MovieFile = "{title} ({year})" }), ""},
+		{"unknown token", with(func(n *NamingTemplates) { n.MovieFile = "{Title} {Resolution}" }), "Movie file template has unknown token {Resolution}"},
+		{"empty template", with(func(n *NamingTemplates) { n.ShowFolder = " " }), "Show folder template is empty"},
+		{"path separator", with(func(n *NamingTemplates) { n.MovieFolder = "{Title}/{Year}" }), "Movie folder template can't contain path separators"},
+		{"missing title", with(func(n *NamingTemplates) { n.MovieFolder = "{Year}" }), "Movie folder template must include {Title}"},
+		{"missing season", with(func(n *NamingTemplates) { n.SeasonFolder = "Specials" }), "Season folder template must include {Season}"},
+		{"missing episode", with(func(n *NamingTemplates) { n.EpisodeFile = "{Title} S{Season:00}" }), "Episode file template must include {Episode}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNamingTemplates(tt.templates)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateNamingTemplates() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateNamingTemplates() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectEdition(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Blade.Runner.1982.Directors.Cut.1080p.BluRay.x264-GRP", "Director's Cut"},
+		{"Aliens (1986) Extended Edition", "Extended Edition"},
+		{"Dune.2021.IMAX.2160p.WEB-DL", "IMAX"},
+		{"Amadeus 1984 Theatrical Cut", "Theatrical Cut"},
+		{"Blade Runner (1982) {edition-Final Cut} {tmdb-78}", "Final Cut"},
+		{"Uncut.Gems.2019.1080p.WEBRip", ""},
+		{"The.Matrix.1999.1080p.BluRay", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectEdition(tt.name); got != tt.want {
+				t.Errorf("DetectEdition(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameEdition(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Extended", "Extended Cut", true},
+		{"extended edition", "Extended", true},
+		{"Director's Cut", "Theatrical", false},
+		{"", "", true},
+		{"", "IMAX", false},
+	}
+	for _, tt := range tests {
+		if got := SameEdition(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameEdition(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDetectCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Show.S01E01.1080p.WEB-DL.x265-GRP", "HEVC"},
+		{"Show.S01E01.1080p.WEB-DL.H.265-GRP", "HEVC"},
+		{"Movie 2020 HEVC 2160p", "HEVC"},
+		{"Movie.2020.1080p.BluRay.x264-GRP", "H264"},
+		{"Movie.2020.1080p.h264", "H264"},
+		{"Movie.2020.2160p.AV1", "AV1"},
+		{"Movie.2020.VP9", "VP9"},
+		{"Movie.2004.DVDRip.XviD-GRP", "XviD"},
+		{"Movie.2004.DivX", "XviD"},
+		{"Movie (2020)", ""},
+		{"Havc.2020", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectCodec(tt.name); got != tt.want {
+				t.Errorf("DetectCodec(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
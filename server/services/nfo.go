@@ -5,17 +5,45 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
+// nfoTrailerRegex finds the trailer link FetchMovieTrailer records in a movie NFO
+var nfoTrailerRegex = regexp.MustCompile(`(?m)^\s*<trailer>.*</trailer>\s*$`)
+
+// writeMovieNFO writes movie.nfo, keeping any trailer link already recorded in it
 func writeMovieNFO(dirPath, tmdbID string) {
+	nfoPath := filepath.Join(dirPath, "movie.nfo")
+	trailer := ""
+	if existing, err := os.ReadFile(nfoPath); err == nil {
+		if m := nfoTrailerRegex.Find(existing); m != nil {
+			trailer = "\n" + strings.TrimRight(string(m), "\r\n")
+		}
+	}
 	content := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>
 <movie>
-  <tmdbid>%s</tmdbid>
-</movie>`, tmdbID)
+  <tmdbid>%s</tmdbid>%s
+</movie>`, tmdbID, trailer)
+	if err := os.WriteFile(nfoPath, []byte(content), 0644); err != nil {
+		slog.Error("Failed to write movie NFO", "path", nfoPath, "error", err)
+	}
+}
+
+// setMovieNFOTrailer records a YouTube trailer in movie.nfo, in the plugin form Jellyfin
+// turns into a remote trailer
+func setMovieNFOTrailer(dirPath, tmdbID, youtubeKey string) {
 	nfoPath := filepath.Join(dirPath, "movie.nfo")
+	content := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<movie>
+  <tmdbid>%s</tmdbid>
+  <trailer>plugin://plugin.video.youtube/?action=play_video&amp;videoid=%s</trailer>
+</movie>`, tmdbID, youtubeKey)
 	if err := os.WriteFile(nfoPath, []byte(content), 0644); err != nil {
 		slog.Error("Failed to write movie NFO", "path", nfoPath, "error", err)
+		return
 	}
+	slog.Info("Linked movie trailer", "tmdb_id", tmdbID, "path", nfoPath)
 }
 
 func writeShowNFO(dirPath, tvdbID string) {
@@ -0,0 +1,540 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcProviderMetadata is the subset of the discovery document Arrgo uses
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcCache holds the discovery document and signing keys. Keys are refetched when a token
+// is signed with an unknown key ID, which is how providers rotate them.
+var oidcCache struct {
+	sync.Mutex
+	issuer   string
+	metadata *oidcProviderMetadata
+	keys     map[string]crypto.PublicKey
+}
+
+// OIDCAuthRequest is a started login. State, Nonce and Verifier must be kept (in the session)
+// until the provider redirects back to the callback.
+type OIDCAuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// OIDCIdentity is who the provider says signed in
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified *bool
+	Username      string
+	Groups        []string
+}
+
+// NewOIDCAuthRequest builds the authorization URL for the authorization code flow with PKCE
+func NewOIDCAuthRequest(cfg *config.Config) (*OIDCAuthRequest, error) {
+	metadata, err := getOIDCMetadata(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &OIDCAuthRequest{
+		State:    randomURLToken(),
+		Nonce:    randomURLToken(),
+		Verifier: randomURLToken(),
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", cfg.OIDCClientID)
+	params.Set("redirect_uri", cfg.OIDCRedirectURL)
+	params.Set("scope", cfg.OIDCScopes)
+	params.Set("state", req.State)
+	params.Set("nonce", req.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	req.URL = metadata.AuthorizationEndpoint + sep + params.Encode()
+	return req, nil
+}
+
+// CompleteOIDCLogin exchanges the authorization code, verifies the ID token and returns the
+// Arrgo user for the identity, linking or creating the account as needed
+func CompleteOIDCLogin(cfg *config.Config, code, verifier, nonce string) (*models.User, error) {
+	identity, err := ExchangeOIDCCode(cfg, code, verifier, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return provisionOIDCUser(cfg, identity)
+}
+
+// ExchangeOIDCCode redeems an authorization code and returns the verified identity
+func ExchangeOIDCCode(cfg *config.Config, code, verifier, nonce string) (*OIDCIdentity, error) {
+	metadata, err := getOIDCMetadata(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.OIDCRedirectURL)
+	form.Set("client_id", cfg.OIDCClientID)
+	form.Set("code_verifier", verifier)
+	if cfg.OIDCClientSecret != "" {
+		form.Set("client_secret", cfg.OIDCClientSecret)
+	}
+
+	resp, err := oidcClient.PostForm(metadata.TokenEndpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokens struct {
+		IDToken     string `json:"id_token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	claims, err := verifyIDToken(cfg, metadata, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	// Some providers only put email and groups in the userinfo response
+	if metadata.UserinfoEndpoint != "" && tokens.AccessToken != "" && (claims["email"] == nil || claims[cfg.OIDCGroupsClaim] == nil) {
+		if info, err := fetchOIDCUserinfo(metadata.UserinfoEndpoint, tokens.AccessToken); err != nil {
+			slog.Debug("Failed to fetch OIDC userinfo", "error", err)
+		} else if info["sub"] == claims["sub"] {
+			for k, v := range info {
+				if claims[k] == nil {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	identity := &OIDCIdentity{
+		Subject:  claimString(claims, "sub"),
+		Email:    claimString(claims, "email"),
+		Username: claimString(claims, "preferred_username"),
+		Groups:   claimStrings(claims, cfg.OIDCGroupsClaim),
+	}
+	if verified, ok := claims["email_verified"].(bool); ok {
+		identity.EmailVerified = &verified
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+	return identity, nil
+}
+
+func getOIDCMetadata(cfg *config.Config) (*oidcProviderMetadata, error) {
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+
+	if oidcCache.metadata != nil && oidcCache.issuer == cfg.OIDCIssuer {
+		return oidcCache.metadata, nil
+	}
+
+	resp, err := oidcClient.Get(strings.TrimRight(cfg.OIDCIssuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery returned status %d", resp.StatusCode)
+	}
+
+	var metadata oidcProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode oidc discovery document: %w", err)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing endpoints")
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != strings.TrimRight(cfg.OIDCIssuer, "/") {
+		return nil, fmt.Errorf("oidc issuer mismatch: configured %s, provider says %s", cfg.OIDCIssuer, metadata.Issuer)
+	}
+
+	oidcCache.issuer = cfg.OIDCIssuer
+	oidcCache.metadata = &metadata
+	oidcCache.keys = nil
+	return &metadata, nil
+}
+
+// oidcSigningKey returns the provider key with the given ID, refetching the key set once
+// when it isn't known
+func oidcSigningKey(metadata *oidcProviderMetadata, kid string) (crypto.PublicKey, error) {
+	oidcCache.Lock()
+	defer oidcCache.Unlock()
+
+	if key, ok := oidcCache.keys[kid]; ok {
+		return key, nil
+	}
+
+	resp, err := oidcClient.Get(metadata.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	oidcCache.keys = keys
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Tokens may omit kid when the provider only has one key
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// verifyIDToken checks the token's signature against the provider's keys and validates the
+// issuer, audience, expiry and nonce. Returns the token's claims.
+func verifyIDToken(cfg *config.Config, metadata *oidcProviderMetadata, token, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	var hash crypto.Hash
+	switch header.Alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	key, err := oidcSigningKey(metadata, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(header.Alg, "RS") || rsa.VerifyPKCS1v15(k, hash, digest, signature) != nil {
+			return nil, fmt.Errorf("signature verification failed")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(header.Alg, "ES") || len(signature) != 2*size ||
+			!ecdsa.Verify(k, digest, new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])) {
+			return nil, fmt.Errorf("signature verification failed")
+		}
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if claimString(claims, "iss") != metadata.Issuer {
+		return nil, fmt.Errorf("issuer mismatch")
+	}
+	audienceOK := false
+	for _, aud := range claimStrings(claims, "aud") {
+		if aud == cfg.OIDCClientID {
+			audienceOK = true
+		}
+	}
+	if !audienceOK {
+		return nil, fmt.Errorf("token not issued for this client")
+	}
+	// Allow a minute of clock skew between Arrgo and the provider
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Add(-time.Minute).After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("nonce mismatch")
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("malformed token: %w", err)
+	}
+	return nil
+}
+
+func fetchOIDCUserinfo(endpoint, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo returned status %d", resp.StatusCode)
+	}
+
+	var info map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// claimStrings reads a claim that may be a single string or a list of strings
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// oidcRole maps the identity's groups to Arrgo access. allowed is false when OIDC_USER_GROUP
+// is set and the user is in neither it nor the admin group. isAdmin is nil when no admin group
+// is configured, leaving admin status to be managed in Arrgo.
+func oidcRole(cfg *config.Config, groups []string) (allowed bool, isAdmin *bool) {
+	inGroup := func(group string) bool {
+		for _, g := range groups {
+			if g == group {
+				return true
+			}
+		}
+		return false
+	}
+
+	admin := cfg.OIDCAdminGroup != "" && inGroup(cfg.OIDCAdminGroup)
+	if cfg.OIDCAdminGroup != "" {
+		isAdmin = &admin
+	}
+	allowed = cfg.OIDCUserGroup == "" || admin || inGroup(cfg.OIDCUserGroup)
+	return allowed, isAdmin
+}
+
+// canLinkByEmail reports whether an identity may be linked to an existing account with the
+// same email. Only emails the provider explicitly marks as verified count: a provider that
+// omits email_verified may let users set any address, which would hand them that account.
+func (identity *OIDCIdentity) canLinkByEmail() bool {
+	return identity.Email != "" && identity.EmailVerified != nil && *identity.EmailVerified
+}
+
+// provisionOIDCUser finds the Arrgo account for an OIDC identity: by subject, then by verified
+// email, and otherwise creates one with a random password so it can only sign in through the
+// provider
+func provisionOIDCUser(cfg *config.Config, identity *OIDCIdentity) (*models.User, error) {
+	allowed, isAdmin := oidcRole(cfg, identity.Groups)
+	if !allowed {
+		return nil, fmt.Errorf("not a member of %s", cfg.OIDCUserGroup)
+	}
+
+	var userID int64
+	err := database.DB.QueryRow("SELECT id FROM users WHERE oidc_subject = $1", identity.Subject).Scan(&userID)
+	if err == sql.ErrNoRows && identity.canLinkByEmail() {
+		err = database.DB.QueryRow(
+			"UPDATE users SET oidc_subject = $1, updated_at = NOW() WHERE LOWER(email) = LOWER($2) AND oidc_subject IS NULL RETURNING id",
+			identity.Subject, identity.Email,
+		).Scan(&userID)
+		if err == nil {
+			slog.Info("Linked existing user to OIDC identity by email", "email", identity.Email, "user_id", userID)
+		}
+	}
+	if err == sql.ErrNoRows {
+		userID, err = createOIDCUser(identity, isAdmin != nil && *isAdmin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to provision oidc user: %w", err)
+	}
+
+	if isAdmin != nil {
+		if _, err := database.DB.Exec("UPDATE users SET is_admin = $1 WHERE id = $2 AND is_admin != $1", *isAdmin, userID); err != nil {
+			return nil, fmt.Errorf("failed to update admin status: %w", err)
+		}
+	}
+
+	return activeUser(GetUserByID(userID))
+}
+
+func createOIDCUser(identity *OIDCIdentity, isAdmin bool) (int64, error) {
+	base := identity.Username
+	if base == "" && identity.Email != "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	// Usernames are unique; suffix a number when the provider's name is already taken locally
+	username, err := availableUsername(base)
+	if err != nil {
+		return 0, err
+	}
+
+	// Emails are unique too; an email that matches another account but wasn't verified isn't
+	// linked, so the new account gets a placeholder instead
+	email := identity.Email
+	var emailTaken bool
+	if email != "" {
+		if err := database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))", email).Scan(&emailTaken); err != nil {
+			return 0, err
+		}
+	}
+	if email == "" || emailTaken {
+		email = fmt.Sprintf("%s@oidc.local", strings.ToLower(username))
+	}
+
+	hash, err := randomPasswordHash()
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int64
+	err = database.DB.QueryRow(
+		"INSERT INTO users (username, email, password_hash, is_admin, oidc_subject) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		username, email, hash, isAdmin, identity.Subject,
+	).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("Provisioned user from OIDC", "username", username, "is_admin", isAdmin)
+	return userID, nil
+}
+
+func randomURLToken() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"Arrgo/config"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubIdP is an OpenID provider serving discovery, token and JWKS endpoints. The token
+// endpoint checks the PKCE verifier against the challenge of the last authorization request
+// and returns an ID token with the given claims, signed with signKey.
+type stubIdP struct {
+	srv       *httptest.Server
+	key       *rsa.PrivateKey // published in the JWKS
+	signKey   *rsa.PrivateKey // signs ID tokens
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key, signKey: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(t)})
+	})
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *stubIdP) sign(t *testing.T) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test"})
+	payload, _ := json.Marshal(idp.claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.signKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Error(err) // called from the server goroutine, so no Fatal
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestExchangeOIDCCode(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier func(req *OIDCAuthRequest) string
+		nonce    func(req *OIDCAuthRequest) string
+		signKey  *rsa.PrivateKey
+		wantErr  string
+	}{
+		{name: "valid"},
+		{name: "pkce verifier mismatch", verifier: func(*OIDCAuthRequest) string { return "wrong-verifier" }, wantErr: "status 400"},
+		{name: "unknown code", code: "stolen-code", wantErr: "status 400"},
+		{name: "bad signature", signKey: otherKey, wantErr: "signature verification failed"},
+		{name: "nonce mismatch", nonce: func(*OIDCAuthRequest) string { return "replayed-nonce" }, wantErr: "nonce mismatch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			cfg := &config.Config{
+				OIDCIssuer:      idp.srv.URL,
+				OIDCClientID:    "arrgo",
+				OIDCRedirectURL: "https://arrgo.test/auth/oidc/callback",
+				OIDCScopes:      "openid email",
+				OIDCGroupsClaim: "groups",
+			}
+
+			req, err := NewOIDCAuthRequest(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			authURL, _ := url.Parse(req.URL)
+			if authURL.Query().Get("code_challenge_method") != "S256" || authURL.Query().Get("state") != req.State {
+				t.Fatalf("authorization URL is missing PKCE or state: %s", req.URL)
+			}
+			idp.challenge = authURL.Query().Get("code_challenge")
+			idp.claims = map[string]interface{}{
+				"iss":   idp.srv.URL,
+				"aud":   "arrgo",
+				"sub":   "user-1",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": req.Nonce,
+				"email": "someone@example.com",
+			}
+			if tt.signKey != nil {
+				idp.signKey = tt.signKey
+			}
+
+			code, verifier, nonce := "good-code", req.Verifier, req.Nonce
+			if tt.code != "" {
+				code = tt.code
+			}
+			if tt.verifier != nil {
+				verifier = tt.verifier(req)
+			}
+			if tt.nonce != nil {
+				nonce = tt.nonce(req)
+			}
+
+			identity, err := ExchangeOIDCCode(cfg, code, verifier, nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExchangeOIDCCode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExchangeOIDCCode() error = %v", err)
+			}
+			if identity.Subject != "user-1" || identity.Email != "someone@example.com" {
+				t.Errorf("ExchangeOIDCCode() = %+v", identity)
+			}
+			// The stub omits email_verified, so the email must not link to an existing account
+			if identity.canLinkByEmail() {
+				t.Errorf("identity without email_verified can link by email")
+			}
+		})
+	}
+}
+
+func TestOIDCCanLinkByEmail(t *testing.T) {
+	verified, unverified := true, false
+	tests := []struct {
+		name     string
+		identity OIDCIdentity
+		want     bool
+	}{
+		{"verified", OIDCIdentity{Email: "a@example.com", EmailVerified: &verified}, true},
+		{"unverified", OIDCIdentity{Email: "a@example.com", EmailVerified: &unverified}, false},
+		{"email_verified omitted", OIDCIdentity{Email: "a@example.com"}, false},
+		{"no email", OIDCIdentity{EmailVerified: &verified}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.identity.canLinkByEmail(); got != tt.want {
+				t.Errorf("canLinkByEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package services
+
+import (
+	"Arrgo/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Section listings with GUIDs can be large, so Plex gets a longer timeout than Jellyfin
+var plexClient = &http.Client{Timeout: 30 * time.Second}
+
+// plexServer is the Plex Media Server backend, authenticated with an X-Plex-Token. Items are
+// identified by their ratingKey.
+type plexServer struct {
+	cfg *config.Config
+}
+
+type plexSection struct {
+	Key       string `json:"key"`
+	Type      string `json:"type"` // "movie" or "show"
+	Title     string `json:"title"`
+	Locations []struct {
+		Path string `json:"path"`
+	} `json:"Location"`
+}
+
+type plexMetadata struct {
+	RatingKey string `json:"ratingKey"`
+	Title     string `json:"title"`
+	Guids     []struct {
+		ID string `json:"id"` // e.g. "tmdb://603"
+	} `json:"Guid"`
+	Media []struct {
+		Parts []struct {
+			File string `json:"file"`
+		} `json:"Part"`
+	} `json:"Media"`
+	Locations []struct {
+		Path string `json:"path"`
+	} `json:"Location"`
+}
+
+// path returns the movie file or show folder of a Plex item
+func (m plexMetadata) path() string {
+	for _, media := range m.Media {
+		for _, part := range media.Parts {
+			if part.File != "" {
+				return part.File
+			}
+		}
+	}
+	if len(m.Locations) > 0 {
+		return m.Locations[0].Path
+	}
+	return ""
+}
+
+type plexContainer struct {
+	MediaContainer struct {
+		MachineIdentifier string         `json:"machineIdentifier"`
+		Directory         []plexSection  `json:"Directory"`
+		Metadata          []plexMetadata `json:"Metadata"`
+		Account           []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"Account"`
+	} `json:"MediaContainer"`
+}
+
+func (p *plexServer) Name() string {
+	return "Plex"
+}
+
+// plexSectionType maps Arrgo media types and library names to Plex section types
+func plexSectionType(mediaType string) string {
+	if mediaType == "show" || mediaType == "shows" {
+		return "show"
+	}
+	return "movie"
+}
+
+// sections returns the Plex libraries holding movies or shows. An explicitly configured
+// section wins; otherwise sections whose folders overlap a root folder of the type are used,
+// falling back to every section of the right type.
+func (p *plexServer) sections(mediaType string) ([]plexSection, error) {
+	sectionType := plexSectionType(mediaType)
+	configured := p.cfg.PlexMoviesSection
+	if sectionType == "show" {
+		configured = p.cfg.PlexShowsSection
+	}
+	roots := LibraryRoots(p.cfg, sectionType)
+
+	var result plexContainer
+	if err := p.request("GET", "/library/sections", &result); err != nil {
+		return nil, fmt.Errorf("failed to list plex sections: %w", err)
+	}
+
+	var ofType, matching []plexSection
+	for _, s := range result.MediaContainer.Directory {
+		if configured != "" {
+			if s.Key == configured {
+				return []plexSection{s}, nil
+			}
+			continue
+		}
+		if s.Type != sectionType {
+			continue
+		}
+		ofType = append(ofType, s)
+		if sectionOverlaps(s, roots) {
+			matching = append(matching, s)
+		}
+	}
+	if configured != "" {
+		return nil, fmt.Errorf("plex section %s not found", configured)
+	}
+	if len(matching) > 0 {
+		return matching, nil
+	}
+	return ofType, nil
+}
+
+// sectionOverlaps reports whether a folder of the section is, holds, or is inside one of the roots
+func sectionOverlaps(s plexSection, roots []string) bool {
+	for _, loc := range s.Locations {
+		for _, root := range roots {
+			if pathWithin(root, loc.Path) || pathWithin(loc.Path, root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pathWithin reports whether path is dir or inside it
+func pathWithin(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// RefreshLibrary scans the Plex sections for "movies" or "shows", or both when section is empty
+func (p *plexServer) RefreshLibrary(section string) error {
+	mediaTypes := []string{"movie", "show"}
+	if section != "" {
+		mediaTypes = []string{plexSectionType(section)}
+	}
+	for _, mediaType := range mediaTypes {
+		sections, err := p.sections(mediaType)
+		if err != nil {
+			return err
+		}
+		for _, s := range sections {
+			if err := p.request("GET", "/library/sections/"+url.PathEscape(s.Key)+"/refresh", nil); err != nil {
+				return fmt.Errorf("plex refresh of %s failed: %w", s.Title, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RefreshItem runs a partial scan of the item's folder, which picks up renamed, added and
+// removed files without scanning the whole section
+func (p *plexServer) RefreshItem(mediaType string, item MediaServerItem) error {
+	dir := item.Path
+	if mediaType == "movie" {
+		dir = filepath.Dir(item.Path)
+	}
+
+	sections, err := p.sections(mediaType)
+	if err != nil {
+		return err
+	}
+	for _, s := range sections {
+		for _, loc := range s.Locations {
+			if pathWithin(loc.Path, dir) {
+				return p.request("GET", "/library/sections/"+url.PathEscape(s.Key)+"/refresh?path="+url.QueryEscape(dir), nil)
+			}
+		}
+	}
+	return fmt.Errorf("no plex section contains %s", dir)
+}
+
+// FindItemsByProvider matches section items on their external GUIDs. Plex can't filter on
+// those server-side, so each section is listed with GUIDs included.
+func (p *plexServer) FindItemsByProvider(mediaType, source, id string) ([]MediaServerItem, error) {
+	guid := source + "://" + id
+	params := url.Values{}
+	params.Set("includeGuids", "1")
+
+	var keys []string
+	err := p.eachSectionItem(mediaType, params, func(m plexMetadata) {
+		for _, g := range m.Guids {
+			if g.ID == guid {
+				keys = append(keys, m.RatingKey)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.metadataItems(keys)
+}
+
+func (p *plexServer) SearchItems(mediaType, title string) ([]MediaServerItem, error) {
+	params := url.Values{}
+	params.Set("title", title)
+
+	var keys []string
+	err := p.eachSectionItem(mediaType, params, func(m plexMetadata) {
+		keys = append(keys, m.RatingKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.metadataItems(keys)
+}
+
+func (p *plexServer) eachSectionItem(mediaType string, params url.Values, fn func(plexMetadata)) error {
+	sections, err := p.sections(mediaType)
+	if err != nil {
+		return err
+	}
+	params.Set("type", "1")
+	if plexSectionType(mediaType) == "show" {
+		params.Set("type", "2")
+	}
+	for _, s := range sections {
+		var result plexContainer
+		if err := p.request("GET", "/library/sections/"+url.PathEscape(s.Key)+"/all?"+params.Encode(), &result); err != nil {
+			return err
+		}
+		for _, m := range result.MediaContainer.Metadata {
+			fn(m)
+		}
+	}
+	return nil
+}
+
+// metadataItems fetches full metadata for rating keys, since section listings don't include
+// show folders
+func (p *plexServer) metadataItems(keys []string) ([]MediaServerItem, error) {
+	const batchSize = 100
+	var items []MediaServerItem
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var result plexContainer
+		if err := p.request("GET", "/library/metadata/"+strings.Join(keys[start:end], ","), &result); err != nil {
+			if err == errMediaItemNotFound {
+				continue
+			}
+			return nil, err
+		}
+		for _, m := range result.MediaContainer.Metadata {
+			items = append(items, MediaServerItem{ID: m.RatingKey, Name: m.Title, Path: m.path()})
+		}
+	}
+	return items, nil
+}
+
+func (p *plexServer) GetUsers() ([]MediaServerUser, error) {
+	var result plexContainer
+	if err := p.request("GET", "/accounts", &result); err != nil {
+		return nil, fmt.Errorf("failed to list plex accounts: %w", err)
+	}
+	var users []MediaServerUser
+	for _, a := range result.MediaContainer.Account {
+		if a.Name == "" {
+			continue
+		}
+		users = append(users, MediaServerUser{ID: strconv.Itoa(a.ID), Name: a.Name})
+	}
+	return users, nil
+}
+
+// GetPlayedItems builds a user's watched items from their Plex play history. Items marked as
+// watched without being played have no history entry, so they aren't included.
+func (p *plexServer) GetPlayedItems(userID string) ([]PlayedItem, error) {
+	const pageSize = 500
+	type plays struct {
+		count int
+		last  time.Time
+	}
+	byKey := make(map[string]*plays)
+	var keys []string
+
+	for start := 0; ; start += pageSize {
+		params := url.Values{}
+		params.Set("accountID", userID)
+		params.Set("sort", "viewedAt:desc")
+		params.Set("X-Plex-Container-Start", strconv.Itoa(start))
+		params.Set("X-Plex-Container-Size", strconv.Itoa(pageSize))
+
+		var result struct {
+			MediaContainer struct {
+				Metadata []struct {
+					RatingKey string `json:"ratingKey"`
+					Type      string `json:"type"`
+					ViewedAt  int64  `json:"viewedAt"`
+				} `json:"Metadata"`
+			} `json:"MediaContainer"`
+		}
+		if err := p.request("GET", "/status/sessions/history/all?"+params.Encode(), &result); err != nil {
+			return nil, fmt.Errorf("failed to fetch plex history: %w", err)
+		}
+
+		for _, h := range result.MediaContainer.Metadata {
+			if h.Type != "movie" && h.Type != "episode" {
+				continue
+			}
+			viewed := time.Unix(h.ViewedAt, 0)
+			if pl, ok := byKey[h.RatingKey]; ok {
+				pl.count++
+				if viewed.After(pl.last) {
+					pl.last = viewed
+				}
+				continue
+			}
+			byKey[h.RatingKey] = &plays{count: 1, last: viewed}
+			keys = append(keys, h.RatingKey)
+		}
+		if len(result.MediaContainer.Metadata) < pageSize {
+			break
+		}
+	}
+
+	// Items deleted from the library since they were played are skipped by metadataItems
+	mediaItems, err := p.metadataItems(keys)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]PlayedItem, 0, len(mediaItems))
+	for _, m := range mediaItems {
+		pl := byKey[m.ID]
+		if pl == nil || m.Path == "" {
+			continue
+		}
+		last := pl.last
+		items = append(items, PlayedItem{Path: m.Path, PlayCount: pl.count, LastPlayed: &last})
+	}
+	return items, nil
+}
+
+func (p *plexServer) GetCollectionItems(collectionID string) ([]string, error) {
+	var result plexContainer
+	if err := p.request("GET", "/library/collections/"+url.PathEscape(collectionID)+"/children", &result); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(result.MediaContainer.Metadata))
+	for i, m := range result.MediaContainer.Metadata {
+		ids[i] = m.RatingKey
+	}
+	return ids, nil
+}
+
+// CreateCollection creates a movie collection in the first movie section
+func (p *plexServer) CreateCollection(name string, itemIDs []string) (string, error) {
+	sections, err := p.sections("movie")
+	if err != nil {
+		return "", err
+	}
+	if len(sections) == 0 {
+		return "", fmt.Errorf("no plex movie section found")
+	}
+	uri, err := p.itemsURI(itemIDs)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("type", "1")
+	params.Set("title", name)
+	params.Set("smart", "0")
+	params.Set("sectionId", sections[0].Key)
+	params.Set("uri", uri)
+
+	var result plexContainer
+	if err := p.request("POST", "/library/collections?"+params.Encode(), &result); err != nil {
+		return "", err
+	}
+	if len(result.MediaContainer.Metadata) == 0 {
+		return "", fmt.Errorf("plex did not return the new collection")
+	}
+	return result.MediaContainer.Metadata[0].RatingKey, nil
+}
+
+func (p *plexServer) AddCollectionItems(collectionID string, itemIDs []string) error {
+	uri, err := p.itemsURI(itemIDs)
+	if err != nil {
+		return err
+	}
+	return p.request("PUT", "/library/collections/"+url.PathEscape(collectionID)+"/items?uri="+url.QueryEscape(uri), nil)
+}
+
+func (p *plexServer) RemoveCollectionItems(collectionID string, itemIDs []string) error {
+	for _, id := range itemIDs {
+		if err := p.request("DELETE", "/library/collections/"+url.PathEscape(collectionID)+"/items/"+url.PathEscape(id), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *plexServer) DeleteCollection(collectionID string) error {
+	return p.request("DELETE", "/library/collections/"+url.PathEscape(collectionID), nil)
+}
+
+// SetCollectionPoster has Plex fetch the poster from its URL itself
+func (p *plexServer) SetCollectionPoster(collectionID, imageURL string) error {
+	return p.request("POST", "/library/metadata/"+url.PathEscape(collectionID)+"/posters?url="+url.QueryEscape(imageURL), nil)
+}
+
+// itemsURI builds the server:// URI Plex uses to reference library items in collection calls
+func (p *plexServer) itemsURI(itemIDs []string) (string, error) {
+	var result plexContainer
+	if err := p.request("GET", "/identity", &result); err != nil {
+		return "", fmt.Errorf("failed to get plex machine identifier: %w", err)
+	}
+	return fmt.Sprintf("server://%s/com.plexapp.plugins.library/library/metadata/%s",
+		result.MediaContainer.MachineIdentifier, strings.Join(itemIDs, ",")), nil
+}
+
+// request sends an authenticated request to Plex and decodes the JSON response into out when
+// it's non-nil. A 404 is reported as errMediaItemNotFound.
+func (p *plexServer) request(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, strings.TrimRight(p.cfg.PlexURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Plex-Token", p.cfg.PlexToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := plexClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("plex request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errMediaItemNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("plex returned status %d", resp.StatusCode)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode plex response: %w", err)
+		}
+	}
+	return nil
+}
@@ -62,3 +62,33 @@ func CompareQuality(q1, q2 string) int {
 	}
 	return 0
 }
+
+// libraryResults keeps the search results a library accepts: the 4K library only takes 2160p
+// releases, and while a 4K library exists for the media type the standard one leaves them to it
+func libraryResults(results []TorrentSearchResult, mediaType string, is4K bool) []TorrentSearchResult {
+	if !is4K && !Has4KLibrary(mediaType) {
+		return results
+	}
+	var kept []TorrentSearchResult
+	for _, r := range results {
+		if (DetectQuality(r.Title+" "+r.Resolution) == Quality4K) == is4K {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// editionResults keeps the search results of the requested movie edition, or all of them when
+// any edition will do
+func editionResults(results []TorrentSearchResult, edition string) []TorrentSearchResult {
+	if edition == "" {
+		return results
+	}
+	var kept []TorrentSearchResult
+	for _, r := range results {
+		if SameEdition(DetectEdition(r.Title), edition) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
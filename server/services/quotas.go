@@ -0,0 +1,134 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuotaOverride is a user's own quota, which takes precedence over their role's. Nil falls
+// back to the role.
+type QuotaOverride struct {
+	MovieQuota  *int `json:"movie_quota"`
+	SeasonQuota *int `json:"season_quota"`
+}
+
+// nullableQuota converts a nullable quota column, where NULL means unlimited
+func nullableQuota(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func validateQuotas(quotas ...*int) error {
+	for _, q := range quotas {
+		if q != nil && *q < 0 {
+			return fmt.Errorf("quotas can't be negative")
+		}
+	}
+	return nil
+}
+
+// RequestSeasonCount is how many seasons a show request counts as against the season quota.
+// A request for individual episodes counts as one season.
+func RequestSeasonCount(req models.Request) int {
+	if req.MediaType != "show" {
+		return 0
+	}
+	count := 0
+	for _, s := range strings.Split(req.Seasons, ",") {
+		if strings.TrimSpace(s) != "" {
+			count++
+		}
+	}
+	if count == 0 && strings.TrimSpace(req.Episodes) != "" {
+		count = 1
+	}
+	return count
+}
+
+// GetUserQuota returns a user's limits and how much of them they've used in the last
+// models.QuotaWindow. Admins are never limited.
+func GetUserQuota(user *models.User) (*models.Quota, error) {
+	var quota models.Quota
+	if !user.IsAdmin {
+		var movieQuota, seasonQuota sql.NullInt64
+		err := database.DB.QueryRow(`
+			SELECT COALESCE(u.movie_quota, r.movie_quota), COALESCE(u.season_quota, r.season_quota)
+			FROM `+userWithRole+` WHERE u.id = $1`, user.ID).Scan(&movieQuota, &seasonQuota)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load quota: %w", err)
+		}
+		quota.MovieLimit = nullableQuota(movieQuota)
+		quota.SeasonLimit = nullableQuota(seasonQuota)
+	}
+
+	since := time.Now().Add(-models.QuotaWindow)
+	var oldest sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE media_type = 'movie'), COALESCE(SUM(seasons), 0), MIN(created_at)
+		FROM request_usage WHERE user_id = $1 AND created_at > $2`, user.ID, since).Scan(&quota.MoviesUsed, &quota.SeasonsUsed, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count requests: %w", err)
+	}
+	if oldest.Valid {
+		next := oldest.Time.Add(models.QuotaWindow)
+		quota.NextFreeAt = &next
+	}
+	return &quota, nil
+}
+
+// RecordRequestUsage counts a submitted request against the user's quota
+func RecordRequestUsage(userID int64, requestID int, req models.Request) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO request_usage (user_id, request_id, media_type, seasons) VALUES ($1, $2, $3, $4)`,
+		userID, requestID, req.MediaType, RequestSeasonCount(req))
+	return err
+}
+
+// RefundRequestUsage gives back the quota used by a request, e.g. when it's denied
+func RefundRequestUsage(requestID int) error {
+	_, err := database.DB.Exec("DELETE FROM request_usage WHERE request_id = $1", requestID)
+	return err
+}
+
+// GetUserQuotaOverrides returns the users that have their own quota, keyed by user ID
+func GetUserQuotaOverrides() (map[int64]QuotaOverride, error) {
+	rows, err := database.DB.Query("SELECT id, movie_quota, season_quota FROM users WHERE movie_quota IS NOT NULL OR season_quota IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user quotas: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[int64]QuotaOverride)
+	for rows.Next() {
+		var id int64
+		var movieQuota, seasonQuota sql.NullInt64
+		if err := rows.Scan(&id, &movieQuota, &seasonQuota); err != nil {
+			return nil, fmt.Errorf("failed to scan user quota: %w", err)
+		}
+		overrides[id] = QuotaOverride{MovieQuota: nullableQuota(movieQuota), SeasonQuota: nullableQuota(seasonQuota)}
+	}
+	return overrides, rows.Err()
+}
+
+// SetUserQuota sets a user's own quota. Nil quotas fall back to the user's role.
+func SetUserQuota(userID int64, override QuotaOverride) error {
+	if err := validateQuotas(override.MovieQuota, override.SeasonQuota); err != nil {
+		return err
+	}
+	res, err := database.DB.Exec("UPDATE users SET movie_quota = $1, season_quota = $2, updated_at = NOW() WHERE id = $3",
+		override.MovieQuota, override.SeasonQuota, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user quota: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// RecyclePath moves a replaced or deleted file or folder to the recycle bin, recording where
+// it came from and the library rows that pointed at it. Without a recycle bin it's deleted.
+func RecyclePath(cfg *config.Config, path, reason string) error {
+	return recycle(cfg, path, path, reason, nil)
+}
+
+// recycle moves path to the recycle bin as originalPath, which differs when the item was
+// already held elsewhere, like a finalized batch's undo folder. records are the library rows
+// to restore with it; when nil they're read from the database.
+func recycle(cfg *config.Config, path, originalPath, reason string, records []byte) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil // Already gone
+	}
+	if err != nil {
+		return err
+	}
+	if !cfg.RecycleBinEnabled() {
+		return os.RemoveAll(path)
+	}
+
+	if records == nil {
+		if records, err = snapshotLibraryRows(originalPath); err != nil {
+			slog.Warn("Failed to save library rows of recycled item", "path", originalPath, "error", err)
+		}
+	}
+
+	var id int64
+	err = database.DB.QueryRow(`
+		INSERT INTO recycle_bin (original_path, reason, size, is_dir, records)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		originalPath, reason, pathSize(path), info.IsDir(), records).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to record recycled item: %w", err)
+	}
+
+	// Each item gets its own folder, so items with the same name don't collide
+	dest := filepath.Join(cfg.RecycleBinPath, strconv.FormatInt(id, 10), filepath.Base(originalPath))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		database.DB.Exec("DELETE FROM recycle_bin WHERE id = $1", id)
+		return err
+	}
+	if err := moveTree(path, dest); err != nil {
+		database.DB.Exec("DELETE FROM recycle_bin WHERE id = $1", id)
+		os.Remove(filepath.Dir(dest))
+		return fmt.Errorf("failed to move %s to the recycle bin: %w", path, err)
+	}
+	database.DB.Exec("UPDATE recycle_bin SET recycled_path = $2 WHERE id = $1", id, dest)
+
+	slog.Info("Moved to recycle bin", "path", originalPath, "reason", reason, "recycled_path", dest)
+	return nil
+}
+
+// moveTree moves a file or folder, copying it when the destination is on another filesystem
+func moveTree(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return safeRename(src, dst)
+	}
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return safeRename(path, target)
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// GetRecycledItems returns everything in the recycle bin, newest first
+func GetRecycledItems() ([]models.RecycledItem, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, original_path, recycled_path, reason, size, is_dir, deleted_at
+		FROM recycle_bin ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recycle bin: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.RecycledItem{}
+	for rows.Next() {
+		var item models.RecycledItem
+		if err := rows.Scan(&item.ID, &item.OriginalPath, &item.RecycledPath, &item.Reason, &item.Size, &item.IsDir, &item.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recycled item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func getRecycledItem(id int64) (*models.RecycledItem, error) {
+	var item models.RecycledItem
+	err := database.DB.QueryRow(`
+		SELECT id, original_path, recycled_path, reason, size, is_dir, deleted_at
+		FROM recycle_bin WHERE id = $1`, id).
+		Scan(&item.ID, &item.OriginalPath, &item.RecycledPath, &item.Reason, &item.Size, &item.IsDir, &item.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	return &item, err
+}
+
+// RestoreRecycledItem moves an item back to its original path and puts its library rows back
+func RestoreRecycledItem(cfg *config.Config, id int64) error {
+	item, err := getRecycledItem(id)
+	if err != nil {
+		return err
+	}
+
+	unlock := lockPath(item.OriginalPath)
+	defer unlock()
+
+	if _, err := os.Stat(item.OriginalPath); err == nil {
+		return fmt.Errorf("%s exists again, move it away first", item.OriginalPath)
+	}
+	if _, err := os.Stat(item.RecycledPath); err != nil {
+		return fmt.Errorf("the recycled copy is missing")
+	}
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return err
+	}
+	if err := moveTree(item.RecycledPath, item.OriginalPath); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", item.OriginalPath, err)
+	}
+
+	var records sql.NullString
+	database.DB.QueryRow("SELECT records FROM recycle_bin WHERE id = $1", id).Scan(&records)
+	if records.Valid {
+		if err := restoreLibraryRecords(records.String); err != nil {
+			slog.Error("Failed to restore library rows of recycled item", "path", item.OriginalPath, "error", err)
+		}
+	}
+
+	database.DB.Exec("DELETE FROM recycle_bin WHERE id = $1", id)
+	os.Remove(filepath.Dir(item.RecycledPath))
+
+	if libraryRoot(cfg, item.OriginalPath) != "" {
+		QueueMediaServerLibraryRefresh(cfg)
+	}
+	slog.Info("Restored from recycle bin", "path", item.OriginalPath)
+	return nil
+}
+
+// DeleteRecycledItem permanently deletes an item in the recycle bin
+func DeleteRecycledItem(cfg *config.Config, id int64) error {
+	item, err := getRecycledItem(id)
+	if err != nil {
+		return err
+	}
+	return purgeRecycledItem(cfg, *item)
+}
+
+func purgeRecycledItem(cfg *config.Config, item models.RecycledItem) error {
+	if cfg.RecycleBinPath != "" {
+		// The item's own folder, <bin>/<id>, never the bin itself
+		dir := filepath.Join(cfg.RecycleBinPath, strconv.FormatInt(item.ID, 10))
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", dir, err)
+		}
+	}
+	_, err := database.DB.Exec("DELETE FROM recycle_bin WHERE id = $1", item.ID)
+	return err
+}
+
+// EmptyRecycleBin permanently deletes everything in the recycle bin
+func EmptyRecycleBin(cfg *config.Config) (int, error) {
+	items, err := GetRecycledItems()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, item := range items {
+		if err := purgeRecycledItem(cfg, item); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	slog.Info("Emptied recycle bin", "items", deleted)
+	return deleted, nil
+}
+
+// PurgeRecycleBin deletes items older than RECYCLE_BIN_DAYS, then the oldest items while free
+// space on the recycle bin's disk is below RECYCLE_BIN_MIN_FREE_GB
+func PurgeRecycleBin(cfg *config.Config) (int, error) {
+	items, err := GetRecycledItems()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	cutoff := time.Now().AddDate(0, 0, -cfg.RecycleBinDays)
+	kept := items[:0]
+	for _, item := range items {
+		if cfg.RecycleBinDays > 0 && item.DeletedAt.Before(cutoff) {
+			if err := purgeRecycledItem(cfg, item); err != nil {
+				slog.Error("Failed to purge recycled item", "path", item.OriginalPath, "error", err)
+				continue
+			}
+			purged++
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	if cfg.RecycleBinMinFreeGB > 0 {
+		minFree := uint64(cfg.RecycleBinMinFreeGB * 1024 * 1024 * 1024)
+		// Oldest first; items are sorted newest first
+		for i := len(kept) - 1; i >= 0; i-- {
+			free, err := diskFree(cfg.RecycleBinPath)
+			if err != nil {
+				slog.Warn("Failed to check free space for recycle bin", "path", cfg.RecycleBinPath, "error", err)
+				break
+			}
+			if free >= minFree {
+				break
+			}
+			if err := purgeRecycledItem(cfg, kept[i]); err != nil {
+				slog.Error("Failed to purge recycled item", "path", kept[i].OriginalPath, "error", err)
+				continue
+			}
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		slog.Info("Purged recycle bin", "items", purged)
+	}
+	return purged, nil
+}
+
+// StartRecycleBinWorker periodically purges old items from the recycle bin when it's enabled
+func StartRecycleBinWorker(cfg *config.Config) {
+	if !cfg.RecycleBinEnabled() {
+		return
+	}
+
+	slog.Info("Starting recycle bin purge background worker", "path", cfg.RecycleBinPath, "days", cfg.RecycleBinDays, "min_free_gb", cfg.RecycleBinMinFreeGB)
+
+	purge := func() {
+		if _, err := PurgeRecycleBin(cfg); err != nil {
+			slog.Error("Error purging recycle bin", "error", err)
+		}
+	}
+
+	go func() {
+		purge()
+
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
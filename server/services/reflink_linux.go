@@ -0,0 +1,32 @@
+//go:build linux
+
+package services
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, which makes dst share src's data blocks copy-on-write
+const ficlone = 0x40049409
+
+// reflinkFile creates dst as a reflink of src on filesystems that support it (btrfs, XFS,
+// bcachefs). It fails on others, and across filesystems.
+func reflinkFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	dest, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dest.Fd(), ficlone, source.Fd()); errno != 0 {
+		dest.Close()
+		os.Remove(dst)
+		return errno
+	}
+	return dest.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package services
+
+import "errors"
+
+// reflinkFile is only implemented on Linux
+func reflinkFile(src, dst string) error {
+	return errors.ErrUnsupported
+}
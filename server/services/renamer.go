@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -161,14 +162,17 @@ func normalizeForComparison(title string) string {
 	return strings.ToLower(strings.TrimSpace(normalized))
 }
 
-// moveAncillaryFiles moves non-video ancillary media files (posters, fanart, banners, NFOs,
-// subtitles, etc.) from oldPath to newPath, preserving directory structure. This is used when
+// moveAncillaryFiles moves ancillary media files (posters, fanart, banners, NFOs, subtitles,
+// extras subfolders, etc.) from oldPath to newPath, preserving directory structure. This is used when
 // renaming show/movie folders within the library to preserve Jellyfin metadata and artwork.
 func moveAncillaryFiles(oldPath, newPath string) {
 	ancillaryExts := map[string]bool{
 		".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".gif": true, ".tbn": true,
 		".nfo": true, ".xml": true,
-		".srt": true, ".ass": true, ".ssa": true, ".sub": true, ".idx": true, ".vtt": true,
+		".orig": true,
+	}
+	for ext := range subtitleExts {
+		ancillaryExts[ext] = true
 	}
 
 	err := filepath.Walk(oldPath, func(path string, info os.FileInfo, err error) error {
@@ -176,13 +180,15 @@ func moveAncillaryFiles(oldPath, newPath string) {
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if !ancillaryExts[ext] {
+		relPath, err := filepath.Rel(oldPath, path)
+		if err != nil {
 			return nil
 		}
 
-		relPath, err := filepath.Rel(oldPath, path)
-		if err != nil {
+		// Videos only come along when they're extras in a Trailers, Featurettes, etc. subfolder
+		ext := strings.ToLower(filepath.Ext(path))
+		inExtras := strings.Contains(relPath, string(filepath.Separator)) && isExtraFolder(strings.Split(relPath, string(filepath.Separator))[0])
+		if !ancillaryExts[ext] && !(MovieExtensions[ext] && inExtras) {
 			return nil
 		}
 
@@ -198,6 +204,9 @@ func moveAncillaryFiles(oldPath, newPath string) {
 			slog.Error("Failed to move ancillary file", "from", path, "to", destPath, "error", err)
 		} else {
 			slog.Info("Moved ancillary media file", "from", path, "to", destPath)
+			if ext == ".orig" || subtitleExts[ext] {
+				moveSubtitleSync(path, destPath)
+			}
 		}
 		return nil
 	})
@@ -359,8 +368,8 @@ func RenameAndMoveMovie(cfg *config.Config, movieID int) error {
 func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bool) error {
 	var m models.Movie
 	var torrentHash sql.NullString
-	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, size, poster_path, torrent_hash FROM movies WHERE id = $1`
-	err := database.DB.QueryRow(query, movieID).Scan(&m.ID, &m.Title, &m.Year, &m.TMDBID, &m.IMDBID, &m.Path, &m.Quality, &m.Size, &m.PosterPath, &torrentHash)
+	query := `SELECT id, title, year, tmdb_id, imdb_id, path, quality, COALESCE(edition, ''), size, poster_path, torrent_hash FROM movies WHERE id = $1`
+	err := database.DB.QueryRow(query, movieID).Scan(&m.ID, &m.Title, &m.Year, &m.TMDBID, &m.IMDBID, &m.Path, &m.Quality, &m.Edition, &m.Size, &m.PosterPath, &torrentHash)
 	if err != nil {
 		return err
 	}
@@ -369,19 +378,11 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 		return fmt.Errorf("movie must be matched before renaming")
 	}
 
+	// Destination comes from the naming templates, by default Movies/Title (Year) {tmdb-id} [Quality]/
 	ext := filepath.Ext(m.Path)
-	sanitizedTitle := sanitizePath(m.Title)
-
-	qualitySuffix := ""
-	if m.Quality != "" {
-		qualitySuffix = fmt.Sprintf(" [%s]", sanitizePath(m.Quality))
-	}
-
-	newName := fmt.Sprintf("%s (%d) {tmdb-%s}%s%s", sanitizedTitle, m.Year, m.TMDBID, qualitySuffix, ext)
-
-	// Create destination directory: Movies/Title (Year) {tmdb-id} [Quality]/
-	destDirName := fmt.Sprintf("%s (%d) {tmdb-%s}%s", sanitizedTitle, m.Year, m.TMDBID, qualitySuffix)
-	destDirPath := filepath.Join(cfg.MoviesPath, destDirName)
+	templates := GetNamingTemplates()
+	destDirPath, baseName := movieDestination(movieRootFolder(cfg, m, templates), m, templates)
+	newName := baseName + ext
 
 	// Check for existing folder with different casing
 	destDirPath = findExistingDirCaseInsensitive(destDirPath)
@@ -417,7 +418,9 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 		destPath, m.Path, m.Quality, m.Size,
 		"SELECT quality, size FROM movies WHERE path = $1",
 		func() error {
-			os.Remove(m.Path)
+			if err := RecyclePath(cfg, m.Path, "Library already has a better copy"); err != nil {
+				slog.Error("Failed to remove lower quality movie", "path", m.Path, "error", err)
+			}
 			database.DB.Exec("DELETE FROM movies WHERE id = $1", m.ID)
 			if doCleanup {
 				CleanupEmptyDirs(cfg.IncomingMoviesPath)
@@ -425,7 +428,9 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 			return nil
 		},
 		func() error {
-			os.Remove(destPath)
+			if err := RecyclePath(cfg, destPath, "Replaced by "+filepath.Base(m.Path)); err != nil {
+				slog.Error("Failed to remove replaced movie", "path", destPath, "error", err)
+			}
 			database.DB.Exec("DELETE FROM movies WHERE path = $1", destPath)
 			return nil
 		},
@@ -434,27 +439,28 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 		return nil
 	}
 
-	// Check seeding criteria BEFORE moving - if still seeding, copy instead of move
+	// Check seeding criteria BEFORE moving - if still seeding, keep the original for qBittorrent
 	oldPath := m.Path
-	shouldCopyInsteadOfMove := false
+	keepOriginal := false
 	if torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingMoviesPath) {
 		if qb, err := NewQBittorrentClient(cfg); err == nil {
 			ctx := context.Background()
 			meetsCriteria, err := CheckSeedingCriteriaOnImport(ctx, cfg, qb, torrentHash.String)
 			if err == nil && !meetsCriteria {
-				// Still seeding, copy instead of move to keep original for seeding
-				shouldCopyInsteadOfMove = true
-				slog.Info("Movie still seeding, copying instead of moving", "movie_id", m.ID, "torrent_hash", torrentHash.String)
+				// Still seeding, so the original has to stay where qBittorrent expects it
+				keepOriginal = true
+				slog.Info("Movie still seeding, keeping the original", "movie_id", m.ID, "torrent_hash", torrentHash.String)
 			}
 		}
 	}
 
-	// Move or copy the file based on seeding status
-	if shouldCopyInsteadOfMove {
-		if err := copyFile(oldPath, destPath); err != nil {
+	// Move the file, or copy or link it if it's still seeding
+	importMethod := ImportMoved
+	if keepOriginal {
+		if importMethod, err = importSeedingFile(cfg, oldPath, destPath); err != nil {
 			return err
 		}
-		slog.Info("Successfully copied movie (still seeding)", "old_path", oldPath, "new_path", destPath)
+		slog.Info("Successfully imported movie (still seeding)", "old_path", oldPath, "new_path", destPath, "method", importMethod)
 	} else {
 		if err := safeRename(oldPath, destPath); err != nil {
 			return err
@@ -462,6 +468,15 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 		slog.Info("Successfully moved movie", "old_path", oldPath, "new_path", destPath)
 	}
 
+	// Bring subtitles along: clean up and import any shipped with a release, or keep
+	// existing library subtitles matching the renamed video
+	if strings.HasPrefix(oldPath, cfg.IncomingMoviesPath) {
+		importReleaseSubtitles(oldPath, destPath, filepath.Dir(oldPath) != cfg.IncomingMoviesPath, cfg.SubtitleRemoveHI)
+		importReleaseExtras(cfg, oldPath, destDirPath, keepOriginal)
+	} else {
+		moveVideoSubtitles(oldPath, destPath)
+	}
+
 	writeMovieNFO(destDirPath, m.TMDBID)
 
 	// Copy the poster if it exists and is in the same directory as the movie
@@ -491,7 +506,7 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 	// Cleanup old directory if it was in incoming (only if requested)
 	if doCleanup {
 		CleanupEmptyDirs(cfg.IncomingMoviesPath)
-	} else if !shouldCopyInsteadOfMove {
+	} else if !keepOriginal {
 		// Clean up the specific old directory if it's now empty (and not the root library path)
 		oldDir := filepath.Dir(oldPath)
 		if oldDir != cfg.MoviesPath && oldDir != cfg.IncomingMoviesPath {
@@ -506,9 +521,15 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 	if err != nil {
 		return err
 	}
+	if strings.HasPrefix(oldPath, cfg.IncomingMoviesPath) {
+		recordImportMethod("movies", m.ID, importMethod, oldPath)
+		go FetchMovieTrailer(cfg, m.TMDBID, destDirPath, baseName)
+	}
 
-	// Check seeding criteria and clean up torrent if needed (only if we moved, not copied)
-	if !shouldCopyInsteadOfMove && torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingMoviesPath) {
+	QueueMediaServerMovieRefresh(cfg, m.TMDBID, destPath)
+
+	// Check seeding criteria and clean up torrent if needed (only if we moved, not copied or linked)
+	if !keepOriginal && torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingMoviesPath) {
 		// Try to get qBittorrent client
 		if qb, err := NewQBittorrentClient(cfg); err == nil {
 			go func() {
@@ -520,12 +541,14 @@ func RenameAndMoveMovieWithCleanup(cfg *config.Config, movieID int, doCleanup bo
 		}
 	}
 
-	// Trigger subtitle download
-	go func() {
-		if err := globalSubtitle.DownloadSubtitlesForMovie(m.ID); err != nil {
-			slog.Error("Subtitle download failed for movie", "movie_id", m.ID, "title", m.Title, "error", err)
-		}
-	}()
+	// Trigger subtitle download if the release didn't come with one
+	if !HasSubtitles(destPath) {
+		go func() {
+			if err := globalSubtitle.DownloadSubtitlesForMovie(m.ID); err != nil {
+				slog.Error("Subtitle download failed for movie", "movie_id", m.ID, "title", m.Title, "error", err)
+			}
+		}()
+	}
 
 	return nil
 }
@@ -576,53 +599,31 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 	var torrentHash sql.NullString
 
 	query := `
-		SELECT e.id, e.episode_number, e.title, e.file_path, e.quality, e.size, e.torrent_hash, s.season_number, sh.id, sh.title, sh.year, sh.tvdb_id, sh.imdb_id, sh.poster_path
+		SELECT e.id, e.episode_number, e.title, e.file_path, e.quality, e.size, e.torrent_hash, s.season_number, sh.id, sh.title, sh.year, sh.tvdb_id, COALESCE(sh.tmdb_id, ''), sh.imdb_id, sh.path, sh.poster_path
 		FROM episodes e
 		JOIN seasons s ON e.season_id = s.id
 		JOIN shows sh ON s.show_id = sh.id
 		WHERE e.id = $1
 	`
-	err := database.DB.QueryRow(query, episodeID).Scan(&e.ID, &e.EpisodeNumber, &e.Title, &e.FilePath, &e.Quality, &e.Size, &torrentHash, &s.SeasonNumber, &sh.ID, &sh.Title, &sh.Year, &sh.TVDBID, &sh.IMDBID, &sh.PosterPath)
+	err := database.DB.QueryRow(query, episodeID).Scan(&e.ID, &e.EpisodeNumber, &e.Title, &e.FilePath, &e.Quality, &e.Size, &torrentHash, &s.SeasonNumber, &sh.ID, &sh.Title, &sh.Year, &sh.TVDBID, &sh.TMDBID, &sh.IMDBID, &sh.Path, &sh.PosterPath)
 	if err != nil {
 		return err
 	}
 
-	// TV Shows: Title (Year) {tvdb-ID}/Season XX/Title - SXXEXX - Episode Title.ext
-	ext := filepath.Ext(e.FilePath)
-
-	sanitizedShowTitle := sanitizePath(sh.Title)
+	// An episode goes to the library its root or quality puts it in, which isn't always the
+	// library of the show it was scanned into, e.g. a 4K episode of a show in the standard one
+	is4K := wants4K(cfg, "show", e.FilePath, e.Quality)
+	otherLibrary := is4K != wants4K(cfg, "show", sh.Path, showQuality(sh.ID))
 
-	// Better episode title cleaning
-	epTitle := e.Title
-	// Only run the scene-name cleaner if the title actually looks like a raw scene filename:
-	// dots-as-separators OR explicit quality/codec tags. Hyphens alone are not a signal
-	// (many real episode titles contain them, e.g. "Spider-Man", "Step-by-Step").
-	sceneTagRegex := regexp.MustCompile(`(?i)\b(1080p|720p|2160p|480p|WEB-DL|WEBRip|BluRay|BDRip|x264|x265|HEVC|H264|H265)\b`)
-	if strings.Contains(epTitle, ".") || sceneTagRegex.MatchString(epTitle) {
-		// Clean the episode title using the shared parser
-		epTitle, _, _, _, _ = ParseMediaName(epTitle)
-	}
-
-	if epTitle == "" || strings.EqualFold(epTitle, sh.Title) {
-		epTitle = fmt.Sprintf("Episode %d", e.EpisodeNumber)
-	}
-
-	// Strip show title prefix from episode title to avoid recursive and redundant naming
-	epTitle = StripShowTitlePrefix(epTitle, sh.Title)
-
-	sanitizedEpTitle := sanitizePath(epTitle)
-
-	showDirName := fmt.Sprintf("%s (%d)", sanitizedShowTitle, sh.Year)
-	if sh.TVDBID != "" {
-		showDirName = fmt.Sprintf("%s (%d) {tvdb-%s}", sanitizedShowTitle, sh.Year, sh.TVDBID)
-	}
+	// TV Shows come from the naming templates, by default
+	// Title (Year) {tvdb-ID}/Season XX/Title - SXXEXX - Episode Title.ext
+	ext := filepath.Ext(e.FilePath)
+	showDirName, seasonDirName, baseName := episodeDestination(sh, s.SeasonNumber, e, GetNamingTemplates())
 
 	// Check for existing show folder with different casing/punctuation to avoid creating duplicates
 	// (e.g., "Star Trek Deep Space Nine" vs "Star Trek - Deep Space Nine")
-	showDirPath := findExistingDirCaseInsensitive(filepath.Join(cfg.ShowsPath, showDirName))
-	seasonDirName := fmt.Sprintf("Season %02d", s.SeasonNumber)
-
-	newFileName := fmt.Sprintf("%s - S%02dE%02d - %s%s", sanitizedShowTitle, s.SeasonNumber, e.EpisodeNumber, sanitizedEpTitle, ext)
+	showDirPath := findExistingDirCaseInsensitive(filepath.Join(showRootFolder(cfg, sh, showDirName, is4K), showDirName))
+	newFileName := baseName + ext
 
 	destDirPath := filepath.Join(showDirPath, seasonDirName)
 	destPath := filepath.Join(destDirPath, newFileName)
@@ -654,7 +655,9 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		destPath, e.FilePath, e.Quality, e.Size,
 		"SELECT quality, size FROM episodes WHERE file_path = $1",
 		func() error {
-			os.Remove(e.FilePath)
+			if err := RecyclePath(cfg, e.FilePath, "Library already has a better copy"); err != nil {
+				slog.Error("Failed to remove lower quality episode", "path", e.FilePath, "error", err)
+			}
 			database.DB.Exec("DELETE FROM episodes WHERE id = $1", e.ID)
 			if doCleanup {
 				CleanupEmptyDirs(cfg.IncomingShowsPath)
@@ -662,7 +665,9 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 			return nil
 		},
 		func() error {
-			os.Remove(destPath)
+			if err := RecyclePath(cfg, destPath, "Replaced by "+filepath.Base(e.FilePath)); err != nil {
+				slog.Error("Failed to remove replaced episode", "path", destPath, "error", err)
+			}
 			database.DB.Exec("DELETE FROM episodes WHERE file_path = $1", destPath)
 			return nil
 		},
@@ -671,27 +676,28 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		return nil
 	}
 
-	// Check seeding criteria BEFORE moving - if still seeding, copy instead of move
+	// Check seeding criteria BEFORE moving - if still seeding, keep the original for qBittorrent
 	oldPath := e.FilePath
-	shouldCopyInsteadOfMove := false
+	keepOriginal := false
 	if torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingShowsPath) {
 		if qb, err := NewQBittorrentClient(cfg); err == nil {
 			ctx := context.Background()
 			meetsCriteria, err := CheckSeedingCriteriaOnImport(ctx, cfg, qb, torrentHash.String)
 			if err == nil && !meetsCriteria {
-				// Still seeding, copy instead of move to keep original for seeding
-				shouldCopyInsteadOfMove = true
-				slog.Info("Episode still seeding, copying instead of moving", "episode_id", e.ID, "torrent_hash", torrentHash.String)
+				// Still seeding, so the original has to stay where qBittorrent expects it
+				keepOriginal = true
+				slog.Info("Episode still seeding, keeping the original", "episode_id", e.ID, "torrent_hash", torrentHash.String)
 			}
 		}
 	}
 
-	// Move or copy the file based on seeding status
-	if shouldCopyInsteadOfMove {
-		if err := copyFile(oldPath, destPath); err != nil {
+	// Move the file, or copy or link it if it's still seeding
+	importMethod := ImportMoved
+	if keepOriginal {
+		if importMethod, err = importSeedingFile(cfg, oldPath, destPath); err != nil {
 			return err
 		}
-		slog.Info("Successfully copied episode (still seeding)", "old_path", oldPath, "new_path", destPath)
+		slog.Info("Successfully imported episode (still seeding)", "old_path", oldPath, "new_path", destPath, "method", importMethod)
 	} else {
 		if err := safeRename(oldPath, destPath); err != nil {
 			return err
@@ -699,19 +705,37 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		slog.Info("Successfully moved episode", "old_path", oldPath, "new_path", destPath)
 	}
 
+	if strings.HasPrefix(oldPath, cfg.IncomingShowsPath) {
+		importReleaseSubtitles(oldPath, destPath, filepath.Dir(oldPath) != cfg.IncomingShowsPath, cfg.SubtitleRemoveHI)
+	} else {
+		moveVideoSubtitles(oldPath, destPath)
+	}
+
 	// Update DB with new path, mark as imported
 	updateQuery := `UPDATE episodes SET file_path = $1, imported_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
 	_, err = database.DB.Exec(updateQuery, destPath, e.ID)
 	if err != nil {
 		return err
 	}
+	if strings.HasPrefix(oldPath, cfg.IncomingShowsPath) {
+		recordImportMethod("episodes", e.ID, importMethod, oldPath)
+	}
+	if otherLibrary {
+		if showID, err := moveEpisodeToShowCopy(sh, showDirPath, s.SeasonNumber, e.ID); err != nil {
+			slog.Error("Failed to move episode to the show in its library", "episode_id", e.ID, "show_path", showDirPath, "error", err)
+		} else {
+			sh.ID = showID
+		}
+	}
+
+	QueueMediaServerShowRefresh(cfg, sh.TVDBID, showDirPath)
 
 	// Rescan the show directory to ensure all episodes are detected and added to the database
 	// This is important after importing episodes so the library is up-to-date
 	if !skipRescan {
 		resolvedShowDirPath := showDirPath // capture already-resolved path (findExistingDirCaseInsensitive applied)
 		go func() {
-			scanSeasons(sh.ID, resolvedShowDirPath, sh.Title, cfg, nil)
+			indexScannedEpisodes(scanSeasons(sh.ID, resolvedShowDirPath, sh.Title, cfg, nil))
 			slog.Info("Rescanned show directory after episode import",
 				"show_id", sh.ID,
 				"show_title", sh.Title,
@@ -720,8 +744,8 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		}()
 	}
 
-	// Check seeding criteria and clean up torrent if needed (only if we moved, not copied)
-	if !shouldCopyInsteadOfMove && torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingShowsPath) {
+	// Check seeding criteria and clean up torrent if needed (only if we moved, not copied or linked)
+	if !keepOriginal && torrentHash.Valid && torrentHash.String != "" && strings.HasPrefix(oldPath, cfg.IncomingShowsPath) {
 		// Try to get qBittorrent client
 		if qb, err := NewQBittorrentClient(cfg); err == nil {
 			go func() {
@@ -735,10 +759,10 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		}
 	}
 
-	if !shouldCopyInsteadOfMove {
+	if !keepOriginal {
 		// Clean up the specific old directory if it's now empty
 		oldDir := filepath.Dir(oldPath)
-		if !strings.HasPrefix(oldDir, cfg.ShowsPath) || oldDir != cfg.ShowsPath {
+		if !slices.Contains(LibraryRoots(cfg, "show"), oldDir) {
 			os.Remove(oldDir)
 		}
 	}
@@ -748,17 +772,19 @@ func renameAndMoveEpisodeInternal(cfg *config.Config, episodeID int, doCleanup b
 		CleanupEmptyDirs(cfg.IncomingShowsPath)
 	}
 
-	// Trigger subtitle download for episode
-	go func() {
-		if err := globalSubtitle.DownloadSubtitlesForEpisode(e.ID); err != nil {
-			slog.Error("Subtitle download failed for episode",
-				"episode_id", e.ID,
-				"show_title", sh.Title,
-				"season", s.SeasonNumber,
-				"episode", e.EpisodeNumber,
-				"error", err)
-		}
-	}()
+	// Trigger subtitle download for episode if the release didn't come with one
+	if !HasSubtitles(destPath) {
+		go func() {
+			if err := globalSubtitle.DownloadSubtitlesForEpisode(e.ID); err != nil {
+				slog.Error("Subtitle download failed for episode",
+					"episode_id", e.ID,
+					"show_title", sh.Title,
+					"season", s.SeasonNumber,
+					"episode", e.EpisodeNumber,
+					"error", err)
+			}
+		}()
+	}
 
 	return nil
 }
@@ -774,14 +800,8 @@ func RenameAndMoveShowWithCleanup(cfg *config.Config, showID int, doCleanup bool
 		return err
 	}
 
-	sanitizedShowTitle := sanitizePath(sh.Title)
-	var showDirName string
-	if sh.TVDBID != "" {
-		showDirName = fmt.Sprintf("%s {tvdb-%s}", sanitizedShowTitle, sh.TVDBID)
-	} else {
-		showDirName = sanitizedShowTitle
-	}
-	destShowPath := filepath.Join(cfg.ShowsPath, showDirName)
+	showDirName := showFolderName(sh, GetNamingTemplates())
+	destShowPath := filepath.Join(showRootFolder(cfg, sh, showDirName, wants4K(cfg, "show", sh.Path, showQuality(showID))), showDirName)
 
 	// Check for existing folder with different casing (e.g., "BOFURI" vs "Bofuri")
 	destShowPath = findExistingDirCaseInsensitive(destShowPath)
@@ -904,6 +924,8 @@ func RenameAndMoveShowWithCleanup(cfg *config.Config, showID int, doCleanup bool
 		}
 	}
 
+	QueueMediaServerShowRefresh(cfg, sh.TVDBID, destShowPath)
+
 	// Always cleanup empty directories in incoming if the show was in incoming
 	// This ensures empty directories are removed after episodes are moved/copied
 	if strings.HasPrefix(sh.Path, cfg.IncomingShowsPath) {
@@ -912,7 +934,7 @@ func RenameAndMoveShowWithCleanup(cfg *config.Config, showID int, doCleanup bool
 
 	// Rescan the show directory once for all imported episodes
 	go func() {
-		scanSeasons(showID, destShowPath, sh.Title, cfg, nil)
+		indexScannedEpisodes(scanSeasons(showID, destShowPath, sh.Title, cfg, nil))
 		slog.Info("Rescanned show directory after full show import", "show_id", showID)
 	}()
 
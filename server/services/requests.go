@@ -7,6 +7,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"slices"
 	"strconv"
@@ -23,13 +24,20 @@ type LibraryStatus struct {
 	RequestedEpisodes []string `json:"requested_episodes,omitempty"` // Individual episodes already requested (S01E01)
 }
 
-func CreateRequest(req models.Request) error {
+// CreateRequest adds a request, or merges a show request into an existing active one. Returns
+// the ID of the request that was created or extended.
+func CreateRequest(req models.Request) (int, error) {
 	// If it's a show, we might be adding seasons to an existing request
 	if req.MediaType == "show" {
 		var existingID int
 		var existingSeasons string
-		// Look for active requests (pending or downloading) to append seasons to
-		err := database.DB.QueryRow("SELECT id, seasons FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND status IN ('pending', 'downloading')", req.TVDBID).Scan(&existingID, &existingSeasons)
+		// Look for active requests (pending or downloading) to append seasons to. Requests
+		// awaiting approval only join other unapproved requests, so they can't skip the queue.
+		activeStatuses := "'pending', 'downloading'"
+		if req.Status == "awaiting_approval" {
+			activeStatuses = "'awaiting_approval'"
+		}
+		err := database.DB.QueryRow("SELECT id, seasons FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND is_4k = $2 AND status IN ("+activeStatuses+")", req.TVDBID, req.Is4K).Scan(&existingID, &existingSeasons)
 		if err == nil {
 			// Update existing request
 			newSeasons := existingSeasons
@@ -69,11 +77,11 @@ func CreateRequest(req models.Request) error {
 			}
 
 			slog.Info("Updating existing show request with additional items", "request_id", existingID, "title", req.Title, "new_seasons", newSeasons, "new_episodes", newEpisodes)
-			_, err = database.DB.Exec("UPDATE requests SET seasons = $1, episodes = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3", newSeasons, newEpisodes, existingID)
+			_, err = database.DB.Exec("UPDATE requests SET seasons = $1, episodes = $2, root_folder_id = COALESCE(root_folder_id, $4), budget_checked_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $3", newSeasons, newEpisodes, existingID, req.RootFolderID)
 			if err == nil {
 				slog.Info("Successfully updated existing request", "request_id", existingID, "seasons", newSeasons, "episodes", newEpisodes)
 			}
-			return err
+			return existingID, err
 		}
 	}
 
@@ -116,29 +124,70 @@ func CreateRequest(req models.Request) error {
 		}
 	}
 
-	slog.Info("Creating new request", "title", req.Title, "original_title", originalTitle, "media_type", req.MediaType, "seasons", req.Seasons, "episodes", req.Episodes, "user_id", req.UserID)
+	status := req.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	slog.Info("Creating new request", "title", req.Title, "original_title", originalTitle, "media_type", req.MediaType, "seasons", req.Seasons, "episodes", req.Episodes, "4k", req.Is4K, "edition", req.Edition, "user_id", req.UserID)
 	query := `
-		INSERT INTO requests (user_id, title, original_title, media_type, tmdb_id, tvdb_id, year, poster_path, overview, seasons, episodes, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 'pending', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO requests (user_id, title, original_title, media_type, tmdb_id, tvdb_id, year, poster_path, overview, seasons, episodes, status, root_folder_id, is_4k, edition, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		RETURNING id
 	`
-	_, err := database.DB.Exec(query, req.UserID, req.Title, originalTitle, req.MediaType, req.TMDBID, req.TVDBID, req.Year, req.PosterPath, req.Overview, req.Seasons, req.Episodes)
+	var id int
+	err := database.DB.QueryRow(query, req.UserID, req.Title, originalTitle, req.MediaType, req.TMDBID, req.TVDBID, req.Year, req.PosterPath, req.Overview, req.Seasons, req.Episodes, status, req.RootFolderID, req.Is4K, req.Edition).Scan(&id)
 	if err != nil {
 		slog.Error("Failed to insert request into database", "error", err, "title", req.Title)
-		return err
+		return 0, err
 	}
 
-	slog.Info("Successfully created new request", "title", req.Title, "original_title", originalTitle, "media_type", req.MediaType, "status", "pending")
-	return nil
+	slog.Info("Successfully created new request", "request_id", id, "title", req.Title, "original_title", originalTitle, "media_type", req.MediaType, "status", status)
+	return id, nil
 }
 
 func GetRequests() ([]models.Request, error) {
+	return queryRequests("")
+}
+
+// GetRequestByID returns a single request
+func GetRequestByID(id int) (*models.Request, error) {
+	requests, err := queryRequests("WHERE r.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &requests[0], nil
+}
+
+// GetRequestsForMedia returns the requests for a movie by TMDB ID or a show by TVDB ID
+func GetRequestsForMedia(mediaType, externalID string) ([]models.Request, error) {
+	column := "r.tmdb_id"
+	if mediaType == "show" {
+		column = "r.tvdb_id"
+	}
+	return queryRequests("WHERE r.media_type = $1 AND "+column+" = $2", mediaType, externalID)
+}
+
+// SetRequestTMDBID records the TMDB ID of a request. Show requests made from TVDB searches
+// don't have one until something looks it up.
+func SetRequestTMDBID(id int, tmdbID string) error {
+	_, err := database.DB.Exec("UPDATE requests SET tmdb_id = $1 WHERE id = $2", tmdbID, id)
+	return err
+}
+
+// queryRequests returns the requests matching a WHERE clause, newest first
+func queryRequests(where string, args ...any) ([]models.Request, error) {
 	query := `
-		SELECT r.id, r.user_id, u.username, r.title, r.original_title, r.media_type, r.tmdb_id, r.tvdb_id, r.imdb_id, r.year, r.poster_path, r.overview, r.seasons, r.episodes, r.status, r.retry_count, r.last_search_at, r.created_at, r.updated_at
+		SELECT r.id, r.user_id, u.username, r.title, r.original_title, r.media_type, r.tmdb_id, r.tvdb_id, r.imdb_id, r.year, r.poster_path, r.overview, r.seasons, r.episodes, r.status, r.root_folder_id, r.is_4k, r.edition, r.retry_count, r.last_search_at, r.created_at, r.updated_at
 		FROM requests r
 		JOIN users u ON r.user_id = u.id
+		` + where + `
 		ORDER BY r.created_at DESC
 	`
-	rows, err := database.DB.Query(query)
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +198,8 @@ func GetRequests() ([]models.Request, error) {
 		var req models.Request
 		var originalTitle, tmdbID, tvdbID, imdbID, seasons, episodes sql.NullString
 		var lastSearchAt sql.NullTime
-		err := rows.Scan(&req.ID, &req.UserID, &req.Username, &req.Title, &originalTitle, &req.MediaType, &tmdbID, &tvdbID, &imdbID, &req.Year, &req.PosterPath, &req.Overview, &seasons, &episodes, &req.Status, &req.RetryCount, &lastSearchAt, &req.CreatedAt, &req.UpdatedAt)
+		var rootFolderID sql.NullInt64
+		err := rows.Scan(&req.ID, &req.UserID, &req.Username, &req.Title, &originalTitle, &req.MediaType, &tmdbID, &tvdbID, &imdbID, &req.Year, &req.PosterPath, &req.Overview, &seasons, &episodes, &req.Status, &rootFolderID, &req.Is4K, &req.Edition, &req.RetryCount, &lastSearchAt, &req.CreatedAt, &req.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -164,6 +214,9 @@ func GetRequests() ([]models.Request, error) {
 		if lastSearchAt.Valid {
 			req.LastSearchAt = &lastSearchAt.Time
 		}
+		if rootFolderID.Valid {
+			req.RootFolderID = &rootFolderID.Int64
+		}
 		requests = append(requests, req)
 	}
 	return requests, nil
@@ -198,6 +251,22 @@ func decodeUnicodeEscapes(s string) string {
 	return s
 }
 
+// SetRequestRootFolder chooses the root folder a request's media goes to, or clears it for nil
+func SetRequestRootFolder(id int, rootFolderID *int64) error {
+	var mediaType string
+	var is4K bool
+	if err := database.DB.QueryRow("SELECT media_type, is_4k FROM requests WHERE id = $1", id).Scan(&mediaType, &is4K); err != nil {
+		return fmt.Errorf("request not found: %w", err)
+	}
+	if rootFolderID != nil {
+		if err := ValidateRootFolder(*rootFolderID, mediaType, is4K); err != nil {
+			return err
+		}
+	}
+	_, err := database.DB.Exec("UPDATE requests SET root_folder_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", rootFolderID, id)
+	return err
+}
+
 func UpdateRequestStatus(id int, status string) error {
 	_, err := database.DB.Exec("UPDATE requests SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", status, id)
 	return err
@@ -259,34 +328,77 @@ func CleanupCompletedRequests() (int, error) {
 	return int(count), nil
 }
 
-func CheckLibraryStatus(mediaType string, externalID string) (LibraryStatus, error) {
+// CheckMovieEditionStatus is CheckLibraryStatusFor for one edition of a movie, so a director's
+// cut can be requested while the regular edition is in the library
+func CheckMovieEditionStatus(tmdbID, edition string, is4K bool) LibraryStatus {
+	return checkMovieStatus(tmdbID, is4K, edition)
+}
+
+// checkMovieStatus checks whether a movie is in a library or requested for it, in any edition
+// when edition is ""
+func checkMovieStatus(tmdbID string, is4K bool, edition string) LibraryStatus {
 	status := LibraryStatus{Exists: false}
+	movieFilter := " AND NOT " + in4KLibrarySQL("path")
+	if is4K {
+		movieFilter = " AND " + in4KLibrarySQL("path")
+	}
+	requestFilter := ""
+	if edition != "" {
+		// Editions match by their first word, like SameEdition
+		movieFilter += " AND split_part(lower(COALESCE(edition, '')), ' ', 1) = $2"
+		requestFilter = " AND split_part(lower(edition), ' ', 1) = $3"
+	}
+	args := []any{tmdbID}
+	if edition != "" {
+		args = append(args, editionKey(edition))
+	}
 
-	if mediaType == "movie" {
-		var id int
-		var path string
-		err := database.DB.QueryRow("SELECT id, path FROM movies WHERE tmdb_id = $1", externalID).Scan(&id, &path)
+	var id int
+	var path string
+	err := database.DB.QueryRow("SELECT id, path FROM movies WHERE tmdb_id = $1"+movieFilter, args...).Scan(&id, &path)
+	if err == nil {
+		cfg := config.Load()
+		status.Exists = true
+		status.LocalID = id
+		if strings.HasPrefix(path, cfg.IncomingMoviesPath) {
+			status.Message = "Downloading/Processing"
+		} else {
+			status.Message = "Already in library"
+		}
+	} else if err == sql.ErrNoRows {
+		// Not in library, check if already requested
+		var reqStatus string
+		requestArgs := []any{tmdbID, is4K}
+		if edition != "" {
+			requestArgs = append(requestArgs, editionKey(edition))
+		}
+		err = database.DB.QueryRow("SELECT status FROM requests WHERE tmdb_id = $1 AND media_type = 'movie' AND is_4k = $2"+requestFilter, requestArgs...).Scan(&reqStatus)
 		if err == nil {
-			cfg := config.Load()
-			status.Exists = true
-			status.LocalID = id
-			if strings.HasPrefix(path, cfg.IncomingMoviesPath) {
-				status.Message = "Downloading/Processing"
-			} else {
-				status.Message = "Already in library"
-			}
-		} else if err == sql.ErrNoRows {
-			// Not in library, check if already requested
-			var reqStatus string
-			err = database.DB.QueryRow("SELECT status FROM requests WHERE tmdb_id = $1 AND media_type = 'movie'", externalID).Scan(&reqStatus)
-			if err == nil {
-				status.Message = "Already requested (Status: " + reqStatus + ")"
-			}
+			status.Message = "Already requested (Status: " + reqStatus + ")"
 		}
+	}
+	return status
+}
+
+func CheckLibraryStatus(mediaType string, externalID string) (LibraryStatus, error) {
+	return CheckLibraryStatusFor(mediaType, externalID, false)
+}
+
+// CheckLibraryStatusFor is CheckLibraryStatus for the standard or the 4K library, which each
+// hold their own copy of a title and take their own requests
+func CheckLibraryStatusFor(mediaType string, externalID string, is4K bool) (LibraryStatus, error) {
+	status := LibraryStatus{Exists: false}
+	library := "NOT " + in4KLibrarySQL("path")
+	if is4K {
+		library = in4KLibrarySQL("path")
+	}
+
+	if mediaType == "movie" {
+		return checkMovieStatus(externalID, is4K, ""), nil
 	} else if mediaType == "show" {
 		var showID int
 		var path string
-		err := database.DB.QueryRow("SELECT id, path FROM shows WHERE tvdb_id = $1", externalID).Scan(&showID, &path)
+		err := database.DB.QueryRow("SELECT id, path FROM shows WHERE tvdb_id = $1 AND "+library, externalID).Scan(&showID, &path)
 		if err == nil {
 			cfg := config.Load()
 			status.Exists = true
@@ -362,7 +474,7 @@ func CheckLibraryStatus(mediaType string, externalID string) (LibraryStatus, err
 		var reqSeasons sql.NullString
 		var reqEps sql.NullString
 		var reqStatus string
-		err = database.DB.QueryRow("SELECT seasons, episodes, status FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND status IN ('pending', 'downloading')", externalID).Scan(&reqSeasons, &reqEps, &reqStatus)
+		err = database.DB.QueryRow("SELECT seasons, episodes, status FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND is_4k = $2 AND status IN ('awaiting_approval', 'pending', 'downloading')", externalID, is4K).Scan(&reqSeasons, &reqEps, &reqStatus)
 		if err == nil {
 			if reqSeasons.Valid && reqSeasons.String != "" {
 				seasonStrs := strings.Split(reqSeasons.String, ",")
@@ -389,7 +501,7 @@ func CheckLibraryStatus(mediaType string, externalID string) (LibraryStatus, err
 
 		// Also check completed requests - if they're incomplete, don't block re-requesting
 		var completedReqSeasons sql.NullString
-		err = database.DB.QueryRow("SELECT seasons FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND status = 'completed'", externalID).Scan(&completedReqSeasons)
+		err = database.DB.QueryRow("SELECT seasons FROM requests WHERE tvdb_id = $1 AND media_type = 'show' AND is_4k = $2 AND status = 'completed'", externalID, is4K).Scan(&completedReqSeasons)
 		if err == nil && completedReqSeasons.Valid && completedReqSeasons.String != "" {
 			// Check if any requested seasons are incomplete - if so, allow re-requesting
 			seasonStrs := strings.Split(completedReqSeasons.String, ",")
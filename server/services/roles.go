@@ -0,0 +1,178 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// splitPermissions parses a role's comma-separated permission list, dropping unknown names
+func splitPermissions(s string) []string {
+	var permissions []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if slices.Contains(models.AllPermissions, p) && !slices.Contains(permissions, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// GetRoles returns all roles with the number of users assigned to each. Users without a
+// role count towards the default role.
+func GetRoles() ([]models.Role, error) {
+	rows, err := database.DB.Query(`
+		SELECT r.id, r.name, r.permissions, r.is_default, r.movie_quota, r.season_quota, r.movie_root_folder_id, r.show_root_folder_id,
+			r.created_at, r.updated_at,
+			(SELECT COUNT(*) FROM users u WHERE u.is_admin = FALSE AND (u.role_id = r.id OR (u.role_id IS NULL AND r.is_default)))
+		FROM roles r
+		ORDER BY r.is_default DESC, r.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		var permissions string
+		var movieQuota, seasonQuota, movieRoot, showRoot sql.NullInt64
+		if err := rows.Scan(&role.ID, &role.Name, &permissions, &role.IsDefault, &movieQuota, &seasonQuota, &movieRoot, &showRoot, &role.CreatedAt, &role.UpdatedAt, &role.UserCount); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		role.Permissions = splitPermissions(permissions)
+		role.MovieQuota = nullableQuota(movieQuota)
+		role.SeasonQuota = nullableQuota(seasonQuota)
+		if movieRoot.Valid {
+			role.MovieRootFolderID = &movieRoot.Int64
+		}
+		if showRoot.Valid {
+			role.ShowRootFolderID = &showRoot.Int64
+		}
+		if role.Permissions == nil {
+			role.Permissions = []string{}
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SaveRole creates a role, or updates an existing one when id is non-zero. Making a role the
+// default takes that over from the previous default. Nil quotas are unlimited, and nil root
+// folders place the role's requests in the default ones. Returns the role ID.
+func SaveRole(id int64, name string, permissions []string, isDefault bool, movieQuota, seasonQuota *int, movieRootFolderID, showRootFolderID *int64) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("role name is required")
+	}
+	if err := validateQuotas(movieQuota, seasonQuota); err != nil {
+		return 0, err
+	}
+	if movieRootFolderID != nil {
+		if err := ValidateRootFolder(*movieRootFolderID, "movie", false); err != nil {
+			return 0, err
+		}
+	}
+	if showRootFolderID != nil {
+		if err := ValidateRootFolder(*showRootFolderID, "show", false); err != nil {
+			return 0, err
+		}
+	}
+	permissionList := strings.Join(splitPermissions(strings.Join(permissions, ",")), ",")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if isDefault {
+		if _, err := tx.Exec("UPDATE roles SET is_default = FALSE, updated_at = CURRENT_TIMESTAMP WHERE is_default AND id != $1", id); err != nil {
+			return 0, err
+		}
+	}
+
+	if id == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO roles (name, permissions, is_default, movie_quota, season_quota, movie_root_folder_id, show_root_folder_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			name, permissionList, isDefault, movieQuota, seasonQuota, movieRootFolderID, showRootFolderID).Scan(&id)
+	} else {
+		// The default can only be moved to another role, not unset, so users without a role
+		// always have one to fall back to
+		var res sql.Result
+		res, err = tx.Exec(`
+			UPDATE roles SET name = $1, permissions = $2, is_default = is_default OR $3, movie_quota = $4, season_quota = $5,
+				movie_root_folder_id = $6, show_root_folder_id = $7, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $8`, name, permissionList, isDefault, movieQuota, seasonQuota, movieRootFolderID, showRootFolderID, id)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				err = fmt.Errorf("role %d not found", id)
+			}
+		}
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return 0, fmt.Errorf("a role named %q already exists", name)
+		}
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// GetRolePermissions returns a role's permissions, or the default role's for role 0
+func GetRolePermissions(roleID int64) ([]string, error) {
+	var permissions string
+	var err error
+	if roleID == 0 {
+		err = database.DB.QueryRow("SELECT permissions FROM roles WHERE is_default").Scan(&permissions)
+	} else {
+		err = database.DB.QueryRow("SELECT permissions FROM roles WHERE id = $1", roleID).Scan(&permissions)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+	return splitPermissions(permissions), nil
+}
+
+// DeleteRole removes a role. Its users fall back to the default role, which can't be deleted.
+func DeleteRole(id int64) error {
+	var isDefault bool
+	if err := database.DB.QueryRow("SELECT is_default FROM roles WHERE id = $1", id).Scan(&isDefault); err != nil {
+		return fmt.Errorf("role not found: %w", err)
+	}
+	if isDefault {
+		return fmt.Errorf("the default role can't be deleted")
+	}
+	_, err := database.DB.Exec("DELETE FROM roles WHERE id = $1", id)
+	return err
+}
+
+// SetUserRole assigns a role to a user (0 for the default role) and sets their admin flag
+func SetUserRole(userID, roleID int64, isAdmin bool) error {
+	var role sql.NullInt64
+	if roleID != 0 {
+		if err := database.DB.QueryRow("SELECT id FROM roles WHERE id = $1", roleID).Scan(&role); err != nil {
+			return fmt.Errorf("role not found: %w", err)
+		}
+	}
+
+	if !isAdmin {
+		// Never demote the last admin, or nobody could manage roles any more
+		if err := requireOtherAdmin(userID, "AND NOT disabled"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := database.DB.Exec("UPDATE users SET role_id = $1, is_admin = $2, updated_at = NOW() WHERE id = $3", role, isAdmin, userID); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,526 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnsureRootFolders adds MOVIES_PATH and SHOWS_PATH as root folders, and makes them the
+// standard library defaults of their media type when there's no default yet
+func EnsureRootFolders(cfg *config.Config) error {
+	for _, root := range []struct{ name, mediaType, path string }{
+		{"Movies", "movie", cfg.MoviesPath},
+		{"Shows", "show", cfg.ShowsPath},
+	} {
+		if root.path == "" {
+			continue
+		}
+		path := filepath.Clean(root.path)
+		if _, err := database.DB.Exec(`
+			INSERT INTO root_folders (name, media_type, path) VALUES ($1, $2, $3)
+			ON CONFLICT (path) DO NOTHING`, root.name, root.mediaType, path); err != nil {
+			return fmt.Errorf("failed to add %s root folder: %w", root.mediaType, err)
+		}
+		if _, err := database.DB.Exec(`
+			UPDATE root_folders SET is_default = TRUE
+			WHERE path = $1 AND media_type = $2
+				AND NOT EXISTS (SELECT 1 FROM root_folders WHERE media_type = $2 AND NOT is_4k AND is_default)`, path, root.mediaType); err != nil {
+			return fmt.Errorf("failed to set default %s root folder: %w", root.mediaType, err)
+		}
+	}
+	return nil
+}
+
+// GetRootFolders returns the root folders of a media type, or all of them for "", standard
+// library first and defaults first, with whether each is available and its free space
+func GetRootFolders(mediaType string) ([]models.RootFolder, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, name, media_type, path, min_free_gb, is_default, is_4k, created_at
+		FROM root_folders
+		WHERE $1 = '' OR media_type = $1
+		ORDER BY media_type, is_4k, is_default DESC, name`, mediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query root folders: %w", err)
+	}
+	defer rows.Close()
+
+	var roots []models.RootFolder
+	for rows.Next() {
+		var rf models.RootFolder
+		if err := rows.Scan(&rf.ID, &rf.Name, &rf.MediaType, &rf.Path, &rf.MinFreeGB, &rf.IsDefault, &rf.Is4K, &rf.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan root folder: %w", err)
+		}
+		if info, err := os.Stat(rf.Path); err == nil && info.IsDir() {
+			rf.Available = true
+			if free, err := diskFree(rf.Path); err == nil {
+				rf.FreeBytes = &free
+			}
+		}
+		roots = append(roots, rf)
+	}
+	return roots, rows.Err()
+}
+
+// LibraryRoots returns the paths of the root folders of a media type in both libraries, the
+// standard default first. Falls back to MOVIES_PATH or SHOWS_PATH when the root folders can't
+// be read.
+func LibraryRoots(cfg *config.Config, mediaType string) []string {
+	return queryLibraryRoots(cfg, mediaType, "")
+}
+
+// libraryRoots returns the paths of the root folders of a media type in the standard or the
+// 4K library, the default first. The standard library falls back like LibraryRoots.
+func libraryRoots(cfg *config.Config, mediaType string, is4K bool) []string {
+	if is4K {
+		return queryLibraryRoots(nil, mediaType, "AND is_4k")
+	}
+	return queryLibraryRoots(cfg, mediaType, "AND NOT is_4k")
+}
+
+// Has4KLibrary reports whether a media type has any 4K root folders
+func Has4KLibrary(mediaType string) bool {
+	var exists bool
+	database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM root_folders WHERE media_type = $1 AND is_4k)", mediaType).Scan(&exists)
+	return exists
+}
+
+// queryLibraryRoots returns the root folder paths matching a filter. Without a config there's
+// no fallback.
+func queryLibraryRoots(cfg *config.Config, mediaType string, filter string) []string {
+	rows, err := database.DB.Query(`
+		SELECT path FROM root_folders WHERE media_type = $1 `+filter+`
+		ORDER BY is_4k, is_default DESC, name`, mediaType)
+	if err == nil {
+		defer rows.Close()
+		var paths []string
+		for rows.Next() {
+			var path string
+			if rows.Scan(&path) == nil {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			return paths
+		}
+	}
+	if cfg == nil {
+		return nil
+	}
+
+	fallback := cfg.MoviesPath
+	if mediaType == "show" {
+		fallback = cfg.ShowsPath
+	}
+	if fallback == "" {
+		return nil
+	}
+	return []string{filepath.Clean(fallback)}
+}
+
+// allLibraryRoots returns the root folders of movies and shows
+func allLibraryRoots(cfg *config.Config) []string {
+	return append(LibraryRoots(cfg, "movie"), LibraryRoots(cfg, "show")...)
+}
+
+// rootOrDefault returns the root a path is in, or the first (default) root
+func rootOrDefault(roots []string, path string) string {
+	if root := rootOf(roots, path); root != "" {
+		return root
+	}
+	if len(roots) > 0 {
+		return roots[0]
+	}
+	return ""
+}
+
+// rootOf returns the root a path is in, or "". Nested roots resolve to the innermost one.
+func rootOf(roots []string, path string) string {
+	found := ""
+	for _, root := range roots {
+		if root != "" && strings.HasPrefix(path, root+string(filepath.Separator)) && len(root) > len(found) {
+			found = root
+		}
+	}
+	return found
+}
+
+// unavailableRoots returns the root folders of a media type that are missing, e.g. because
+// their disk isn't mounted, so files under them aren't taken for deleted
+func unavailableRoots(mediaType string) []string {
+	rows, err := database.DB.Query("SELECT path FROM root_folders WHERE media_type = $1", mediaType)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var path string
+		if rows.Scan(&path) != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// CreateRootFolder adds a root folder to the standard or the 4K library. The path must be an
+// existing folder that doesn't overlap the incoming folders. The first root of a media type in
+// a library becomes its default.
+func CreateRootFolder(cfg *config.Config, name, mediaType, path string, minFreeGB float64, is4K bool) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("root folder name is required")
+	}
+	if mediaType != "movie" && mediaType != "show" {
+		return 0, fmt.Errorf("media type must be movie or show")
+	}
+	if minFreeGB < 0 {
+		return 0, fmt.Errorf("minimum free space can't be negative")
+	}
+	if !filepath.IsAbs(path) {
+		return 0, fmt.Errorf("root folder path must be absolute")
+	}
+	path = filepath.Clean(path)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return 0, fmt.Errorf("%s is not a folder", path)
+	}
+	for _, incoming := range []string{cfg.IncomingMoviesPath, cfg.IncomingShowsPath} {
+		if incoming == "" {
+			continue
+		}
+		incoming = filepath.Clean(incoming)
+		if path == incoming || strings.HasPrefix(path, incoming+string(filepath.Separator)) || strings.HasPrefix(incoming, path+string(filepath.Separator)) {
+			return 0, fmt.Errorf("root folders can't overlap the incoming folder %s", incoming)
+		}
+	}
+
+	var id int64
+	err := database.DB.QueryRow(`
+		INSERT INTO root_folders (name, media_type, path, min_free_gb, is_4k, is_default)
+		VALUES ($1, $2, $3, $4, $5, NOT EXISTS (SELECT 1 FROM root_folders WHERE media_type = $2 AND is_4k = $5 AND is_default))
+		RETURNING id`, name, mediaType, path, minFreeGB, is4K).Scan(&id)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return 0, fmt.Errorf("%s is already a root folder", path)
+		}
+		return 0, err
+	}
+	slog.Info("Added root folder", "root_folder_id", id, "name", name, "media_type", mediaType, "path", path, "4k", is4K)
+	return id, nil
+}
+
+// UpdateRootFolder renames a root folder and changes its minimum free space
+func UpdateRootFolder(id int64, name string, minFreeGB float64) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("root folder name is required")
+	}
+	if minFreeGB < 0 {
+		return fmt.Errorf("minimum free space can't be negative")
+	}
+	res, err := database.DB.Exec("UPDATE root_folders SET name = $1, min_free_gb = $2 WHERE id = $3", name, minFreeGB, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("root folder %d not found", id)
+	}
+	return nil
+}
+
+// SetDefaultRootFolder makes a root folder the default of its media type in its library
+func SetDefaultRootFolder(id int64) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var mediaType string
+	var is4K bool
+	if err := tx.QueryRow("SELECT media_type, is_4k FROM root_folders WHERE id = $1", id).Scan(&mediaType, &is4K); err != nil {
+		return fmt.Errorf("root folder not found: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE root_folders SET is_default = FALSE WHERE media_type = $1 AND is_4k = $2 AND is_default AND id != $3", mediaType, is4K, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE root_folders SET is_default = TRUE WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRootFolder removes a root folder. The default, the folders from MOVIES_PATH and
+// SHOWS_PATH, and folders that still hold library items can't be removed.
+func DeleteRootFolder(cfg *config.Config, id int64) error {
+	var rf models.RootFolder
+	err := database.DB.QueryRow("SELECT path, media_type, is_default FROM root_folders WHERE id = $1", id).
+		Scan(&rf.Path, &rf.MediaType, &rf.IsDefault)
+	if err != nil {
+		return fmt.Errorf("root folder not found: %w", err)
+	}
+	if rf.IsDefault {
+		return fmt.Errorf("the default root folder can't be removed; make another one the default first")
+	}
+	for _, configured := range []string{cfg.MoviesPath, cfg.ShowsPath} {
+		if configured != "" && filepath.Clean(configured) == rf.Path {
+			return fmt.Errorf("%s is set by MOVIES_PATH or SHOWS_PATH and can't be removed", rf.Path)
+		}
+	}
+
+	table := "movies"
+	if rf.MediaType == "show" {
+		table = "shows"
+	}
+	var items int
+	database.DB.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE left(path, length($1) + 1) = $1 || '/'", rf.Path).Scan(&items)
+	if items > 0 {
+		return fmt.Errorf("%s still holds %d library items; move or remove them first", rf.Path, items)
+	}
+
+	_, err = database.DB.Exec("DELETE FROM root_folders WHERE id = $1", id)
+	return err
+}
+
+// chooseRootFolder picks the root folder new media goes to in the standard or 4K library: the
+// one its request asked for, else the requester's role folder, else the library's default. A
+// root with less free space than its minimum is passed over for the root of the same type and
+// library with the most free space.
+func chooseRootFolder(cfg *config.Config, mediaType, externalID string, is4K bool) string {
+	all, err := GetRootFolders(mediaType)
+	var roots []models.RootFolder
+	for _, rf := range all {
+		if rf.Is4K == is4K {
+			roots = append(roots, rf)
+		}
+	}
+	if err != nil || len(roots) == 0 {
+		if paths := libraryRoots(cfg, mediaType, false); len(paths) > 0 {
+			return paths[0]
+		}
+		return ""
+	}
+
+	// Roots come back default first
+	chosen := roots[0]
+	if preferred := requestedRootFolder(mediaType, externalID, is4K); preferred != 0 {
+		for _, rf := range roots {
+			if rf.ID == preferred {
+				chosen = rf
+				break
+			}
+		}
+	}
+
+	if hasRoom(chosen) {
+		return chosen.Path
+	}
+	var best *models.RootFolder
+	for i, rf := range roots {
+		if hasRoom(rf) && rf.FreeBytes != nil && (best == nil || *rf.FreeBytes > *best.FreeBytes) {
+			best = &roots[i]
+		}
+	}
+	if best == nil {
+		slog.Warn("All root folders are short of space or missing, using the preferred one", "media_type", mediaType, "path", chosen.Path)
+		return chosen.Path
+	}
+	slog.Info("Root folder is short of space, placing media in another one",
+		"media_type", mediaType, "preferred", chosen.Path, "chosen", best.Path)
+	return best.Path
+}
+
+// hasRoom reports whether a root folder is available with at least its minimum free space.
+// Free space that can't be read counts as enough.
+func hasRoom(rf models.RootFolder) bool {
+	if !rf.Available {
+		return false
+	}
+	if rf.MinFreeGB <= 0 || rf.FreeBytes == nil {
+		return true
+	}
+	return float64(*rf.FreeBytes) >= rf.MinFreeGB*1024*1024*1024
+}
+
+// requestedRootFolder returns the root folder ID of the latest request for the media in the
+// standard or 4K library, or of its requester's role, or 0. Role folders are standard roots,
+// so chooseRootFolder ignores them for 4K requests.
+func requestedRootFolder(mediaType, externalID string, is4K bool) int64 {
+	if externalID == "" {
+		return 0
+	}
+	idColumn, roleColumn := "tmdb_id", "movie_root_folder_id"
+	if mediaType == "show" {
+		idColumn, roleColumn = "tvdb_id", "show_root_folder_id"
+	}
+	var rootID sql.NullInt64
+	err := database.DB.QueryRow(fmt.Sprintf(`
+		SELECT COALESCE(r.root_folder_id, ro.%s)
+		FROM requests r
+		JOIN users u ON r.user_id = u.id
+		LEFT JOIN roles ro ON ro.id = COALESCE(u.role_id, (SELECT id FROM roles WHERE is_default))
+		WHERE r.media_type = $1 AND r.%s = $2 AND r.is_4k = $3
+		ORDER BY r.created_at DESC
+		LIMIT 1`, roleColumn, idColumn), mediaType, externalID, is4K).Scan(&rootID)
+	if err != nil {
+		return 0
+	}
+	return rootID.Int64
+}
+
+// ValidateRootFolder checks that a root folder exists and holds the given media type in the
+// standard or 4K library
+func ValidateRootFolder(id int64, mediaType string, is4K bool) error {
+	var rootType string
+	var root4K bool
+	if err := database.DB.QueryRow("SELECT media_type, is_4k FROM root_folders WHERE id = $1", id).Scan(&rootType, &root4K); err != nil {
+		return fmt.Errorf("root folder %d not found", id)
+	}
+	if rootType != mediaType {
+		return fmt.Errorf("root folder %d holds %ss, not %ss", id, rootType, mediaType)
+	}
+	if root4K != is4K {
+		if root4K {
+			return fmt.Errorf("root folder %d is in the 4K library", id)
+		}
+		return fmt.Errorf("root folder %d isn't in the 4K library", id)
+	}
+	return nil
+}
+
+// wants4K reports whether media belongs in the 4K library. Media in a root folder stays in
+// that root's library; anything else goes to the 4K library when it's 4K and there is one.
+func wants4K(cfg *config.Config, mediaType, path, quality string) bool {
+	if !Has4KLibrary(mediaType) {
+		return false
+	}
+	if rootOf(libraryRoots(cfg, mediaType, true), path) != "" {
+		return true
+	}
+	if rootOf(libraryRoots(cfg, mediaType, false), path) != "" {
+		return false
+	}
+	return quality == Quality4K
+}
+
+// showQuality returns 4K when every episode of a show is 4K, so a 4K season pack is imported
+// into the 4K library as a whole, or "" otherwise
+func showQuality(showID int) string {
+	var total, fourK int
+	database.DB.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE e.quality = $2)
+		FROM episodes e
+		JOIN seasons s ON e.season_id = s.id
+		WHERE s.show_id = $1`, showID, Quality4K).Scan(&total, &fourK)
+	if total > 0 && fourK == total {
+		return Quality4K
+	}
+	return ""
+}
+
+// moveEpisodeToShowCopy moves an episode to the show at showPath, the copy of sh in the other
+// library, creating it from sh's metadata when needed. Returns the ID of that show.
+func moveEpisodeToShowCopy(sh models.Show, showPath string, seasonNumber, episodeID int) (int, error) {
+	showID, err := upsertShow(models.Show{
+		Title:      sh.Title,
+		Year:       sh.Year,
+		TMDBID:     sh.TMDBID,
+		TVDBID:     sh.TVDBID,
+		IMDBID:     sh.IMDBID,
+		Path:       showPath,
+		PosterPath: findLocalPoster(showPath),
+		Status:     "ready",
+	})
+	if err != nil {
+		return 0, err
+	}
+	seasonID, err := upsertSeason(showID, seasonNumber)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := database.DB.Exec("UPDATE episodes SET season_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", seasonID, episodeID); err != nil {
+		return 0, err
+	}
+	slog.Info("Moved episode to the show's copy in its library", "episode_id", episodeID, "show_id", showID, "show_path", showPath)
+	if globalMetadata != nil {
+		go globalMetadata.MatchShow(showID)
+	}
+	return showID, nil
+}
+
+// in4KLibrarySQL is a SQL condition on whether a path column is in a 4K root folder
+func in4KLibrarySQL(column string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM root_folders rf WHERE rf.is_4k AND left(%s, length(rf.path) + 1) = rf.path || '/')", column)
+}
+
+// movieRootFolder returns the root folder a movie belongs in, within its library: the one it's
+// already in, the one holding its folder or another copy of it, or where new media is placed
+func movieRootFolder(cfg *config.Config, m models.Movie, templates NamingTemplates) string {
+	is4K := wants4K(cfg, "movie", m.Path, m.Quality)
+	roots := libraryRoots(cfg, "movie", is4K)
+	if root := rootOf(roots, m.Path); root != "" {
+		return root
+	}
+	for _, root := range roots {
+		dir, _ := movieDestination(root, m, templates)
+		if info, err := os.Stat(findExistingDirCaseInsensitive(dir)); err == nil && info.IsDir() {
+			return root
+		}
+	}
+	if m.TMDBID != "" {
+		if root := rootOfLibraryCopy(roots, "SELECT path FROM movies WHERE tmdb_id = $1 AND id != $2", m.TMDBID, m.ID); root != "" {
+			return root
+		}
+	}
+	return chooseRootFolder(cfg, "movie", m.TMDBID, is4K)
+}
+
+// showRootFolder returns the root folder a show belongs in, within the standard or 4K library:
+// the one it's already in, the one holding its folder or another copy of it, or where new
+// media is placed
+func showRootFolder(cfg *config.Config, sh models.Show, showDirName string, is4K bool) string {
+	roots := libraryRoots(cfg, "show", is4K)
+	if root := rootOf(roots, sh.Path); root != "" {
+		return root
+	}
+	for _, root := range roots {
+		dir := findExistingDirCaseInsensitive(filepath.Join(root, showDirName))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return root
+		}
+	}
+	if sh.TVDBID != "" {
+		if root := rootOfLibraryCopy(roots, "SELECT path FROM shows WHERE tvdb_id = $1 AND id != $2", sh.TVDBID, sh.ID); root != "" {
+			return root
+		}
+	}
+	return chooseRootFolder(cfg, "show", sh.TVDBID, is4K)
+}
+
+// rootOfLibraryCopy returns the root of the first path the query finds inside a root folder
+func rootOfLibraryCopy(roots []string, query string, args ...any) string {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return ""
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		if rows.Scan(&path) == nil {
+			if root := rootOf(roots, path); root != "" {
+				return root
+			}
+		}
+	}
+	return ""
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type ScanType string
@@ -19,6 +21,60 @@ var (
 	scanMu      sync.RWMutex
 )
 
+// ScanProgress counts the media files a scan has looked at: skipped ones were unchanged since
+// they were last probed, updated ones were probed again or followed to where they moved
+type ScanProgress struct {
+	Seen    int64 `json:"seen"`
+	Skipped int64 `json:"skipped"`
+	Updated int64 `json:"updated"`
+}
+
+type scanCounters struct {
+	seen, skipped, updated atomic.Int64
+}
+
+var scanProgress = map[ScanType]*scanCounters{
+	ScanIncomingMovies: {},
+	ScanIncomingShows:  {},
+	ScanMovieLibrary:   {},
+	ScanShowLibrary:    {},
+}
+
+// GetScanProgress returns the counts of the running scan, or of the last one
+func GetScanProgress(scanType ScanType) ScanProgress {
+	c, ok := scanProgress[scanType]
+	if !ok {
+		return ScanProgress{}
+	}
+	return ScanProgress{Seen: c.seen.Load(), Skipped: c.skipped.Load(), Updated: c.updated.Load()}
+}
+
+func resetScanProgress(scanType ScanType) {
+	c := scanProgress[scanType]
+	c.seen.Store(0)
+	c.skipped.Store(0)
+	c.updated.Store(0)
+}
+
+// progressFor returns the counters of the incoming scan for paths under incomingPath, and of
+// the library scan otherwise
+func progressFor(path, incomingPath string, incoming, library ScanType) *scanCounters {
+	if incomingPath != "" && strings.HasPrefix(path, incomingPath) {
+		return scanProgress[incoming]
+	}
+	return scanProgress[library]
+}
+
+func (c *scanCounters) skip(files int64) {
+	c.seen.Add(files)
+	c.skipped.Add(files)
+}
+
+func (c *scanCounters) update(files int64) {
+	c.seen.Add(files)
+	c.updated.Add(files)
+}
+
 func IsScanning(scanType ScanType) bool {
 	scanMu.RLock()
 	defer scanMu.RUnlock()
@@ -4,13 +4,26 @@ import (
 	"Arrgo/config"
 	"context"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
+// incomingSettleTime is how long an incoming folder must go without changes, twice with the
+// same size, before the watcher processes it
+const incomingSettleTime = 30 * time.Second
+
 // StartIncomingScanner starts a background worker that scans the incoming folder every hour on the hour.
+// With WATCH_INCOMING, folders are also processed as soon as they change and settle.
 func StartIncomingScanner(cfg *config.Config) {
 	slog.Info("Starting incoming media background scanner")
 
+	if cfg.WatchIncoming {
+		startIncomingWatcher(cfg)
+	}
+
 	go func() {
 		for {
 			now := time.Now()
@@ -40,3 +53,213 @@ func StartIncomingScanner(cfg *config.Config) {
 		}
 	}()
 }
+
+// fetchIncomingTorrents fetches the torrent list once per incoming scan, so hash linking
+// doesn't need a qBittorrent call per file
+func fetchIncomingTorrents(cfg *config.Config, mediaType string) []TorrentStatus {
+	qb, err := NewQBittorrentClient(cfg)
+	if err != nil {
+		return nil
+	}
+	torrents, err := qb.GetTorrentsDetailed(context.Background(), "")
+	if err != nil {
+		slog.Debug("Failed to fetch torrent list for incoming scan, hash linking will be skipped", "error", err)
+		return nil
+	}
+	slog.Info("Fetched torrent list for incoming "+mediaType+" scan", "count", len(torrents))
+	return torrents
+}
+
+// pendingFolder is an incoming folder that changed and waits to settle
+type pendingFolder struct {
+	root    string
+	name    string
+	changed time.Time
+	checked bool // size and file count were recorded once the folder went quiet
+	size    int64
+	files   int
+}
+
+// incomingWatcher processes top-level incoming folders as they change
+type incomingWatcher struct {
+	cfg     *config.Config
+	mu      sync.Mutex
+	pending map[string]*pendingFolder // folder path -> state
+}
+
+// startIncomingWatcher watches the incoming folders and processes each changed folder once
+// it's stable. Without inotify, or when it fails, the hourly scan is all that runs.
+func startIncomingWatcher(cfg *config.Config) {
+	watcher, err := newDirWatcher()
+	if err != nil {
+		slog.Warn("Incoming folder watcher unavailable, relying on hourly scans", "error", err)
+		return
+	}
+
+	iw := &incomingWatcher{cfg: cfg, pending: map[string]*pendingFolder{}}
+	for _, root := range []string{cfg.IncomingMoviesPath, cfg.IncomingShowsPath} {
+		if root == "" {
+			continue
+		}
+		if err := watcher.AddRecursive(root); err != nil {
+			slog.Warn("Failed to watch incoming folder, relying on hourly scans for it", "path", root, "error", err)
+			continue
+		}
+		slog.Info("Watching incoming folder for changes", "path", root)
+	}
+
+	go func() {
+		for path := range watcher.Events() {
+			iw.changed(path)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			iw.processSettled()
+		}
+	}()
+}
+
+// changed marks the top-level incoming folder containing path as changed. A change to a root
+// itself, reported when events were dropped, marks all of its folders.
+func (iw *incomingWatcher) changed(path string) {
+	for _, root := range []string{iw.cfg.IncomingMoviesPath, iw.cfg.IncomingShowsPath} {
+		if root == "" {
+			continue
+		}
+		if path == root {
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				return
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					iw.mark(root, entry.Name())
+				}
+			}
+			return
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		name := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+		if info, err := os.Stat(filepath.Join(root, name)); err == nil && !info.IsDir() {
+			return // Loose files in the root aren't imported by scans either
+		}
+		iw.mark(root, name)
+		return
+	}
+}
+
+func (iw *incomingWatcher) mark(root, name string) {
+	if strings.HasPrefix(name, ".") {
+		return
+	}
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+
+	path := filepath.Join(root, name)
+	p, ok := iw.pending[path]
+	if !ok {
+		p = &pendingFolder{root: root, name: name}
+		iw.pending[path] = p
+		slog.Debug("Incoming folder changed", "path", path)
+	}
+	p.changed = time.Now()
+	p.checked = false
+}
+
+// processSettled processes pending folders that stopped changing: no events for the settle
+// time, no partial downloads, and the same size and file count on two checks in a row
+func (iw *incomingWatcher) processSettled() {
+	iw.mu.Lock()
+	var due []string
+	for path, p := range iw.pending {
+		if time.Since(p.changed) >= incomingSettleTime {
+			due = append(due, path)
+		}
+	}
+	iw.mu.Unlock()
+
+	for _, path := range due {
+		// Walk outside the lock so events keep flowing meanwhile
+		size, files, partial, err := incomingFolderState(path)
+
+		iw.mu.Lock()
+		p, ok := iw.pending[path]
+		if !ok || time.Since(p.changed) < incomingSettleTime {
+			iw.mu.Unlock()
+			continue // Changed again while we looked
+		}
+		settled := false
+		switch {
+		case os.IsNotExist(err):
+			delete(iw.pending, path) // Imported or removed meanwhile
+		case err != nil || partial:
+			p.changed = time.Now()
+		case !p.checked || size != p.size || files != p.files:
+			p.checked, p.size, p.files = true, size, files
+			p.changed = time.Now()
+		default:
+			settled = true
+			delete(iw.pending, path)
+		}
+		folder := *p
+		iw.mu.Unlock()
+
+		if settled && !iw.process(folder) {
+			// A full scan holds the lock; try again once it's done
+			iw.mark(folder.root, folder.name)
+		}
+	}
+}
+
+// process runs a settled folder through the same import path as a scan, counted as its own
+// incoming scan in the scan progress. Returns false when a scan of the same kind is running.
+func (iw *incomingWatcher) process(p pendingFolder) bool {
+	switch p.root {
+	case iw.cfg.IncomingMoviesPath:
+		if !scanMoviesMutex.TryLock() {
+			return false
+		}
+		defer scanMoviesMutex.Unlock()
+		slog.Info("Processing changed incoming movie folder", "folder", p.name)
+		resetScanProgress(ScanIncomingMovies)
+		processMovieDir(iw.cfg, p.root, p.name, fetchIncomingTorrents(iw.cfg, "movie"))
+	case iw.cfg.IncomingShowsPath:
+		if !scanShowsMutex.TryLock() {
+			return false
+		}
+		defer scanShowsMutex.Unlock()
+		slog.Info("Processing changed incoming show folder", "folder", p.name)
+		resetScanProgress(ScanIncomingShows)
+		processShowDir(iw.cfg, p.root, p.name, fetchIncomingTorrents(iw.cfg, "show"))
+	}
+	return true
+}
+
+// incomingFolderState returns the total size and file count of a folder, and whether it holds
+// partial downloads (qBittorrent's .!qB or .part files)
+func incomingFolderState(path string) (size int64, files int, partial bool, err error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, 0, false, err
+	}
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		name := strings.ToLower(info.Name())
+		if strings.HasSuffix(name, ".!qb") || strings.HasSuffix(name, ".part") {
+			partial = true
+		}
+		size += info.Size()
+		files++
+		return nil
+	})
+	return size, files, partial, err
+}
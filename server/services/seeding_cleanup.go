@@ -51,6 +51,31 @@ func CheckAndCleanupSeedingTorrents(ctx context.Context, cfg *config.Config, qb
 				"meets_time", meetsTimeThreshold,
 				"meets_ratio", meetsRatioThreshold)
 
+			// Every file was copied or linked into the library while seeding, so the torrent's
+			// files are only there for qBittorrent. Let it delete exactly those; a hardlinked
+			// or reflinked library file keeps its data.
+			if sourceOnlyTorrent(normalizedHash) {
+				if err := qb.DeleteTorrent(ctx, normalizedHash, true); err != nil {
+					slog.Error("Failed to remove torrent from qBittorrent",
+						"hash", normalizedHash,
+						"error", err)
+					continue
+				}
+				if _, verifyErr := qb.GetTorrentByHash(ctx, normalizedHash); verifyErr == nil {
+					slog.Warn("Torrent still exists in qBittorrent after deletion attempt",
+						"hash", normalizedHash)
+					continue
+				}
+				slog.Info("Removed torrent and its seeding copies from qBittorrent",
+					"hash", normalizedHash,
+					"ratio", torrent.Ratio)
+
+				database.DB.Exec("UPDATE movies SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+				database.DB.Exec("UPDATE episodes SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+				cleanedCount++
+				continue
+			}
+
 			// Always remove torrent from qBittorrent when threshold is met
 			// Files are only deleted if BOTH conditions are met:
 			// 1. Torrent has been removed from qBittorrent (verified after deletion)
@@ -181,8 +206,8 @@ func CheckAndCleanupSeedingTorrents(ctx context.Context, cfg *config.Config, qb
 				slog.Warn("Torrent still exists in qBittorrent after deletion attempt - skipping file cleanup",
 					"hash", normalizedHash)
 				// Still remove torrent_hash from database entries
-				database.DB.Exec("UPDATE movies SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
-				database.DB.Exec("UPDATE episodes SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+				database.DB.Exec("UPDATE movies SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+				database.DB.Exec("UPDATE episodes SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
 				continue
 			}
 
@@ -213,7 +238,7 @@ func CheckAndCleanupSeedingTorrents(ctx context.Context, cfg *config.Config, qb
 
 					// Only delete incoming files if we're certain they've been moved
 					if fileMoved {
-						if err := cleanupIncomingFiles(torrent.SavePath, incomingPath); err != nil {
+						if err := cleanupIncomingFiles(cfg, torrent.SavePath, incomingPath); err != nil {
 							slog.Error("Failed to cleanup incoming files",
 								"save_path", torrent.SavePath,
 								"incoming_path", incomingPath,
@@ -239,8 +264,8 @@ func CheckAndCleanupSeedingTorrents(ctx context.Context, cfg *config.Config, qb
 			}
 
 			// Remove torrent_hash from database entries (after cleanup decision is made)
-			database.DB.Exec("UPDATE movies SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
-			database.DB.Exec("UPDATE episodes SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+			database.DB.Exec("UPDATE movies SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+			database.DB.Exec("UPDATE episodes SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
 
 			cleanedCount++
 		}
@@ -249,8 +274,8 @@ func CheckAndCleanupSeedingTorrents(ctx context.Context, cfg *config.Config, qb
 	return cleanedCount, nil
 }
 
-// cleanupIncomingFiles removes files from the incoming folder
-func cleanupIncomingFiles(savePath, incomingPath string) error {
+// cleanupIncomingFiles removes files from the incoming folder, through the recycle bin
+func cleanupIncomingFiles(cfg *config.Config, savePath, incomingPath string) error {
 	// Check if path exists
 	if _, err := os.Stat(savePath); os.IsNotExist(err) {
 		return nil // Already deleted
@@ -258,11 +283,11 @@ func cleanupIncomingFiles(savePath, incomingPath string) error {
 
 	// If it's a directory, remove the entire directory
 	if info, err := os.Stat(savePath); err == nil && info.IsDir() {
-		return os.RemoveAll(savePath)
+		return RecyclePath(cfg, savePath, "Imported download")
 	}
 
 	// If it's a file, remove it and try to clean up parent directories
-	if err := os.Remove(savePath); err != nil {
+	if err := RecyclePath(cfg, savePath, "Imported download"); err != nil {
 		return err
 	}
 
@@ -464,7 +489,7 @@ func CleanupTorrentOnImport(ctx context.Context, cfg *config.Config, qb *QBittor
 			// File exists at final location - safe to delete incoming files
 			fileDir := filepath.Dir(filePath)
 			if strings.HasPrefix(fileDir, incomingPath) {
-				if err := cleanupIncomingFiles(fileDir, incomingPath); err != nil {
+				if err := cleanupIncomingFiles(cfg, fileDir, incomingPath); err != nil {
 					slog.Error("Failed to cleanup incoming files on import",
 						"file_dir", fileDir,
 						"incoming_path", incomingPath,
@@ -490,8 +515,8 @@ func CleanupTorrentOnImport(ctx context.Context, cfg *config.Config, qb *QBittor
 	}
 
 	// Remove torrent_hash from database entries
-	database.DB.Exec("UPDATE movies SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
-	database.DB.Exec("UPDATE episodes SET torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+	database.DB.Exec("UPDATE movies SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
+	database.DB.Exec("UPDATE episodes SET source_path = NULL, torrent_hash = NULL WHERE LOWER(torrent_hash) = $1", normalizedHash)
 
 	return nil
 }
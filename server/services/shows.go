@@ -108,9 +108,14 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 	}()
 
 	slog.Info("Starting show scan", "scan_type", scanType, "workers", DefaultWorkerCount)
+	resetScanProgress(scanType)
 
-	// Clean up missing files first
-	PurgeMissingShows()
+	// Clean up missing files first. Library scans do it afterwards instead, so shows and
+	// episodes that were moved or renamed are followed to their new path rather than removed
+	// and added again.
+	if onlyIncoming {
+		PurgeMissingShows()
+	}
 
 	type showTask struct {
 		root string
@@ -123,14 +128,7 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 	// Fetch torrents once for incoming scans so we can do hash linking without per-file QB calls
 	var cachedTorrents []TorrentStatus
 	if onlyIncoming {
-		if qb, err := NewQBittorrentClient(cfg); err == nil {
-			if torrents, err := qb.GetTorrentsDetailed(context.Background(), ""); err == nil {
-				cachedTorrents = torrents
-				slog.Info("Fetched torrent list for incoming show scan", "count", len(cachedTorrents))
-			} else {
-				slog.Debug("Failed to fetch torrent list for incoming scan, hash linking will be skipped", "error", err)
-			}
-		}
+		cachedTorrents = fetchIncomingTorrents(cfg, "show")
 	}
 
 	// Start workers
@@ -157,7 +155,7 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 	if onlyIncoming {
 		paths = []string{cfg.IncomingShowsPath}
 	} else {
-		paths = []string{cfg.ShowsPath}
+		paths = LibraryRoots(cfg, "show")
 	}
 
 	for _, p := range paths {
@@ -180,7 +178,7 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 			case <-ctx.Done():
 				stopped = true
 			default:
-				if entry.IsDir() {
+				if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 					taskChan <- showTask{root: p, name: entry.Name()}
 				}
 			}
@@ -196,10 +194,15 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 	close(taskChan)
 	wg.Wait()
 
+	if !onlyIncoming {
+		PurgeMissingShows()
+	}
+
+	progress := GetScanProgress(scanType)
 	if ctx.Err() == context.Canceled {
-		slog.Info("Show scan cancelled", "scan_type", scanType)
+		slog.Info("Show scan cancelled", "scan_type", scanType, "seen", progress.Seen, "skipped", progress.Skipped, "updated", progress.Updated)
 	} else {
-		slog.Info("Show scan complete", "scan_type", scanType)
+		slog.Info("Show scan complete", "scan_type", scanType, "seen", progress.Seen, "skipped", progress.Skipped, "updated", progress.Updated)
 	}
 
 	return nil
@@ -207,7 +210,35 @@ func ScanShows(ctx context.Context, cfg *config.Config, onlyIncoming bool) error
 
 func processShowDir(cfg *config.Config, root string, name string, cachedTorrents []TorrentStatus) {
 	showPath := filepath.Join(root, name)
-	
+
+	// Skip shows whose videos are all unchanged since the last scan, following the folder first
+	// if it was moved or renamed
+	showFP, videos, err := showFingerprint(showPath)
+	if err != nil {
+		return
+	}
+	moved := false
+	if !fileUnchanged(showPath, "show", showFP) {
+		if oldPath := findMovedFile(showPath, "show", showFP); oldPath != "" {
+			followMovedFile(oldPath, showPath)
+			moved = true
+		}
+	}
+	if fileUnchanged(showPath, "show", showFP) {
+		slog.Debug("Show unchanged since last scan, skipping", "path", showPath)
+		progress := progressFor(showPath, cfg.IncomingShowsPath, ScanIncomingShows, ScanShowLibrary)
+		if !moved {
+			progress.skip(int64(videos))
+			return
+		}
+		progress.update(int64(videos))
+		// Its episodes were followed along with it, but it may need renaming for its new folder
+		var showID int
+		err := database.DB.QueryRow("SELECT id FROM shows WHERE path = $1", showPath).Scan(&showID)
+		settleScannedShow(cfg, showID, showPath, showFP, nil, err)
+		return
+	}
+
 	// Fallback to parsing folder name for show info and IDs
 	folderTitle, folderYear, tmdbID, tvdbID, imdbID := ParseMediaName(name)
 
@@ -365,11 +396,11 @@ func processShowDir(cfg *config.Config, root string, name string, cachedTorrents
 	}
 
 	// Fetch metadata immediately
-	globalMetadata.MatchShow(showID)
+	matchErr := globalMetadata.MatchShow(showID)
 
 	// Always scan the current path's seasons/episodes
 	// Even if we reused an existing show, we need to scan this new path
-	scanSeasons(showID, showPath, title, cfg, cachedTorrents)
+	episodes := scanSeasons(showID, showPath, title, cfg, cachedTorrents)
 
 	// After scanning episodes, check if we can find a request TVDB ID from episode torrent hashes
 	// This is a fallback in case the initial check didn't find a match
@@ -387,18 +418,15 @@ func processShowDir(cfg *config.Config, root string, name string, cachedTorrents
 					"tvdb_id", episodeTVDBID)
 				// Update the show with the TVDB ID and re-match
 				database.DB.Exec("UPDATE shows SET tvdb_id = $1 WHERE id = $2", episodeTVDBID, showID)
-				globalMetadata.MatchShow(showID)
+				matchErr = globalMetadata.MatchShow(showID)
 			}
 		}
 	}
 
 	// Force rename the show if its metadata, episodes, or quality dictate a new path.
-	// Only do this for library shows — incoming shows should stay put until the user manually imports them.
-	if !strings.HasPrefix(showPath, cfg.IncomingShowsPath) {
-		if err := RenameAndMoveShow(cfg, showID); err != nil {
-			slog.Debug("Error moving/renaming show during scan", "show_id", showID, "title", title, "error", err)
-		}
-	}
+	// Only library shows are renamed — incoming shows should stay put until the user manually
+	// imports them. The show and its episodes are indexed once matching and renaming succeeded.
+	settleScannedShow(cfg, showID, showPath, showFP, episodes, matchErr)
 }
 
 func upsertShow(show models.Show) (int, error) {
@@ -420,14 +448,16 @@ func upsertShow(show models.Show) (int, error) {
 	return id, err
 }
 
-func scanSeasons(showID int, showPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) {
+// scanSeasons scans a show folder's seasons and episodes, and returns the episode files it
+// probed so the caller can index them once the show is settled
+func scanSeasons(showID int, showPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) (scanned []scannedFile) {
 	if showTitle == "" {
 		database.DB.QueryRow("SELECT title FROM shows WHERE id = $1", showID).Scan(&showTitle)
 	}
 
 	entries, err := os.ReadDir(showPath)
 	if err != nil {
-		return
+		return nil
 	}
 
 	seasonRegex := regexp.MustCompile(`(?i)Season\s+(\d+)`)
@@ -456,7 +486,7 @@ func scanSeasons(showID int, showPath string, showTitle string, cfg *config.Conf
 			continue
 		}
 
-		scanEpisodes(showID, seasonID, seasonPath, showTitle, cfg, cachedTorrents)
+		scanned = append(scanned, scanEpisodes(showID, seasonID, seasonPath, showTitle, cfg, cachedTorrents)...)
 	}
 
 	// Second pass: if no standard Season folders found, try alternative patterns
@@ -479,7 +509,7 @@ func scanSeasons(showID int, showPath string, showTitle string, cfg *config.Conf
 				continue
 			}
 
-			scanEpisodes(showID, seasonID, seasonPath, showTitle, cfg, cachedTorrents)
+			scanned = append(scanned, scanEpisodes(showID, seasonID, seasonPath, showTitle, cfg, cachedTorrents)...)
 		}
 	}
 
@@ -500,21 +530,22 @@ func scanSeasons(showID int, showPath string, showTitle string, cfg *config.Conf
 			seasonNum, _ := strconv.Atoi(matches[1])
 			seasonID, err := upsertSeason(showID, seasonNum)
 			if err == nil {
-				scanEpisodes(showID, seasonID, showPath, showTitle, cfg, cachedTorrents)
+				scanned = append(scanned, scanEpisodes(showID, seasonID, showPath, showTitle, cfg, cachedTorrents)...)
 			}
 		} else {
 			// No season info in folder name, try to detect season from episode files
 			// Scan episodes directly from show folder and try to infer season from filenames
-			scanEpisodesFromShowFolder(showID, showPath, showTitle, cfg, cachedTorrents)
+			scanned = append(scanned, scanEpisodesFromShowFolder(showID, showPath, showTitle, cfg, cachedTorrents)...)
 		}
 	}
+	return scanned
 }
 
 // scanEpisodesFromShowFolder scans episodes directly from show folder and infers season from filenames
-func scanEpisodesFromShowFolder(showID int, showPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) {
+func scanEpisodesFromShowFolder(showID int, showPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) (scanned []scannedFile) {
 	entries, err := os.ReadDir(showPath)
 	if err != nil {
-		return
+		return nil
 	}
 
 	// Match SXXEXX pattern to extract both season and episode
@@ -538,12 +569,21 @@ func scanEpisodesFromShowFolder(showID int, showPath string, showTitle string, c
 		seasonNum, _ := strconv.Atoi(matches[1])
 		episodeNum, _ := strconv.Atoi(matches[2])
 		episodePath := filepath.Join(showPath, entry.Name())
+		if strings.HasPrefix(episodePath, cfg.IncomingShowsPath) && IsImportSource(episodePath) {
+			// Original of an episode that was copied or linked into the library while seeding
+			continue
+		}
 
 		seasonID, err := upsertSeason(showID, seasonNum)
 		if err != nil {
 			continue
 		}
 
+		fp, upToDate := episodeUpToDate(cfg, seasonID, episodeNum, episodePath)
+		if upToDate {
+			continue
+		}
+
 		// Clean the episode title using the new logic
 		officialTitle := getOfficialEpisodeTitle(showID, seasonNum, episodeNum)
 		epNameOnly := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
@@ -590,12 +630,14 @@ func scanEpisodesFromShowFolder(showID int, showPath string, showTitle string, c
 		}
 
 		upsertEpisode(seasonID, episodeNum, finalEpTitle, episodePath, quality, size)
+		scanned = append(scanned, episodeScanned(cfg, episodePath, fp))
 
 		// Try to link torrent hash if file is in incoming folder
 		if cfg != nil && strings.HasPrefix(episodePath, cfg.IncomingShowsPath) {
 			LinkTorrentHashToFile(cfg, cachedTorrents, episodePath, "show")
 		}
 	}
+	return scanned
 }
 
 func upsertSeason(showID int, seasonNum int) (int, error) {
@@ -611,13 +653,13 @@ func upsertSeason(showID int, seasonNum int) (int, error) {
 	return id, err
 }
 
-func scanEpisodes(showID int, seasonID int, seasonPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) {
+func scanEpisodes(showID int, seasonID int, seasonPath string, showTitle string, cfg *config.Config, cachedTorrents []TorrentStatus) (scanned []scannedFile) {
 	var seasonNum int
 	database.DB.QueryRow("SELECT season_number FROM seasons WHERE id = $1", seasonID).Scan(&seasonNum)
 
 	entries, err := os.ReadDir(seasonPath)
 	if err != nil {
-		return
+		return nil
 	}
 
 	// Match SXXEXX
@@ -640,6 +682,15 @@ func scanEpisodes(showID int, seasonID int, seasonPath string, showTitle string,
 
 		episodeNum, _ := strconv.Atoi(matches[1])
 		episodePath := filepath.Join(seasonPath, entry.Name())
+		if strings.HasPrefix(episodePath, cfg.IncomingShowsPath) && IsImportSource(episodePath) {
+			// Original of an episode that was copied or linked into the library while seeding
+			continue
+		}
+
+		fp, upToDate := episodeUpToDate(cfg, seasonID, episodeNum, episodePath)
+		if upToDate {
+			continue
+		}
 
 		// Get official title from cache
 		officialTitle := getOfficialEpisodeTitle(showID, seasonNum, episodeNum)
@@ -694,12 +745,48 @@ func scanEpisodes(showID int, seasonID int, seasonPath string, showTitle string,
 		}
 
 		upsertEpisode(seasonID, episodeNum, finalEpTitle, episodePath, quality, size)
+		scanned = append(scanned, episodeScanned(cfg, episodePath, fp))
 
 		// Try to link torrent hash if file is in incoming folder
 		if cfg != nil && strings.HasPrefix(episodePath, cfg.IncomingShowsPath) {
 			LinkTorrentHashToFile(cfg, cachedTorrents, episodePath, "show")
 		}
 	}
+	return scanned
+}
+
+// episodeUpToDate reports whether an episode file is unchanged since it was last probed, or
+// was moved from a path its row is then repointed from, so it doesn't need probing again
+func episodeUpToDate(cfg *config.Config, seasonID int, episodeNum int, episodePath string) (fileFingerprint, bool) {
+	info, err := os.Stat(episodePath)
+	if err != nil {
+		return fileFingerprint{}, false
+	}
+	fp := fingerprintOf(info)
+	progress := progressFor(episodePath, cfg.IncomingShowsPath, ScanIncomingShows, ScanShowLibrary)
+	if fileUnchanged(episodePath, "episode", fp) {
+		progress.skip(1)
+		return fp, true
+	}
+	if oldPath := findMovedFile(episodePath, "episode", fp); oldPath != "" {
+		followMovedFile(oldPath, episodePath)
+		// It may have moved to another season or show folder
+		if _, err := database.DB.Exec(`UPDATE episodes SET season_id = $1, episode_number = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE file_path = $3`, seasonID, episodeNum, episodePath); err != nil {
+			slog.Warn("Failed to update season of moved episode", "path", episodePath, "error", err)
+		}
+		indexFile(episodePath, "episode", fp)
+		progress.update(1)
+		return fp, true
+	}
+	return fp, false
+}
+
+// episodeScanned counts an episode file that was just probed, and returns it for indexing
+// once its show is settled
+func episodeScanned(cfg *config.Config, episodePath string, fp fileFingerprint) scannedFile {
+	progressFor(episodePath, cfg.IncomingShowsPath, ScanIncomingShows, ScanShowLibrary).update(1)
+	return scannedFile{path: episodePath, fp: fp}
 }
 
 func upsertEpisode(seasonID int, episodeNum int, title string, path string, quality string, size int64) {
@@ -776,6 +863,12 @@ func GetShowCount(excludeIncomingPath string) (int, error) {
 func PurgeMissingShows() {
 	slog.Debug("Checking for missing shows")
 
+	// Shows on a root folder whose disk isn't mounted are unreachable, not gone
+	offline := unavailableRoots("show")
+	if len(offline) > 0 {
+		slog.Warn("Root folders are unavailable, keeping their shows", "paths", offline)
+	}
+
 	// Check Shows — collect IDs first, then delete after closing the cursor
 	rows, err := database.DB.Query("SELECT id, path FROM shows")
 	if err != nil {
@@ -789,7 +882,7 @@ func PurgeMissingShows() {
 		if err := rows.Scan(&id, &path); err != nil {
 			continue
 		}
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(path); os.IsNotExist(err) && rootOf(offline, path) == "" {
 			showsToDelete = append(showsToDelete, id)
 		}
 	}
@@ -813,7 +906,7 @@ func PurgeMissingShows() {
 		if err := epRows.Scan(&id, &path); err != nil {
 			continue
 		}
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(path); os.IsNotExist(err) && rootOf(offline, path) == "" {
 			episodesToDelete = append(episodesToDelete, id)
 		}
 	}
@@ -823,6 +916,7 @@ func PurgeMissingShows() {
 		slog.Info("Removing missing episode from DB", "episode_id", id)
 		database.DB.Exec("DELETE FROM episodes WHERE id = $1", id)
 	}
+	purgeFileIndex("show", "episode")
 }
 
 func SearchShowsLocal(query string) ([]models.Show, error) {
@@ -945,7 +1039,7 @@ func GetShowByID(id int) (*models.Show, error) {
 
 type SeasonWithEpisodes struct {
 	models.Season
-	Episodes []models.Episode
+	Episodes []models.Episode `json:"episodes"`
 }
 
 func GetShowSeasons(showID int) ([]SeasonWithEpisodes, error) {
@@ -0,0 +1,566 @@
+package services
+
+import (
+	"Arrgo/database"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// subtitleExts are the subtitle formats we recognise next to a video
+var subtitleExts = map[string]bool{
+	".srt": true, ".ass": true, ".ssa": true, ".vtt": true, ".sub": true, ".idx": true,
+}
+
+// textSubtitleExts are the formats ProcessSubtitleFile can parse and rewrite as SRT.
+// SUB/IDX (VobSub) is image-based and is only copied and renamed.
+var textSubtitleExts = map[string]bool{
+	".srt": true, ".ass": true, ".ssa": true, ".vtt": true,
+}
+
+// subtitleLanguages maps language names and ISO 639-1/639-2 codes found in subtitle
+// filenames to the two-letter code used in our naming
+var subtitleLanguages = map[string]string{
+	"en": "en", "eng": "en", "english": "en",
+	"es": "es", "spa": "es", "spanish": "es",
+	"fr": "fr", "fre": "fr", "fra": "fr", "french": "fr",
+	"de": "de", "ger": "de", "deu": "de", "german": "de",
+	"it": "it", "ita": "it", "italian": "it",
+	"pt": "pt", "por": "pt", "portuguese": "pt",
+	"nl": "nl", "dut": "nl", "nld": "nl", "dutch": "nl",
+	"sv": "sv", "swe": "sv", "swedish": "sv",
+	"no": "no", "nor": "no", "norwegian": "no",
+	"da": "da", "dan": "da", "danish": "da",
+	"fi": "fi", "fin": "fi", "finnish": "fi",
+	"pl": "pl", "pol": "pl", "polish": "pl",
+	"ru": "ru", "rus": "ru", "russian": "ru",
+	"ja": "ja", "jpn": "ja", "japanese": "ja",
+	"ko": "ko", "kor": "ko", "korean": "ko",
+	"zh": "zh", "chi": "zh", "zho": "zh", "chinese": "zh",
+	"ar": "ar", "ara": "ar", "arabic": "ar",
+}
+
+// SubtitleTags are the language and flags encoded in a subtitle filename,
+// e.g. "Movie (2020).en.forced.srt" or "2_English_SDH.srt"
+type SubtitleTags struct {
+	Language string
+	Forced   bool
+	SDH      bool
+}
+
+// IsSubtitleFile reports whether a filename has a recognised subtitle extension
+func IsSubtitleFile(name string) bool {
+	return subtitleExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// ParseSubtitleTags reads language/forced/sdh tags from the end of a subtitle filename.
+// Tags are only taken from trailing tokens so titles like "It (2017)" aren't read as Italian.
+func ParseSubtitleTags(name string) SubtitleTags {
+	var tags SubtitleTags
+	stem := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	tokens := strings.FieldsFunc(strings.ToLower(stem), func(r rune) bool {
+		return r == '.' || r == '_' || r == ' ' || r == '-'
+	})
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		tok := tokens[i]
+		switch {
+		case tok == "forced" || tok == "foreign":
+			tags.Forced = true
+		case tok == "sdh" || tok == "cc" || tok == "hearing" || tok == "impaired":
+			tags.SDH = true
+		case subtitleLanguages[tok] != "" && tags.Language == "":
+			tags.Language = subtitleLanguages[tok]
+		default:
+			return tags
+		}
+	}
+	return tags
+}
+
+// SubtitlePathForVideo builds the Plex/Jellyfin style subtitle path for a video:
+// <video base>.<lang>[.forced][.sdh]<ext>. Untagged subtitles are assumed to be English.
+func SubtitlePathForVideo(videoPath string, tags SubtitleTags, ext string) string {
+	lang := tags.Language
+	if lang == "" {
+		lang = "en"
+	}
+	name := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "." + lang
+	if tags.Forced {
+		name += ".forced"
+	}
+	if tags.SDH {
+		name += ".sdh"
+	}
+	return name + strings.ToLower(ext)
+}
+
+type subtitleCue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// ProcessSubtitleFile cleans up a subtitle and writes it next to videoPath with normalized
+// language/forced/sdh tags. Text formats are converted to UTF-8 SRT, ad/credit cues are
+// stripped, and hearing-impaired annotations are removed when removeHI is set. Image-based
+// formats are copied as-is. The source file is never removed. Returns the written path, or
+// "" if a subtitle already exists at the destination.
+func ProcessSubtitleFile(srcPath, videoPath string, removeHI bool) (string, error) {
+	ext := strings.ToLower(filepath.Ext(srcPath))
+	if !subtitleExts[ext] {
+		return "", fmt.Errorf("unsupported subtitle format: %s", ext)
+	}
+
+	tags := ParseSubtitleTags(srcPath)
+
+	if !textSubtitleExts[ext] {
+		destPath := SubtitlePathForVideo(videoPath, tags, ext)
+		if destPath == srcPath {
+			return destPath, nil
+		}
+		if _, err := os.Stat(destPath); err == nil {
+			return "", nil
+		}
+		if err := copyFile(srcPath, destPath); err != nil {
+			return "", fmt.Errorf("failed to copy subtitle: %w", err)
+		}
+		return destPath, nil
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read subtitle: %w", err)
+	}
+	text := decodeSubtitleText(data)
+
+	var cues []subtitleCue
+	switch ext {
+	case ".ass", ".ssa":
+		cues = parseASS(text)
+	default:
+		cues = parseSRT(text)
+	}
+	if len(cues) == 0 {
+		return "", fmt.Errorf("no subtitle cues found in %s", filepath.Base(srcPath))
+	}
+
+	cues = stripAdCues(cues)
+	if removeHI {
+		cues = stripHearingImpaired(cues)
+		tags.SDH = false
+	}
+
+	destPath := SubtitlePathForVideo(videoPath, tags, ".srt")
+	if destPath != srcPath {
+		if _, err := os.Stat(destPath); err == nil {
+			return "", nil
+		}
+	}
+
+	if err := os.WriteFile(destPath, formatSRT(cues), 0644); err != nil {
+		return "", fmt.Errorf("failed to write subtitle: %w", err)
+	}
+	return destPath, nil
+}
+
+// decodeSubtitleText converts subtitle bytes to a UTF-8 string. UTF-16 is detected by BOM;
+// anything that isn't valid UTF-8 is assumed to be Windows-1252, the usual culprit.
+func decodeSubtitleText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err == nil {
+			return string(decoded)
+		}
+	case utf8.Valid(data):
+		return string(data)
+	}
+
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+var (
+	cueBlockSplitRegex = regexp.MustCompile(`\n\s*\n`)
+	htmlTagRegex       = regexp.MustCompile(`</?([a-zA-Z]+)[^>]*>`)
+	assOverrideRegex   = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// parseSRT parses SRT and WebVTT cues. VTT headers, NOTE/STYLE blocks, cue settings
+// and non-basic formatting tags are dropped.
+func parseSRT(text string) []subtitleCue {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var cues []subtitleCue
+	for _, block := range cueBlockSplitRegex.Split(strings.TrimSpace(text), -1) {
+		lines := strings.Split(block, "\n")
+		timingIdx := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timingIdx = i
+				break
+			}
+		}
+		if timingIdx == -1 {
+			continue
+		}
+
+		parts := strings.SplitN(lines[timingIdx], "-->", 2)
+		start, ok1 := parseSubtitleTimestamp(parts[0])
+		endFields := strings.Fields(parts[1])
+		if len(endFields) == 0 {
+			continue
+		}
+		end, ok2 := parseSubtitleTimestamp(endFields[0])
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		var textLines []string
+		for _, line := range lines[timingIdx+1:] {
+			line = strings.TrimSpace(htmlTagRegex.ReplaceAllStringFunc(line, keepBasicTags))
+			if line != "" {
+				textLines = append(textLines, line)
+			}
+		}
+		if len(textLines) > 0 {
+			cues = append(cues, subtitleCue{Start: start, End: end, Lines: textLines})
+		}
+	}
+	return cues
+}
+
+// keepBasicTags keeps the italic/bold/underline tags players understand and drops the rest
+// (VTT voice/class spans, fonts, etc.)
+func keepBasicTags(tag string) string {
+	name := strings.ToLower(htmlTagRegex.FindStringSubmatch(tag)[1])
+	if name == "i" || name == "b" || name == "u" {
+		if strings.HasPrefix(tag, "</") {
+			return "</" + name + ">"
+		}
+		return "<" + name + ">"
+	}
+	return ""
+}
+
+// parseASS parses the [Events] section of an ASS/SSA script, using its Format line
+// to locate the Start, End and Text fields. Override blocks and drawings are dropped.
+func parseASS(text string) []subtitleCue {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var cues []subtitleCue
+	inEvents := false
+	startIdx, endIdx, textIdx := 1, 2, 9
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		if strings.HasPrefix(line, "Format:") {
+			fields := strings.Split(strings.TrimPrefix(line, "Format:"), ",")
+			for i, f := range fields {
+				switch strings.ToLower(strings.TrimSpace(f)) {
+				case "start":
+					startIdx = i
+				case "end":
+					endIdx = i
+				case "text":
+					textIdx = i
+				}
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(line, "Dialogue:") {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(line, "Dialogue:"), ",", textIdx+1)
+		if len(fields) <= textIdx || len(fields) <= startIdx || len(fields) <= endIdx {
+			continue
+		}
+
+		start, ok1 := parseSubtitleTimestamp(fields[startIdx])
+		end, ok2 := parseSubtitleTimestamp(fields[endIdx])
+		body := fields[textIdx]
+		if !ok1 || !ok2 || strings.Contains(body, `\p1`) {
+			continue
+		}
+
+		body = assOverrideRegex.ReplaceAllString(body, "")
+		body = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(body)
+
+		var textLines []string
+		for _, l := range strings.Split(body, "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				textLines = append(textLines, l)
+			}
+		}
+		if len(textLines) > 0 {
+			cues = append(cues, subtitleCue{Start: start, End: end, Lines: textLines})
+		}
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues
+}
+
+// parseSubtitleTimestamp parses SRT (00:01:02,345), VTT (01:02.345) and ASS (0:01:02.34) times
+func parseSubtitleTimestamp(s string) (time.Duration, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+
+	var hours, minutes int
+	var err error
+	if len(parts) == 3 {
+		if hours, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, false
+		}
+		parts = parts[1:]
+	}
+	if minutes, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, false
+	}
+
+	secParts := strings.SplitN(parts[1], ".", 2)
+	seconds, err := strconv.Atoi(secParts[0])
+	if err != nil {
+		return 0, false
+	}
+	millis := 0
+	if len(secParts) == 2 {
+		frac := (secParts[1] + "000")[:3]
+		if millis, err = strconv.Atoi(frac); err != nil {
+			return 0, false
+		}
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond, true
+}
+
+func formatSRTTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+func formatSRT(cues []subtitleCue) []byte {
+	var buf bytes.Buffer
+	for i, cue := range cues {
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n\n", i+1, formatSRTTimestamp(cue.Start), formatSRTTimestamp(cue.End), strings.Join(cue.Lines, "\n"))
+	}
+	return buf.Bytes()
+}
+
+// subtitleAdRegex matches the ad and credit lines OpenSubtitles and release groups insert
+var subtitleAdRegex = regexp.MustCompile(`(?i)(opensubtitles|osdb\.link|addic7ed|subscene|podnapisi|` +
+	`advertise your product|become vip member|support us and become|` +
+	`subtitles? (by|downloaded from|provided by|ripped by|created by)|` +
+	`sync(ed|hronized)?( and | & |, )?correct(ed|ions)? by|encoded by|` +
+	`www\.[a-z0-9-]+\.(com|org|net|tv))`)
+
+func stripAdCues(cues []subtitleCue) []subtitleCue {
+	kept := cues[:0]
+	for _, cue := range cues {
+		if subtitleAdRegex.MatchString(strings.Join(cue.Lines, " ")) {
+			continue
+		}
+		kept = append(kept, cue)
+	}
+	return kept
+}
+
+var (
+	hiBracketRegex   = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	hiSpeakerRegex   = regexp.MustCompile(`^(-\s*)?[A-Z][A-Z0-9 .'#&-]*:\s*`)
+	hiMusicOnlyRegex = regexp.MustCompile(`^[\s♪♫#*-]*$`)
+	emptyTagRegex    = regexp.MustCompile(`<([ibu])>\s*</[ibu]>`)
+
+	// hiSoundRegex matches the start of lowercase sound descriptions like "(laughs)" or
+	// "(door slams)", so dialogue asides like "(if you ask me)" are kept
+	hiSoundRegex = regexp.MustCompile(`(?i)^(laugh(s|ing)?|chuckl(es|ing)|giggl(es|ing)|sigh(s|ing)?|gasp(s|ing)?|` +
+		`groan(s|ing)?|grunt(s|ing)?|moan(s|ing)?|scream(s|ing)?|shout(s|ing)?|yell(s|ing)?|sob(s|bing)?|` +
+		`cries|crying|cough(s|ing)?|sniff(s|les|ling|ing)?|sneez(es|ing)|whisper(s|ing)?|mutter(s|ing)?|` +
+		`murmur(s|ing)?|panting|exhales?|inhales?|clears throat|hum(s|ming)|sing(s|ing)|whistl(es|ing)|` +
+		`scoffs?|snorts?|howl(s|ing)?|bark(s|ing)?|growl(s|ing)?|music|song|applause|cheer(s|ing)|sirens?|` +
+		`thunder|explosions?|gunshots?|footsteps|door|phone|knock(s|ing)?|bang(s|ing)?|thuds?|beep(s|ing)?|` +
+		`buzz(es|ing)?|click(s|ing)?|crash(es|ing)?|creak(s|ing)?|ring(s|ing)|indistinct|inaudible|` +
+		`chatter(ing)?|continues|speaking)\b`)
+)
+
+// isHIAnnotation reports whether a bracketed span is a hearing-impaired annotation. Square
+// brackets are only used for annotations; parentheses also hold dialogue, so they need
+// all-caps text or a known sound word.
+func isHIAnnotation(span string) bool {
+	if strings.HasPrefix(span, "[") {
+		return true
+	}
+	text := strings.TrimSpace(span[1 : len(span)-1])
+	if len(text) > 1 && strings.ToUpper(text) == text && strings.ToLower(text) != text {
+		return true
+	}
+	return hiSoundRegex.MatchString(text)
+}
+
+// stripHearingImpaired removes sound descriptions ([door slams], (LAUGHS), (sighs)), speaker
+// labels (JOHN:) and music-only lines, dropping cues that end up empty
+func stripHearingImpaired(cues []subtitleCue) []subtitleCue {
+	kept := cues[:0]
+	for _, cue := range cues {
+		var lines []string
+		for _, line := range cue.Lines {
+			line = hiBracketRegex.ReplaceAllStringFunc(line, func(span string) string {
+				if isHIAnnotation(span) {
+					return ""
+				}
+				return span
+			})
+			line = strings.Join(strings.Fields(line), " ")
+			line = hiSpeakerRegex.ReplaceAllString(line, "$1")
+			line = strings.TrimSpace(emptyTagRegex.ReplaceAllString(line, ""))
+			if hiMusicOnlyRegex.MatchString(line) {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) > 0 {
+			cue.Lines = lines
+			kept = append(kept, cue)
+		}
+	}
+	return kept
+}
+
+// importReleaseSubtitles processes subtitles shipped with a release into the library next to
+// destVideoPath. Subtitles beside the source video must share its name or SxxExx; subtitles in
+// a Subs/ folder are taken for movies, and matched by SxxExx for episodes. Existing library
+// subtitles are never overwritten.
+func importReleaseSubtitles(srcVideoPath, destVideoPath string, scanSubfolders, removeHI bool) int {
+	srcDir := filepath.Dir(srcVideoPath)
+	srcBase := strings.ToLower(strings.TrimSuffix(filepath.Base(srcVideoPath), filepath.Ext(srcVideoPath)))
+	seMatch := strings.ToLower(regexp.MustCompile(`(?i)(s\d+e\d+)`).FindString(srcBase))
+
+	var candidates []string
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return 0
+	}
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if entry.IsDir() {
+			if scanSubfolders && (name == "subs" || name == "subtitles" || name == "sub") {
+				filepath.WalkDir(filepath.Join(srcDir, entry.Name()), func(path string, d os.DirEntry, err error) error {
+					if err != nil || d.IsDir() || !IsSubtitleFile(path) {
+						return nil
+					}
+					// Episode packs usually use Subs/<episode name>/2_English.srt
+					rel := strings.ToLower(path)
+					if seMatch == "" || strings.Contains(rel, seMatch) || strings.Contains(rel, srcBase) {
+						candidates = append(candidates, path)
+					}
+					return nil
+				})
+			}
+			continue
+		}
+		if !IsSubtitleFile(name) {
+			continue
+		}
+		if strings.HasPrefix(name, srcBase) || (seMatch != "" && strings.Contains(name, seMatch)) {
+			candidates = append(candidates, filepath.Join(srcDir, entry.Name()))
+		}
+	}
+
+	imported := 0
+	for _, src := range candidates {
+		destPath, err := ProcessSubtitleFile(src, destVideoPath, removeHI)
+		if err != nil {
+			slog.Warn("Failed to import release subtitle", "subtitle", src, "error", err)
+			continue
+		}
+		if destPath != "" {
+			imported++
+			slog.Info("Imported release subtitle", "from", src, "to", destPath)
+		}
+	}
+	return imported
+}
+
+// moveVideoSubtitles renames subtitles (and their sync backups) that belong to oldVideoPath
+// so they keep matching the video after it is renamed, preserving their language tags and
+// recorded syncs
+func moveVideoSubtitles(oldVideoPath, newVideoPath string) {
+	oldBase := strings.TrimSuffix(oldVideoPath, filepath.Ext(oldVideoPath))
+	newBase := strings.TrimSuffix(newVideoPath, filepath.Ext(newVideoPath))
+	if oldBase == newBase {
+		return
+	}
+
+	matches, err := filepath.Glob(escapeGlob(oldBase) + ".*")
+	if err != nil {
+		return
+	}
+	for _, path := range matches {
+		name := strings.TrimSuffix(path, ".orig")
+		if !IsSubtitleFile(name) {
+			continue
+		}
+		destPath := newBase + strings.TrimPrefix(path, oldBase)
+		if _, err := os.Stat(destPath); err == nil {
+			continue
+		}
+		if err := safeRename(path, destPath); err != nil {
+			slog.Error("Failed to move subtitle with video", "from", path, "to", destPath, "error", err)
+		} else {
+			slog.Info("Moved subtitle with video", "from", path, "to", destPath)
+			moveSubtitleSync(path, destPath)
+		}
+	}
+}
+
+// moveSubtitleSync points subtitle_syncs at the new path of a moved subtitle or sync backup,
+// so the sync can still be reviewed and reverted
+func moveSubtitleSync(oldPath, newPath string) {
+	var err error
+	if strings.HasSuffix(oldPath, ".orig") {
+		_, err = database.DB.Exec("UPDATE subtitle_syncs SET backup_path = $2 WHERE backup_path = $1", oldPath, newPath)
+	} else {
+		// Nothing was at the new path, so any sync recorded for it is stale
+		if _, err = database.DB.Exec("DELETE FROM subtitle_syncs WHERE subtitle_path = $1", newPath); err == nil {
+			_, err = database.DB.Exec("UPDATE subtitle_syncs SET subtitle_path = $2 WHERE subtitle_path = $1", oldPath, newPath)
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to update subtitle sync path", "from", oldPath, "to", newPath, "error", err)
+	}
+}
+
+// escapeGlob escapes glob metacharacters, which are common in media names (e.g. "[1080p]")
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(path)
+}
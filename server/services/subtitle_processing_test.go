@@ -0,0 +1,39 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStripHearingImpaired(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string // nil when the cue is dropped
+	}{
+		{"square brackets", []string{"[door slams]", "Who's there?"}, []string{"Who's there?"}},
+		{"all-caps parenthetical", []string{"(GUNSHOT) Get down!"}, []string{"Get down!"}},
+		{"lowercase sound word", []string{"(laughs) You wish."}, []string{"You wish."}},
+		{"sound description", []string{"(phone ringing)"}, nil},
+		{"speaker label", []string{"JOHN: Over here."}, []string{"Over here."}},
+		{"dash and speaker label", []string{"- MARY: Wait!"}, []string{"- Wait!"}},
+		{"music only", []string{"♪ ♪"}, nil},
+		{"dialogue aside is kept", []string{"It's fine (if you ask me)."}, []string{"It's fine (if you ask me)."}},
+		{"parenthetical starting with a sound-like word is kept", []string{"(Ringo) is here."}, []string{"(Ringo) is here."}},
+		{"single capital letter is kept", []string{"Plan (B) it is."}, []string{"Plan (B) it is."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stripHearingImpaired([]subtitleCue{{Lines: tt.lines}})
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Errorf("stripHearingImpaired() = %q, want the cue dropped", got[0].Lines)
+				}
+				return
+			}
+			if len(got) != 1 || !slices.Equal(got[0].Lines, tt.want) {
+				t.Errorf("stripHearingImpaired() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subtitle queue priorities. Higher values are processed first.
+const (
+	SubtitlePriorityBacklog = 0
+	SubtitlePriorityWatched = 10
+	SubtitlePriorityImport  = 20
+
+	// recentImportWindow is how long after import an item keeps import priority
+	recentImportWindow = 7 * 24 * time.Hour
+
+	// defaultDailySubtitleQuota is assumed until OpenSubtitles reports the real allowance
+	defaultDailySubtitleQuota = 20
+)
+
+const (
+	quotaRemainingKey = "opensubtitles_quota_remaining"
+	quotaLimitKey     = "opensubtitles_quota_limit"
+	quotaRenewsAtKey  = "opensubtitles_quota_renews_at"
+)
+
+// SubtitleQuota is the OpenSubtitles daily download allowance as last reported by the API
+type SubtitleQuota struct {
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	RenewsAt  time.Time `json:"renews_at"`
+	Known     bool      `json:"known"`
+}
+
+// SubtitleQueueStatus summarises the queue and quota for the admin page
+type SubtitleQueueStatus struct {
+	Quota               SubtitleQuota `json:"quota"`
+	Reserve             int           `json:"reserve"`
+	Queued              int           `json:"queued"`
+	QueuedImports       int           `json:"queued_imports"`
+	QueuedWatched       int           `json:"queued_watched"`
+	QueuedBacklog       int           `json:"queued_backlog"`
+	BudgetThisRun       int           `json:"budget_this_run"`
+	ProjectedCompletion *time.Time    `json:"projected_completion,omitempty"`
+}
+
+// recordQuota stores the remaining allowance reported by a /download response
+func (s *SubtitleService) recordQuota(info OSDownloadResponse) {
+	settings := quotaSettings(info)
+	if settings == nil {
+		return
+	}
+	for key, value := range settings {
+		s.SetSetting(key, value)
+	}
+	slog.Debug("OpenSubtitles quota updated", "remaining", info.Remaining, "used", info.Requests, "reset_time", info.ResetTimeUTC)
+}
+
+// quotaSettings returns the settings recordQuota stores for a /download response, or nil
+// when the response didn't report the quota
+func quotaSettings(info OSDownloadResponse) map[string]string {
+	if info.Remaining == 0 && info.Requests == 0 {
+		return nil
+	}
+	settings := map[string]string{
+		quotaRemainingKey: strconv.Itoa(info.Remaining),
+		quotaLimitKey:     strconv.Itoa(info.Requests + info.Remaining),
+	}
+	if t, err := time.Parse(time.RFC3339, info.ResetTimeUTC); err == nil {
+		settings[quotaRenewsAtKey] = t.Format(time.RFC3339)
+	}
+	return settings
+}
+
+// GetQuota returns the current OpenSubtitles quota. Once the renewal time has passed the
+// full allowance is assumed to be available again.
+func (s *SubtitleService) GetQuota() SubtitleQuota {
+	settings := make(map[string]string)
+	rows, err := s.db.Query("SELECT key, value FROM settings WHERE key IN ($1, $2, $3)", quotaRemainingKey, quotaLimitKey, quotaRenewsAtKey)
+	if err != nil {
+		return quotaFromSettings(nil, time.Now())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err == nil {
+			settings[key] = value
+		}
+	}
+
+	quota := quotaFromSettings(settings, time.Now())
+	if s.IsQuotaLocked() {
+		quota.Remaining = 0
+	}
+	return quota
+}
+
+// quotaFromSettings reads the quota recordQuota stored, as of now
+func quotaFromSettings(settings map[string]string, now time.Time) SubtitleQuota {
+	quota := SubtitleQuota{Remaining: defaultDailySubtitleQuota, Limit: defaultDailySubtitleQuota}
+	if v, err := strconv.Atoi(settings[quotaLimitKey]); err == nil && v > 0 {
+		quota.Limit = v
+		quota.Known = true
+	}
+	quota.Remaining = quota.Limit
+	if v, err := strconv.Atoi(settings[quotaRemainingKey]); err == nil {
+		quota.Remaining = v
+	}
+
+	quota.RenewsAt = nextUTCMidnight(now)
+	if t, err := time.Parse(time.RFC3339, settings[quotaRenewsAtKey]); err == nil {
+		quota.RenewsAt = t
+	}
+	if now.After(quota.RenewsAt) {
+		quota.Remaining = quota.Limit
+		quota.RenewsAt = nextUTCMidnight(now)
+	}
+	return quota
+}
+
+func nextUTCMidnight(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// queueBudget is how many backlog downloads the scheduler may spend this hour. The quota left
+// above the import reserve is spread evenly over the hours until it renews.
+func (s *SubtitleService) queueBudget(quota SubtitleQuota) int {
+	spendable := quota.Remaining - s.cfg.SubtitleQuotaReserve
+	if spendable <= 0 {
+		return 0
+	}
+	hoursLeft := math.Ceil(time.Until(quota.RenewsAt).Hours())
+	if hoursLeft < 1 {
+		hoursLeft = 1
+	}
+	return int(math.Ceil(float64(spendable) / hoursLeft))
+}
+
+// subtitlePriorityFor gives recently imported items import priority so they aren't stuck
+// behind the backlog when they had to be queued
+func (s *SubtitleService) subtitlePriorityFor(mediaType string, mediaID int) int {
+	query := "SELECT imported_at FROM movies WHERE id = $1"
+	if mediaType == "episode" {
+		query = "SELECT imported_at FROM episodes WHERE id = $1"
+	}
+	var importedAt *time.Time
+	if err := s.db.QueryRow(query, mediaID).Scan(&importedAt); err == nil && importedAt != nil {
+		if time.Since(*importedAt) < recentImportWindow {
+			return SubtitlePriorityImport
+		}
+	}
+	return SubtitlePriorityBacklog
+}
+
+// PrioritizeRecentlyWatched bumps queued items the user base is actively watching: movies
+// that were recently played and any episode of a show with a recently played episode
+func (s *SubtitleService) PrioritizeRecentlyWatched() {
+	paths, err := GetRecentlyPlayedPaths(s.cfg)
+	if err != nil {
+		slog.Debug("Could not fetch recently played items", "error", err)
+		return
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	res, err := s.db.Exec(`
+		UPDATE subtitle_queue q SET priority = $2
+		WHERE q.priority < $2 AND (
+			(q.media_type = 'movie' AND q.media_id IN (SELECT id FROM movies WHERE path = ANY($1)))
+			OR (q.media_type = 'episode' AND q.media_id IN (
+				SELECT e.id FROM episodes e
+				JOIN seasons se ON e.season_id = se.id
+				WHERE se.show_id IN (
+					SELECT se2.show_id FROM episodes e2
+					JOIN seasons se2 ON e2.season_id = se2.id
+					WHERE e2.file_path = ANY($1)
+				)
+			))
+		)`, paths, SubtitlePriorityWatched)
+	if err != nil {
+		slog.Error("Failed to prioritize recently watched subtitles", "error", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Info("Prioritized subtitle queue for recently watched media", "count", n)
+	}
+}
+
+// GetQueueStatus reports queue size, quota and when the backlog is projected to be cleared
+func (s *SubtitleService) GetQueueStatus() (*SubtitleQueueStatus, error) {
+	status := &SubtitleQueueStatus{
+		Quota:   s.GetQuota(),
+		Reserve: s.cfg.SubtitleQuotaReserve,
+	}
+	status.BudgetThisRun = s.queueBudget(status.Quota)
+
+	err := s.db.QueryRow(`
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE priority >= $1),
+			COUNT(*) FILTER (WHERE priority >= $2 AND priority < $1),
+			COUNT(*) FILTER (WHERE priority < $2)
+		FROM subtitle_queue`, SubtitlePriorityImport, SubtitlePriorityWatched).Scan(
+		&status.Queued, &status.QueuedImports, &status.QueuedWatched, &status.QueuedBacklog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subtitle queue: %w", err)
+	}
+
+	status.ProjectedCompletion = projectedCompletion(status, time.Now())
+	return status, nil
+}
+
+// projectedCompletion is when the queue should be cleared, or nil when it's empty or the
+// reserve leaves nothing to download it with: what's left today above the reserve, then a
+// full day's allowance (minus reserve) per day
+func projectedCompletion(status *SubtitleQueueStatus, now time.Time) *time.Time {
+	dailyUsable := status.Quota.Limit - status.Reserve
+	if status.Queued == 0 || dailyUsable <= 0 {
+		return nil
+	}
+	remaining := status.Queued - max(status.Quota.Remaining-status.Reserve, 0)
+	var eta time.Time
+	if remaining <= 0 {
+		eta = now.Add(time.Duration(status.Queued) * time.Hour / time.Duration(max(status.BudgetThisRun, 1)))
+		if eta.After(status.Quota.RenewsAt) {
+			eta = status.Quota.RenewsAt
+		}
+	} else {
+		days := (remaining + dailyUsable - 1) / dailyUsable
+		eta = status.Quota.RenewsAt.AddDate(0, 0, days)
+	}
+	return &eta
+}
+
+type subtitleJob struct {
+	id       int
+	mType    string
+	mID      int
+	priority int
+}
+
+// ProcessQueue downloads queued subtitles in priority order (imports, recently watched, backlog)
+// while keeping SUBTITLE_QUOTA_RESERVE downloads free for new imports. Backlog downloads are
+// spread across the day rather than spent in one burst.
+func (s *SubtitleService) ProcessQueue() {
+	if s.IsQuotaLocked() {
+		slog.Debug("Still in OpenSubtitles quota lockdown")
+		return
+	}
+
+	s.PrioritizeRecentlyWatched()
+
+	rows, err := s.db.Query(`
+		SELECT id, media_type, media_id, COALESCE(priority, 0) FROM subtitle_queue
+		WHERE next_retry <= CURRENT_TIMESTAMP
+		ORDER BY priority DESC, created_at ASC`)
+	if err != nil {
+		slog.Error("Error querying subtitle queue", "error", err)
+		return
+	}
+	var jobs []subtitleJob
+	for rows.Next() {
+		var j subtitleJob
+		if err := rows.Scan(&j.id, &j.mType, &j.mID, &j.priority); err == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	budget := s.queueBudget(s.GetQuota())
+	slog.Debug("Processing subtitle queue", "ready_jobs", len(jobs), "budget", budget)
+
+	for _, j := range jobs {
+		quota := s.GetQuota()
+		if quota.Remaining <= 0 {
+			break
+		}
+		// Imports may dip into the reserve; everything else stays within this run's budget
+		if j.priority < SubtitlePriorityImport {
+			if budget <= 0 {
+				break
+			}
+			budget--
+		}
+
+		slog.Info("Retrying subtitle download", "media_type", j.mType, "media_id", j.mID, "priority", j.priority)
+		var err error
+		if j.mType == "movie" {
+			err = s.DownloadSubtitlesForMovie(j.mID)
+		} else {
+			err = s.DownloadSubtitlesForEpisode(j.mID)
+		}
+
+		if err == nil {
+			// Success! Remove from queue (unless the download re-queued itself on quota lock)
+			if !s.IsQuotaLocked() {
+				s.db.Exec("DELETE FROM subtitle_queue WHERE id = $1", j.id)
+				slog.Info("Successfully downloaded subtitles on retry", "media_type", j.mType, "media_id", j.mID)
+			}
+			continue
+		}
+
+		// Check if it was a quota error again
+		if strings.Contains(err.Error(), "406") {
+			// Quota hit again, next_retry was updated by QueueSubtitleDownload called inside DownloadSubtitlesForX
+			slog.Warn("Hit quota again while retrying subtitle download", "media_type", j.mType, "media_id", j.mID)
+			break // Stop processing queue for now
+		}
+
+		// Some other error, increment retry count and back off
+		s.db.Exec("UPDATE subtitle_queue SET retry_count = retry_count + 1, next_retry = CURRENT_TIMESTAMP + interval '1 hour' WHERE id = $1", j.id)
+
+		var retries int
+		s.db.QueryRow("SELECT retry_count FROM subtitle_queue WHERE id = $1", j.id).Scan(&retries)
+		if retries > 5 {
+			slog.Warn("Giving up on subtitles after 5 retries", "media_type", j.mType, "media_id", j.mID, "retries", retries)
+			s.db.Exec("DELETE FROM subtitle_queue WHERE id = $1", j.id)
+		}
+	}
+}
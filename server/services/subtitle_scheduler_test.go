@@ -0,0 +1,117 @@
+package services
+
+import (
+	"Arrgo/config"
+	"testing"
+	"time"
+)
+
+func TestQueueBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining int
+		reserve   int
+		renewsIn  time.Duration
+		want      int
+	}{
+		{"spread over the hours left", 100, 20, 4*time.Hour - time.Minute, 20},
+		{"rounded up", 30, 20, 3*time.Hour - time.Minute, 4},
+		{"last hour spends the rest", 100, 20, 10 * time.Minute, 80},
+		{"renewal already passed", 50, 0, -time.Hour, 50},
+		{"exhausted down to the reserve", 20, 20, 4 * time.Hour, 0},
+		{"below the reserve", 5, 20, 4 * time.Hour, 0},
+		{"exhausted", 0, 0, 4 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SubtitleService{cfg: &config.Config{SubtitleQuotaReserve: tt.reserve}}
+			quota := SubtitleQuota{Remaining: tt.remaining, Limit: 100, RenewsAt: time.Now().Add(tt.renewsIn)}
+			if got := s.queueBudget(quota); got != tt.want {
+				t.Errorf("queueBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordedQuota(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	midnight := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		info *OSDownloadResponse // nil when nothing was recorded yet
+		want SubtitleQuota
+	}{
+		{"nothing recorded", nil, SubtitleQuota{Remaining: defaultDailySubtitleQuota, Limit: defaultDailySubtitleQuota, RenewsAt: midnight}},
+		{"response without a quota", &OSDownloadResponse{Link: "https://example.com/sub"}, SubtitleQuota{Remaining: defaultDailySubtitleQuota, Limit: defaultDailySubtitleQuota, RenewsAt: midnight}},
+		{
+			"reported quota",
+			&OSDownloadResponse{Requests: 95, Remaining: 5, ResetTimeUTC: "2026-10-18T18:30:00Z"},
+			SubtitleQuota{Remaining: 5, Limit: 100, RenewsAt: time.Date(2026, 10, 18, 18, 30, 0, 0, time.UTC), Known: true},
+		},
+		{
+			"used up",
+			&OSDownloadResponse{Requests: 100, ResetTimeUTC: "2026-10-18T18:30:00Z"},
+			SubtitleQuota{Remaining: 0, Limit: 100, RenewsAt: time.Date(2026, 10, 18, 18, 30, 0, 0, time.UTC), Known: true},
+		},
+		{
+			"reset once the renewal time passes",
+			&OSDownloadResponse{Requests: 100, ResetTimeUTC: "2026-10-18T06:00:00Z"},
+			SubtitleQuota{Remaining: 100, Limit: 100, RenewsAt: midnight, Known: true},
+		},
+		{
+			"renews at midnight without a reset time",
+			&OSDownloadResponse{Requests: 10, Remaining: 10},
+			SubtitleQuota{Remaining: 10, Limit: 20, RenewsAt: midnight, Known: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings map[string]string
+			if tt.info != nil {
+				settings = quotaSettings(*tt.info)
+			}
+			got := quotaFromSettings(settings, now)
+			if got.Remaining != tt.want.Remaining || got.Limit != tt.want.Limit || got.Known != tt.want.Known || !got.RenewsAt.Equal(tt.want.RenewsAt) {
+				t.Errorf("quota = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectedCompletion(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	renews := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		queued    int
+		remaining int
+		reserve   int
+		budget    int
+		want      time.Time // zero when there's no projection
+	}{
+		{"empty queue", 0, 100, 20, 20, time.Time{}},
+		{"reserve takes the whole allowance", 10, 100, 100, 0, time.Time{}},
+		{"cleared within today's budget", 10, 100, 20, 20, now.Add(30 * time.Minute)},
+		{"no budget this hour", 5, 100, 20, 0, now.Add(5 * time.Hour)},
+		{"spread until the renewal", 60, 100, 20, 4, renews},
+		{"today's quota is used up", 10, 10, 20, 0, renews.AddDate(0, 0, 1)},
+		{"takes several days", 200, 30, 20, 10, renews.AddDate(0, 0, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &SubtitleQueueStatus{
+				Quota:         SubtitleQuota{Remaining: tt.remaining, Limit: 100, RenewsAt: renews},
+				Reserve:       tt.reserve,
+				Queued:        tt.queued,
+				BudgetThisRun: tt.budget,
+			}
+			got := projectedCompletion(status, now)
+			switch {
+			case tt.want.IsZero() && got != nil:
+				t.Errorf("projectedCompletion() = %v, want nil", *got)
+			case !tt.want.IsZero() && (got == nil || !got.Equal(tt.want)):
+				t.Errorf("projectedCompletion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
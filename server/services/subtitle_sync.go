@@ -0,0 +1,324 @@
+package services
+
+import (
+	"Arrgo/models"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// subSyncResult mirrors the JSON returned by ffsubsync-api's /sync endpoint. Metrics
+// ffsubsync didn't report are nil.
+type subSyncResult struct {
+	Message              string   `json:"message"`
+	Accepted             bool     `json:"accepted"`
+	Reason               string   `json:"reason"`
+	OffsetSeconds        *float64 `json:"offset_seconds"`
+	FramerateScaleFactor *float64 `json:"framerate_scale_factor"`
+	Score                *float64 `json:"score"`
+	Backup               string   `json:"backup"`
+	Reference            string   `json:"reference"`
+}
+
+// syncMetric returns a sync metric for logging, or nil when it's unknown
+func syncMetric(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// syncSubtitleFile sends a subtitle to ffsubsync-api and records the outcome in subtitle_syncs.
+// The timing reference is, in order of preference, another sidecar subtitle of the video that
+// was already synced, an embedded text subtitle track, and the audio. Subtitle references are
+// faster and more accurate than audio; if an alignment fails we fall back to the next one.
+// Syncs that exceed the configured offset/score thresholds are rejected by the API, which
+// leaves the original untouched. Returns nil for rejected syncs and undetectable speech so
+// the caller marks the media as synced and skips it on future runs.
+func (s *SubtitleService) syncSubtitleFile(mediaType string, mediaID int, videoPath, subPath string) error {
+	payload := map[string]interface{}{
+		"video":              videoPath,
+		"subtitle":           subPath,
+		"max_offset_seconds": s.cfg.SubSyncMaxOffset,
+		"min_score":          s.cfg.SubSyncMinScore,
+	}
+
+	if ref, ok := s.findReferenceSidecar(videoPath, subPath); ok {
+		payload["reference"] = ref
+		result, status, err := s.requestSubtitleSync(payload)
+		if err == nil && status == http.StatusOK {
+			return s.applySyncResult(mediaType, mediaID, subPath, result)
+		}
+		slog.Warn("SubSync: Reference subtitle alignment failed, falling back",
+			"media_type", mediaType,
+			"media_id", mediaID,
+			"reference", ref,
+			"status", status,
+			"error", err)
+		delete(payload, "reference")
+	}
+
+	if ref, ok := findReferenceStream(videoPath); ok {
+		payload["reference_stream"] = ref.Index
+		result, status, err := s.requestSubtitleSync(payload)
+		if err == nil && status == http.StatusOK {
+			return s.applySyncResult(mediaType, mediaID, subPath, result)
+		}
+		slog.Warn("SubSync: Reference stream alignment failed, falling back to audio",
+			"media_type", mediaType,
+			"media_id", mediaID,
+			"stream", ref.Index,
+			"language", ref.Language,
+			"status", status,
+			"error", err)
+		delete(payload, "reference_stream")
+	}
+
+	result, status, err := s.requestSubtitleSync(payload)
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		result.Reference = "audio"
+		s.recordSubtitleSync(mediaType, mediaID, subPath, "failed", result)
+		if strings.Contains(result.Message, "Unable to detect speech") {
+			slog.Warn("SubSync: Unable to detect speech, marking as synced to skip future attempts", "media_type", mediaType, "media_id", mediaID, "video", videoPath)
+			return nil
+		}
+		return fmt.Errorf("subsync api returned error (%d): %s", status, result.Message)
+	}
+
+	return s.applySyncResult(mediaType, mediaID, subPath, result)
+}
+
+// requestSubtitleSync calls ffsubsync-api's /sync endpoint. For non-200 responses the
+// returned result carries the response body as its message.
+func (s *SubtitleService) requestSubtitleSync(payload map[string]interface{}) (*subSyncResult, int, error) {
+	body, _ := json.Marshal(payload)
+
+	resp, err := http.Post(s.cfg.SubSyncURL+"/sync", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to call subsync api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &subSyncResult{Message: string(respBody)}, resp.StatusCode, nil
+	}
+
+	var result subSyncResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode subsync response: %w", err)
+	}
+	return &result, resp.StatusCode, nil
+}
+
+// applySyncResult logs and records a completed sync, whether it was applied or rejected
+func (s *SubtitleService) applySyncResult(mediaType string, mediaID int, subPath string, result *subSyncResult) error {
+	if !result.Accepted {
+		slog.Warn("SubSync: Sync rejected, original subtitle kept",
+			"media_type", mediaType,
+			"media_id", mediaID,
+			"subtitle", subPath,
+			"reference", result.Reference,
+			"reason", result.Reason,
+			"offset_seconds", syncMetric(result.OffsetSeconds),
+			"score", syncMetric(result.Score))
+		result.Message = result.Reason
+		s.recordSubtitleSync(mediaType, mediaID, subPath, "rejected", result)
+		return nil
+	}
+
+	slog.Info("SubSync: Sync applied",
+		"media_type", mediaType,
+		"media_id", mediaID,
+		"reference", result.Reference,
+		"offset_seconds", syncMetric(result.OffsetSeconds),
+		"framerate_scale_factor", syncMetric(result.FramerateScaleFactor),
+		"score", syncMetric(result.Score))
+	s.recordSubtitleSync(mediaType, mediaID, subPath, "applied", result)
+	return nil
+}
+
+// referenceSidecarCandidates lists the text subtitles next to a video that could be the timing
+// reference for subPath, English first. Forced subtitles only cover foreign dialogue, and sync
+// backups hold the unsynced original, so neither is a candidate.
+func referenceSidecarCandidates(videoPath, subPath string) []string {
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	matches, err := filepath.Glob(escapeGlob(base) + ".*")
+	if err != nil {
+		return nil
+	}
+
+	var english, other []string
+	for _, path := range matches {
+		if path == subPath || !textSubtitleExts[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		tags := ParseSubtitleTags(path)
+		if tags.Forced {
+			continue
+		}
+		if tags.Language == "" || tags.Language == "en" {
+			english = append(english, path)
+		} else {
+			other = append(other, path)
+		}
+	}
+	return append(english, other...)
+}
+
+// findReferenceSidecar picks another sidecar subtitle of the video whose last sync was applied,
+// so its timing is known to match the video. Its language doesn't matter for alignment.
+func (s *SubtitleService) findReferenceSidecar(videoPath, subPath string) (string, bool) {
+	candidates := referenceSidecarCandidates(videoPath, subPath)
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	rows, err := s.db.Query("SELECT subtitle_path FROM subtitle_syncs WHERE status = 'applied' AND subtitle_path = ANY($1)", candidates)
+	if err != nil {
+		slog.Warn("SubSync: Failed to look up synced subtitles", "video", videoPath, "error", err)
+		return "", false
+	}
+	defer rows.Close()
+
+	synced := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err == nil {
+			synced[path] = true
+		}
+	}
+	for _, path := range candidates {
+		if synced[path] {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// findReferenceStream picks an embedded text subtitle track to align against. Forced tracks
+// only cover foreign dialogue so they are skipped; English tracks are preferred.
+func findReferenceStream(videoPath string) (SubtitleStream, bool) {
+	metadata, err := ProbeVideo(context.Background(), videoPath)
+	if err != nil {
+		return SubtitleStream{}, false
+	}
+
+	var fallback *SubtitleStream
+	for _, stream := range metadata.GetTextSubtitleStreams() {
+		if stream.Forced {
+			continue
+		}
+		lang := strings.ToLower(stream.Language)
+		if lang == "eng" || lang == "en" {
+			return stream, true
+		}
+		if fallback == nil {
+			fallback = &stream
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+	return SubtitleStream{}, false
+}
+
+// recordSubtitleSync upserts the latest sync result for a subtitle file
+func (s *SubtitleService) recordSubtitleSync(mediaType string, mediaID int, subPath, status string, result *subSyncResult) {
+	_, err := s.db.Exec(`
+		INSERT INTO subtitle_syncs (media_type, media_id, subtitle_path, backup_path, status, offset_seconds, framerate_scale_factor, score, message, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (subtitle_path) DO UPDATE SET
+			media_type = $1, media_id = $2,
+			backup_path = COALESCE(NULLIF($4, ''), subtitle_syncs.backup_path),
+			status = $5, offset_seconds = $6, framerate_scale_factor = $7, score = $8, message = $9, reference = $10,
+			updated_at = CURRENT_TIMESTAMP`,
+		mediaType, mediaID, subPath, result.Backup, status, result.OffsetSeconds, result.FramerateScaleFactor, result.Score, result.Message, result.Reference)
+	if err != nil {
+		slog.Error("Failed to record subtitle sync result", "subtitle", subPath, "error", err)
+	}
+}
+
+// GetSubtitleSyncs returns the most recent sync results, newest first
+func (s *SubtitleService) GetSubtitleSyncs(limit int) ([]models.SubtitleSync, error) {
+	query := `
+		SELECT ss.id, ss.media_type, ss.media_id,
+			COALESCE(m.title, sh.title || ' - S' || LPAD(se.season_number::text, 2, '0') || 'E' || LPAD(e.episode_number::text, 2, '0'), ''),
+			ss.subtitle_path, ss.backup_path, ss.status, ss.offset_seconds, ss.framerate_scale_factor, ss.score, ss.message,
+			COALESCE(ss.reference, ''), ss.created_at, ss.updated_at
+		FROM subtitle_syncs ss
+		LEFT JOIN movies m ON ss.media_type = 'movie' AND m.id = ss.media_id
+		LEFT JOIN episodes e ON ss.media_type = 'episode' AND e.id = ss.media_id
+		LEFT JOIN seasons se ON e.season_id = se.id
+		LEFT JOIN shows sh ON se.show_id = sh.id
+		ORDER BY ss.updated_at DESC
+		LIMIT $1
+	`
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtitle syncs: %w", err)
+	}
+	defer rows.Close()
+
+	var syncs []models.SubtitleSync
+	for rows.Next() {
+		var ss models.SubtitleSync
+		var backupPath, message sql.NullString
+		if err := rows.Scan(&ss.ID, &ss.MediaType, &ss.MediaID, &ss.MediaTitle, &ss.SubtitlePath, &backupPath, &ss.Status,
+			&ss.OffsetSeconds, &ss.FramerateScaleFactor, &ss.Score, &message, &ss.Reference, &ss.CreatedAt, &ss.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan subtitle sync: %w", err)
+		}
+		ss.BackupPath = backupPath.String
+		ss.Message = message.String
+		syncs = append(syncs, ss)
+	}
+	return syncs, nil
+}
+
+// RevertSubtitleSync restores the pre-sync original of a subtitle from its backup
+func (s *SubtitleService) RevertSubtitleSync(syncID int) error {
+	var subPath, status string
+	var backupPath sql.NullString
+	err := s.db.QueryRow("SELECT subtitle_path, backup_path, status FROM subtitle_syncs WHERE id = $1", syncID).Scan(&subPath, &backupPath, &status)
+	if err != nil {
+		return fmt.Errorf("subtitle sync not found: %w", err)
+	}
+
+	if status != "applied" {
+		return fmt.Errorf("only applied syncs can be reverted (status: %s)", status)
+	}
+	if backupPath.String == "" {
+		return fmt.Errorf("no backup recorded for %s", subPath)
+	}
+	if _, err := os.Stat(backupPath.String); err != nil {
+		return fmt.Errorf("backup file is missing: %w", err)
+	}
+
+	unlock := lockPath(subPath)
+	defer unlock()
+
+	if err := os.Rename(backupPath.String, subPath); err != nil {
+		return fmt.Errorf("failed to restore original subtitle: %w", err)
+	}
+
+	_, err = s.db.Exec("UPDATE subtitle_syncs SET status = 'reverted', backup_path = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1", syncID)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Reverted subtitle sync to original", "sync_id", syncID, "subtitle", subPath)
+	return nil
+}
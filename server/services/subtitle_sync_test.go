@@ -0,0 +1,48 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReferenceSidecarCandidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		video string   // "Movie.mkv" if empty
+		files []string // the first is the subtitle being synced
+		want  []string
+	}{
+		{"no other subtitles", "", []string{"Movie.en.srt"}, nil},
+		{"other language", "", []string{"Movie.en.srt", "Movie.es.srt"}, []string{"Movie.es.srt"}},
+		{"english before other languages", "", []string{"Movie.en.srt", "Movie.de.srt", "Movie.en.sdh.srt"}, []string{"Movie.en.sdh.srt", "Movie.de.srt"}},
+		{"forced subtitles are skipped", "", []string{"Movie.en.srt", "Movie.en.forced.srt"}, nil},
+		{"sync backups are skipped", "", []string{"Movie.en.srt", "Movie.fr.srt.orig"}, nil},
+		{"image subtitles are skipped", "", []string{"Movie.en.srt", "Movie.es.sub", "Movie.es.idx"}, nil},
+		{"other videos' subtitles are skipped", "", []string{"Movie.en.srt", "Movie 2.es.srt"}, nil},
+		{"glob characters in the name", "Movie [1080p].mkv", []string{"Movie [1080p].en.srt", "Movie [1080p].es.ass"}, []string{"Movie [1080p].es.ass"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			video := tt.video
+			if video == "" {
+				video = "Movie.mkv"
+			}
+			for _, name := range append(tt.files, video) {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []string
+			for _, path := range referenceSidecarCandidates(filepath.Join(dir, video), filepath.Join(dir, tt.files[0])) {
+				got = append(got, filepath.Base(path))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("referenceSidecarCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
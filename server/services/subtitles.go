@@ -55,6 +55,10 @@ func (s *SubtitleService) parseOpenSubtitlesError(resp *http.Response) error {
 	bodyStr := string(body)
 	var osErr OpenSubtitlesError
 
+	if resp.StatusCode == 406 {
+		s.SetSetting(quotaRemainingKey, "0")
+	}
+
 	// Try parsing as JSON first
 	if err := json.Unmarshal(body, &osErr); err == nil {
 		osErr.Status = resp.StatusCode
@@ -109,10 +113,10 @@ func (s *SubtitleService) QueueSubtitleDownload(mediaType string, mediaID int) e
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO subtitle_queue (media_type, media_id, next_retry)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (media_type, media_id) DO UPDATE SET next_retry = $3`,
-		mediaType, mediaID, nextRetry)
+		INSERT INTO subtitle_queue (media_type, media_id, next_retry, priority)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (media_type, media_id) DO UPDATE SET next_retry = $3, priority = GREATEST(subtitle_queue.priority, $4)`,
+		mediaType, mediaID, nextRetry, s.subtitlePriorityFor(mediaType, mediaID))
 	return err
 }
 
@@ -190,8 +194,11 @@ type OSSearchResponse struct {
 }
 
 type OSDownloadResponse struct {
-	Link     string `json:"link"`
-	FileName string `json:"file_name"`
+	Link         string `json:"link"`
+	FileName     string `json:"file_name"`
+	Requests     int    `json:"requests"`
+	Remaining    int    `json:"remaining"`
+	ResetTimeUTC string `json:"reset_time_utc"`
 }
 
 func (s *SubtitleService) getOSToken() (string, string, error) {
@@ -326,7 +333,7 @@ func (s *SubtitleService) DownloadSubtitlesForMovie(movieID int) error {
 		return nil
 	}
 
-	// 2. Find the best match (prioritize hearing impaired if available)
+	// 2. Find the best match (prioritize hearing impaired if available, unless HI is being stripped)
 	var bestMatch *struct {
 		ID         string `json:"id"`
 		Attributes struct {
@@ -341,15 +348,16 @@ func (s *SubtitleService) DownloadSubtitlesForMovie(movieID int) error {
 		} `json:"attributes"`
 	}
 
+	wantHI := !s.cfg.SubtitleRemoveHI
 	for _, d := range searchResult.Data {
-		// If we find one that is hearing impaired, take it immediately if it's the first one or better
-		if d.Attributes.HearingImpaired {
+		// If we find one that matches the HI preference, take it immediately if it's the first one or better
+		if d.Attributes.HearingImpaired == wantHI {
 			bestMatch = &d
 			break
 		}
 	}
 
-	// Fallback to the first one if no hearing impaired found
+	// Fallback to the first one if no preferred match found
 	if bestMatch == nil {
 		bestMatch = &searchResult.Data[0]
 	}
@@ -397,6 +405,7 @@ func (s *SubtitleService) DownloadSubtitlesForMovie(movieID int) error {
 	if err := json.NewDecoder(downloadResp.Body).Decode(&downloadInfo); err != nil {
 		return fmt.Errorf("failed to decode download info: %w", err)
 	}
+	s.recordQuota(downloadInfo)
 
 	// 3. Download the actual file
 	fileResp, err := s.doRequestWithRetry(sharedhttp.DefaultClient, func() (*http.Request, error) {
@@ -427,6 +436,9 @@ func (s *SubtitleService) DownloadSubtitlesForMovie(movieID int) error {
 	if err != nil {
 		return fmt.Errorf("failed to save subtitle file: %w", err)
 	}
+	out.Close()
+
+	destPath = s.cleanupDownloadedSubtitle(destPath, videoPath)
 
 	slog.Info("Successfully downloaded subtitle for movie", "title", title, "dest_path", destPath)
 
@@ -529,7 +541,7 @@ func (s *SubtitleService) DownloadSubtitlesForEpisode(episodeID int) error {
 		return nil
 	}
 
-	// 2. Find the best match (prioritize hearing impaired if available)
+	// 2. Find the best match (prioritize hearing impaired if available, unless HI is being stripped)
 	var bestMatch *struct {
 		ID         string `json:"id"`
 		Attributes struct {
@@ -544,8 +556,9 @@ func (s *SubtitleService) DownloadSubtitlesForEpisode(episodeID int) error {
 		} `json:"attributes"`
 	}
 
+	wantHI := !s.cfg.SubtitleRemoveHI
 	for _, d := range searchResult.Data {
-		if d.Attributes.HearingImpaired {
+		if d.Attributes.HearingImpaired == wantHI {
 			bestMatch = &d
 			break
 		}
@@ -596,6 +609,7 @@ func (s *SubtitleService) DownloadSubtitlesForEpisode(episodeID int) error {
 	if err := json.NewDecoder(downloadResp.Body).Decode(&downloadInfo); err != nil {
 		return fmt.Errorf("failed to decode download info: %w", err)
 	}
+	s.recordQuota(downloadInfo)
 
 	// 3. Download the actual file
 	fileResp, err := s.doRequestWithRetry(sharedhttp.DefaultClient, func() (*http.Request, error) {
@@ -626,6 +640,9 @@ func (s *SubtitleService) DownloadSubtitlesForEpisode(episodeID int) error {
 	if err != nil {
 		return fmt.Errorf("failed to save subtitle file: %w", err)
 	}
+	out.Close()
+
+	destPath = s.cleanupDownloadedSubtitle(destPath, videoPath)
 
 	slog.Info("Successfully downloaded subtitle for episode", "show_title", showTitle, "season", season, "episode", episode, "dest_path", destPath)
 
@@ -674,25 +691,8 @@ func (s *SubtitleService) SyncSubtitlesForMovie(movieID int) error {
 
 	slog.Info("Syncing subtitles for movie", "movie_id", movieID, "video", videoPath, "subtitle", subPath)
 
-	payload, _ := json.Marshal(map[string]string{
-		"video":    videoPath,
-		"subtitle": subPath,
-	})
-
-	resp, err := http.Post(s.cfg.SubSyncURL+"/sync", "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		return fmt.Errorf("failed to call subsync api: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		bodyStr := string(body)
-		if strings.Contains(bodyStr, "Unable to detect speech") {
-			slog.Warn("SubSync: Unable to detect speech, marking as synced to skip future attempts", "movie_id", movieID, "video", videoPath)
-		} else {
-			return fmt.Errorf("subsync api returned error (%d): %s", resp.StatusCode, bodyStr)
-		}
+	if err := s.syncSubtitleFile("movie", movieID, videoPath, subPath); err != nil {
+		return err
 	}
 
 	// Update DB
@@ -736,25 +736,8 @@ func (s *SubtitleService) SyncSubtitlesForEpisode(episodeID int) error {
 
 	slog.Info("Syncing subtitles for episode", "episode_id", episodeID, "video", videoPath, "subtitle", subPath)
 
-	payload, _ := json.Marshal(map[string]string{
-		"video":    videoPath,
-		"subtitle": subPath,
-	})
-
-	resp, err := http.Post(s.cfg.SubSyncURL+"/sync", "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		return fmt.Errorf("failed to call subsync api: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		bodyStr := string(body)
-		if strings.Contains(bodyStr, "Unable to detect speech") {
-			slog.Warn("SubSync: Unable to detect speech, marking as synced to skip future attempts", "episode_id", episodeID, "video", videoPath)
-		} else {
-			return fmt.Errorf("subsync api returned error (%d): %s", resp.StatusCode, bodyStr)
-		}
+	if err := s.syncSubtitleFile("episode", episodeID, videoPath, subPath); err != nil {
+		return err
 	}
 
 	// Update DB
@@ -765,6 +748,26 @@ func (s *SubtitleService) SyncSubtitlesForEpisode(episodeID int) error {
 	return nil
 }
 
+// cleanupDownloadedSubtitle runs a freshly downloaded subtitle through the processing pipeline
+// (encoding, ad removal, optional HI stripping) and returns its final path
+func (s *SubtitleService) cleanupDownloadedSubtitle(subPath, videoPath string) string {
+	destPath, err := ProcessSubtitleFile(subPath, videoPath, s.cfg.SubtitleRemoveHI)
+	if err != nil {
+		slog.Warn("Failed to clean up downloaded subtitle, keeping it as-is", "subtitle", subPath, "error", err)
+		return subPath
+	}
+	if destPath == "" {
+		return subPath
+	}
+
+	// Stripping HI annotations drops the .sdh tag, so the cleaned copy may have a new name
+	if destPath != subPath {
+		os.Remove(subPath)
+	}
+	return destPath
+}
+
+// HasSubtitles reports whether a video has a subtitle next to it, in any recognised format
 func HasSubtitles(videoPath string) bool {
 	if videoPath == "" {
 		return false
@@ -786,13 +789,7 @@ func HasSubtitles(videoPath string) bool {
 		}
 	}
 
-	// Also check for a bare .srt matching the video name (no language tag)
-	bareSrt := base + ".srt"
-	if _, err := os.Stat(bareSrt); err == nil {
-		return true
-	}
-
-	// Check directory for any .srt file matching by name or season/episode pattern
+	// Check directory for any subtitle file matching by name or season/episode pattern
 	dir := filepath.Dir(videoPath)
 	files, err := os.ReadDir(dir)
 	if err == nil {
@@ -806,15 +803,12 @@ func HasSubtitles(videoPath string) bool {
 				continue
 			}
 			name := strings.ToLower(f.Name())
-			if !strings.HasSuffix(name, ".srt") {
+			// .idx is only the index half of a VobSub pair; the .sub is what counts
+			if !IsSubtitleFile(name) || strings.HasSuffix(name, ".idx") {
 				continue
 			}
-			// Match by full video base name
-			if strings.Contains(name, videoBase) {
-				return true
-			}
-			// Match by S##E## pattern (handles mismatched episode titles)
-			if seMatch != "" && strings.Contains(name, seMatch) {
+			// Match by full video base name, or by S##E## pattern (handles mismatched episode titles)
+			if strings.Contains(name, videoBase) || (seMatch != "" && strings.Contains(name, seMatch)) {
 				return true
 			}
 		}
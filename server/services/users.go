@@ -0,0 +1,311 @@
+package services
+
+import (
+	"Arrgo/database"
+	"Arrgo/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Lifetimes of admin-issued links
+const (
+	DefaultInviteTTL = 7 * 24 * time.Hour
+	PasswordResetTTL = 24 * time.Hour
+)
+
+// newLinkToken returns a random token for an invite or reset link, and the hash stored in
+// place of it
+func newLinkToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, hashLinkToken(token), nil
+}
+
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateInvite issues a single-use registration link. roleID 0 gives the new user the default
+// role. Returns the token for the link.
+func CreateInvite(createdBy, roleID int64, ttl time.Duration) (string, error) {
+	token, hash, err := newLinkToken()
+	if err != nil {
+		return "", err
+	}
+	var role sql.NullInt64
+	if roleID != 0 {
+		if err := database.DB.QueryRow("SELECT id FROM roles WHERE id = $1", roleID).Scan(&role); err != nil {
+			return "", fmt.Errorf("role not found: %w", err)
+		}
+	}
+	_, err = database.DB.Exec("INSERT INTO user_invites (token_hash, role_id, created_by, expires_at) VALUES ($1, $2, $3, $4)",
+		hash, role, createdBy, time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to create invite: %w", err)
+	}
+	return token, nil
+}
+
+// GetInvites returns invites that haven't expired, plus those used in the last 30 days
+func GetInvites() ([]models.Invite, error) {
+	rows, err := database.DB.Query(`
+		SELECT i.id, COALESCE(i.role_id, 0), COALESCE(r.name, ''), COALESCE(c.username, ''), COALESCE(u.username, ''),
+			i.expires_at, i.used_at, i.created_at
+		FROM user_invites i
+		LEFT JOIN roles r ON r.id = i.role_id
+		LEFT JOIN users c ON c.id = i.created_by
+		LEFT JOIN users u ON u.id = i.used_by
+		WHERE (i.used_at IS NULL AND i.expires_at > NOW()) OR i.used_at > NOW() - INTERVAL '30 days'
+		ORDER BY i.created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []models.Invite
+	for rows.Next() {
+		var invite models.Invite
+		var usedAt sql.NullTime
+		if err := rows.Scan(&invite.ID, &invite.RoleID, &invite.RoleName, &invite.CreatedBy, &invite.UsedBy,
+			&invite.ExpiresAt, &usedAt, &invite.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		if usedAt.Valid {
+			invite.UsedAt = &usedAt.Time
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// DeleteInvite revokes an invite
+func DeleteInvite(id int64) error {
+	_, err := database.DB.Exec("DELETE FROM user_invites WHERE id = $1", id)
+	return err
+}
+
+// ValidInvite reports whether an invite token can still be used to register
+func ValidInvite(token string) bool {
+	var id int64
+	err := database.DB.QueryRow("SELECT id FROM user_invites WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()",
+		hashLinkToken(token)).Scan(&id)
+	return err == nil
+}
+
+// RegisterUserWithInvite creates an account from an invite link and uses up the invite. The
+// new user gets the role the invite was created with.
+func RegisterUserWithInvite(token, username, email, password string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Claiming the invite first means two people can't register with the same link
+	var inviteID int64
+	var roleID sql.NullInt64
+	err = tx.QueryRow(`
+		UPDATE user_invites SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, role_id`, hashLinkToken(token)).Scan(&inviteID, &roleID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("this invite link is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	err = tx.QueryRow("INSERT INTO users (username, email, password_hash, role_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		username, email, string(hashedPassword), roleID).Scan(&userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("that username or email is already taken")
+		}
+		return nil, fmt.Errorf("failed to register user: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE user_invites SET used_by = $1 WHERE id = $2", userID, inviteID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Registered user from invite", "username", username, "invite_id", inviteID)
+	return GetUserByID(userID)
+}
+
+// CreatePasswordReset issues a single-use password reset link for a user, replacing any
+// earlier unused ones. Returns the token for the link.
+func CreatePasswordReset(userID, createdBy int64) (string, error) {
+	token, hash, err := newLinkToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL", userID); err != nil {
+		return "", err
+	}
+	_, err = tx.Exec("INSERT INTO password_resets (token_hash, user_id, created_by, expires_at) VALUES ($1, $2, $3, $4)",
+		hash, userID, createdBy, time.Now().Add(PasswordResetTTL))
+	if err != nil {
+		return "", fmt.Errorf("failed to create password reset: %w", err)
+	}
+	return token, tx.Commit()
+}
+
+// GetPasswordResetUser returns the user a reset token belongs to, if it's still valid
+func GetPasswordResetUser(token string) (*models.User, error) {
+	var userID int64
+	err := database.DB.QueryRow("SELECT user_id FROM password_resets WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()",
+		hashLinkToken(token)).Scan(&userID)
+	if err != nil {
+		return nil, fmt.Errorf("this reset link is invalid, expired or already used")
+	}
+	return GetUserByID(userID)
+}
+
+// ResetPassword sets a new password using a reset token and uses up the token
+func ResetPassword(token, newPassword string) (*models.User, error) {
+	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRow(`
+		UPDATE password_resets SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, hashLinkToken(token)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("this reset link is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", string(newHash), userID); err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return GetUserByID(userID)
+}
+
+// SetUserDisabled disables or re-enables an account. The last enabled admin can't be disabled.
+func SetUserDisabled(userID int64, disabled bool) error {
+	if disabled {
+		if err := requireOtherAdmin(userID, "AND NOT disabled"); err != nil {
+			return err
+		}
+	}
+	res, err := database.DB.Exec("UPDATE users SET disabled = $1, updated_at = NOW() WHERE id = $2", disabled, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// requireOtherAdmin refuses to proceed when userID is an admin and no other admin matching
+// the extra condition exists, so nobody can lock everyone out of user management
+func requireOtherAdmin(userID int64, condition string) error {
+	var isAdmin bool
+	if err := database.DB.QueryRow("SELECT is_admin FROM users WHERE id = $1", userID).Scan(&isAdmin); err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	if !isAdmin {
+		return nil
+	}
+	var otherAdmins int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM users WHERE is_admin AND id != $1 "+condition, userID).Scan(&otherAdmins); err != nil {
+		return err
+	}
+	if otherAdmins == 0 {
+		return fmt.Errorf("at least one admin is required")
+	}
+	return nil
+}
+
+// DeleteUser removes an account. With reassignTo set, the user's requests move to that user;
+// otherwise their unfinished requests are cancelled (removing any torrents) and all their
+// requests are deleted with the account.
+func DeleteUser(userID, reassignTo int64, qb *QBittorrentClient) error {
+	if err := requireOtherAdmin(userID, "AND NOT disabled"); err != nil {
+		return err
+	}
+
+	if reassignTo != 0 {
+		if reassignTo == userID {
+			return fmt.Errorf("can't reassign requests to the user being deleted")
+		}
+		if _, err := GetUserByID(reassignTo); err != nil {
+			return fmt.Errorf("user to reassign requests to: %w", err)
+		}
+		res, err := database.DB.Exec("UPDATE requests SET user_id = $1, updated_at = NOW() WHERE user_id = $2", reassignTo, userID)
+		if err != nil {
+			return fmt.Errorf("failed to reassign requests: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		slog.Info("Reassigned requests of deleted user", "user_id", userID, "reassigned_to", reassignTo, "count", n)
+	} else {
+		rows, err := database.DB.Query("SELECT id FROM requests WHERE user_id = $1 AND status IN ('awaiting_approval', 'pending', 'downloading')", userID)
+		if err != nil {
+			return fmt.Errorf("failed to query requests: %w", err)
+		}
+		var requestIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				requestIDs = append(requestIDs, id)
+			}
+		}
+		rows.Close()
+		for _, id := range requestIDs {
+			if err := DeleteRequest(id, qb); err != nil {
+				return fmt.Errorf("failed to cancel request %d: %w", id, err)
+			}
+		}
+		slog.Info("Cancelled requests of deleted user", "user_id", userID, "count", len(requestIDs))
+	}
+
+	res, err := database.DB.Exec("DELETE FROM users WHERE id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -15,13 +16,53 @@ type VideoMetadata struct {
 		Tags map[string]string `json:"tags"`
 	} `json:"format"`
 	Streams []struct {
-		CodecType string `json:"codec_type"`
-		CodecName string `json:"codec_name"`
-		Width     int    `json:"width"`
-		Height    int    `json:"height"`
+		CodecType   string            `json:"codec_type"`
+		CodecName   string            `json:"codec_name"`
+		Width       int               `json:"width"`
+		Height      int               `json:"height"`
+		Tags        map[string]string `json:"tags"`
+		Disposition struct {
+			Forced int `json:"forced"`
+		} `json:"disposition"`
 	} `json:"streams"`
 }
 
+// SubtitleStream is an embedded subtitle track. Index counts subtitle streams only,
+// matching ffmpeg's "s:N" stream specifier.
+type SubtitleStream struct {
+	Index    int
+	Codec    string
+	Language string
+	Forced   bool
+}
+
+// textSubtitleCodecs are subtitle codecs with timed text that ffsubsync can use as a reference.
+// Image-based formats (PGS, VobSub, DVB) are excluded.
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "srt": true, "ass": true, "ssa": true, "webvtt": true, "mov_text": true, "text": true,
+}
+
+// GetTextSubtitleStreams returns the embedded text-based subtitle streams
+func (m *VideoMetadata) GetTextSubtitleStreams() []SubtitleStream {
+	var streams []SubtitleStream
+	index := 0
+	for _, stream := range m.Streams {
+		if stream.CodecType != "subtitle" {
+			continue
+		}
+		if textSubtitleCodecs[stream.CodecName] {
+			streams = append(streams, SubtitleStream{
+				Index:    index,
+				Codec:    stream.CodecName,
+				Language: stream.Tags["language"],
+				Forced:   stream.Disposition.Forced == 1,
+			})
+		}
+		index++
+	}
+	return streams
+}
+
 // GetTitle attempts to safely extract the title from tags (case-insensitive keys)
 func (m *VideoMetadata) GetTitle() string {
 	if m.Format.Tags == nil {
@@ -70,6 +111,22 @@ func (m *VideoMetadata) GetQuality() string {
 	return ""
 }
 
+// GetCodec returns the codec of the video stream, named the way GetQuality names it
+func (m *VideoMetadata) GetCodec() string {
+	for _, stream := range m.Streams {
+		if stream.CodecType == "video" {
+			switch stream.CodecName {
+			case "hevc":
+				return "HEVC"
+			case "h264", "avc":
+				return "H264"
+			}
+			return strings.ToUpper(stream.CodecName)
+		}
+	}
+	return ""
+}
+
 // ProbeVideo uses ffprobe to extract metadata and stream information from a media file.
 func ProbeVideo(ctx context.Context, filePath string) (*VideoMetadata, error) {
 	// ffprobe can hang on broken or very large files over network shares, enforce 30s timeout
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"Arrgo/config"
+	"Arrgo/database"
+	"Arrgo/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadMediaPathIndex maps library file paths to their movie or episode IDs
+func loadMediaPathIndex() (map[string]int, map[string]int, error) {
+	movies := make(map[string]int)
+	episodes := make(map[string]int)
+
+	load := func(query string, dest map[string]int) error {
+		rows, err := database.DB.Query(query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			var path string
+			if err := rows.Scan(&id, &path); err != nil {
+				return err
+			}
+			dest[path] = id
+		}
+		return rows.Err()
+	}
+
+	if err := load("SELECT id, path FROM movies", movies); err != nil {
+		return nil, nil, fmt.Errorf("failed to load movie paths: %w", err)
+	}
+	if err := load("SELECT id, file_path FROM episodes", episodes); err != nil {
+		return nil, nil, fmt.Errorf("failed to load episode paths: %w", err)
+	}
+	return movies, episodes, nil
+}
+
+// SyncWatchState imports played state and play counts for every Arrgo user that has a media
+// server account with the same username. The media server is the source of truth, so each
+// user's rows are replaced wholesale; items unmarked as played there disappear here too.
+func SyncWatchState(cfg *config.Config) error {
+	server := GetMediaServer(cfg)
+	if server == nil {
+		return fmt.Errorf("no media server configured")
+	}
+
+	serverUsers, err := server.GetUsers()
+	if err != nil {
+		return fmt.Errorf("failed to get %s users: %w", server.Name(), err)
+	}
+	serverIDs := make(map[string]string)
+	for _, u := range serverUsers {
+		serverIDs[strings.ToLower(u.Name)] = u.ID
+	}
+
+	users, err := GetAllUsers()
+	if err != nil {
+		return fmt.Errorf("failed to get arrgo users: %w", err)
+	}
+
+	moviePaths, episodePaths, err := loadMediaPathIndex()
+	if err != nil {
+		return err
+	}
+
+	synced := 0
+	for _, user := range users {
+		serverID, ok := serverIDs[strings.ToLower(user.Username)]
+		if !ok {
+			continue
+		}
+
+		items, err := server.GetPlayedItems(serverID)
+		if err != nil {
+			slog.Error("Failed to fetch watch state", "server", server.Name(), "username", user.Username, "error", err)
+			continue
+		}
+
+		if err := replaceWatchState(int(user.ID), items, moviePaths, episodePaths); err != nil {
+			slog.Error("Failed to store watch state", "username", user.Username, "error", err)
+			continue
+		}
+		synced++
+
+		if cfg.AutoRequestSeasons {
+			autoRequestNextSeasons(int(user.ID))
+		}
+	}
+
+	slog.Info("Watch state sync complete", "server", server.Name(), "users", synced)
+	return nil
+}
+
+// replaceWatchState swaps a user's stored watch state for the given played items
+func replaceWatchState(userID int, items []PlayedItem, moviePaths, episodePaths map[string]int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM watch_state WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		mediaType := "movie"
+		mediaID, ok := moviePaths[item.Path]
+		if !ok {
+			mediaType = "episode"
+			if mediaID, ok = episodePaths[item.Path]; !ok {
+				continue
+			}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO watch_state (user_id, media_type, media_id, played, play_count, last_played_at, updated_at)
+			VALUES ($1, $2, $3, TRUE, $4, $5, CURRENT_TIMESTAMP)
+			ON CONFLICT (user_id, media_type, media_id) DO NOTHING`,
+			userID, mediaType, mediaID, item.PlayCount, item.LastPlayed)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetMovieWatchState returns the user's watch state for a movie, or nil if they haven't watched it
+func GetMovieWatchState(userID, movieID int) (*models.WatchState, error) {
+	ws := models.WatchState{UserID: userID, MediaType: "movie", MediaID: movieID}
+	var lastPlayed sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT played, play_count, last_played_at FROM watch_state
+		WHERE user_id = $1 AND media_type = 'movie' AND media_id = $2`, userID, movieID).Scan(&ws.Played, &ws.PlayCount, &lastPlayed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastPlayed.Valid {
+		ws.LastPlayedAt = &lastPlayed.Time
+	}
+	return &ws, nil
+}
+
+// GetShowWatchStates returns the user's watch state for each episode of a show, keyed by episode ID
+func GetShowWatchStates(userID, showID int) (map[int]models.WatchState, error) {
+	rows, err := database.DB.Query(`
+		SELECT ws.media_id, ws.played, ws.play_count, ws.last_played_at
+		FROM watch_state ws
+		JOIN episodes e ON e.id = ws.media_id
+		JOIN seasons se ON se.id = e.season_id
+		WHERE ws.user_id = $1 AND ws.media_type = 'episode' AND se.show_id = $2`, userID, showID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int]models.WatchState)
+	for rows.Next() {
+		ws := models.WatchState{UserID: userID, MediaType: "episode"}
+		var lastPlayed sql.NullTime
+		if err := rows.Scan(&ws.MediaID, &ws.Played, &ws.PlayCount, &lastPlayed); err != nil {
+			return nil, err
+		}
+		if lastPlayed.Valid {
+			ws.LastPlayedAt = &lastPlayed.Time
+		}
+		states[ws.MediaID] = ws
+	}
+	return states, rows.Err()
+}
+
+// GetContinueWatching returns, for each show the user has been watching, the first library
+// episode after the furthest one they've played that they haven't seen yet. Shows are ordered
+// by how recently the user watched them.
+func GetContinueWatching(userID, limit int) ([]models.SeriesSuggestion, error) {
+	query := `
+		WITH last_watched AS (
+			SELECT DISTINCT ON (se.show_id) se.show_id, se.season_number, e.episode_number,
+				MAX(ws.last_played_at) OVER (PARTITION BY se.show_id) AS last_played_at
+			FROM watch_state ws
+			JOIN episodes e ON ws.media_type = 'episode' AND e.id = ws.media_id
+			JOIN seasons se ON se.id = e.season_id
+			WHERE ws.user_id = $1 AND ws.played AND se.season_number > 0
+			ORDER BY se.show_id, se.season_number DESC, e.episode_number DESC
+		), next_up AS (
+			SELECT DISTINCT ON (lw.show_id) sh.id AS show_id, sh.title, COALESCE(sh.poster_path, '') AS poster_path,
+				e.id AS episode_id, se.season_number, e.episode_number, COALESCE(e.title, '') AS episode_title, lw.last_played_at
+			FROM last_watched lw
+			JOIN shows sh ON sh.id = lw.show_id
+			JOIN seasons se ON se.show_id = lw.show_id
+			JOIN episodes e ON e.season_id = se.id
+			LEFT JOIN watch_state ws ON ws.user_id = $1 AND ws.media_type = 'episode' AND ws.media_id = e.id AND ws.played
+			WHERE (se.season_number, e.episode_number) > (lw.season_number, lw.episode_number)
+				AND ws.id IS NULL
+			ORDER BY lw.show_id, se.season_number, e.episode_number
+		)
+		SELECT show_id, title, poster_path, episode_id, season_number, episode_number, episode_title, last_played_at
+		FROM next_up
+		ORDER BY last_played_at DESC NULLS LAST
+		LIMIT $2
+	`
+	rows, err := database.DB.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query continue watching: %w", err)
+	}
+	defer rows.Close()
+
+	var suggestions []models.SeriesSuggestion
+	for rows.Next() {
+		var s models.SeriesSuggestion
+		var lastPlayed sql.NullTime
+		if err := rows.Scan(&s.ShowID, &s.ShowTitle, &s.PosterPath, &s.EpisodeID, &s.SeasonNumber, &s.EpisodeNumber, &s.EpisodeTitle, &lastPlayed); err != nil {
+			return nil, fmt.Errorf("failed to scan continue watching: %w", err)
+		}
+		if lastPlayed.Valid {
+			s.LastPlayedAt = &lastPlayed.Time
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// autoRequestNextSeasons requests the following season of any show where the user has
+// played every aired episode of a season. Each season is only ever auto-requested once,
+// no matter how many users finish the previous one.
+func autoRequestNextSeasons(userID int) {
+	if globalMetadata == nil || globalMetadata.cfg.TVDBAPIKey == "" {
+		return
+	}
+
+	// Seasons where every library episode has been played by this user, and the next
+	// season hasn't been auto-requested yet
+	rows, err := database.DB.Query(`
+		SELECT se.show_id, se.season_number, COUNT(DISTINCT e.episode_number)
+		FROM seasons se
+		JOIN episodes e ON e.season_id = se.id
+		LEFT JOIN watch_state ws ON ws.user_id = $1 AND ws.media_type = 'episode' AND ws.media_id = e.id AND ws.played
+		WHERE se.season_number > 0
+			AND NOT EXISTS (SELECT 1 FROM season_auto_requests sar WHERE sar.show_id = se.show_id AND sar.season_number = se.season_number + 1)
+		GROUP BY se.show_id, se.season_number
+		HAVING COUNT(DISTINCT e.episode_number) = COUNT(DISTINCT e.episode_number) FILTER (WHERE ws.id IS NOT NULL)`, userID)
+	if err != nil {
+		slog.Error("Failed to find finished seasons", "user_id", userID, "error", err)
+		return
+	}
+
+	type finishedSeason struct {
+		showID, season, played int
+	}
+	var finished []finishedSeason
+	for rows.Next() {
+		var f finishedSeason
+		if err := rows.Scan(&f.showID, &f.season, &f.played); err == nil {
+			finished = append(finished, f)
+		}
+	}
+	rows.Close()
+
+	today := time.Now().Format("2006-01-02")
+	for _, f := range finished {
+		show, err := GetShowByID(f.showID)
+		if err != nil || show.TVDBID == "" {
+			continue
+		}
+
+		// Any episodes of the next season in the library means it's already here or on its way.
+		// Seasons that are only requested are fine to pass on, CreateRequest merges them.
+		var nextInLibrary bool
+		err = database.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM seasons se JOIN episodes e ON e.season_id = se.id
+			WHERE se.show_id = $1 AND se.season_number = $2)`, f.showID, f.season+1).Scan(&nextInLibrary)
+		if err != nil || nextInLibrary {
+			continue
+		}
+
+		episodes, err := globalMetadata.GetTVDBShowEpisodes(show.TVDBID)
+		if err != nil {
+			slog.Warn("Failed to fetch TVDB episodes for next season check", "show", show.Title, "error", err)
+			continue
+		}
+
+		airedInSeason, nextSeasonAired := 0, false
+		for _, ep := range episodes {
+			if ep.Aired == "" || ep.Aired > today {
+				continue
+			}
+			switch ep.SeasonNumber {
+			case f.season:
+				airedInSeason++
+			case f.season + 1:
+				nextSeasonAired = true
+			}
+		}
+
+		// The user is only done with the season if they've seen everything that has aired
+		if f.played < airedInSeason || !nextSeasonAired {
+			continue
+		}
+
+		// Claim the season first so concurrent syncs can't request it twice
+		res, err := database.DB.Exec(`
+			INSERT INTO season_auto_requests (show_id, season_number, user_id) VALUES ($1, $2, $3)
+			ON CONFLICT (show_id, season_number) DO NOTHING`, f.showID, f.season+1, userID)
+		if err != nil {
+			slog.Error("Failed to record season auto-request", "show", show.Title, "season", f.season+1, "error", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		_, err = CreateRequest(models.Request{
+			UserID:     userID,
+			Title:      show.Title,
+			MediaType:  "show",
+			TMDBID:     show.TMDBID,
+			TVDBID:     show.TVDBID,
+			IMDBID:     show.IMDBID,
+			Year:       show.Year,
+			PosterPath: show.PosterPath,
+			Overview:   show.Overview,
+			Seasons:    strconv.Itoa(f.season + 1),
+		})
+		if err != nil {
+			slog.Error("Failed to auto-request next season", "show", show.Title, "season", f.season+1, "error", err)
+			database.DB.Exec("DELETE FROM season_auto_requests WHERE show_id = $1 AND season_number = $2", f.showID, f.season+1)
+			continue
+		}
+		slog.Info("Auto-requested next season after user finished the previous one",
+			"show", show.Title, "season", f.season+1, "user_id", userID)
+	}
+}
+
+// StartWatchStateSyncWorker periodically imports watch state from the media server
+func StartWatchStateSyncWorker(cfg *config.Config) {
+	server := GetMediaServer(cfg)
+	if server == nil {
+		return
+	}
+
+	slog.Info("Starting watch state sync background worker", "server", server.Name())
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := SyncWatchState(cfg); err != nil {
+				slog.Error("Error during watch state sync", "error", err)
+			}
+		}
+	}()
+}
@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 // GetEnv gets an environment variable or returns a default value
 func GetEnv(key, defaultValue string) string {
@@ -10,6 +13,28 @@ func GetEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// GetEnvFloat gets an environment variable as a float or returns a default value
+// if it is unset or not a valid number
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvInt gets an environment variable as an int or returns a default value
+// if it is unset or not a valid number
+func GetEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
 // GetEnvRequired gets an environment variable and panics if not set
 func GetEnvRequired(key string) string {
 	value := os.Getenv(key)
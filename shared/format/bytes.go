@@ -1,6 +1,14 @@
 package format
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*([KMGTPE]?)i?B\b`)
 
 // Bytes formats bytes into human-readable format (KB, MB, GB, TB, etc.)
 func Bytes(b int64) string {
@@ -21,3 +29,23 @@ func Bytes(b int64) string {
 
 	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
+
+// ParseBytes converts a size string like "1.50 GB" or "700 MiB" back to bytes.
+// Returns 0 if the string can't be parsed.
+func ParseBytes(s string) int64 {
+	matches := sizePattern.FindStringSubmatch(strings.ReplaceAll(s, ",", ""))
+	if len(matches) != 3 {
+		return 0
+	}
+
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	exp := strings.Index("KMGTPE", strings.ToUpper(matches[2]))
+	if matches[2] == "" {
+		exp = -1
+	}
+	return int64(value * math.Pow(1024, float64(exp+1)))
+}